@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"expense-api/admin"
+	"expense-api/database"
+)
+
+const adminUsage = `usage: expense-api admin <command>
+
+commands:
+  create-user NAME                   add an API user and print its token
+  recompute-balances [--dry-run]     set account balances to opening balance plus transactions
+  verify                             check migrations and transaction references`
+
+// runAdmin handles the admin subcommand
+func runAdmin(args []string) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+
+	fs := flag.NewFlagSet("admin "+args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "only print the balances that would change")
+	if err := fs.Parse(args[1:]); err != nil {
+		return errors.New(adminUsage)
+	}
+
+	switch {
+	case args[0] == "create-user" && fs.NArg() == 1 && !*dryRun:
+		db, err := connectMigrated()
+		if err != nil {
+			return err
+		}
+		user, token, err := admin.CreateUser(db, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created user %q with id %d\n", user.Name, user.ID)
+		fmt.Printf("token: %s\n", token)
+		fmt.Println("the token is not stored and cannot be shown again")
+		return nil
+
+	case args[0] == "recompute-balances" && fs.NArg() == 0:
+		db, err := connectMigrated()
+		if err != nil {
+			return err
+		}
+		changes, err := admin.RecomputeBalances(db, *dryRun)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Println("all balances are up to date")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tACCOUNT\tSTORED\tCOMPUTED")
+		for _, change := range changes {
+			fmt.Fprintf(w, "%d\t%s\t%.2f\t%.2f\n", change.AccountID, change.Name, change.Stored, change.Computed)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if *dryRun {
+			fmt.Printf("%d balance(s) would change\n", len(changes))
+		} else {
+			fmt.Printf("updated %d balance(s)\n", len(changes))
+		}
+		return nil
+
+	case args[0] == "verify" && fs.NArg() == 0 && !*dryRun:
+		database.Connect()
+		issues, err := admin.Verify(database.GetDB())
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		if len(issues) > 0 {
+			return fmt.Errorf("verify found %d issue(s)", len(issues))
+		}
+		fmt.Println("no issues found")
+		return nil
+	}
+	return errors.New(adminUsage)
+}
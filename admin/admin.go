@@ -0,0 +1,212 @@
+// Package admin implements the maintenance tasks of the admin command.
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"expense-api/database/migrations"
+	"expense-api/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateUser stores a user with a new API token. The token is only returned
+// here; the database keeps its hash.
+func CreateUser(db *gorm.DB, name string) (models.User, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.User{}, "", errors.New("user name is required")
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return models.User{}, "", err
+	}
+	if count > 0 {
+		return models.User{}, "", fmt.Errorf("a user named %q already exists", name)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return models.User{}, "", err
+	}
+	token := hex.EncodeToString(secret)
+
+	user := models.User{Name: name, TokenHash: HashToken(token)}
+	if err := db.Create(&user).Error; err != nil {
+		return models.User{}, "", err
+	}
+	return user, token, nil
+}
+
+// HashToken returns the value stored for an API token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// BalanceChange is a bank account whose stored balance differs from its transactions
+type BalanceChange struct {
+	AccountID uint
+	Name      string
+	Stored    float64
+	Computed  float64
+}
+
+// RecomputeBalances sets the balance of every bank account to its opening
+// balance plus the net of its transactions: income and incoming transfers
+// minus expenses and outgoing transfers. It returns the accounts whose
+// balance changed; with dryRun nothing is written.
+func RecomputeBalances(db *gorm.DB, dryRun bool) ([]BalanceChange, error) {
+	var changes []BalanceChange
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var accounts []models.BankAccount
+		if err := tx.Order("id").Find(&accounts).Error; err != nil {
+			return err
+		}
+
+		var transactions []models.Transaction
+		if err := tx.Select("type", "amount", "bank_account_id", "destination_bank_account_id").Find(&transactions).Error; err != nil {
+			return err
+		}
+		net := map[uint]float64{}
+		for _, transaction := range transactions {
+			switch transaction.Type {
+			case "income":
+				net[transaction.BankAccountID] += transaction.Amount
+			case "expense":
+				net[transaction.BankAccountID] -= transaction.Amount
+			case "transfer":
+				net[transaction.BankAccountID] -= transaction.Amount
+				if transaction.DestinationBankAccountID != nil {
+					net[*transaction.DestinationBankAccountID] += transaction.Amount
+				}
+			}
+		}
+
+		for _, account := range accounts {
+			computed := math.Round((account.OpeningBalance+net[account.ID])*100) / 100
+			if computed == math.Round(account.Balance*100)/100 {
+				continue
+			}
+			changes = append(changes, BalanceChange{AccountID: account.ID, Name: account.Name, Stored: account.Balance, Computed: computed})
+			if dryRun {
+				continue
+			}
+			if err := tx.Model(&account).Update("balance", computed).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// Issue is an inconsistency found by Verify
+type Issue struct {
+	Check  string
+	Detail string
+}
+
+func (i Issue) String() string {
+	return i.Check + ": " + i.Detail
+}
+
+// Verify checks that every migration is applied and that transactions refer
+// to accounts and categories that fit their type. It returns the issues found.
+func Verify(db *gorm.DB) ([]Issue, error) {
+	var issues []Issue
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := migrator.Status()
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		switch {
+		case status.Unknown:
+			issues = append(issues, Issue{"migrations", fmt.Sprintf("%d_%s is applied but not part of this build", status.Version, status.Name)})
+		case !status.Applied:
+			issues = append(issues, Issue{"migrations", fmt.Sprintf("%d_%s is not applied", status.Version, status.Name)})
+		}
+	}
+	if len(issues) > 0 {
+		// The checks below rely on the current schema
+		return issues, nil
+	}
+
+	var accounts []models.BankAccount
+	if err := db.Unscoped().Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	accountIDs := map[uint]bool{}
+	for _, account := range accounts {
+		accountIDs[account.ID] = true
+	}
+
+	var categories []models.Category
+	if err := db.Unscoped().Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	categoryTypes := map[uint]string{}
+	for _, category := range categories {
+		categoryTypes[category.ID] = category.Type
+	}
+
+	var transactions []models.Transaction
+	if err := db.Order("id").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	for _, transaction := range transactions {
+		problem := func(format string, args ...interface{}) {
+			issues = append(issues, Issue{"transactions", fmt.Sprintf("transaction %d: ", transaction.ID) + fmt.Sprintf(format, args...)})
+		}
+
+		if !accountIDs[transaction.BankAccountID] {
+			problem("bank account %d does not exist", transaction.BankAccountID)
+		}
+		if transaction.Amount <= 0 {
+			problem("amount %.2f is not positive", transaction.Amount)
+		}
+
+		switch transaction.Type {
+		case "transfer":
+			if transaction.CategoryID != nil {
+				problem("transfer has category %d", *transaction.CategoryID)
+			}
+			switch {
+			case transaction.DestinationBankAccountID == nil:
+				problem("transfer has no destination account")
+			case *transaction.DestinationBankAccountID == transaction.BankAccountID:
+				problem("transfer goes to its own account")
+			case !accountIDs[*transaction.DestinationBankAccountID]:
+				problem("destination bank account %d does not exist", *transaction.DestinationBankAccountID)
+			}
+		default:
+			if transaction.DestinationBankAccountID != nil {
+				problem("%s has destination account %d", transaction.Type, *transaction.DestinationBankAccountID)
+			}
+			if transaction.CategoryID == nil {
+				problem("%s has no category", transaction.Type)
+				break
+			}
+			categoryType, ok := categoryTypes[*transaction.CategoryID]
+			switch {
+			case !ok:
+				problem("category %d does not exist", *transaction.CategoryID)
+			case categoryType != transaction.Type:
+				problem("%s has %s category %d", transaction.Type, categoryType, *transaction.CategoryID)
+			}
+		}
+	}
+	return issues, nil
+}
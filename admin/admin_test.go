@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"testing"
+	"time"
+
+	"expense-api/database/migrations"
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	migrator, err := migrations.New(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	return db
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+// seed adds three accounts, an expense and an income category, and a transaction of each type
+func seed(t *testing.T, db *gorm.DB) {
+	assert.NoError(t, db.Create(&[]models.BankAccount{
+		{Name: "Checking", BankName: "Test Bank", AccountType: "checking", Balance: 1000, OpeningBalance: 1000, IsActive: true},
+		{Name: "Savings", BankName: "Test Bank", AccountType: "savings", Balance: 5000, OpeningBalance: 5000, IsActive: true},
+		{Name: "Cash", BankName: "Wallet", AccountType: "other", Balance: 50, OpeningBalance: 50, IsActive: true},
+	}).Error)
+	assert.NoError(t, db.Create(&[]models.Category{{Name: "Food", Type: "expense"}, {Name: "Salary", Type: "income"}}).Error)
+
+	date := models.FlexibleDate{Time: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	assert.NoError(t, db.Create(&[]models.Transaction{
+		{Amount: 3000, Type: "income", CategoryID: uintPtr(2), BankAccountID: 1, Description: "Salary", Date: date},
+		{Amount: 12.5, Type: "expense", CategoryID: uintPtr(1), BankAccountID: 1, Description: "Lunch", Date: date},
+		{Amount: 200, Type: "transfer", BankAccountID: 1, DestinationBankAccountID: uintPtr(2), Description: "Savings", Date: date},
+	}).Error)
+}
+
+func TestCreateUser(t *testing.T) {
+	db := setupTestDB(t)
+
+	user, token, err := CreateUser(db, " alice ")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", user.Name)
+	assert.Len(t, token, 64)
+
+	// Only the hash of the token is stored
+	var stored models.User
+	assert.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Equal(t, HashToken(token), stored.TokenHash)
+	assert.NotEqual(t, token, stored.TokenHash)
+
+	_, _, err = CreateUser(db, "alice")
+	assert.ErrorContains(t, err, "already exists")
+	_, _, err = CreateUser(db, "  ")
+	assert.Error(t, err)
+}
+
+func TestRecomputeBalances(t *testing.T) {
+	db := setupTestDB(t)
+	seed(t, db)
+
+	changes, err := RecomputeBalances(db, true)
+	assert.NoError(t, err)
+	// Cash has no transactions, so it keeps its opening balance
+	assert.Equal(t, []BalanceChange{
+		{AccountID: 1, Name: "Checking", Stored: 1000, Computed: 3787.5},
+		{AccountID: 2, Name: "Savings", Stored: 5000, Computed: 5200},
+	}, changes)
+
+	// A dry run writes nothing
+	var account models.BankAccount
+	assert.NoError(t, db.First(&account, 1).Error)
+	assert.Equal(t, 1000.0, account.Balance)
+
+	changes, err = RecomputeBalances(db, false)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.NoError(t, db.First(&account, 1).Error)
+	assert.Equal(t, 3787.5, account.Balance)
+
+	// Deleted transactions do not count
+	assert.NoError(t, db.Delete(&models.Transaction{}, 2).Error)
+	changes, err = RecomputeBalances(db, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []BalanceChange{{AccountID: 1, Name: "Checking", Stored: 3787.5, Computed: 3800}}, changes)
+}
+
+func TestVerify(t *testing.T) {
+	db := setupTestDB(t)
+	seed(t, db)
+
+	issues, err := Verify(db)
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	// Break the data behind the services' back
+	assert.NoError(t, db.Exec("UPDATE transactions SET category_id = 2 WHERE id = 2").Error)
+	assert.NoError(t, db.Exec("UPDATE transactions SET destination_bank_account_id = 9 WHERE id = 3").Error)
+	assert.NoError(t, db.Exec("UPDATE transactions SET category_id = NULL WHERE id = 1").Error)
+
+	issues, err = Verify(db)
+	assert.NoError(t, err)
+	assert.Equal(t, []Issue{
+		{"transactions", "transaction 1: income has no category"},
+		{"transactions", "transaction 2: expense has income category 2"},
+		{"transactions", "transaction 3: destination bank account 9 does not exist"},
+	}, issues)
+
+	// Pending migrations are reported on their own
+	migrator, err := migrations.New(db)
+	assert.NoError(t, err)
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+	issues, err = Verify(db)
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, "migrations", issues[0].Check)
+		assert.Contains(t, issues[0].Detail, "is not applied")
+	}
+}
@@ -0,0 +1,194 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"expense-api/logging"
+	"expense-api/models"
+	"expense-api/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// Stable error codes returned in the code field of problem responses
+const (
+	CodeInvalidBody                = "INVALID_REQUEST_BODY"
+	CodeInvalidID                  = "INVALID_ID"
+	CodeInvalidDate                = "INVALID_DATE"
+	CodeInvalidParameter           = "INVALID_PARAMETER"
+	CodeValidationFailed           = "VALIDATION_FAILED"
+	CodeInternal                   = "INTERNAL_ERROR"
+	CodeDatabaseUnavailable        = "DATABASE_UNAVAILABLE"
+	CodeTransactionNotFound        = "TRANSACTION_NOT_FOUND"
+	CodeTransactionNotDeleted      = "TRANSACTION_NOT_DELETED"
+	CodeTransactionVersionNotFound = "TRANSACTION_VERSION_NOT_FOUND"
+	CodeCategoryNotFound           = "CATEGORY_NOT_FOUND"
+	CodeCategoryRequired           = "CATEGORY_REQUIRED"
+	CodeCategoryTypeMismatch       = "CATEGORY_TYPE_MISMATCH"
+	CodeCategoryNameTaken          = "CATEGORY_NAME_TAKEN"
+	CodeCategoryInUse              = "CATEGORY_IN_USE"
+	CodeCategoryHasChildren        = "CATEGORY_HAS_CHILDREN"
+	CodeCategoryCycle              = "CATEGORY_CYCLE"
+	CodePayeeNotFound              = "PAYEE_NOT_FOUND"
+	CodePayeeNameTaken             = "PAYEE_NAME_TAKEN"
+	CodePayeeAliasTaken            = "PAYEE_ALIAS_TAKEN"
+	CodeSubscriptionNotFound       = "SUBSCRIPTION_NOT_FOUND"
+	CodeSubscriptionScheduled      = "SUBSCRIPTION_ALREADY_SCHEDULED"
+	CodeRecurringNotFound          = "RECURRING_TRANSACTION_NOT_FOUND"
+	CodeBankAccountNotFound        = "BANK_ACCOUNT_NOT_FOUND"
+	CodeBankAccountInUse           = "BANK_ACCOUNT_IN_USE"
+	CodeDestinationAccountRequired = "DESTINATION_ACCOUNT_REQUIRED"
+	CodeDestinationAccountNotFound = "DESTINATION_ACCOUNT_NOT_FOUND"
+	CodeSameAccountTransfer        = "SAME_ACCOUNT_TRANSFER"
+	CodeWebhookNotFound            = "WEBHOOK_NOT_FOUND"
+	CodeInvalidFile                = "INVALID_FILE"
+	CodeAsyncUnavailable           = "ASYNC_UNAVAILABLE"
+	CodeJobNotFound                = "JOB_NOT_FOUND"
+	CodeJobNotCancellable          = "JOB_NOT_CANCELLABLE"
+	CodeJobNotRetryable            = "JOB_NOT_RETRYABLE"
+	CodeIdempotencyKeyReused       = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyKeyInProgress   = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeRateLimited                = "RATE_LIMITED"
+	CodeBodyTooLarge               = "REQUEST_ENTITY_TOO_LARGE"
+)
+
+// Error is an API error with an HTTP status and a stable machine-readable code
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []models.FieldError
+	Err    error // Underlying cause, logged but never returned to clients
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	}
+	return e.Code + ": " + e.Detail
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error with the given status, code and detail
+func New(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// BadRequest creates a 400 error
+func BadRequest(code, detail string) *Error {
+	return New(fiber.StatusBadRequest, code, detail)
+}
+
+// NotFound creates a 404 error
+func NotFound(code, detail string) *Error {
+	return New(fiber.StatusNotFound, code, detail)
+}
+
+// Conflict creates a 409 error
+func Conflict(code, detail string) *Error {
+	return New(fiber.StatusConflict, code, detail)
+}
+
+// TooManyRequests creates the 429 error returned when a client exceeds its rate limit
+func TooManyRequests(detail string) *Error {
+	return New(fiber.StatusTooManyRequests, CodeRateLimited, detail)
+}
+
+// BodyTooLarge creates the 413 error returned for request bodies over limit bytes
+func BodyTooLarge(limit int) *Error {
+	return New(fiber.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("Request body must be at most %d bytes", limit))
+}
+
+// Internal creates a 500 error that keeps the cause for logging
+func Internal(detail string, err error) *Error {
+	return &Error{Status: fiber.StatusInternalServerError, Code: CodeInternal, Detail: detail, Err: err}
+}
+
+// Unavailable creates a 503 error for when the database is not ready
+func Unavailable() *Error {
+	return New(fiber.StatusServiceUnavailable, CodeDatabaseUnavailable, "Database not ready")
+}
+
+// InvalidBody creates the error returned when a request body cannot be parsed
+func InvalidBody(err error) *Error {
+	return &Error{Status: fiber.StatusBadRequest, Code: CodeInvalidBody, Detail: "Request body is not valid JSON", Err: err}
+}
+
+// Validation wraps a validation failure so every invalid field is returned
+func Validation(err error) *Error {
+	return &Error{
+		Status: fiber.StatusBadRequest,
+		Code:   CodeValidationFailed,
+		Detail: "Validation failed",
+		Fields: validation.Fields(err),
+		Err:    err,
+	}
+}
+
+// Lookup returns notFound when a query found no record, and an internal error for any other failure
+func Lookup(err error, notFound *Error) *Error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		notFound.Err = err
+		return notFound
+	}
+	return Internal("Failed to query the database", err)
+}
+
+// Problem converts any error returned by a handler into an RFC 7807 problem
+func Problem(c *fiber.Ctx, err error) models.Problem {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			apiErr = New(fiberErr.Code, codeForStatus(fiberErr.Code), fiberErr.Message)
+		} else {
+			apiErr = Internal("An unexpected error occurred", err)
+		}
+	}
+
+	return models.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Code:      apiErr.Code,
+		Detail:    apiErr.Detail,
+		Instance:  c.OriginalURL(),
+		RequestID: c.GetRespHeader(fiber.HeaderXRequestID),
+		Errors:    apiErr.Fields,
+	}
+}
+
+// Handler is the Fiber error handler that renders every error as problem+json
+func Handler(c *fiber.Ctx, err error) error {
+	problem := Problem(c, err)
+
+	if problem.Status >= fiber.StatusInternalServerError {
+		logging.For(logging.ComponentHTTP).ErrorContext(c.UserContext(), "request failed",
+			"method", c.Method(), "path", c.OriginalURL(), "status", problem.Status, "error", err)
+	}
+
+	c.Status(problem.Status)
+	c.Set(fiber.HeaderContentType, ProblemContentType)
+	return c.JSON(problem, ProblemContentType)
+}
+
+// codeForStatus derives a code such as NOT_FOUND from an HTTP status
+func codeForStatus(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInternal
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
@@ -0,0 +1,58 @@
+package apperrors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"expense-api/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestLookupDistinguishesNotFoundFromFailures(t *testing.T) {
+	notFound := Lookup(gorm.ErrRecordNotFound, NotFound(CodeTransactionNotFound, "Transaction not found"))
+	assert.Equal(t, 404, notFound.Status)
+	assert.Equal(t, CodeTransactionNotFound, notFound.Code)
+
+	failed := Lookup(errors.New("connection reset"), NotFound(CodeTransactionNotFound, "Transaction not found"))
+	assert.Equal(t, 500, failed.Status)
+	assert.Equal(t, CodeInternal, failed.Code)
+}
+
+func TestHandlerRendersProblems(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	app.Get("/internal", func(c *fiber.Ctx) error {
+		return errors.New("pq: password authentication failed")
+	})
+	app.Get("/fiber", func(c *fiber.Ctx) error {
+		return fiber.ErrMethodNotAllowed
+	})
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{path: "/internal", expectedStatus: 500, expectedCode: CodeInternal},
+		{path: "/fiber", expectedStatus: 405, expectedCode: "METHOD_NOT_ALLOWED"},
+		{path: "/missing", expectedStatus: 404, expectedCode: "NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		assert.Equal(t, ProblemContentType, resp.Header.Get("Content-Type"))
+
+		var problem models.Problem
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		assert.Equal(t, tt.expectedCode, problem.Code)
+		assert.Equal(t, "about:blank", problem.Type)
+		// Causes of internal errors are logged, never returned
+		assert.NotContains(t, problem.Detail, "password")
+	}
+}
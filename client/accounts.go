@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"expense-api/models"
+)
+
+// BankAccountInput is the body for creating or updating a bank account.
+// On update, empty and zero fields are kept, except IsActive which is always applied.
+type BankAccountInput struct {
+	Name           string  `json:"name,omitempty"`
+	AccountNumber  string  `json:"account_number,omitempty"`
+	BankName       string  `json:"bank_name,omitempty"`
+	AccountType    string  `json:"account_type,omitempty"`
+	Balance        float64 `json:"balance,omitempty"`
+	OpeningBalance float64 `json:"opening_balance,omitempty"`
+	IsActive       bool    `json:"is_active"`
+}
+
+// CreateBankAccount calls POST /api/bank-accounts
+func (c *Client) CreateBankAccount(ctx context.Context, input BankAccountInput) (*models.BankAccountResponse, error) {
+	var account models.BankAccountResponse
+	if err := c.do(ctx, "POST", "/api/bank-accounts", nil, input, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListBankAccounts calls GET /api/bank-accounts, including deactivated accounts when includeInactive is set
+func (c *Client) ListBankAccounts(ctx context.Context, includeInactive bool) ([]models.BankAccountResponse, error) {
+	var query url.Values
+	if includeInactive {
+		query = url.Values{"include_inactive": {"true"}}
+	}
+	var accounts []models.BankAccountResponse
+	if err := c.do(ctx, "GET", "/api/bank-accounts", query, nil, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetBankAccount calls GET /api/bank-accounts/:id
+func (c *Client) GetBankAccount(ctx context.Context, id uint) (*models.BankAccountResponse, error) {
+	var account models.BankAccountResponse
+	if err := c.do(ctx, "GET", idPath("/api/bank-accounts", id, ""), nil, nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// UpdateBankAccount calls PUT /api/bank-accounts/:id
+func (c *Client) UpdateBankAccount(ctx context.Context, id uint, input BankAccountInput) (*models.BankAccountResponse, error) {
+	var account models.BankAccountResponse
+	if err := c.do(ctx, "PUT", idPath("/api/bank-accounts", id, ""), nil, input, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// DeleteBankAccount calls DELETE /api/bank-accounts/:id
+func (c *Client) DeleteBankAccount(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", idPath("/api/bank-accounts", id, ""), nil, nil, nil)
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"expense-api/models"
+	"expense-api/openapi"
+)
+
+// CreateCategory calls POST /api/categories; categoryType is "expense" or "income"
+func (c *Client) CreateCategory(ctx context.Context, name, categoryType string) (*models.CategoryResponse, error) {
+	var category models.CategoryResponse
+	body := models.CategoryResponse{Name: name, Type: categoryType}
+	if err := c.do(ctx, "POST", "/api/categories", nil, body, &category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// ListCategories calls GET /api/categories
+func (c *Client) ListCategories(ctx context.Context) ([]models.CategoryResponse, error) {
+	var categories []models.CategoryResponse
+	if err := c.do(ctx, "GET", "/api/categories", nil, nil, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// CategoryTree calls GET /api/categories?tree=true
+func (c *Client) CategoryTree(ctx context.Context) ([]models.CategoryTreeNode, error) {
+	var tree []models.CategoryTreeNode
+	if err := c.do(ctx, "GET", "/api/categories", url.Values{"tree": {"true"}}, nil, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// GetCategory calls GET /api/categories/:id
+func (c *Client) GetCategory(ctx context.Context, id uint) (*models.CategoryResponse, error) {
+	var category models.CategoryResponse
+	if err := c.do(ctx, "GET", idPath("/api/categories", id, ""), nil, nil, &category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// UpdateCategory calls PUT /api/categories/:id; empty fields of update are kept
+func (c *Client) UpdateCategory(ctx context.Context, id uint, update openapi.CategoryUpdate) (*models.CategoryResponse, error) {
+	var category models.CategoryResponse
+	if err := c.do(ctx, "PUT", idPath("/api/categories", id, ""), nil, update, &category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// MoveCategory calls POST /api/categories/:id/move; a nil parentID moves the category to the top level
+func (c *Client) MoveCategory(ctx context.Context, id uint, parentID *uint) (*models.CategoryResponse, error) {
+	var category models.CategoryResponse
+	body := models.CategoryMoveRequest{ParentID: parentID}
+	if err := c.do(ctx, "POST", idPath("/api/categories", id, "/move"), nil, body, &category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// MergeCategory calls POST /api/categories/:id/merge, moving the category's transactions and subcategories to targetID
+func (c *Client) MergeCategory(ctx context.Context, id, targetID uint) (*models.CategoryMergeResponse, error) {
+	var result models.CategoryMergeResponse
+	body := models.CategoryMergeRequest{TargetID: targetID}
+	if err := c.do(ctx, "POST", idPath("/api/categories", id, "/merge"), nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteCategory calls DELETE /api/categories/:id
+func (c *Client) DeleteCategory(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", idPath("/api/categories", id, ""), nil, nil, nil)
+}
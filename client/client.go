@@ -0,0 +1,252 @@
+// Package client is a typed Go client for the expense API.
+//
+// Failed requests are retried with exponential backoff when the server
+// answers with a 5xx status, e.g. 503 while its database is not ready, or
+// cannot be reached. POST requests carry an Idempotency-Key so a retry never
+// creates a second record.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+)
+
+// Client calls the expense API
+type Client struct {
+	// BaseURL is the server address, e.g. http://localhost:8080
+	BaseURL string
+	// Token is sent as a bearer token when set
+	Token      string
+	HTTPClient *http.Client
+	// MaxRetries is how often a failed request is retried; 0 disables retries
+	MaxRetries int
+	// MinRetryWait and MaxRetryWait bound the backoff between attempts
+	MinRetryWait time.Duration
+	MaxRetryWait time.Duration
+}
+
+// New creates a client for the server at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		MaxRetries:   3,
+		MinRetryWait: 250 * time.Millisecond,
+		MaxRetryWait: 5 * time.Second,
+	}
+}
+
+var (
+	// ErrBadRequest matches errors for invalid requests (400)
+	ErrBadRequest = errors.New("bad request")
+	// ErrNotFound matches errors for missing resources (404)
+	ErrNotFound = errors.New("not found")
+	// ErrConflict matches errors for requests that conflict with the current state (409)
+	ErrConflict = errors.New("conflict")
+	// ErrRateLimited matches errors for requests over the client's rate limit (429)
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNotReady matches errors returned while the server's database is not ready (503)
+	ErrNotReady = errors.New("database not ready")
+	// ErrServer matches every 5xx error
+	ErrServer = errors.New("server error")
+)
+
+// Error is an error response returned by the API. Use errors.Is with
+// ErrNotFound and the other sentinels to check its kind, or Code for the
+// exact reason.
+type Error struct {
+	StatusCode int
+	models.Problem
+	// RetryAfter is the wait the server asked for, if any
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	message := e.Detail
+	if message == "" {
+		message = e.Title
+	}
+	if e.Code == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, message)
+}
+
+// Is reports whether the error is of the kind of a sentinel such as ErrNotFound
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrNotReady:
+		return e.StatusCode == http.StatusServiceUnavailable && e.Code == apperrors.CodeDatabaseUnavailable
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+type idempotencyKeyContext struct{}
+
+// WithIdempotencyKey sets the Idempotency-Key sent with POST requests made with ctx.
+// By default every call gets a random key, which protects its own retries;
+// a key of your own also protects retries of the whole call, e.g. after a restart.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContext{}, key)
+}
+
+// do sends a request with a JSON body, if any, and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var payload []byte
+	contentType := ""
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	return c.send(ctx, method, path, query, payload, contentType, out)
+}
+
+// send sends a request, retrying it while it fails with a retryable error.
+// Error responses with a plain JSON body, such as a bulk request where every
+// item failed, are decoded into out as well as returned as an *Error.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body []byte, contentType string, out interface{}) error {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if method == http.MethodPost {
+		key, _ := ctx.Value(idempotencyKeyContext{}).(string)
+		if key == "" {
+			key = newIdempotencyKey()
+		}
+		header.Set("Idempotency-Key", key)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, target, header, body, out)
+		if err == nil || attempt >= c.MaxRetries || !retryable(ctx, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.backoff(attempt, err)):
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, target string, header http.Header, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode < 400 || (mediaType == "application/json" && out != nil) {
+		if out != nil && len(data) > 0 {
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("decode %s %s response: %w", method, req.URL.Path, err)
+			}
+		}
+		if resp.StatusCode < 400 {
+			return nil
+		}
+	}
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if mediaType != apperrors.ProblemContentType || json.Unmarshal(data, &apiErr.Problem) != nil {
+		apiErr.Problem = models.Problem{Status: resp.StatusCode, Title: http.StatusText(resp.StatusCode)}
+		if mediaType != "application/json" {
+			apiErr.Detail = strings.TrimSpace(string(data))
+		}
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// retryable reports whether a failed attempt may succeed when sent again
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		// A retry can arrive while the server still works on the first attempt,
+		// and a rate limited request succeeds once the bucket refills
+		return apiErr.StatusCode >= 500 || apiErr.Code == apperrors.CodeIdempotencyKeyInProgress ||
+			apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	// The server could not be reached or the connection broke
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// backoff is the wait before retry attempt+1: exponential with jitter, or what the server asked for
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, c.MaxRetryWait)
+	}
+	wait := float64(c.MinRetryWait) * math.Pow(2, float64(attempt))
+	wait = math.Min(wait, float64(c.MaxRetryWait))
+	return time.Duration(wait/2 + mathrand.Float64()*wait/2)
+}
+
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+func idPath(prefix string, id uint, suffix string) string {
+	return prefix + "/" + strconv.FormatUint(uint64(id), 10) + suffix
+}
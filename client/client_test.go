@@ -0,0 +1,380 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/client"
+	"expense-api/container"
+	"expense-api/database/migrations"
+	"expense-api/health"
+	"expense-api/models"
+	"expense-api/openapi"
+	"expense-api/ratelimit"
+	"expense-api/server"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testServer serves the real routes over a migrated in-memory database
+type testServer struct {
+	*httptest.Server
+	// unavailable is how many more requests see the database as not ready
+	unavailable atomic.Int32
+	// authorization is the Authorization header of the last request
+	authorization atomic.Value
+}
+
+func startServer(t *testing.T) *testServer {
+	return startServerWith(t, func(*container.Container) {})
+}
+
+// startServerWith lets configure change the container before the routes are registered
+func startServerWith(t *testing.T, configure func(*container.Container)) *testServer {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	migrator, err := migrations.New(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	ts := &testServer{}
+	provider := func() *gorm.DB {
+		if ts.unavailable.Add(-1) >= 0 {
+			return nil
+		}
+		ts.unavailable.Store(0)
+		return db
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperrors.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		ts.authorization.Store(c.Get(fiber.HeaderAuthorization))
+		return c.Next()
+	})
+	deps := container.New(provider)
+	configure(deps)
+	server.RegisterRoutes(app, deps)
+
+	ts.Server = httptest.NewServer(adaptor.FiberApp(app))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newClient(ts *testServer) *client.Client {
+	c := client.New(ts.URL)
+	c.MinRetryWait = time.Millisecond
+	c.MaxRetryWait = 5 * time.Millisecond
+	return c
+}
+
+func TestTransactionsAccountsAndCategories(t *testing.T) {
+	ctx := context.Background()
+	api := newClient(startServer(t))
+
+	checking, err := api.CreateBankAccount(ctx, client.BankAccountInput{Name: "Checking", BankName: "Test Bank", AccountType: "checking", Balance: 1000, IsActive: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, checking.OpeningBalance)
+	savings, err := api.CreateBankAccount(ctx, client.BankAccountInput{Name: "Savings", BankName: "Test Bank", AccountType: "savings", IsActive: true})
+	assert.NoError(t, err)
+	food, err := api.CreateCategory(ctx, "Food", "expense")
+	assert.NoError(t, err)
+	salary, err := api.CreateCategory(ctx, "Salary", "income")
+	assert.NoError(t, err)
+
+	lunch, err := api.CreateTransaction(ctx, client.TransactionInput{Amount: 12.5, Type: "expense", CategoryID: &food.ID, BankAccountID: checking.ID, Description: "Lunch", Date: "2024-01-15"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Food", lunch.Category)
+
+	bulk, err := api.CreateTransactions(ctx, []client.TransactionInput{
+		{Amount: 3000, Type: "income", CategoryID: &salary.ID, BankAccountID: checking.ID, Description: "Salary", Date: "2024-01-31"},
+		{Amount: 5, Type: "expense", CategoryID: &salary.ID, BankAccountID: checking.ID, Description: "Wrong category"},
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, bulk.SuccessCount)
+	if assert.Len(t, bulk.Failed, 1) {
+		assert.Equal(t, apperrors.CodeCategoryTypeMismatch, bulk.Failed[0].Code)
+	}
+
+	// Rows that all fail come back along with an error
+	bulk, err = api.CreateTransactions(ctx, []client.TransactionInput{
+		{Amount: 5, Type: "expense", CategoryID: &salary.ID, BankAccountID: checking.ID, Description: "Wrong category"},
+	}, true)
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	if assert.NotNil(t, bulk) {
+		assert.Equal(t, 1, bulk.FailedCount)
+	}
+
+	transfer, err := api.CreateTransfer(ctx, client.TransferInput{Amount: 200, BankAccountID: checking.ID, DestinationBankAccountID: savings.ID, Description: "To savings", Date: "2024-02-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Savings", transfer.DestinationBankAccount.Name)
+
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	listed, err := api.ListTransactions(ctx, client.TransactionFilter{From: from, To: to})
+	assert.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	transfers, err := api.ListTransfers(ctx, client.TransactionFilter{BankAccountID: savings.ID})
+	assert.NoError(t, err)
+	assert.Len(t, transfers, 1)
+
+	incomes, err := api.ListTransactionsByDateRange(ctx, from, to, "income")
+	assert.NoError(t, err)
+	if assert.Len(t, incomes, 1) {
+		assert.Equal(t, "Salary", incomes[0].Description)
+	}
+
+	table, err := api.AggregateTable(ctx, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, 2987.5, table.Summary.NetAmount)
+
+	aggregate, err := api.Aggregate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3000.0, aggregate.TotalIncome)
+
+	summary, err := api.Summary(ctx)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, summary.Overview.TotalTransactions)
+
+	account, err := api.GetBankAccount(ctx, checking.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Checking", account.Name)
+
+	// Updates, history and restoring a version
+	description := "Team lunch"
+	updated, err := api.UpdateTransaction(ctx, lunch.ID, client.TransactionUpdate{Description: &description})
+	assert.NoError(t, err)
+	assert.Equal(t, description, updated.Description)
+
+	history, err := api.TransactionHistory(ctx, lunch.ID)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	restored, err := api.RestoreTransaction(ctx, lunch.ID, history[0].Version)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lunch", restored.Description)
+
+	// Deleting, the trash and undeleting
+	assert.NoError(t, api.DeleteTransaction(ctx, lunch.ID))
+	_, err = api.GetTransaction(ctx, lunch.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	trash, err := api.ListDeletedTransactions(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+
+	undeleted, err := api.UndeleteTransaction(ctx, lunch.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, lunch.ID, undeleted.ID)
+
+	// Categories
+	renamed, err := api.UpdateCategory(ctx, food.ID, openapi.CategoryUpdate{Name: "Groceries"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Groceries", renamed.Name)
+
+	recategorized, err := api.UpdateTransactionCategory(ctx, lunch.ID, food.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Groceries", recategorized.Category)
+
+	err = api.DeleteCategory(ctx, food.ID)
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	categories, err := api.ListCategories(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, categories, 2)
+
+	// Payees are matched from the description and bring their default category
+	cafe, err := api.CreatePayee(ctx, models.PayeeRequest{Name: "Corner Cafe", DefaultCategoryID: &food.ID, Aliases: []string{"corner cafe*"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CORNER CAFE*"}, cafe.Aliases)
+	coffee, err := api.CreateTransaction(ctx, client.TransactionInput{Amount: 4, Type: "expense", BankAccountID: checking.ID, Description: "CORNER CAFE #12", Date: "2024-01-20"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Corner Cafe", coffee.Payee)
+	assert.Equal(t, "Groceries", coffee.Category)
+
+	report, err := api.PayeeReport(ctx, from, to, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 16.5, report.TotalAmount)
+	if assert.Len(t, report.Payees, 1) {
+		assert.Equal(t, cafe.ID, report.Payees[0].PayeeID)
+	}
+
+	// One coffee is not a subscription
+	subscriptions, err := api.Subscriptions(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, subscriptions.Subscriptions)
+	_, err = api.ScheduleSubscription(ctx, "payee:1")
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	schedules, err := api.ListRecurringTransactions(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, schedules)
+	anomalies, err := api.Anomalies(ctx, from, to)
+	assert.NoError(t, err)
+	assert.Empty(t, anomalies.Anomalies)
+
+	// Bank accounts
+	_, err = api.UpdateBankAccount(ctx, savings.ID, client.BankAccountInput{IsActive: false})
+	assert.NoError(t, err)
+	active, err := api.ListBankAccounts(ctx, false)
+	assert.NoError(t, err)
+	assert.Len(t, active, 1)
+	all, err := api.ListBankAccounts(ctx, true)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	deleted, err := api.DeleteTransactions(ctx, []uint{lunch.ID, 999}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{lunch.ID}, deleted.Deleted)
+	assert.Equal(t, 1, deleted.FailedCount)
+}
+
+func TestTypedErrors(t *testing.T) {
+	ctx := context.Background()
+	api := newClient(startServer(t))
+
+	_, err := api.GetCategory(ctx, 42)
+	var apiErr *client.Error
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, apperrors.CodeCategoryNotFound, apiErr.Code)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	assert.NotErrorIs(t, err, client.ErrServer)
+
+	_, err = api.CreateBankAccount(ctx, client.BankAccountInput{Name: "Broken", BankName: "Test Bank", AccountType: "piggy-bank"})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apperrors.CodeValidationFailed, apiErr.Code)
+	assert.NotEmpty(t, apiErr.Errors)
+}
+
+func TestRetriesWhileDatabaseIsNotReady(t *testing.T) {
+	ctx := context.Background()
+	ts := startServer(t)
+	api := newClient(ts)
+
+	// The first two attempts are answered with 503
+	ts.unavailable.Store(2)
+	category, err := api.CreateCategory(ctx, "Food", "expense")
+	assert.NoError(t, err)
+	assert.Equal(t, "Food", category.Name)
+
+	// Without retries the error is returned
+	api.MaxRetries = 0
+	ts.unavailable.Store(1)
+	_, err = api.ListCategories(ctx)
+	assert.ErrorIs(t, err, client.ErrNotReady)
+	assert.ErrorIs(t, err, client.ErrServer)
+
+	// Retries stop when the context ends
+	api.MaxRetries = 100
+	api.MinRetryWait, api.MaxRetryWait = time.Hour, time.Hour
+	ts.unavailable.Store(1)
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = api.ListCategories(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetriesWhenRateLimited(t *testing.T) {
+	ctx := context.Background()
+	ts := startServerWith(t, func(deps *container.Container) {
+		deps.RateLimiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[ratelimit.Class]ratelimit.Limit{
+			ratelimit.ClassRead: {Requests: 1, Period: 20 * time.Millisecond},
+		})
+	})
+	api := newClient(ts)
+	api.MaxRetries = 0
+
+	_, err := api.ListCategories(ctx)
+	assert.NoError(t, err)
+	_, err = api.ListCategories(ctx)
+	assert.ErrorIs(t, err, client.ErrRateLimited)
+	var apiErr *client.Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, apperrors.CodeRateLimited, apiErr.Code)
+		assert.Equal(t, time.Second, apiErr.RetryAfter)
+	}
+
+	// Retrying waits for the bucket to refill
+	api.MaxRetries = 3
+	api.MaxRetryWait = 50 * time.Millisecond
+	_, err = api.ListCategories(ctx)
+	assert.NoError(t, err)
+}
+
+// dropFirstResponse sends every request but loses the first response, as a broken connection would
+type dropFirstResponse struct {
+	dropped atomic.Bool
+}
+
+func (d *dropFirstResponse) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil && d.dropped.CompareAndSwap(false, true) {
+		resp.Body.Close()
+		return nil, errors.New("connection reset")
+	}
+	return resp, err
+}
+
+func TestRetriedPostIsNotDuplicated(t *testing.T) {
+	ctx := context.Background()
+	api := newClient(startServer(t))
+	api.HTTPClient = &http.Client{Transport: &dropFirstResponse{}}
+
+	account, err := api.CreateBankAccount(ctx, client.BankAccountInput{Name: "Checking", BankName: "Test Bank", AccountType: "checking", IsActive: true})
+	assert.NoError(t, err)
+
+	accounts, err := api.ListBankAccounts(ctx, true)
+	assert.NoError(t, err)
+	if assert.Len(t, accounts, 1) {
+		assert.Equal(t, account.ID, accounts[0].ID)
+	}
+}
+
+func TestToken(t *testing.T) {
+	ts := startServer(t)
+	api := newClient(ts)
+
+	_, err := api.Health(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "", ts.authorization.Load())
+
+	api.Token = "secret"
+	status, err := api.Health(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "healthy", status.Status)
+	assert.Equal(t, "Bearer secret", ts.authorization.Load())
+}
+
+func TestHealthProbes(t *testing.T) {
+	ctx := context.Background()
+	api := newClient(startServer(t))
+	api.MaxRetries = 0
+
+	report, err := api.Liveness(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, health.StatusOK, report.Status)
+
+	// The test server never starts its job workers
+	report, err = api.Readiness(ctx)
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.NotErrorIs(t, err, client.ErrNotReady)
+	if assert.NotNil(t, report) {
+		assert.Equal(t, health.StatusFail, report.Status)
+		assert.Len(t, report.Checks, 4)
+	}
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+
+	"expense-api/health"
+	"expense-api/openapi"
+)
+
+// Health calls GET /health, which answers even while the database is not ready
+func (c *Client) Health(ctx context.Context) (*openapi.HealthStatus, error) {
+	return c.health(ctx, "/health")
+}
+
+// DBStatus calls GET /db-status
+func (c *Client) DBStatus(ctx context.Context) (*openapi.HealthStatus, error) {
+	return c.health(ctx, "/db-status")
+}
+
+func (c *Client) health(ctx context.Context, path string) (*openapi.HealthStatus, error) {
+	var status openapi.HealthStatus
+	if err := c.do(ctx, "GET", path, nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Liveness calls GET /livez
+func (c *Client) Liveness(ctx context.Context) (*health.Report, error) {
+	return c.probe(ctx, "/livez")
+}
+
+// Readiness calls GET /readyz. When a check fails the report is returned
+// together with an error matching ErrServer.
+func (c *Client) Readiness(ctx context.Context) (*health.Report, error) {
+	return c.probe(ctx, "/readyz")
+}
+
+func (c *Client) probe(ctx context.Context, path string) (*health.Report, error) {
+	var report health.Report
+	err := c.do(ctx, "GET", path, nil, nil, &report)
+	if err != nil && report.Status == "" {
+		return nil, err
+	}
+	return &report, err
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"expense-api/models"
+)
+
+// Subscriptions calls GET /api/insights/subscriptions. A nil active returns
+// every subscription, otherwise only the active or the stopped ones.
+func (c *Client) Subscriptions(ctx context.Context, active *bool) (*models.SubscriptionsResponse, error) {
+	var query url.Values
+	if active != nil {
+		query = url.Values{"active": {strconv.FormatBool(*active)}}
+	}
+
+	var subscriptions models.SubscriptionsResponse
+	if err := c.do(ctx, "GET", "/api/insights/subscriptions", query, nil, &subscriptions); err != nil {
+		return nil, err
+	}
+	return &subscriptions, nil
+}
+
+// ScheduleSubscription calls POST /api/insights/subscriptions/schedule, creating
+// a recurring transaction from the subscription with the given key
+func (c *Client) ScheduleSubscription(ctx context.Context, key string) (*models.RecurringTransactionResponse, error) {
+	var schedule models.RecurringTransactionResponse
+	body := models.SubscriptionScheduleRequest{Key: key}
+	if err := c.do(ctx, "POST", "/api/insights/subscriptions/schedule", nil, body, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListRecurringTransactions calls GET /api/recurring
+func (c *Client) ListRecurringTransactions(ctx context.Context) ([]models.RecurringTransactionResponse, error) {
+	var schedules []models.RecurringTransactionResponse
+	if err := c.do(ctx, "GET", "/api/recurring", nil, nil, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// GetRecurringTransaction calls GET /api/recurring/:id
+func (c *Client) GetRecurringTransaction(ctx context.Context, id uint) (*models.RecurringTransactionResponse, error) {
+	var schedule models.RecurringTransactionResponse
+	if err := c.do(ctx, "GET", idPath("/api/recurring", id, ""), nil, nil, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// DeleteRecurringTransaction calls DELETE /api/recurring/:id
+func (c *Client) DeleteRecurringTransaction(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", idPath("/api/recurring", id, ""), nil, nil, nil)
+}
+
+// Anomalies calls GET /api/insights/anomalies for the expenses dated from and to, inclusive
+func (c *Client) Anomalies(ctx context.Context, from, to time.Time) (*models.AnomaliesResponse, error) {
+	var anomalies models.AnomaliesResponse
+	if err := c.do(ctx, "GET", "/api/insights/anomalies", dateRange(from, to), nil, &anomalies); err != nil {
+		return nil, err
+	}
+	return &anomalies, nil
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+
+	"expense-api/models"
+)
+
+// GetJob calls GET /api/jobs/:id
+func (c *Client) GetJob(ctx context.Context, id uint) (*models.JobResponse, error) {
+	return c.job(ctx, "GET", idPath("/api/jobs", id, ""))
+}
+
+// CancelJob calls POST /api/jobs/:id/cancel
+func (c *Client) CancelJob(ctx context.Context, id uint) (*models.JobResponse, error) {
+	return c.job(ctx, "POST", idPath("/api/jobs", id, "/cancel"))
+}
+
+// RetryJob calls POST /api/jobs/:id/retry
+func (c *Client) RetryJob(ctx context.Context, id uint) (*models.JobResponse, error) {
+	return c.job(ctx, "POST", idPath("/api/jobs", id, "/retry"))
+}
+
+func (c *Client) job(ctx context.Context, method, path string) (*models.JobResponse, error) {
+	var job models.JobResponse
+	if err := c.do(ctx, method, path, nil, nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
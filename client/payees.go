@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"expense-api/models"
+)
+
+// CreatePayee calls POST /api/payees
+func (c *Client) CreatePayee(ctx context.Context, payee models.PayeeRequest) (*models.PayeeResponse, error) {
+	var created models.PayeeResponse
+	if err := c.do(ctx, "POST", "/api/payees", nil, payee, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListPayees calls GET /api/payees
+func (c *Client) ListPayees(ctx context.Context) ([]models.PayeeResponse, error) {
+	var payees []models.PayeeResponse
+	if err := c.do(ctx, "GET", "/api/payees", nil, nil, &payees); err != nil {
+		return nil, err
+	}
+	return payees, nil
+}
+
+// GetPayee calls GET /api/payees/:id
+func (c *Client) GetPayee(ctx context.Context, id uint) (*models.PayeeResponse, error) {
+	var payee models.PayeeResponse
+	if err := c.do(ctx, "GET", idPath("/api/payees", id, ""), nil, nil, &payee); err != nil {
+		return nil, err
+	}
+	return &payee, nil
+}
+
+// UpdatePayee calls PUT /api/payees/:id, replacing the name, default category and aliases
+func (c *Client) UpdatePayee(ctx context.Context, id uint, payee models.PayeeRequest) (*models.PayeeResponse, error) {
+	var updated models.PayeeResponse
+	if err := c.do(ctx, "PUT", idPath("/api/payees", id, ""), nil, payee, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// MergePayee calls POST /api/payees/:id/merge, moving the payee's transactions and aliases to targetID
+func (c *Client) MergePayee(ctx context.Context, id, targetID uint) (*models.PayeeMergeResponse, error) {
+	var result models.PayeeMergeResponse
+	body := models.PayeeMergeRequest{TargetID: targetID}
+	if err := c.do(ctx, "POST", idPath("/api/payees", id, "/merge"), nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeletePayee calls DELETE /api/payees/:id
+func (c *Client) DeletePayee(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", idPath("/api/payees", id, ""), nil, nil, nil)
+}
+
+// PayeeReport calls GET /api/reports/payees for the expenses dated from and to, inclusive.
+// A limit of 0 returns every payee.
+func (c *Client) PayeeReport(ctx context.Context, from, to time.Time, limit int) (*models.PayeeReportResponse, error) {
+	var report models.PayeeReportResponse
+	query := dateRange(from, to)
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if err := c.do(ctx, "GET", "/api/reports/payees", query, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"strconv"
+	"time"
+
+	"expense-api/models"
+	"expense-api/openapi"
+)
+
+// TransactionInput is the body for creating a transaction
+type TransactionInput struct {
+	TransactionID            string  `json:"transaction_id,omitempty"`
+	Amount                   float64 `json:"amount"`
+	Type                     string  `json:"type"`
+	CategoryID               *uint   `json:"category_id,omitempty"`
+	BankAccountID            uint    `json:"bank_account_id"`
+	DestinationBankAccountID *uint   `json:"destination_bank_account_id,omitempty"`
+	PayeeID                  *uint   `json:"payee_id,omitempty"` // Matched from the description when nil
+	Description              string  `json:"description"`
+	Date                     string  `json:"date,omitempty"` // YYYY-MM-DD; the server uses today when empty
+}
+
+// TransactionUpdate lists the fields to change on a transaction; nil fields are kept
+type TransactionUpdate struct {
+	TransactionID *string  `json:"transaction_id,omitempty"`
+	Amount        *float64 `json:"amount,omitempty"`
+	Type          *string  `json:"type,omitempty"`
+	CategoryID    *uint    `json:"category_id,omitempty"`
+	PayeeID       *uint    `json:"payee_id,omitempty"`
+	Description   *string  `json:"description,omitempty"`
+	Date          *string  `json:"date,omitempty"` // YYYY-MM-DD
+}
+
+// TransferInput is the body for creating a transfer
+type TransferInput struct {
+	TransactionID            string  `json:"transaction_id,omitempty"`
+	Amount                   float64 `json:"amount"`
+	BankAccountID            uint    `json:"bank_account_id"`
+	DestinationBankAccountID uint    `json:"destination_bank_account_id"`
+	Description              string  `json:"description"`
+	Date                     string  `json:"date,omitempty"` // YYYY-MM-DD; the server uses today when empty
+}
+
+// TransactionFilter narrows ListTransactions and ListTransfers; zero fields are not applied
+type TransactionFilter struct {
+	Type          string // Ignored by ListTransfers
+	BankAccountID uint
+	From          time.Time
+	To            time.Time
+}
+
+func (f TransactionFilter) query() url.Values {
+	query := url.Values{}
+	if f.Type != "" {
+		query.Set("type", f.Type)
+	}
+	if f.BankAccountID != 0 {
+		query.Set("bank_account_id", strconv.FormatUint(uint64(f.BankAccountID), 10))
+	}
+	if !f.From.IsZero() {
+		query.Set("start_date", f.From.Format("2006-01-02"))
+	}
+	if !f.To.IsZero() {
+		query.Set("end_date", f.To.Format("2006-01-02"))
+	}
+	return query
+}
+
+func dateRange(from, to time.Time) url.Values {
+	return url.Values{"start_date": {from.Format("2006-01-02")}, "end_date": {to.Format("2006-01-02")}}
+}
+
+func atomicQuery(atomic bool) url.Values {
+	if !atomic {
+		return nil
+	}
+	return url.Values{"atomic": {"true"}}
+}
+
+// CreateTransaction calls POST /api/transactions
+func (c *Client) CreateTransaction(ctx context.Context, input TransactionInput) (*models.TransactionResponse, error) {
+	var transaction models.TransactionResponse
+	if err := c.do(ctx, "POST", "/api/transactions", nil, input, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// CreateTransactions calls POST /api/transactions/bulk. Rows that failed are
+// listed in the response. When every row failed, or any row did with atomic
+// set, the response is returned together with an error matching ErrBadRequest.
+func (c *Client) CreateTransactions(ctx context.Context, inputs []TransactionInput, atomic bool) (*models.BulkTransactionResponse, error) {
+	var result models.BulkTransactionResponse
+	err := c.do(ctx, "POST", "/api/transactions/bulk", atomicQuery(atomic), map[string]interface{}{"transactions": inputs}, &result)
+	if err != nil && result.TotalCount == 0 {
+		return nil, err
+	}
+	return &result, err
+}
+
+// CreateTransactionsAsync queues the rows of a bulk create as a background job
+func (c *Client) CreateTransactionsAsync(ctx context.Context, inputs []TransactionInput, atomic bool) (*models.JobResponse, error) {
+	query := url.Values{"async": {"true"}}
+	if atomic {
+		query.Set("atomic", "true")
+	}
+	var job models.JobResponse
+	if err := c.do(ctx, "POST", "/api/transactions/bulk", query, map[string]interface{}{"transactions": inputs}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ImportCSV uploads a CSV file to POST /api/transactions/import and returns the queued job
+func (c *Client) ImportCSV(ctx context.Context, filename string, csv io.Reader, atomic bool) (*models.JobResponse, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, csv); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var job models.JobResponse
+	if err := c.send(ctx, "POST", "/api/transactions/import", atomicQuery(atomic), body.Bytes(), writer.FormDataContentType(), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// DeleteTransactions calls DELETE /api/transactions/bulk. Like CreateTransactions,
+// a response where deletions failed may come with an error matching ErrBadRequest.
+func (c *Client) DeleteTransactions(ctx context.Context, ids []uint, atomic bool) (*models.BulkDeleteResponse, error) {
+	var result models.BulkDeleteResponse
+	err := c.do(ctx, "DELETE", "/api/transactions/bulk", atomicQuery(atomic), models.BulkDeleteRequest{TransactionIDs: ids}, &result)
+	if err != nil && result.TotalCount == 0 {
+		return nil, err
+	}
+	return &result, err
+}
+
+// CreateTransfer calls POST /api/transactions/transfer
+func (c *Client) CreateTransfer(ctx context.Context, input TransferInput) (*models.TransferResponse, error) {
+	var transfer models.TransferResponse
+	if err := c.do(ctx, "POST", "/api/transactions/transfer", nil, input, &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// ListTransactions calls GET /api/transactions
+func (c *Client) ListTransactions(ctx context.Context, filter TransactionFilter) ([]models.TransactionResponse, error) {
+	var transactions []models.TransactionResponse
+	if err := c.do(ctx, "GET", "/api/transactions", filter.query(), nil, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// ListTransfers calls GET /api/transactions/transfers
+func (c *Client) ListTransfers(ctx context.Context, filter TransactionFilter) ([]models.TransferResponse, error) {
+	filter.Type = ""
+	var transfers []models.TransferResponse
+	if err := c.do(ctx, "GET", "/api/transactions/transfers", filter.query(), nil, &transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// ListTransactionsByDateRange calls GET /api/transactions/date-range for the dates from and to, inclusive.
+// transactionType may be empty, "expense" or "income".
+func (c *Client) ListTransactionsByDateRange(ctx context.Context, from, to time.Time, transactionType string) ([]models.TransactionResponse, error) {
+	query := dateRange(from, to)
+	if transactionType != "" {
+		query.Set("type", transactionType)
+	}
+	var transactions []models.TransactionResponse
+	if err := c.do(ctx, "GET", "/api/transactions/date-range", query, nil, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// ListDeletedTransactions calls GET /api/transactions/trash
+func (c *Client) ListDeletedTransactions(ctx context.Context) ([]models.TransactionResponse, error) {
+	var transactions []models.TransactionResponse
+	if err := c.do(ctx, "GET", "/api/transactions/trash", nil, nil, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// Summary calls GET /api/transactions/summary
+func (c *Client) Summary(ctx context.Context) (*openapi.Summary, error) {
+	var summary openapi.Summary
+	if err := c.do(ctx, "GET", "/api/transactions/summary", nil, nil, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// Aggregate calls GET /api/transactions/aggregate
+func (c *Client) Aggregate(ctx context.Context) (*models.AggregateResponse, error) {
+	var aggregate models.AggregateResponse
+	if err := c.do(ctx, "GET", "/api/transactions/aggregate", nil, nil, &aggregate); err != nil {
+		return nil, err
+	}
+	return &aggregate, nil
+}
+
+// AggregateTable calls GET /api/transactions/aggregate-table for the dates from and to, inclusive
+func (c *Client) AggregateTable(ctx context.Context, from, to time.Time) (*models.AggregateTableResponse, error) {
+	var table models.AggregateTableResponse
+	if err := c.do(ctx, "GET", "/api/transactions/aggregate-table", dateRange(from, to), nil, &table); err != nil {
+		return nil, err
+	}
+	return &table, nil
+}
+
+// GetTransaction calls GET /api/transactions/:id
+func (c *Client) GetTransaction(ctx context.Context, id uint) (*models.TransactionResponse, error) {
+	var transaction models.TransactionResponse
+	if err := c.do(ctx, "GET", idPath("/api/transactions", id, ""), nil, nil, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// TransactionHistory calls GET /api/transactions/:id/history and returns the versions oldest first
+func (c *Client) TransactionHistory(ctx context.Context, id uint) ([]models.TransactionVersion, error) {
+	var versions []models.TransactionVersion
+	if err := c.do(ctx, "GET", idPath("/api/transactions", id, "/history"), nil, nil, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// UpdateTransaction calls PUT /api/transactions/:id
+func (c *Client) UpdateTransaction(ctx context.Context, id uint, update TransactionUpdate) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := c.do(ctx, "PUT", idPath("/api/transactions", id, ""), nil, update, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// UpdateTransactionCategory calls PATCH /api/transactions/:id/category
+func (c *Client) UpdateTransactionCategory(ctx context.Context, id, categoryID uint) (*models.TransactionResponse, error) {
+	var transaction models.TransactionResponse
+	body := openapi.TransactionCategoryUpdate{CategoryID: categoryID}
+	if err := c.do(ctx, "PATCH", idPath("/api/transactions", id, "/category"), nil, body, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// RestoreTransaction calls POST /api/transactions/:id/restore to go back to a recorded version
+func (c *Client) RestoreTransaction(ctx context.Context, id uint, version int) (*models.TransactionResponse, error) {
+	var transaction models.TransactionResponse
+	query := url.Values{"version": {strconv.Itoa(version)}}
+	if err := c.do(ctx, "POST", idPath("/api/transactions", id, "/restore"), query, nil, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// UndeleteTransaction calls POST /api/transactions/:id/undelete
+func (c *Client) UndeleteTransaction(ctx context.Context, id uint) (*models.TransactionResponse, error) {
+	var transaction models.TransactionResponse
+	if err := c.do(ctx, "POST", idPath("/api/transactions", id, "/undelete"), nil, nil, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// DeleteTransaction calls DELETE /api/transactions/:id
+func (c *Client) DeleteTransaction(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", idPath("/api/transactions", id, ""), nil, nil, nil)
+}
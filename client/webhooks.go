@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+
+	"expense-api/models"
+)
+
+// CreateWebhook calls POST /api/webhooks. The response holds the signing secret, which is not returned again.
+func (c *Client) CreateWebhook(ctx context.Context, input models.WebhookSubscriptionRequest) (*models.WebhookSubscriptionResponse, error) {
+	var webhook models.WebhookSubscriptionResponse
+	if err := c.do(ctx, "POST", "/api/webhooks", nil, input, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks calls GET /api/webhooks
+func (c *Client) ListWebhooks(ctx context.Context) ([]models.WebhookSubscriptionResponse, error) {
+	var webhooks []models.WebhookSubscriptionResponse
+	if err := c.do(ctx, "GET", "/api/webhooks", nil, nil, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhook calls GET /api/webhooks/:id
+func (c *Client) GetWebhook(ctx context.Context, id uint) (*models.WebhookSubscriptionResponse, error) {
+	var webhook models.WebhookSubscriptionResponse
+	if err := c.do(ctx, "GET", idPath("/api/webhooks", id, ""), nil, nil, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook calls PUT /api/webhooks/:id
+func (c *Client) UpdateWebhook(ctx context.Context, id uint, input models.WebhookSubscriptionRequest) (*models.WebhookSubscriptionResponse, error) {
+	var webhook models.WebhookSubscriptionResponse
+	if err := c.do(ctx, "PUT", idPath("/api/webhooks", id, ""), nil, input, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook calls DELETE /api/webhooks/:id
+func (c *Client) DeleteWebhook(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", idPath("/api/webhooks", id, ""), nil, nil, nil)
+}
+
+// WebhookDeliveries calls GET /api/webhooks/:id/deliveries
+func (c *Client) WebhookDeliveries(ctx context.Context, id uint) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := c.do(ctx, "GET", idPath("/api/webhooks", id, "/deliveries"), nil, nil, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// TestWebhook calls POST /api/webhooks/:id/test, which sends a test event right away
+func (c *Client) TestWebhook(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := c.do(ctx, "POST", idPath("/api/webhooks", id, "/test"), nil, nil, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
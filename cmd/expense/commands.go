@@ -0,0 +1,420 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"expense-api/client"
+	"expense-api/jobs"
+	"expense-api/models"
+)
+
+// jobPollInterval is how often "import csv --wait" checks the job
+var jobPollInterval = time.Second
+
+func rootCommand() *command {
+	return &command{name: "expense", subcommands: []*command{
+		{name: "tx", subcommands: []*command{
+			{name: "add", summary: "Record an expense or income", setup: txAdd},
+			{name: "list", summary: "List transactions", setup: txList},
+		}},
+		{name: "transfer", summary: "Move money between bank accounts", setup: transfer},
+		{name: "accounts", summary: "List bank accounts", setup: accounts},
+		{name: "categories", summary: "List categories", setup: categories},
+		{name: "report", subcommands: []*command{
+			{name: "aggregate", summary: "Totals per category, optionally for a date range", setup: reportAggregate},
+		}},
+		{name: "import", subcommands: []*command{
+			{name: "csv", args: "<file>", summary: "Import transactions from a CSV file as a background job", setup: importCSV},
+		}},
+		{name: "config", subcommands: []*command{
+			{name: "show", summary: "Print the config file", setup: configShow},
+			{name: "set", args: "<server_url|token> <value>", summary: "Change a config value", setup: configSet},
+		}},
+		{name: "completion", args: "<bash|zsh|fish>", summary: "Print a shell completion script", setup: completion},
+	}}
+}
+
+func txAdd(fs *flag.FlagSet) func(*cli, []string) error {
+	amount := fs.Float64("amount", 0, "Amount, greater than zero (required)")
+	txType := fs.String("type", "expense", "Transaction type: expense or income")
+	category := fs.String("category", "", "Category name or ID (required)")
+	account := fs.String("account", "", "Bank account name or ID (required)")
+	description := fs.String("description", "", "Description (required)")
+	date := fs.String("date", "", "Date as YYYY-MM-DD (default today)")
+	id := fs.String("id", "", "Your own transaction reference")
+
+	return func(c *cli, args []string) error {
+		if *amount <= 0 || *category == "" || *account == "" || *description == "" || len(args) > 0 {
+			return errUsage
+		}
+		if *date != "" {
+			if _, err := parseDate("date", *date); err != nil {
+				return err
+			}
+		}
+		categoryID, err := c.resolveCategory(*category)
+		if err != nil {
+			return err
+		}
+		accountID, err := c.resolveAccount(*account)
+		if err != nil {
+			return err
+		}
+
+		transaction, err := c.client.CreateTransaction(c.ctx, client.TransactionInput{
+			TransactionID: *id,
+			Amount:        *amount,
+			Type:          *txType,
+			CategoryID:    &categoryID,
+			BankAccountID: accountID,
+			Description:   *description,
+			Date:          *date,
+		})
+		if err != nil {
+			return err
+		}
+		return c.print(transactionTable([]models.TransactionResponse{*transaction}, *transaction))
+	}
+}
+
+func txList(fs *flag.FlagSet) func(*cli, []string) error {
+	txType := fs.String("type", "", "Only this type: expense, income or transfer")
+	account := fs.String("account", "", "Only transactions from or to this bank account (name or ID)")
+	from := fs.String("from", "", "Only transactions on or after this date (YYYY-MM-DD)")
+	to := fs.String("to", "", "Only transactions on or before this date (YYYY-MM-DD)")
+
+	return func(c *cli, args []string) error {
+		if len(args) > 0 {
+			return errUsage
+		}
+		filter := client.TransactionFilter{Type: *txType}
+		var err error
+		if filter.From, err = parseDate("from", *from); err != nil {
+			return err
+		}
+		if filter.To, err = parseDate("to", *to); err != nil {
+			return err
+		}
+		if *account != "" {
+			if filter.BankAccountID, err = c.resolveAccount(*account); err != nil {
+				return err
+			}
+		}
+
+		transactions, err := c.client.ListTransactions(c.ctx, filter)
+		if err != nil {
+			return err
+		}
+		return c.print(transactionTable(transactions, transactions))
+	}
+}
+
+func transfer(fs *flag.FlagSet) func(*cli, []string) error {
+	amount := fs.Float64("amount", 0, "Amount, greater than zero (required)")
+	from := fs.String("from", "", "Source bank account name or ID (required)")
+	to := fs.String("to", "", "Destination bank account name or ID (required)")
+	description := fs.String("description", "", "Description (required)")
+	date := fs.String("date", "", "Date as YYYY-MM-DD (default today)")
+	id := fs.String("id", "", "Your own transaction reference")
+
+	return func(c *cli, args []string) error {
+		if *amount <= 0 || *from == "" || *to == "" || *description == "" || len(args) > 0 {
+			return errUsage
+		}
+		if *date != "" {
+			if _, err := parseDate("date", *date); err != nil {
+				return err
+			}
+		}
+		source, err := c.resolveAccount(*from)
+		if err != nil {
+			return err
+		}
+		destination, err := c.resolveAccount(*to)
+		if err != nil {
+			return err
+		}
+
+		result, err := c.client.CreateTransfer(c.ctx, client.TransferInput{
+			TransactionID:            *id,
+			Amount:                   *amount,
+			BankAccountID:            source,
+			DestinationBankAccountID: destination,
+			Description:              *description,
+			Date:                     *date,
+		})
+		if err != nil {
+			return err
+		}
+		return c.print(table{
+			headers: []string{"ID", "DATE", "FROM", "TO", "AMOUNT", "DESCRIPTION"},
+			rows:    [][]string{{id2s(result.ID), result.Date.Format("2006-01-02"), result.BankAccount.Name, result.DestinationBankAccount.Name, money(result.Amount), result.Description}},
+			raw:     result,
+		})
+	}
+}
+
+func accounts(fs *flag.FlagSet) func(*cli, []string) error {
+	all := fs.Bool("all", false, "Include deactivated accounts")
+
+	return func(c *cli, args []string) error {
+		if len(args) > 0 {
+			return errUsage
+		}
+		accounts, err := c.client.ListBankAccounts(c.ctx, *all)
+		if err != nil {
+			return err
+		}
+		t := table{headers: []string{"ID", "NAME", "BANK", "TYPE", "BALANCE", "ACTIVE"}, raw: accounts}
+		for _, account := range accounts {
+			t.rows = append(t.rows, []string{id2s(account.ID), account.Name, account.BankName, account.AccountType, money(account.Balance), strconv.FormatBool(account.IsActive)})
+		}
+		return c.print(t)
+	}
+}
+
+func categories(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) > 0 {
+			return errUsage
+		}
+		categories, err := c.client.ListCategories(c.ctx)
+		if err != nil {
+			return err
+		}
+		t := table{headers: []string{"ID", "NAME", "TYPE"}, raw: categories}
+		for _, category := range categories {
+			t.rows = append(t.rows, []string{id2s(category.ID), category.Name, category.Type})
+		}
+		return c.print(t)
+	}
+}
+
+func reportAggregate(fs *flag.FlagSet) func(*cli, []string) error {
+	from := fs.String("from", "", "Start date (YYYY-MM-DD); requires --to")
+	to := fs.String("to", "", "End date (YYYY-MM-DD); requires --from")
+
+	return func(c *cli, args []string) error {
+		if len(args) > 0 || (*from == "") != (*to == "") {
+			return errUsage
+		}
+
+		if *from == "" {
+			aggregate, err := c.client.Aggregate(c.ctx)
+			if err != nil {
+				return err
+			}
+			names := make([]string, 0, len(aggregate.Categories))
+			for name := range aggregate.Categories {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			t := table{headers: []string{"CATEGORY", "TOTAL"}, raw: aggregate}
+			for _, name := range names {
+				t.rows = append(t.rows, []string{name, money(aggregate.Categories[name])})
+			}
+			t.rows = append(t.rows,
+				[]string{"Total income", money(aggregate.TotalIncome)},
+				[]string{"Total expenses", money(aggregate.TotalExpenses)},
+				[]string{"Net", money(aggregate.NetAmount)})
+			return c.print(t)
+		}
+
+		start, err := parseDate("from", *from)
+		if err != nil {
+			return err
+		}
+		end, err := parseDate("to", *to)
+		if err != nil {
+			return err
+		}
+		report, err := c.client.AggregateTable(c.ctx, start, end)
+		if err != nil {
+			return err
+		}
+
+		t := table{headers: []string{"TYPE", "CATEGORY", "COUNT", "TOTAL"}, raw: report}
+		for _, section := range []struct {
+			name      string
+			aggregate models.TypeAggregate
+		}{{"income", report.Income}, {"expense", report.Expenses}} {
+			categories := section.aggregate.Categories
+			sort.Slice(categories, func(i, j int) bool { return categories[i].CategoryPath < categories[j].CategoryPath })
+			for _, category := range categories {
+				t.rows = append(t.rows, []string{section.name, category.CategoryPath, strconv.Itoa(category.TransactionCount), money(category.TotalAmount)})
+			}
+			t.rows = append(t.rows, []string{section.name, "Total", strconv.Itoa(section.aggregate.TotalTransactions), money(section.aggregate.TotalAmount)})
+		}
+		t.rows = append(t.rows, []string{"net", "", "", money(report.Summary.NetAmount)})
+		return c.print(t)
+	}
+}
+
+func importCSV(fs *flag.FlagSet) func(*cli, []string) error {
+	atomic := fs.Bool("atomic", false, "Import every row or none")
+	wait := fs.Bool("wait", false, "Wait until the import job finishes")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		job, err := c.client.ImportCSV(c.ctx, filepath.Base(args[0]), file, *atomic)
+		if err != nil {
+			return err
+		}
+
+		for *wait && (job.Status == jobs.StatusQueued || job.Status == jobs.StatusRunning) {
+			select {
+			case <-c.ctx.Done():
+				return c.ctx.Err()
+			case <-time.After(jobPollInterval):
+			}
+			if job, err = c.client.GetJob(c.ctx, job.ID); err != nil {
+				return err
+			}
+		}
+
+		row := []string{id2s(job.ID), job.Status, fmt.Sprintf("%d/%d", job.Processed, job.Total), "", "", job.LastError}
+		if job.Result != nil {
+			row[3], row[4] = strconv.Itoa(job.Result.SuccessCount), strconv.Itoa(job.Result.FailedCount)
+		}
+		return c.print(table{headers: []string{"JOB", "STATUS", "PROCESSED", "CREATED", "FAILED", "ERROR"}, rows: [][]string{row}, raw: job})
+	}
+}
+
+func configShow(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) > 0 {
+			return errUsage
+		}
+		config, err := loadConfig(c.configPath)
+		if err != nil {
+			return err
+		}
+		token := config.Token
+		if token != "" {
+			token = "********"
+		}
+		return c.print(table{
+			headers: []string{"KEY", "VALUE"},
+			rows:    [][]string{{"path", c.configPath}, {"server_url", config.ServerURL}, {"token", token}},
+			raw:     map[string]string{"path": c.configPath, "server_url": config.ServerURL, "token": token},
+		})
+	}
+}
+
+func configSet(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) != 2 {
+			return errUsage
+		}
+		field, ok := configKeys[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown config key %q, use server_url or token", args[0])
+		}
+		config, err := loadConfig(c.configPath)
+		if err != nil {
+			return err
+		}
+		*field(&config) = args[1]
+		return saveConfig(c.configPath, config)
+	}
+}
+
+func completion(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		script, ok := completionScripts[args[0]]
+		if !ok {
+			return fmt.Errorf("unsupported shell %q, use bash, zsh or fish", args[0])
+		}
+		_, err := fmt.Fprint(c.stdout, script)
+		return err
+	}
+}
+
+func transactionTable(transactions []models.TransactionResponse, raw interface{}) table {
+	t := table{headers: []string{"ID", "DATE", "TYPE", "CATEGORY", "ACCOUNT", "AMOUNT", "DESCRIPTION"}, raw: raw}
+	for _, transaction := range transactions {
+		account := transaction.BankAccount.Name
+		if transaction.DestinationBankAccount != nil {
+			account += " -> " + transaction.DestinationBankAccount.Name
+		}
+		t.rows = append(t.rows, []string{
+			id2s(transaction.ID),
+			transaction.Date.Format("2006-01-02"),
+			transaction.Type,
+			transaction.Category,
+			account,
+			money(transaction.Amount),
+			transaction.Description,
+		})
+	}
+	return t
+}
+
+// resolveAccount accepts a bank account ID or a case-insensitive name
+func (c *cli) resolveAccount(value string) (uint, error) {
+	if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return uint(id), nil
+	}
+	accounts, err := c.client.ListBankAccounts(c.ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	for _, account := range accounts {
+		if strings.EqualFold(account.Name, value) {
+			return account.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("no active bank account named %q", value)
+}
+
+// resolveCategory accepts a category ID or a case-insensitive name
+func (c *cli) resolveCategory(value string) (uint, error) {
+	if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return uint(id), nil
+	}
+	categories, err := c.client.ListCategories(c.ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, value) {
+			return category.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("no category named %q", value)
+}
+
+// parseDate parses an optional YYYY-MM-DD flag value
+func parseDate(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--%s must be a date like 2024-01-31", name)
+	}
+	return date, nil
+}
+
+func id2s(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
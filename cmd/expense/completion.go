@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The scripts ask the binary for candidates with the hidden __complete
+// command, passing the words typed so far and the word being completed.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for expense
+_expense() {
+    local IFS=$'\n'
+    COMPREPLY=($(expense __complete "${COMP_WORDS[@]:0:COMP_CWORD+1}" 2>/dev/null))
+}
+complete -o default -F _expense expense
+`,
+	"zsh": `#compdef expense
+# zsh completion for expense
+_expense() {
+    local -a candidates
+    candidates=(${(f)"$(expense __complete "${(@)words[1,CURRENT]}" 2>/dev/null)"})
+    compadd -a candidates
+}
+compdef _expense expense
+`,
+	"fish": `# fish completion for expense
+complete -c expense -f -a '(expense __complete (commandline -opc) (commandline -ct))'
+`,
+}
+
+// flagValues lists the values offered after flags that take one of a fixed set
+var flagValues = map[string][]string{
+	"o":      {"table", "json", "csv"},
+	"output": {"table", "json", "csv"},
+	"type":   {"expense", "income", "transfer"},
+}
+
+// complete returns the completions for words, which start with the program
+// name and end with the word being completed
+func complete(root *command, words []string) []string {
+	if len(words) < 2 {
+		return nil
+	}
+	typed, current := words[1:len(words)-1], words[len(words)-1]
+
+	cmd := root
+	for _, word := range typed {
+		if sub := cmd.find(word); sub != nil {
+			cmd = sub
+		}
+	}
+
+	var candidates []string
+	switch {
+	case cmd.setup == nil:
+		for _, sub := range cmd.subcommands {
+			candidates = append(candidates, sub.name)
+		}
+	case cmd.name == "completion":
+		candidates = []string{"bash", "fish", "zsh"}
+	case cmd.name == "set" && len(typed) > 0 && typed[len(typed)-1] == "set":
+		candidates = []string{"server_url", "token"}
+	default:
+		fs := commandFlags(cmd)
+		if len(typed) > 0 && strings.HasPrefix(typed[len(typed)-1], "-") {
+			name := strings.TrimLeft(typed[len(typed)-1], "-")
+			if values, ok := flagValues[name]; ok {
+				candidates = values
+				break
+			}
+			if f := fs.Lookup(name); f != nil && !isBoolFlag(f) {
+				// The flag takes a free-form value
+				return nil
+			}
+		}
+		if strings.HasPrefix(current, "-") {
+			fs.VisitAll(func(f *flag.Flag) {
+				if len(f.Name) > 1 {
+					candidates = append(candidates, "--"+f.Name)
+				}
+			})
+		}
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// commandFlags registers the flags of a leaf command on a throwaway flag set
+func commandFlags(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cmd.setup(fs)
+	var g globals
+	g.register(fs)
+	return fs
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
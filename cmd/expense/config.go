@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultServerURL = "http://localhost:8080"
+
+// config is the JSON config file of the CLI
+type config struct {
+	ServerURL string `json:"server_url,omitempty"`
+	Token     string `json:"token,omitempty"`
+}
+
+// configKeys maps the keys accepted by "config set" to their fields
+var configKeys = map[string]func(c *config) *string{
+	"server_url": func(c *config) *string { return &c.ServerURL },
+	"token":      func(c *config) *string { return &c.Token },
+}
+
+// defaultConfigPath is expense/config.json in the user's config directory
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "expense.json"
+	}
+	return filepath.Join(dir, "expense", "config.json")
+}
+
+// displayConfigPath shortens the default path for help output
+func displayConfigPath() string {
+	path := defaultConfigPath()
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, home) {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+	return path
+}
+
+// loadConfig reads the config file; a missing file is an empty config
+func loadConfig(path string) (config, error) {
+	var c config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// saveConfig writes the config file, readable only by the user since it holds the token
+func saveConfig(path string, c config) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
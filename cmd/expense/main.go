@@ -0,0 +1,201 @@
+// Command expense logs and reports expenses from a terminal using the expense API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"expense-api/client"
+)
+
+// command is a node of the command tree. Leaf commands register their flags
+// in setup, which returns the function that runs the command.
+type command struct {
+	name        string
+	args        string // Positional arguments, for usage
+	summary     string
+	subcommands []*command
+	setup       func(fs *flag.FlagSet) func(cli *cli, args []string) error
+}
+
+// globals are the flags every command accepts
+type globals struct {
+	server string
+	token  string
+	config string
+	output string
+}
+
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.server, "server", "", "API server URL (default from config, EXPENSE_SERVER_URL or "+defaultServerURL+")")
+	fs.StringVar(&g.token, "token", "", "API token (default from config or EXPENSE_TOKEN)")
+	fs.StringVar(&g.config, "config", "", "Config file (default "+displayConfigPath()+")")
+	fs.StringVar(&g.output, "o", "table", "Output format: table, json or csv")
+	fs.StringVar(&g.output, "output", "table", "Output format: table, json or csv")
+}
+
+// cli is the state a running command uses
+type cli struct {
+	ctx        context.Context
+	stdout     io.Writer
+	client     *client.Client
+	configPath string
+	output     string
+}
+
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	root := rootCommand()
+
+	if len(args) > 0 && args[0] == "__complete" {
+		for _, candidate := range complete(root, args[1:]) {
+			fmt.Fprintln(stdout, candidate)
+		}
+		return 0
+	}
+
+	// Find the deepest command named by the leading arguments
+	cmd, path := root, []string{root.name}
+	for len(args) > 0 {
+		sub := cmd.find(args[0])
+		if sub == nil {
+			break
+		}
+		cmd, path, args = sub, append(path, sub.name), args[1:]
+	}
+
+	if cmd.setup == nil {
+		if len(args) > 0 && args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+			fmt.Fprintf(stderr, "unknown command %q\n\n", strings.Join(append(path, args[0]), " "))
+			printUsage(stderr, cmd, path)
+			return 2
+		}
+		printUsage(stdout, cmd, path)
+		return 0
+	}
+
+	var g globals
+	fs := flag.NewFlagSet(strings.Join(path, " "), flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	action := cmd.setup(fs)
+	g.register(fs)
+	fs.Usage = func() { printUsage(stderr, cmd, path); fs.PrintDefaults() }
+
+	positional, err := parseInterspersed(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+
+	c, err := newCLI(ctx, stdout, g)
+	if err == nil {
+		err = action(c, positional)
+	}
+	if errors.Is(err, errUsage) {
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// newCLI resolves the server and token from flags, environment and config file, in that order
+func newCLI(ctx context.Context, stdout io.Writer, g globals) (*cli, error) {
+	switch g.output {
+	case "table", "json", "csv":
+	default:
+		return nil, fmt.Errorf("unknown output format %q, use table, json or csv", g.output)
+	}
+
+	configPath := g.config
+	if configPath == "" {
+		configPath = os.Getenv("EXPENSE_CONFIG")
+	}
+	if configPath == "" {
+		configPath = defaultConfigPath()
+	}
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	server := firstNonEmpty(g.server, os.Getenv("EXPENSE_SERVER_URL"), config.ServerURL, defaultServerURL)
+	api := client.New(server)
+	api.Token = firstNonEmpty(g.token, os.Getenv("EXPENSE_TOKEN"), config.Token)
+
+	return &cli{ctx: ctx, stdout: stdout, client: api, configPath: configPath, output: g.output}, nil
+}
+
+// parseInterspersed parses flags that appear before, between or after positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.subcommands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer, cmd *command, path []string) {
+	if cmd.setup != nil {
+		fmt.Fprintf(w, "Usage: %s\n\n%s\n\nFlags:\n", strings.TrimSpace(strings.Join(append(path, "[flags]", cmd.args), " ")), cmd.summary)
+		return
+	}
+
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", strings.Join(path, " "))
+	var lines [][2]string
+	var walk func(c *command, prefix string)
+	walk = func(c *command, prefix string) {
+		for _, sub := range c.subcommands {
+			if sub.setup != nil {
+				lines = append(lines, [2]string{strings.TrimSpace(prefix + " " + sub.name + " " + sub.args), sub.summary})
+			}
+			walk(sub, prefix+" "+sub.name)
+		}
+	}
+	walk(cmd, "")
+	for _, line := range lines {
+		fmt.Fprintf(w, "  %-38s %s\n", line[0], line[1])
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command.\n", strings.Join(path, " "))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
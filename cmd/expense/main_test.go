@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/container"
+	"expense-api/handlers"
+	"expense-api/models"
+	"expense-api/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/stretchr/testify/assert"
+)
+
+// startServer serves the API handlers over an in-memory store
+func startServer(t *testing.T) *httptest.Server {
+	store := repository.NewMemoryStore()
+	assert.NoError(t, store.Categories().Create(&models.Category{Name: "Food", Type: "expense"}))
+	assert.NoError(t, store.Categories().Create(&models.Category{Name: "Salary", Type: "income"}))
+	assert.NoError(t, store.Accounts().Create(&models.BankAccount{Name: "Checking", BankName: "Test Bank", AccountType: "checking", Balance: 1000, IsActive: true}))
+	assert.NoError(t, store.Accounts().Create(&models.BankAccount{Name: "Savings", BankName: "Test Bank", AccountType: "savings", Balance: 5000, IsActive: true}))
+	deps := container.NewInMemory(store)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperrors.Handler})
+	api := app.Group("/api")
+	api.Get("/bank-accounts", handlers.GetBankAccounts(deps.Accounts))
+	api.Get("/categories", handlers.GetCategories(deps.Categories))
+	api.Post("/transactions", handlers.CreateTransaction(deps.Transactions, nil))
+	api.Get("/transactions", handlers.GetTransactions(deps.Transactions))
+	api.Post("/transactions/transfer", handlers.CreateTransfer(deps.Transactions))
+	api.Get("/transactions/aggregate", handlers.GetTransactionsAggregate(deps.Transactions, deps.Categories))
+	api.Get("/transactions/aggregate-table", handlers.GetTransactionsAggregateTable(deps.Transactions, deps.Categories))
+
+	// The in-memory container has no job queue, so the import endpoints are stubs
+	api.Post("/transactions/import", func(c *fiber.Ctx) error {
+		file, err := c.FormFile("file")
+		if err != nil || file.Filename != "january.csv" || c.Query("atomic") != "true" {
+			return apperrors.BadRequest(apperrors.CodeInvalidFile, "unexpected upload")
+		}
+		return c.Status(202).JSON(models.JobResponse{ID: 7, Type: "transactions.bulk_create", Status: "queued", Total: 2})
+	})
+	api.Get("/jobs/7", func(c *fiber.Ctx) error {
+		return c.JSON(models.JobResponse{ID: 7, Status: "succeeded", Total: 2, Processed: 2,
+			Result: &models.BulkTransactionResponse{TotalCount: 2, SuccessCount: 2}})
+	})
+
+	server := httptest.NewServer(adaptor.FiberApp(app))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runCLI runs the CLI against server and returns its exit code and output
+func runCLI(t *testing.T, server *httptest.Server, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append(args, "--server", server.URL, "--config", filepath.Join(t.TempDir(), "config.json"))
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestTransactionsAndReports(t *testing.T) {
+	server := startServer(t)
+
+	code, out, _ := runCLI(t, server, "tx", "add", "--amount", "12.50", "--category", "food", "--account", "Checking", "--description", "Lunch", "--date", "2024-01-15")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "Lunch")
+	assert.Contains(t, out, "12.50")
+
+	code, _, _ = runCLI(t, server, "tx", "add", "--type", "income", "--amount", "3000", "--category", "2", "--account", "1", "--description", "Salary", "--date", "2024-01-31")
+	assert.Equal(t, 0, code)
+
+	code, out, _ = runCLI(t, server, "transfer", "--amount", "200", "--from", "Checking", "--to", "savings", "--description", "To savings", "--date", "2024-02-01")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "Checking  Savings")
+
+	// Filters are applied by the server, newest first
+	code, out, _ = runCLI(t, server, "tx", "list", "--from", "2024-01-01", "--to", "2024-01-31", "-o", "csv")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ID,DATE,TYPE,CATEGORY,ACCOUNT,AMOUNT,DESCRIPTION\n"+
+		"2,2024-01-31,income,Salary,Checking,3000.00,Salary\n"+
+		"1,2024-01-15,expense,Food,Checking,12.50,Lunch\n", out)
+
+	code, out, _ = runCLI(t, server, "tx", "list", "--type", "transfer", "--output", "json")
+	assert.Equal(t, 0, code)
+	var transfers []models.TransactionResponse
+	assert.NoError(t, json.Unmarshal([]byte(out), &transfers))
+	if assert.Len(t, transfers, 1) {
+		assert.Equal(t, "Savings", transfers[0].DestinationBankAccount.Name)
+	}
+
+	code, out, _ = runCLI(t, server, "report", "aggregate", "--from", "2024-01-01", "--to", "2024-01-31", "-o", "csv")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "income,Salary,1,3000.00\n")
+	assert.Contains(t, out, "expense,Food,1,12.50\n")
+	assert.Contains(t, out, "net,,,2987.50\n")
+
+	code, out, _ = runCLI(t, server, "report", "aggregate")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "Net")
+
+	code, out, _ = runCLI(t, server, "accounts")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "Savings")
+
+	code, out, _ = runCLI(t, server, "categories", "-o", "json")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, `"name": "Salary"`)
+}
+
+func TestErrorsAndUsage(t *testing.T) {
+	server := startServer(t)
+
+	// API problems are printed with their code
+	code, _, stderr := runCLI(t, server, "tx", "add", "--amount", "5", "--category", "Salary", "--account", "Checking", "--description", "Wrong category")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, apperrors.CodeCategoryTypeMismatch)
+
+	code, _, stderr = runCLI(t, server, "tx", "add", "--amount", "5", "--category", "Rent", "--account", "Checking", "--description", "Rent")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `no category named "Rent"`)
+
+	// Missing required flags print the command usage
+	code, _, stderr = runCLI(t, server, "transfer", "--amount", "5")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: expense transfer [flags]")
+
+	code, _, stderr = runCLI(t, server, "tx", "remove")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "expense tx remove"`)
+}
+
+func TestImportCSVWaitsForJob(t *testing.T) {
+	server := startServer(t)
+	jobPollInterval = time.Millisecond
+
+	file := filepath.Join(t.TempDir(), "january.csv")
+	assert.NoError(t, os.WriteFile(file, []byte("amount,type,category_id,bank_account_id,description\n10,expense,1,1,Lunch\n"), 0o600))
+
+	code, out, stderr := runCLI(t, server, "import", "csv", file, "--atomic", "--wait")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, out, "succeeded")
+	assert.Contains(t, out, "2/2")
+}
+
+func TestConfigFile(t *testing.T) {
+	server := startServer(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, run(context.Background(), []string{"config", "set", "server_url", server.URL, "--config", path}, &stdout, &stderr))
+	assert.Equal(t, 0, run(context.Background(), []string{"config", "set", "token", "secret", "--config", path}, &stdout, &stderr))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Commands use the server from the config file and never print the token
+	stdout.Reset()
+	assert.Equal(t, 0, run(context.Background(), []string{"categories", "--config", path}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "Food")
+
+	stdout.Reset()
+	assert.Equal(t, 0, run(context.Background(), []string{"config", "show", "--config", path}, &stdout, &stderr))
+	assert.NotContains(t, stdout.String(), "secret")
+	assert.Contains(t, stdout.String(), server.URL)
+}
+
+func TestCompletion(t *testing.T) {
+	complete := func(words ...string) []string {
+		var stdout bytes.Buffer
+		run(context.Background(), append([]string{"__complete", "expense"}, words...), &stdout, io.Discard)
+		return strings.Fields(stdout.String())
+	}
+
+	assert.Equal(t, []string{"transfer", "tx"}, complete("t"))
+	assert.Equal(t, []string{"add", "list"}, complete("tx", ""))
+	assert.Equal(t, []string{"--to", "--token", "--type"}, complete("tx", "list", "--t"))
+	assert.Equal(t, []string{"expense", "income", "transfer"}, complete("tx", "list", "--type", ""))
+	assert.Empty(t, complete("tx", "add", "--amount", ""))
+	assert.Equal(t, []string{"zsh"}, complete("completion", "z"))
+
+	var stdout bytes.Buffer
+	assert.Equal(t, 0, run(context.Background(), []string{"completion", "bash"}, &stdout, io.Discard))
+	assert.Contains(t, stdout.String(), "complete -o default -F _expense expense")
+}
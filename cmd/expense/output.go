@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// table is a command result that can be printed in any output format
+type table struct {
+	headers []string
+	rows    [][]string
+	// raw is printed instead of the rows for JSON output
+	raw interface{}
+}
+
+// print writes a result in the selected output format
+func (c *cli) print(t table) error {
+	switch c.output {
+	case "json":
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(t.raw)
+	case "csv":
+		writer := csv.NewWriter(c.stdout)
+		writer.Write(t.headers)
+		writer.WriteAll(t.rows)
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(t.headers, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	}
+}
+
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
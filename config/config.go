@@ -0,0 +1,302 @@
+// Package config holds the settings of the HTTP server: the listen port,
+// CORS, TLS, security headers and trusted proxies. Settings are read from an
+// optional JSON file named by CONFIG_FILE and then from the environment, which
+// takes precedence, and are validated before the server starts.
+package config
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of the HTTP server
+type Config struct {
+	Port     string   `json:"port"`
+	CORS     CORS     `json:"cors"`
+	TLS      TLS      `json:"tls"`
+	Security Security `json:"security"`
+	// TrustedProxies are the IPs or CIDR ranges of the proxies whose
+	// X-Forwarded-For header is trusted for the client IP. Without any, the
+	// client IP is the address of the connection.
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// CORS configures cross-origin requests
+type CORS struct {
+	// AllowOrigins lists the origins allowed to call the API, e.g. https://app.example.com,
+	// or "*" for any. Without any, browsers only allow same-origin requests.
+	AllowOrigins []string `json:"allow_origins"`
+	// AllowCredentials lets browsers send cookies and Authorization headers; it needs explicit origins
+	AllowCredentials bool `json:"allow_credentials"`
+	// AllowAuthorization lets cross-origin requests send the Authorization and
+	// X-API-Key headers; it needs explicit origins
+	AllowAuthorization bool `json:"allow_authorization"`
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge Duration `json:"max_age"`
+}
+
+// TLS makes the server serve HTTPS itself when both files are set
+type TLS struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+// Security configures the security headers sent with every response
+type Security struct {
+	HSTS HSTS `json:"hsts"`
+	// ContentSecurityPolicy is sent as Content-Security-Policy; empty sends none
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	// FrameOptions is sent as X-Frame-Options: DENY, SAMEORIGIN or empty for none
+	FrameOptions string `json:"frame_options"`
+	// ReferrerPolicy is sent as Referrer-Policy
+	ReferrerPolicy string `json:"referrer_policy"`
+	// PermissionsPolicy is sent as Permissions-Policy; empty sends none
+	PermissionsPolicy string `json:"permissions_policy"`
+}
+
+// HSTS configures the Strict-Transport-Security header, which is only sent over HTTPS
+type HSTS struct {
+	// MaxAge is how long browsers must only use HTTPS; 0 sends no header
+	MaxAge            Duration `json:"max_age"`
+	IncludeSubdomains bool     `json:"include_subdomains"`
+	Preload           bool     `json:"preload"`
+}
+
+// Duration is a time.Duration written as a string such as "12h" in the config file
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"12h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Default returns the configuration used for settings that are not set
+func Default() Config {
+	return Config{
+		Port: "8080",
+		CORS: CORS{
+			AllowAuthorization: true,
+		},
+		Security: Security{
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "no-referrer",
+		},
+	}
+}
+
+// Load reads the configuration from the file named by CONFIG_FILE, if any, and
+// from the environment through lookup, e.g. os.LookupEnv, and validates it
+func Load(lookup func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+	if path, ok := lookup("CONFIG_FILE"); ok && path != "" {
+		if err := readFile(path, &cfg); err != nil {
+			return cfg, fmt.Errorf("CONFIG_FILE %s: %w", path, err)
+		}
+	}
+
+	env := envReader{lookup: lookup}
+	env.string("PORT", &cfg.Port)
+	env.list("CORS_ALLOW_ORIGINS", &cfg.CORS.AllowOrigins)
+	env.bool("CORS_ALLOW_CREDENTIALS", &cfg.CORS.AllowCredentials)
+	env.bool("CORS_ALLOW_AUTHORIZATION", &cfg.CORS.AllowAuthorization)
+	env.duration("CORS_MAX_AGE", &cfg.CORS.MaxAge)
+	env.string("TLS_CERT_FILE", &cfg.TLS.CertFile)
+	env.string("TLS_KEY_FILE", &cfg.TLS.KeyFile)
+	env.duration("HSTS_MAX_AGE", &cfg.Security.HSTS.MaxAge)
+	env.bool("HSTS_INCLUDE_SUBDOMAINS", &cfg.Security.HSTS.IncludeSubdomains)
+	env.bool("HSTS_PRELOAD", &cfg.Security.HSTS.Preload)
+	env.string("CONTENT_SECURITY_POLICY", &cfg.Security.ContentSecurityPolicy)
+	env.string("FRAME_OPTIONS", &cfg.Security.FrameOptions)
+	env.string("REFERRER_POLICY", &cfg.Security.ReferrerPolicy)
+	env.string("PERMISSIONS_POLICY", &cfg.Security.PermissionsPolicy)
+	env.list("TRUSTED_PROXIES", &cfg.TrustedProxies)
+
+	problems := env.problems
+	var invalid *Error
+	if errors.As(cfg.Validate(), &invalid) {
+		problems = append(problems, invalid.Problems...)
+	}
+	if len(problems) > 0 {
+		return cfg, &Error{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// readFile decodes a JSON config file over cfg, rejecting unknown settings
+func readFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(cfg)
+}
+
+// Error lists every problem found in a configuration
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the configuration and returns an *Error listing every problem
+func (c Config) Validate() error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problem("PORT: %q is not a port number between 1 and 65535", c.Port)
+	}
+
+	for _, origin := range c.CORS.AllowOrigins {
+		if origin == "*" {
+			if len(c.CORS.AllowOrigins) > 1 {
+				problem("CORS_ALLOW_ORIGINS: \"*\" cannot be combined with other origins")
+			}
+			if c.CORS.AllowCredentials {
+				problem("CORS_ALLOW_CREDENTIALS: credentials need explicit CORS_ALLOW_ORIGINS; with \"*\" any site could make authenticated requests")
+			}
+			if c.CORS.AllowAuthorization {
+				problem("CORS_ALLOW_AUTHORIZATION: API tokens need explicit CORS_ALLOW_ORIGINS; with \"*\" any site could make authenticated requests, so set it to false to allow any origin")
+			}
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" {
+			problem("CORS_ALLOW_ORIGINS: %q is not an origin such as https://app.example.com", origin)
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		problem("CORS_MAX_AGE: must not be negative")
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		problem("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	} else if c.TLS.Enabled() {
+		if _, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile); err != nil {
+			problem("TLS_CERT_FILE/TLS_KEY_FILE: %v", err)
+		}
+	}
+
+	hsts := c.Security.HSTS
+	if hsts.MaxAge < 0 {
+		problem("HSTS_MAX_AGE: must not be negative")
+	}
+	if hsts.Preload && (!hsts.IncludeSubdomains || time.Duration(hsts.MaxAge) < 365*24*time.Hour) {
+		problem("HSTS_PRELOAD: preloading needs HSTS_INCLUDE_SUBDOMAINS=true and HSTS_MAX_AGE of at least 8760h")
+	}
+	switch strings.ToUpper(c.Security.FrameOptions) {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		problem("FRAME_OPTIONS: %q must be DENY, SAMEORIGIN or empty", c.Security.FrameOptions)
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problem("TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+// Enabled reports whether the server serves HTTPS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// Config loads the certificate into a TLS configuration for the listener
+func (t TLS) Config() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// envReader reads settings from the environment, collecting values that cannot be parsed
+type envReader struct {
+	lookup   func(string) (string, bool)
+	problems []string
+}
+
+func (r *envReader) string(name string, target *string) {
+	if value, ok := r.lookup(name); ok {
+		*target = strings.TrimSpace(value)
+	}
+}
+
+func (r *envReader) list(name string, target *[]string) {
+	value, ok := r.lookup(name)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+func (r *envReader) bool(name string, target *bool) {
+	value, ok := r.lookup(name)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		r.problems = append(r.problems, fmt.Sprintf("%s: %q is not true or false", name, value))
+		return
+	}
+	*target = parsed
+}
+
+func (r *envReader) duration(name string, target *Duration) {
+	value, ok := r.lookup(name)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		r.problems = append(r.problems, fmt.Sprintf("%s: %q is not a duration such as 12h", name, value))
+		return
+	}
+	*target = Duration(parsed)
+}
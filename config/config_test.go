@@ -0,0 +1,230 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// env returns a lookup function over the given variables
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+// writeCertificate writes a self-signed certificate and its key to dir
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(env(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+	assert.False(t, cfg.TLS.Enabled())
+}
+
+func TestLoadFileAndEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+	path := filepath.Join(dir, "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{
+		"port": "8443",
+		"cors": {"allow_origins": ["https://app.example.com"], "allow_credentials": true, "max_age": "10m"},
+		"tls": {"cert_file": "`+certFile+`", "key_file": "`+keyFile+`"},
+		"security": {"hsts": {"max_age": "8760h", "include_subdomains": true}},
+		"trusted_proxies": ["10.0.0.0/8"]
+	}`), 0o600))
+
+	cfg, err := Load(env(map[string]string{
+		"CONFIG_FILE":        path,
+		"CORS_ALLOW_ORIGINS": "https://app.example.com, https://admin.example.com",
+		"HSTS_PRELOAD":       "true",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "8443", cfg.Port)
+	// The environment takes precedence over the file
+	assert.Equal(t, []string{"https://app.example.com", "https://admin.example.com"}, cfg.CORS.AllowOrigins)
+	assert.True(t, cfg.CORS.AllowCredentials)
+	assert.Equal(t, Duration(10*time.Minute), cfg.CORS.MaxAge)
+	assert.True(t, cfg.TLS.Enabled())
+	assert.True(t, cfg.Security.HSTS.Preload)
+	assert.Equal(t, []string{"10.0.0.0/8"}, cfg.TrustedProxies)
+	// Settings the file leaves out keep their defaults
+	assert.Equal(t, "DENY", cfg.Security.FrameOptions)
+
+	tlsConfig, err := cfg.TLS.Config()
+	assert.NoError(t, err)
+	assert.Len(t, tlsConfig.Certificates, 1)
+}
+
+func TestLoadRejectsUnknownFileSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"cors": {"origins": ["*"]}}`), 0o600))
+
+	_, err := Load(env(map[string]string{"CONFIG_FILE": path}))
+	assert.ErrorContains(t, err, `unknown field "origins"`)
+}
+
+func TestLoadListsEveryProblem(t *testing.T) {
+	_, err := Load(env(map[string]string{
+		"PORT":                   "http",
+		"CORS_ALLOW_ORIGINS":     "*",
+		"CORS_ALLOW_CREDENTIALS": "true",
+		"CORS_MAX_AGE":           "forever",
+		"TLS_CERT_FILE":          "cert.pem",
+		"HSTS_PRELOAD":           "true",
+		"FRAME_OPTIONS":          "ALLOW",
+		"TRUSTED_PROXIES":        "10.0.0.1, proxy.internal",
+	}))
+
+	var invalid *Error
+	if !assert.True(t, errors.As(err, &invalid)) {
+		return
+	}
+	assert.Equal(t, []string{
+		`CORS_MAX_AGE: "forever" is not a duration such as 12h`,
+		`PORT: "http" is not a port number between 1 and 65535`,
+		`CORS_ALLOW_CREDENTIALS: credentials need explicit CORS_ALLOW_ORIGINS; with "*" any site could make authenticated requests`,
+		`CORS_ALLOW_AUTHORIZATION: API tokens need explicit CORS_ALLOW_ORIGINS; with "*" any site could make authenticated requests, so set it to false to allow any origin`,
+		"TLS_CERT_FILE and TLS_KEY_FILE must be set together",
+		"HSTS_PRELOAD: preloading needs HSTS_INCLUDE_SUBDOMAINS=true and HSTS_MAX_AGE of at least 8760h",
+		`FRAME_OPTIONS: "ALLOW" must be DENY, SAMEORIGIN or empty`,
+		`TRUSTED_PROXIES: "proxy.internal" is not an IP address or CIDR range`,
+	}, invalid.Problems)
+}
+
+func TestValidateOrigins(t *testing.T) {
+	cfg := Default()
+	for origin, valid := range map[string]bool{
+		"https://app.example.com":      true,
+		"http://localhost:3000":        true,
+		"https://app.example.com/":     true,
+		"app.example.com":              false,
+		"https://app.example.com/path": false,
+		"ftp://files.example.com":      false,
+	} {
+		cfg.CORS.AllowOrigins = []string{origin}
+		assert.Equal(t, valid, cfg.Validate() == nil, origin)
+	}
+
+	cfg.CORS.AllowOrigins = []string{"*", "https://app.example.com"}
+	assert.ErrorContains(t, cfg.Validate(), `"*" cannot be combined`)
+
+	// Any origin is only allowed for requests that cannot carry a token
+	cfg.CORS.AllowOrigins = []string{"*"}
+	assert.ErrorContains(t, cfg.Validate(), "CORS_ALLOW_AUTHORIZATION")
+	cfg.CORS.AllowAuthorization = false
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestMiddleware(t *testing.T) {
+	cfg := Default()
+	cfg.CORS.AllowOrigins = []string{"https://app.example.com"}
+	cfg.CORS.AllowCredentials = true
+	cfg.Security.HSTS = HSTS{MaxAge: Duration(24 * time.Hour), IncludeSubdomains: true}
+	cfg.TrustedProxies = []string{"0.0.0.0"}
+
+	appConfig := fiber.Config{}
+	cfg.ApplyProxies(&appConfig)
+	app := fiber.New(appConfig)
+	app.Use(cfg.SecurityMiddleware())
+	app.Use(cfg.CORSMiddleware())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.IP())
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderOrigin, "https://app.example.com")
+	req.Header.Set(fiber.HeaderXForwardedFor, "203.0.113.7")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "true", resp.Header.Get(fiber.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, "nosniff", resp.Header.Get(fiber.HeaderXContentTypeOptions))
+	assert.Equal(t, "DENY", resp.Header.Get(fiber.HeaderXFrameOptions))
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", resp.Header.Get(fiber.HeaderContentSecurityPolicy))
+	assert.Equal(t, "no-referrer", resp.Header.Get("Referrer-Policy"))
+	// HSTS is only sent over HTTPS
+	assert.Empty(t, resp.Header.Get(fiber.HeaderStrictTransportSecurity))
+	// The client IP comes from the trusted proxy's X-Forwarded-For
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	assert.Equal(t, "203.0.113.7", string(body[:n]))
+
+	// Other origins get no CORS headers
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderOrigin, "https://evil.example.com")
+	req.Header.Set(fiber.HeaderXForwardedProto, "https")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "max-age=86400; includeSubDomains", resp.Header.Get(fiber.HeaderStrictTransportSecurity))
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	preflight := func(cfg Config) *http.Response {
+		app := fiber.New()
+		app.Use(cfg.CORSMiddleware())
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		req := httptest.NewRequest("OPTIONS", "/", nil)
+		req.Header.Set(fiber.HeaderOrigin, "https://app.example.com")
+		req.Header.Set(fiber.HeaderAccessControlRequestMethod, "GET")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// By default no origin is allowed, so browsers only make same-origin requests
+	resp := preflight(Default())
+	assert.Empty(t, resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+
+	cfg := Default()
+	cfg.CORS.AllowOrigins = []string{"https://app.example.com"}
+	resp = preflight(cfg)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	assert.Contains(t, resp.Header.Get(fiber.HeaderAccessControlAllowHeaders), "Authorization,X-API-Key")
+
+	// Any origin may call the API, but not with a token
+	cfg.CORS.AllowOrigins = []string{"*"}
+	cfg.CORS.AllowAuthorization = false
+	resp = preflight(cfg)
+	assert.Equal(t, "*", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	assert.NotContains(t, resp.Header.Get(fiber.HeaderAccessControlAllowHeaders), "Authorization")
+	assert.NotContains(t, resp.Header.Get(fiber.HeaderAccessControlAllowHeaders), "X-API-Key")
+}
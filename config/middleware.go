@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/helmet"
+)
+
+// Request and response headers allowed across origins
+const (
+	corsAllowHeaders  = "Origin, Content-Type, Accept, X-Request-ID, Idempotency-Key, traceparent, tracestate"
+	corsAuthHeaders   = "Authorization, X-API-Key"
+	corsExposeHeaders = "X-Request-ID, Idempotent-Replayed, Location, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After"
+)
+
+// ApplyProxies makes the app take the client IP from X-Forwarded-For when a request comes from a trusted proxy
+func (c Config) ApplyProxies(app *fiber.Config) {
+	if len(c.TrustedProxies) == 0 {
+		return
+	}
+	app.EnableTrustedProxyCheck = true
+	app.TrustedProxies = c.TrustedProxies
+	app.ProxyHeader = fiber.HeaderXForwardedFor
+}
+
+// CORSMiddleware answers preflight requests and sets the CORS headers for the
+// allowed origins. Without any, it sets no headers, so browsers only allow
+// same-origin requests.
+func (c Config) CORSMiddleware() fiber.Handler {
+	if len(c.CORS.AllowOrigins) == 0 {
+		return func(ctx *fiber.Ctx) error {
+			return ctx.Next()
+		}
+	}
+
+	allowHeaders := corsAllowHeaders
+	if c.CORS.AllowAuthorization {
+		allowHeaders += ", " + corsAuthHeaders
+	}
+	return cors.New(cors.Config{
+		AllowOrigins:     strings.Join(c.CORS.AllowOrigins, ","),
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     allowHeaders,
+		AllowCredentials: c.CORS.AllowCredentials,
+		ExposeHeaders:    corsExposeHeaders,
+		MaxAge:           int(time.Duration(c.CORS.MaxAge).Seconds()),
+	})
+}
+
+// SecurityMiddleware sets the security headers on every response
+func (c Config) SecurityMiddleware() fiber.Handler {
+	security := c.Security
+	return helmet.New(helmet.Config{
+		ContentTypeNosniff:        "nosniff",
+		XFrameOptions:             strings.ToUpper(security.FrameOptions),
+		ContentSecurityPolicy:     security.ContentSecurityPolicy,
+		ReferrerPolicy:            security.ReferrerPolicy,
+		PermissionPolicy:          security.PermissionsPolicy,
+		HSTSMaxAge:                int(time.Duration(security.HSTS.MaxAge).Seconds()),
+		HSTSExcludeSubdomains:     !security.HSTS.IncludeSubdomains,
+		HSTSPreloadEnabled:        security.HSTS.Preload,
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginResourcePolicy: "same-origin",
+		// The API is called across origins, so embedders need not opt in
+		CrossOriginEmbedderPolicy: "unsafe-none",
+	})
+}
@@ -0,0 +1,109 @@
+package container
+
+import (
+	"context"
+
+	"expense-api/health"
+	"expense-api/idempotency"
+	"expense-api/jobs"
+	"expense-api/models"
+	"expense-api/ratelimit"
+	"expense-api/repository"
+	"expense-api/services"
+	"expense-api/webhooks"
+)
+
+// Container holds the services the HTTP handlers are built from
+type Container struct {
+	// DB returns the database connection, or nil while it is not ready.
+	// It is nil for containers that do not use a database.
+	DB repository.Provider
+
+	Transactions services.TransactionService
+	Accounts     services.AccountService
+	Categories   services.CategoryService
+	Payees       services.PayeeService
+	Recurring    services.RecurringService
+	Anomalies    services.AnomalyService
+	Users        services.UserService
+	Webhooks     services.WebhookService
+
+	// CheckAnomalies judges every transaction created through POST /api/transactions
+	// and publishes transaction.anomaly for the ones out of character
+	CheckAnomalies bool
+
+	// Jobs runs background work such as asynchronous imports. It is nil without a database.
+	Jobs *jobs.Runner
+
+	// Idempotency stores responses to POST requests sent with an Idempotency-Key.
+	// It is nil without a database.
+	Idempotency *idempotency.Store
+
+	// RateLimiter limits the requests of each client. It is nil when rate limiting is off.
+	RateLimiter *ratelimit.Limiter
+
+	// Liveness and Readiness hold the checks behind /livez and /readyz
+	Liveness  *health.Registry
+	Readiness *health.Registry
+}
+
+// New creates a container whose services store data through GORM
+func New(db repository.Provider) *Container {
+	transactions := repository.NewGormTransactions(db)
+	categories := repository.NewGormCategories(db)
+	accounts := repository.NewGormAccounts(db)
+	payees := repository.NewGormPayees(db)
+	transactionService := services.NewTransactionService(transactions, categories, accounts, payees)
+	sendTest := func(ctx context.Context, subscription models.WebhookSubscription) (models.WebhookDelivery, error) {
+		return webhooks.NewDispatcher(db.WithContext(ctx)()).SendTest(ctx, subscription)
+	}
+
+	c := &Container{
+		DB:           db,
+		Transactions: transactionService,
+		Accounts:     services.NewAccountService(accounts, transactions),
+		Categories:   services.NewCategoryService(categories, transactions),
+		Payees:       services.NewPayeeService(payees, categories, transactions),
+		Recurring:    services.NewRecurringService(repository.NewGormRecurring(db), transactionService),
+		Anomalies:    services.NewAnomalyService(transactionService),
+		Users:        services.NewUserService(repository.NewGormUsers(db)),
+		Webhooks:     services.NewWebhookService(repository.NewGormWebhooks(db), sendTest),
+		Jobs:         jobs.NewRunner(db),
+		Idempotency:  idempotency.NewStore(db),
+		Liveness:     health.NewRegistry(),
+		Readiness:    health.NewRegistry(),
+	}
+	c.Readiness.Register("database", health.Database(db))
+	c.Readiness.Register("migrations", health.Migrations(db))
+	c.Readiness.Register("storage", health.Writable(db))
+	c.Readiness.Register("jobs", c.Jobs.Check)
+	return c
+}
+
+// NewInMemory creates a container whose services keep data in store.
+// Background jobs, idempotency and sending webhook deliveries need the database, and New.
+func NewInMemory(store *repository.MemoryStore) *Container {
+	transactions := store.Transactions()
+	categories := store.Categories()
+	accounts := store.Accounts()
+	payees := store.Payees()
+	transactionService := services.NewTransactionService(transactions, categories, accounts, payees)
+
+	return &Container{
+		Transactions: transactionService,
+		Accounts:     services.NewAccountService(accounts, transactions),
+		Categories:   services.NewCategoryService(categories, transactions),
+		Payees:       services.NewPayeeService(payees, categories, transactions),
+		Recurring:    services.NewRecurringService(store.Recurring(), transactionService),
+		Anomalies:    services.NewAnomalyService(transactionService),
+		Users:        services.NewUserService(store.Users()),
+		Webhooks:     services.NewWebhookService(store.Webhooks(), nil),
+		Liveness:     health.NewRegistry(),
+		Readiness:    health.NewRegistry(),
+	}
+}
+
+// Ready reports whether the storage behind the services can be used
+func (c *Container) Ready() bool {
+	return c.DB == nil || c.DB() != nil
+}
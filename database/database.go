@@ -1,17 +1,17 @@
 package database
 
 import (
-	"log"
 	"os"
 	"strings"
 	"time"
 
+	"expense-api/database/migrations"
+	"expense-api/logging"
 	"expense-api/models"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
@@ -28,17 +28,17 @@ func Connect() {
 
 	// If still no database URL, use SQLite as fallback
 	if dbURL == "" {
-		log.Println("No database URL found, using SQLite as fallback")
+		logging.For(logging.ComponentDB).Info("No database URL found, using SQLite as fallback")
 		dbURL = "sqlite://expense.db"
 	}
 
-	log.Printf("Attempting to connect to database with URL: %s", maskPassword(dbURL))
+	logging.For(logging.ComponentDB).Info("Attempting to connect to database", "url", maskPassword(dbURL))
 
 	// Retry connection logic
 	maxRetries := 5
 	for i := 0; i < maxRetries; i++ {
 		if i > 0 {
-			log.Printf("Retry attempt %d/%d", i+1, maxRetries)
+			logging.For(logging.ComponentDB).Info("Retrying database connection", "attempt", i+1, "max_attempts", maxRetries)
 			time.Sleep(time.Duration(i*2) * time.Second)
 		}
 
@@ -46,12 +46,12 @@ func Connect() {
 			// SQLite connection
 			dbPath := strings.TrimPrefix(dbURL, "sqlite://")
 			DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-				Logger: logger.Default.LogMode(logger.Info),
+				Logger: logging.NewGormLogger(),
 			})
 		} else {
 			// PostgreSQL connection
 			DB, err = gorm.Open(postgres.Open(dbURL), &gorm.Config{
-				Logger: logger.Default.LogMode(logger.Info),
+				Logger: logging.NewGormLogger(),
 			})
 		}
 
@@ -59,14 +59,20 @@ func Connect() {
 			break
 		}
 
-		log.Printf("Database connection attempt %d failed: %v", i+1, err)
+		logging.For(logging.ComponentDB).Warn("Database connection attempt failed", "attempt", i+1, "error", err)
 	}
 
 	if err != nil {
-		log.Fatal("Failed to connect to database after all retries:", err)
+		fatal("Failed to connect to database after all retries", err)
 	}
 
-	log.Println("Database connected successfully")
+	logging.For(logging.ComponentDB).Info("Database connected successfully")
+}
+
+// fatal logs err and exits, like log.Fatal
+func fatal(msg string, err error) {
+	logging.For(logging.ComponentDB).Error(msg, "error", err)
+	os.Exit(1)
 }
 
 // maskPassword masks the password in database URL for logging
@@ -86,13 +92,17 @@ func maskPassword(dbURL string) string {
 	return dbURL
 }
 
-// Migrate runs database migrations
+// Migrate applies pending schema migrations
 func Migrate() {
-	err := DB.AutoMigrate(&models.BankAccount{}, &models.Category{}, &models.Transaction{})
+	migrator, err := migrations.New(DB)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		fatal("Failed to load migrations", err)
 	}
-	log.Println("Database migrated successfully")
+	applied, err := migrator.Up()
+	if err != nil {
+		fatal("Failed to migrate database", err)
+	}
+	logging.For(logging.ComponentDB).Info("Database migrated successfully", "applied", len(applied))
 }
 
 // SeedDefaultCategories populates the database with default categories
@@ -101,7 +111,7 @@ func SeedDefaultCategories() {
 	DB.Model(&models.Category{}).Count(&count)
 
 	if count > 0 {
-		log.Println("Categories already seeded, skipping...")
+		logging.For(logging.ComponentDB).Info("Categories already seeded, skipping")
 		return
 	}
 
@@ -117,11 +127,11 @@ func SeedDefaultCategories() {
 
 	for _, category := range defaultCategories {
 		if err := DB.Create(&category).Error; err != nil {
-			log.Printf("Failed to create category %s: %v", category.Name, err)
+			logging.For(logging.ComponentDB).Error("Failed to create category", "name", category.Name, "error", err)
 		}
 	}
 
-	log.Printf("Seeded %d default categories", len(defaultCategories))
+	logging.For(logging.ComponentDB).Info("Seeded default categories", "count", len(defaultCategories))
 }
 
 // SeedDefaultBankAccounts populates the database with default bank accounts
@@ -130,37 +140,51 @@ func SeedDefaultBankAccounts() {
 	DB.Model(&models.BankAccount{}).Count(&count)
 
 	if count > 0 {
-		log.Println("Bank accounts already seeded, skipping...")
+		logging.For(logging.ComponentDB).Info("Bank accounts already seeded, skipping")
 		return
 	}
 
 	defaultBankAccounts := []models.BankAccount{
 		{
-			Name:        "Primary Checking",
-			BankName:    "Chase Bank",
-			AccountType: "checking",
-			Balance:     1000.00,
-			IsActive:    true,
+			Name:           "Primary Checking",
+			BankName:       "Chase Bank",
+			AccountType:    "checking",
+			Balance:        1000.00,
+			OpeningBalance: 1000.00,
+			IsActive:       true,
 		},
 		{
-			Name:        "Savings Account",
-			BankName:    "Chase Bank", 
-			AccountType: "savings",
-			Balance:     5000.00,
-			IsActive:    true,
+			Name:           "Savings Account",
+			BankName:       "Chase Bank", 
+			AccountType:    "savings",
+			Balance:        5000.00,
+			OpeningBalance: 5000.00,
+			IsActive:       true,
 		},
 	}
 
 	for _, account := range defaultBankAccounts {
 		if err := DB.Create(&account).Error; err != nil {
-			log.Printf("Failed to create bank account %s: %v", account.Name, err)
+			logging.For(logging.ComponentDB).Error("Failed to create bank account", "name", account.Name, "error", err)
 		}
 	}
 
-	log.Printf("Seeded %d default bank accounts", len(defaultBankAccounts))
+	logging.For(logging.ComponentDB).Info("Seeded default bank accounts", "count", len(defaultBankAccounts))
 }
 
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// Close closes the database connection, if one was opened
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,322 @@
+// Package migrations applies ordered, versioned schema changes and records
+// them in the schema_migrations table.
+//
+// SQL migrations live in a directory per dialect (postgres, sqlite) and are
+// named <version>_<name>.up.sql and <version>_<name>.down.sql. Changes that
+// need code, such as data backfills, are added with Register.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var scripts embed.FS
+
+// Supported dialects, as reported by the GORM dialector
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// lockID is the Postgres advisory lock held while migrations run
+const lockID int64 = 72_019_464_117
+
+// ErrNoDown is returned when rolling back a migration that cannot be undone
+var ErrNoDown = errors.New("migration has no down step")
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change. Up and Down run inside a transaction.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Unknown is set for applied versions this build has no migration for,
+	// e.g. after rolling back to an older release
+	Unknown bool
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var registered []Migration
+
+// Register adds a Go migration for every dialect. Its version must not be
+// used by an SQL migration. Call it from an init function in this package.
+func Register(migration Migration) {
+	registered = append(registered, migration)
+}
+
+// Load returns the SQL and Go migrations for a dialect, ordered by version
+func Load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(scripts, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s/%s", dialect, entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		script, err := fs.ReadFile(scripts, dialect+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files named %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = execScript(string(script))
+		} else {
+			migration.Down = execScript(string(script))
+		}
+	}
+
+	for _, migration := range registered {
+		if _, ok := byVersion[migration.Version]; ok {
+			return nil, fmt.Errorf("migration version %d is used twice", migration.Version)
+		}
+		migration := migration
+		byVersion[migration.Version] = &migration
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up step", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// execScript runs a whole SQL file; both drivers accept several statements per call
+func execScript(script string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec(script).Error
+	}
+}
+
+// Migrator applies and rolls back migrations on a database
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+// New creates a migrator with the migrations for the database's dialect
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.Migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := apply(conn, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recent steps migrations and returns the ones rolled back
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	known := make(map[int64]Migration, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		known[migration.Version] = migration
+	}
+
+	var rolledBack []Migration
+	err := m.locked(func(conn *gorm.DB) error {
+		var rows []schemaMigration
+		if err := conn.Order("version DESC").Limit(steps).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			migration, ok := known[row.Version]
+			if !ok {
+				return fmt.Errorf("migration %d_%s is not part of this build", row.Version, row.Name)
+			}
+			if err := revert(conn, migration); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Pending lists the migrations that are not applied yet. Unlike Status it
+// never writes, so it can run against a read-only database.
+func (m *Migrator) Pending() ([]Migration, error) {
+	if !m.DB.Migrator().HasTable(&schemaMigration{}) {
+		return m.Migrations, nil
+	}
+	done, err := appliedVersions(m.DB)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := done[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Status lists every known migration and any applied version this build does not know
+func (m *Migrator) Status() ([]Status, error) {
+	if err := ensureTable(m.DB); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := m.DB.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	statuses := make([]Status, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range rows {
+		if _, ok := applied[row.Version]; ok {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// locked runs fn on a single connection while holding the migration lock.
+// On Postgres this is a session advisory lock, so replicas starting together
+// apply each migration once. SQLite has no advisory locks; there the primary
+// key of schema_migrations makes a racing run fail and roll back instead.
+func (m *Migrator) locked(fn func(conn *gorm.DB) error) error {
+	return m.DB.Connection(func(conn *gorm.DB) error {
+		if conn.Dialector.Name() == DialectPostgres {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+				return fmt.Errorf("acquire migration lock: %w", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", lockID)
+		}
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamp NOT NULL
+	)`).Error
+}
+
+func appliedVersions(db *gorm.DB) (map[int64]struct{}, error) {
+	var versions []int64
+	if err := db.Model(&schemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int64]struct{}, len(versions))
+	for _, version := range versions {
+		done[version] = struct{}{}
+	}
+	return done, nil
+}
+
+func apply(conn *gorm.DB, migration Migration) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func revert(conn *gorm.DB, migration Migration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("roll back migration %d_%s: %w", migration.Version, migration.Name, ErrNoDown)
+	}
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&schemaMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	// Every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+type column struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Pk      int
+}
+
+func describe(t *testing.T, db *gorm.DB) map[string][]column {
+	var tables []string
+	assert.NoError(t, db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT IN ('sqlite_sequence', 'schema_migrations')").Scan(&tables).Error)
+
+	schema := make(map[string][]column)
+	for _, table := range tables {
+		var columns []column
+		assert.NoError(t, db.Raw("SELECT name, type, \"notnull\" AS not_null, pk FROM pragma_table_info(?) ORDER BY name", table).Scan(&columns).Error)
+		var indexes []string
+		assert.NoError(t, db.Raw("SELECT name FROM pragma_index_list(?) WHERE origin = 'c' ORDER BY name", table).Scan(&indexes).Error)
+		for _, index := range indexes {
+			columns = append(columns, column{Name: "index " + index})
+		}
+		schema[table] = columns
+	}
+	return schema
+}
+
+func TestSQLiteSchemaMatchesModels(t *testing.T) {
+	migrated := openTestDB(t)
+	migrator, err := New(migrated)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	auto := openTestDB(t)
+	assert.NoError(t, auto.AutoMigrate(&models.BankAccount{}, &models.Category{}, &models.Transaction{}, &models.TransactionVersion{}, &models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.Job{}, &models.IdempotencyRecord{}, &models.User{}, &models.Payee{}, &models.PayeeAlias{}, &models.RecurringTransaction{}))
+
+	expected := describe(t, auto)
+	assert.NotEmpty(t, expected)
+	assert.Equal(t, expected, describe(t, migrated))
+}
+
+func TestDialectsShareVersions(t *testing.T) {
+	postgres, err := Load(DialectPostgres)
+	assert.NoError(t, err)
+	sqlite, err := Load(DialectSQLite)
+	assert.NoError(t, err)
+
+	assert.Equal(t, len(postgres), len(sqlite))
+	for i := range postgres {
+		assert.Equal(t, postgres[i].Version, sqlite[i].Version)
+		assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+		assert.NotNil(t, postgres[i].Down)
+		assert.NotNil(t, sqlite[i].Down)
+	}
+}
+
+func TestUpDownAndStatus(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := New(db)
+	assert.NoError(t, err)
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(migrator.Migrations))
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, statuses) {
+		assert.False(t, statuses[0].Applied)
+	}
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(migrator.Migrations))
+	assert.True(t, db.Migrator().HasTable(&models.Transaction{}))
+
+	pending, err = migrator.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Applying again is a no-op
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err = migrator.Status()
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	rolledBack, err := migrator.Down(len(migrator.Migrations))
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, len(migrator.Migrations))
+	assert.False(t, db.Migrator().HasTable(&models.Transaction{}))
+
+	statuses, err = migrator.Status()
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.False(t, status.Applied)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := openTestDB(t)
+	migrator := &Migrator{DB: db, Migrations: []Migration{
+		{Version: 1, Name: "create_notes", Up: execScript("CREATE TABLE notes (id integer PRIMARY KEY, body text)")},
+		{Version: 2, Name: "backfill_notes", Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("INSERT INTO notes (body) VALUES ('draft')").Error; err != nil {
+				return err
+			}
+			return errors.New("backfill failed")
+		}},
+	}}
+
+	applied, err := migrator.Up()
+	assert.ErrorContains(t, err, "apply migration 2_backfill_notes: backfill failed")
+	assert.Len(t, applied, 1)
+
+	// The failed migration left no rows behind and is still pending
+	var notes int64
+	db.Table("notes").Count(&notes)
+	assert.Equal(t, int64(0), notes)
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+
+	// Migrations without a down step cannot be rolled back
+	_, err = migrator.Down(1)
+	assert.ErrorIs(t, err, ErrNoDown)
+
+	// Applied versions this build has no migration for are still reported
+	migrator.Migrations = migrator.Migrations[1:]
+	statuses, err = migrator.Status()
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.True(t, statuses[0].Unknown)
+		assert.Equal(t, "create_notes", statuses[0].Name)
+	}
+}
+
+func TestCategoryParentsKeepsCategories(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := New(db)
+	assert.NoError(t, err)
+	all := migrator.Migrations
+
+	// Stop before 0003_category_parents and add data the rebuild must keep
+	migrator.Migrations = all[:2]
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.NoError(t, db.Exec("INSERT INTO categories (id, name, type) VALUES (7, 'Bills', 'expense'), (8, 'Salary', 'income')").Error)
+
+	migrator.Migrations = all
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	var categories []models.Category
+	assert.NoError(t, db.Order("id").Find(&categories).Error)
+	if assert.Len(t, categories, 2) {
+		assert.Equal(t, "Bills", categories[0].Name)
+		assert.Nil(t, categories[0].ParentID)
+	}
+
+	// Names are unique among siblings only
+	assert.NoError(t, db.Exec("INSERT INTO categories (name, type, parent_id) VALUES ('Bills', 'expense', 7)").Error)
+	assert.Error(t, db.Exec("INSERT INTO categories (name, type, parent_id) VALUES ('Bills', 'expense', 7)").Error)
+
+	// New rows continue after the kept IDs
+	var next uint
+	assert.NoError(t, db.Raw("SELECT MAX(id) FROM categories").Scan(&next).Error)
+	assert.Equal(t, uint(9), next)
+}
+
+func TestUniqueTransactionVersionsRenumbersDuplicates(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := New(db)
+	assert.NoError(t, err)
+	all := migrator.Migrations
+
+	// Stop before 0008_unique_transaction_versions and add versions written concurrently
+	migrator.Migrations = all[:7]
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.NoError(t, db.Exec(`INSERT INTO transaction_versions (id, record_id, version, operation) VALUES
+		(1, 5, 1, 'create'), (2, 5, 2, 'update'), (3, 5, 2, 'update'), (4, 6, 1, 'create'), (5, 5, 3, 'delete')`).Error)
+
+	migrator.Migrations = all
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	var versions []models.TransactionVersion
+	assert.NoError(t, db.Order("id").Find(&versions).Error)
+	numbers := make([]int, len(versions))
+	for i, version := range versions {
+		numbers[i] = version.Version
+	}
+	assert.Equal(t, []int{1, 2, 3, 1, 4}, numbers)
+
+	assert.Error(t, db.Exec("INSERT INTO transaction_versions (record_id, version, operation) VALUES (6, 1, 'update')").Error)
+}
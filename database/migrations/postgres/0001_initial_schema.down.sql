@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS idempotency_records;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS transaction_versions;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS bank_accounts;
@@ -0,0 +1,132 @@
+-- Baseline schema. Every statement is guarded so databases created by the
+-- old AutoMigrate startup step adopt this version without changes.
+
+CREATE TABLE IF NOT EXISTS bank_accounts (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    account_number text,
+    bank_name text NOT NULL,
+    account_type text NOT NULL,
+    balance decimal DEFAULT 0,
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT chk_bank_accounts_account_type CHECK (account_type IN ('checking', 'savings', 'credit', 'investment', 'other'))
+);
+CREATE INDEX IF NOT EXISTS idx_bank_accounts_deleted_at ON bank_accounts (deleted_at);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL UNIQUE,
+    type text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT chk_categories_type CHECK (type IN ('expense', 'income'))
+);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id bigserial PRIMARY KEY,
+    transaction_id text,
+    amount decimal NOT NULL,
+    type text NOT NULL,
+    category_id bigint,
+    bank_account_id bigint NOT NULL,
+    destination_bank_account_id bigint,
+    description text NOT NULL,
+    date timestamptz NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_transactions_category FOREIGN KEY (category_id) REFERENCES categories (id),
+    CONSTRAINT fk_transactions_bank_account FOREIGN KEY (bank_account_id) REFERENCES bank_accounts (id),
+    CONSTRAINT fk_transactions_destination_bank_account FOREIGN KEY (destination_bank_account_id) REFERENCES bank_accounts (id),
+    CONSTRAINT chk_transactions_type CHECK (type IN ('expense', 'income', 'transfer'))
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_deleted_at ON transactions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS transaction_versions (
+    id bigserial PRIMARY KEY,
+    record_id bigint NOT NULL,
+    version bigint NOT NULL,
+    operation text NOT NULL,
+    transaction_id text,
+    amount decimal,
+    type text,
+    category_id bigint,
+    bank_account_id bigint,
+    destination_bank_account_id bigint,
+    description text,
+    date timestamptz,
+    deleted boolean,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_transaction_versions_record_version ON transaction_versions (record_id, version);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id bigserial PRIMARY KEY,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text NOT NULL,
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    subscription_id bigint NOT NULL,
+    event text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL,
+    attempts bigint DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_status_code bigint,
+    last_error text,
+    delivered_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT chk_webhook_deliveries_status CHECK (status IN ('pending', 'succeeded', 'failed'))
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id bigserial PRIMARY KEY,
+    type text NOT NULL,
+    status text NOT NULL,
+    payload text NOT NULL,
+    result text,
+    total bigint,
+    processed bigint,
+    attempts bigint DEFAULT 0,
+    last_error text,
+    started_at timestamptz,
+    finished_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT chk_jobs_status CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled'))
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_type ON jobs (type);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs (status);
+
+CREATE TABLE IF NOT EXISTS idempotency_records (
+    idempotency_key varchar(255) PRIMARY KEY,
+    fingerprint text NOT NULL,
+    status text NOT NULL,
+    response_code bigint,
+    content_type text,
+    location text,
+    response_body text,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT chk_idempotency_records_status CHECK (status IN ('in_progress', 'completed'))
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
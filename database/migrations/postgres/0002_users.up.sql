@@ -0,0 +1,9 @@
+CREATE TABLE users (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    token_hash text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_users_name ON users (name);
+CREATE UNIQUE INDEX idx_users_token_hash ON users (token_hash);
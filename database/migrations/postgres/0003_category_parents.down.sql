@@ -0,0 +1,6 @@
+-- Fails if two subcategories share a name, as names become globally unique again
+
+DROP INDEX IF EXISTS idx_categories_parent_name;
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS fk_categories_parent;
+ALTER TABLE categories DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,9 @@
+-- Categories can be nested under a parent of the same type. Names only need
+-- to be unique among siblings. The old constraint is named after whichever
+-- tool created the table.
+
+ALTER TABLE categories ADD COLUMN parent_id bigint;
+ALTER TABLE categories ADD CONSTRAINT fk_categories_parent FOREIGN KEY (parent_id) REFERENCES categories (id);
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS uni_categories_name;
+CREATE UNIQUE INDEX idx_categories_parent_name ON categories (parent_id, name);
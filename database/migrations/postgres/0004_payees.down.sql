@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transactions_payee_id;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS fk_transactions_payee;
+ALTER TABLE transactions DROP COLUMN IF EXISTS payee_id;
+DROP TABLE IF EXISTS payee_aliases;
+DROP TABLE IF EXISTS payees;
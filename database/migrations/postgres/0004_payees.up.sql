@@ -0,0 +1,24 @@
+-- Payees name the merchants behind raw bank descriptions, which their aliases match
+
+CREATE TABLE payees (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    default_category_id bigint,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_payees_default_category FOREIGN KEY (default_category_id) REFERENCES categories (id)
+);
+CREATE UNIQUE INDEX idx_payees_name ON payees (name);
+
+CREATE TABLE payee_aliases (
+    id bigserial PRIMARY KEY,
+    payee_id bigint NOT NULL,
+    pattern text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_payees_aliases FOREIGN KEY (payee_id) REFERENCES payees (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_payee_aliases_payee_id ON payee_aliases (payee_id);
+
+ALTER TABLE transactions ADD COLUMN payee_id bigint;
+ALTER TABLE transactions ADD CONSTRAINT fk_transactions_payee FOREIGN KEY (payee_id) REFERENCES payees (id);
+CREATE INDEX idx_transactions_payee_id ON transactions (payee_id);
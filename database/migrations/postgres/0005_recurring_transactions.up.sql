@@ -0,0 +1,21 @@
+-- Recurring transactions post the same expense at a regular cadence, such as
+-- a subscription found by GET /api/insights/subscriptions
+
+CREATE TABLE recurring_transactions (
+    id bigserial PRIMARY KEY,
+    description text NOT NULL,
+    amount decimal NOT NULL,
+    category_id bigint,
+    bank_account_id bigint NOT NULL,
+    payee_id bigint,
+    cadence text NOT NULL,
+    next_date timestamptz NOT NULL,
+    subscription_key text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_recurring_transactions_category FOREIGN KEY (category_id) REFERENCES categories (id),
+    CONSTRAINT fk_recurring_transactions_bank_account FOREIGN KEY (bank_account_id) REFERENCES bank_accounts (id),
+    CONSTRAINT fk_recurring_transactions_payee FOREIGN KEY (payee_id) REFERENCES payees (id)
+);
+CREATE INDEX idx_recurring_transactions_next_date ON recurring_transactions (next_date);
+CREATE INDEX idx_recurring_transactions_subscription_key ON recurring_transactions (subscription_key);
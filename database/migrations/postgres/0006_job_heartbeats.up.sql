@@ -0,0 +1,4 @@
+-- Workers report in while running a job, so a job left running by a stopped
+-- worker can be told apart from one another replica is still working on
+
+ALTER TABLE jobs ADD COLUMN heartbeat_at timestamptz;
@@ -0,0 +1 @@
+ALTER TABLE bank_accounts DROP COLUMN opening_balance;
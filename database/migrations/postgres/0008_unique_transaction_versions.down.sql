@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transaction_versions_record_version;
+CREATE INDEX IF NOT EXISTS idx_transaction_versions_record_version ON transaction_versions (record_id, version);
@@ -0,0 +1,3 @@
+-- Fails while a deleted category has the name of a live sibling
+DROP INDEX IF EXISTS idx_categories_live_parent_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_parent_name ON categories (parent_id, name);
@@ -0,0 +1,15 @@
+-- Category names were unique per (parent_id, name), which let top-level
+-- categories share a name, since NULL parents never compare equal, and kept
+-- deleted and merged categories' names taken. Names are now unique among
+-- live siblings, with top-level categories counted under parent 0.
+
+-- Rename live top-level duplicates the old index let through, keeping the oldest
+UPDATE categories SET name = name || ' (' || id || ')'
+WHERE parent_id IS NULL AND deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM categories AS earlier
+    WHERE earlier.parent_id IS NULL AND earlier.deleted_at IS NULL
+        AND earlier.name = categories.name AND earlier.id < categories.id
+);
+
+DROP INDEX IF EXISTS idx_categories_parent_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_live_parent_name ON categories (COALESCE(parent_id, 0), name) WHERE deleted_at IS NULL;
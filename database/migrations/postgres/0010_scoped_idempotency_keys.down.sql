@@ -0,0 +1,16 @@
+-- Stored responses are dropped, since keys of different clients may collide
+DROP TABLE IF EXISTS idempotency_records;
+CREATE TABLE idempotency_records (
+    idempotency_key varchar(255) PRIMARY KEY,
+    fingerprint text NOT NULL,
+    status text NOT NULL,
+    response_code bigint,
+    content_type text,
+    location text,
+    response_body text,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT chk_idempotency_records_status CHECK (status IN ('in_progress', 'completed'))
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
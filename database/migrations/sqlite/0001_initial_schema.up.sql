@@ -0,0 +1,133 @@
+-- Baseline schema. Every statement is guarded so databases created by the
+-- old AutoMigrate startup step adopt this version without changes.
+
+CREATE TABLE IF NOT EXISTS bank_accounts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    account_number text,
+    bank_name text NOT NULL,
+    account_type text NOT NULL,
+    balance real DEFAULT 0,
+    is_active numeric DEFAULT true,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    CONSTRAINT chk_bank_accounts_account_type CHECK (account_type IN ('checking', 'savings', 'credit', 'investment', 'other'))
+);
+CREATE INDEX IF NOT EXISTS idx_bank_accounts_deleted_at ON bank_accounts (deleted_at);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL UNIQUE,
+    type text NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    CONSTRAINT chk_categories_type CHECK (type IN ('expense', 'income'))
+);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    transaction_id text,
+    amount real NOT NULL,
+    type text NOT NULL,
+    category_id integer,
+    bank_account_id integer NOT NULL,
+    destination_bank_account_id integer,
+    description text NOT NULL,
+    date datetime NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    CONSTRAINT fk_transactions_category FOREIGN KEY (category_id) REFERENCES categories (id),
+    CONSTRAINT fk_transactions_bank_account FOREIGN KEY (bank_account_id) REFERENCES bank_accounts (id),
+    CONSTRAINT fk_transactions_destination_bank_account FOREIGN KEY (destination_bank_account_id) REFERENCES bank_accounts (id),
+    CONSTRAINT chk_transactions_type CHECK (type IN ('expense', 'income', 'transfer'))
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_deleted_at ON transactions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS transaction_versions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    record_id integer NOT NULL,
+    version integer NOT NULL,
+    operation text NOT NULL,
+    transaction_id text,
+    amount real,
+    type text,
+    category_id integer,
+    bank_account_id integer,
+    destination_bank_account_id integer,
+    description text,
+    date datetime,
+    deleted numeric,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_transaction_versions_record_version ON transaction_versions (record_id, version);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text NOT NULL,
+    is_active numeric DEFAULT true,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    subscription_id integer NOT NULL,
+    event text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL,
+    attempts integer DEFAULT 0,
+    next_attempt_at datetime,
+    last_status_code integer,
+    last_error text,
+    delivered_at datetime,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT chk_webhook_deliveries_status CHECK (status IN ('pending', 'succeeded', 'failed'))
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    type text NOT NULL,
+    status text NOT NULL,
+    payload text NOT NULL,
+    result text,
+    total integer,
+    processed integer,
+    attempts integer DEFAULT 0,
+    last_error text,
+    started_at datetime,
+    finished_at datetime,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT chk_jobs_status CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled'))
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_type ON jobs (type);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs (status);
+
+CREATE TABLE IF NOT EXISTS idempotency_records (
+    idempotency_key text,
+    fingerprint text NOT NULL,
+    status text NOT NULL,
+    response_code integer,
+    content_type text,
+    location text,
+    response_body text,
+    expires_at datetime NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (idempotency_key),
+    CONSTRAINT chk_idempotency_records_status CHECK (status IN ('in_progress', 'completed'))
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
@@ -0,0 +1,17 @@
+-- Fails if two subcategories share a name, as names become globally unique again
+
+CREATE TABLE categories_old (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL UNIQUE,
+    type text NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    CONSTRAINT chk_categories_type CHECK (type IN ('expense', 'income'))
+);
+INSERT INTO categories_old (id, name, type, created_at, updated_at, deleted_at)
+    SELECT id, name, type, created_at, updated_at, deleted_at FROM categories;
+DROP TABLE categories;
+ALTER TABLE categories_old RENAME TO categories;
+
+CREATE INDEX idx_categories_deleted_at ON categories (deleted_at);
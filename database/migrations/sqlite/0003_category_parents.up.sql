@@ -0,0 +1,22 @@
+-- Categories can be nested under a parent of the same type. Names only need
+-- to be unique among siblings, so the table is rebuilt without the UNIQUE
+-- constraint on name, which SQLite cannot drop in place.
+
+CREATE TABLE categories_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    type text NOT NULL,
+    parent_id integer,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    CONSTRAINT fk_categories_parent FOREIGN KEY (parent_id) REFERENCES categories (id),
+    CONSTRAINT chk_categories_type CHECK (type IN ('expense', 'income'))
+);
+INSERT INTO categories_new (id, name, type, created_at, updated_at, deleted_at)
+    SELECT id, name, type, created_at, updated_at, deleted_at FROM categories;
+DROP TABLE categories;
+ALTER TABLE categories_new RENAME TO categories;
+
+CREATE INDEX idx_categories_deleted_at ON categories (deleted_at);
+CREATE UNIQUE INDEX idx_categories_parent_name ON categories (parent_id, name);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_payee_id;
+ALTER TABLE transactions DROP COLUMN payee_id;
+DROP TABLE IF EXISTS payee_aliases;
+DROP TABLE IF EXISTS payees;
@@ -0,0 +1,25 @@
+-- Payees name the merchants behind raw bank descriptions, which their aliases
+-- match. transactions.payee_id is added without a foreign key because SQLite
+-- cannot drop a column that is part of one, which the down migration needs.
+
+CREATE TABLE payees (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    default_category_id integer,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_payees_default_category FOREIGN KEY (default_category_id) REFERENCES categories (id)
+);
+CREATE UNIQUE INDEX idx_payees_name ON payees (name);
+
+CREATE TABLE payee_aliases (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    payee_id integer NOT NULL,
+    pattern text NOT NULL,
+    created_at datetime,
+    CONSTRAINT fk_payees_aliases FOREIGN KEY (payee_id) REFERENCES payees (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_payee_aliases_payee_id ON payee_aliases (payee_id);
+
+ALTER TABLE transactions ADD COLUMN payee_id integer;
+CREATE INDEX idx_transactions_payee_id ON transactions (payee_id);
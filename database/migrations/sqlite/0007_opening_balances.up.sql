@@ -0,0 +1,5 @@
+-- Creating a transaction does not change the balance of its account, so the
+-- stored balance of an existing account is the one it was opened with
+
+ALTER TABLE bank_accounts ADD COLUMN opening_balance real DEFAULT 0;
+UPDATE bank_accounts SET opening_balance = balance;
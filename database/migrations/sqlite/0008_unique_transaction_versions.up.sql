@@ -0,0 +1,11 @@
+-- Versions written concurrently could share a number. Renumber every
+-- transaction's versions in the order they were written before making
+-- the pair unique.
+
+UPDATE transaction_versions SET version = (
+    SELECT COUNT(*) FROM transaction_versions AS earlier
+    WHERE earlier.record_id = transaction_versions.record_id AND earlier.id <= transaction_versions.id
+);
+
+DROP INDEX IF EXISTS idx_transaction_versions_record_version;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_versions_record_version ON transaction_versions (record_id, version);
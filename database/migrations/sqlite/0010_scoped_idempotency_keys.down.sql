@@ -0,0 +1,17 @@
+-- Stored responses are dropped, since keys of different clients may collide
+DROP TABLE IF EXISTS idempotency_records;
+CREATE TABLE idempotency_records (
+    idempotency_key text,
+    fingerprint text NOT NULL,
+    status text NOT NULL,
+    response_code integer,
+    content_type text,
+    location text,
+    response_body text,
+    expires_at datetime NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (idempotency_key),
+    CONSTRAINT chk_idempotency_records_status CHECK (status IN ('in_progress', 'completed'))
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
@@ -0,0 +1,21 @@
+-- Idempotency keys were global, so two clients that sent the same key got each
+-- other's responses. Keys are now scoped by client. Stored responses only live
+-- for a day and cannot be attributed to a client, so they are dropped.
+
+DROP TABLE IF EXISTS idempotency_records;
+CREATE TABLE idempotency_records (
+    client text,
+    idempotency_key text,
+    fingerprint text NOT NULL,
+    status text NOT NULL,
+    response_code integer,
+    content_type text,
+    location text,
+    response_body text,
+    expires_at datetime NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (client, idempotency_key),
+    CONSTRAINT chk_idempotency_records_status CHECK (status IN ('in_progress', 'completed'))
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
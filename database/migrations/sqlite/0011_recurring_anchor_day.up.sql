@@ -0,0 +1,6 @@
+-- Monthly and yearly schedules carry the day of the month they are charged on,
+-- so a charge moved to the end of a shorter month does not move the later ones.
+-- Existing schedules are anchored on their next date.
+
+ALTER TABLE recurring_transactions ADD COLUMN anchor_day integer;
+UPDATE recurring_transactions SET anchor_day = CAST(strftime('%d', next_date) AS integer);
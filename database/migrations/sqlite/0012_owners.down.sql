@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_jobs_user_id;
+ALTER TABLE jobs DROP COLUMN user_id;
+
+DROP INDEX IF EXISTS idx_webhook_subscriptions_user_id;
+ALTER TABLE webhook_subscriptions DROP COLUMN user_id;
@@ -0,0 +1,12 @@
+-- Webhook subscriptions and jobs belong to the user who created them. Webhooks
+-- only receive the events their owner may see, and jobs can only be read,
+-- cancelled or retried by their owner. Existing subscriptions cannot be
+-- attributed to a user and stay with the anonymous owner; jobs take the user
+-- recorded in their payload.
+
+ALTER TABLE webhook_subscriptions ADD COLUMN user_id integer NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_user_id ON webhook_subscriptions (user_id);
+
+ALTER TABLE jobs ADD COLUMN user_id integer NOT NULL DEFAULT 0;
+UPDATE jobs SET user_id = COALESCE(json_extract(payload, '$.user_id'), 0) WHERE json_valid(payload);
+CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON jobs (user_id);
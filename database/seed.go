@@ -0,0 +1,135 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"expense-api/logging"
+	"expense-api/models"
+
+	"gorm.io/gorm"
+)
+
+// Seed profiles accepted by Seed
+const (
+	// SeedDefault adds the default categories and bank accounts to an empty database
+	SeedDefault = "default"
+	// SeedDemo adds sample transactions for the last few months on top of the defaults
+	SeedDemo = "demo"
+)
+
+// demoTransactionPrefix starts the transaction_id of every demo transaction
+const demoTransactionPrefix = "demo-"
+
+// demoEntry is a transaction repeated every month of the demo data
+type demoEntry struct {
+	day         int
+	kind        string
+	category    string
+	amount      float64
+	description string
+}
+
+var demoMonth = []demoEntry{
+	{1, "income", "Salary", 4200, "Monthly salary"},
+	{2, "expense", "Food", 64.20, "Groceries"},
+	{3, "expense", "Bills", 1500, "Rent"},
+	{5, "transfer", "", 500, "Monthly savings"},
+	{9, "expense", "Food", 71.85, "Groceries"},
+	{12, "expense", "Bills", 85.40, "Electricity"},
+	{14, "expense", "Bills", 49.99, "Internet"},
+	{15, "expense", "Transport", 45, "Metro card"},
+	{16, "expense", "Food", 58.10, "Groceries"},
+	{18, "income", "Freelance", 800, "Freelance project"},
+	{20, "expense", "Shopping", 120, "Online order"},
+	{23, "expense", "Food", 66.75, "Groceries"},
+	{27, "expense", "Food", 38.50, "Dinner out"},
+}
+
+// Seed loads the named profile into the database
+func Seed(profile string) error {
+	switch profile {
+	case SeedDefault:
+		SeedDefaultCategories()
+		SeedDefaultBankAccounts()
+		return nil
+	case SeedDemo:
+		SeedDefaultCategories()
+		SeedDefaultBankAccounts()
+		return seedDemoTransactions(DB, time.Now().UTC())
+	}
+	return fmt.Errorf("unknown seed profile %q, want %q or %q", profile, SeedDefault, SeedDemo)
+}
+
+// seedDemoTransactions adds three months of transactions up to now, unless demo data was seeded before
+func seedDemoTransactions(db *gorm.DB, now time.Time) error {
+	var count int64
+	if err := db.Model(&models.Transaction{}).Where("transaction_id LIKE ?", demoTransactionPrefix+"%").Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		logging.For(logging.ComponentDB).Info("Demo transactions already seeded, skipping")
+		return nil
+	}
+
+	categories := map[string]uint{}
+	for _, entry := range demoMonth {
+		if entry.category == "" || categories[entry.category] != 0 {
+			continue
+		}
+		var category models.Category
+		if err := db.Where("name = ?", entry.category).First(&category).Error; err != nil {
+			return fmt.Errorf("demo data needs the default category %q: %w", entry.category, err)
+		}
+		categories[entry.category] = category.ID
+	}
+
+	var checking, savings models.BankAccount
+	if err := db.Where("name = ?", "Primary Checking").First(&checking).Error; err != nil {
+		return fmt.Errorf("demo data needs the default bank account %q: %w", "Primary Checking", err)
+	}
+	if err := db.Where("name = ?", "Savings Account").First(&savings).Error; err != nil {
+		return fmt.Errorf("demo data needs the default bank account %q: %w", "Savings Account", err)
+	}
+
+	var transactions []models.Transaction
+	first := time.Date(now.Year(), now.Month()-3, 1, 0, 0, 0, 0, time.UTC)
+	for month := 0; month <= 3; month++ {
+		for _, entry := range demoMonth {
+			date := first.AddDate(0, month, entry.day-1)
+			if date.After(now) {
+				continue
+			}
+			// Vary amounts a little from month to month
+			amount := entry.amount
+			if entry.kind == "expense" && entry.category != "Bills" {
+				amount += float64((month*7+entry.day)%5) * 3.25
+			}
+
+			transaction := models.Transaction{
+				TransactionID: demoTransactionPrefix + strconv.Itoa(len(transactions)+1),
+				Amount:        amount,
+				Type:          entry.kind,
+				BankAccountID: checking.ID,
+				Description:   entry.description,
+				Date:          models.FlexibleDate{Time: date},
+			}
+			if entry.kind == "transfer" {
+				transaction.DestinationBankAccountID = &savings.ID
+			} else {
+				id := categories[entry.category]
+				transaction.CategoryID = &id
+			}
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&transactions).Error
+	}); err != nil {
+		return err
+	}
+	logging.For(logging.ComponentDB).Info("Seeded demo transactions", "count", len(transactions))
+	return nil
+}
@@ -0,0 +1,249 @@
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types published by the handlers
+const (
+	TransactionCreated = "transaction.created"
+	TransactionUpdated = "transaction.updated"
+	TransactionDeleted = "transaction.deleted"
+	TransactionAnomaly = "transaction.anomaly"
+	TransferCreated    = "transfer.created"
+	CategoryCreated    = "category.created"
+	CategoryUpdated    = "category.updated"
+	CategoryDeleted    = "category.deleted"
+	PayeeCreated       = "payee.created"
+	PayeeUpdated       = "payee.updated"
+	PayeeDeleted       = "payee.deleted"
+	RecurringCreated   = "recurring.created"
+	RecurringDeleted   = "recurring.deleted"
+	AccountCreated     = "account.created"
+	AccountUpdated     = "account.updated"
+	AccountDeleted     = "account.deleted"
+)
+
+// Types lists every event type the handlers publish
+var Types = []string{
+	TransactionCreated,
+	TransactionUpdated,
+	TransactionDeleted,
+	TransactionAnomaly,
+	TransferCreated,
+	CategoryCreated,
+	CategoryUpdated,
+	CategoryDeleted,
+	PayeeCreated,
+	PayeeUpdated,
+	PayeeDeleted,
+	RecurringCreated,
+	RecurringDeleted,
+	AccountCreated,
+	AccountUpdated,
+	AccountDeleted,
+}
+
+// DefaultBufferSize is the number of recent events kept for resumption
+const DefaultBufferSize = 1024
+
+// Event is a change notification published on the bus
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+	// UserID is the user whose request caused the event, or 0 for anonymous requests and background work
+	UserID uint `json:"-"`
+}
+
+// VisibleTo reports whether a stream of userID may see the event: a user sees
+// its own events and those no user caused, an anonymous client only the latter
+func (e Event) VisibleTo(userID uint) bool {
+	return e.UserID == 0 || e.UserID == userID
+}
+
+// Listener is called synchronously for every published event
+type Listener func(Event)
+
+// Subscription receives events published after it was created
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	closed bool
+}
+
+// Bus is an in-process publish/subscribe hub with a bounded replay buffer
+type Bus struct {
+	mu            sync.Mutex
+	nextID        uint64
+	buffer        []Event
+	size          int
+	listeners     []Listener
+	subscriptions map[*Subscription]struct{}
+}
+
+// Default is the bus the handlers publish to
+var Default = NewBus(DefaultBufferSize)
+
+// NewBus creates a bus that keeps the last size events for resumption
+func NewBus(size int) *Bus {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Bus{
+		size:          size,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish publishes an event on the default bus
+func Publish(eventType string, data interface{}) Event {
+	return Default.Publish(eventType, data)
+}
+
+// PublishAs publishes an event caused by userID on the default bus
+func PublishAs(userID uint, eventType string, data interface{}) Event {
+	return Default.PublishAs(userID, eventType, data)
+}
+
+// Publish publishes an event that no user caused
+func (b *Bus) Publish(eventType string, data interface{}) Event {
+	return b.PublishAs(0, eventType, data)
+}
+
+// PublishAs assigns the next event ID, buffers the event and fans it out
+func (b *Bus) PublishAs(userID uint, eventType string, data interface{}) Event {
+	b.mu.Lock()
+	b.nextID++
+	event := Event{
+		ID:        b.nextID,
+		Type:      eventType,
+		Data:      data,
+		CreatedAt: time.Now().UTC(),
+		UserID:    userID,
+	}
+
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > b.size {
+		b.buffer = b.buffer[len(b.buffer)-b.size:]
+	}
+
+	// Subscribers that cannot keep up are dropped; they resume with Last-Event-ID
+	for sub := range b.subscriptions {
+		select {
+		case sub.ch <- event:
+		default:
+			b.closeLocked(sub)
+		}
+	}
+
+	listeners := b.listeners
+	b.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+
+	return event
+}
+
+// Listen registers a listener that is called for every published event
+func (b *Bus) Listen(listener Listener) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, listener)
+}
+
+// Subscribe returns a subscription for events published from now on
+func (b *Bus) Subscribe(capacity int) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscribeLocked(capacity)
+}
+
+// SubscribeSince atomically subscribes and returns the buffered events after lastID.
+// complete is false when events after lastID have already been evicted from the buffer.
+func (b *Bus) SubscribeSince(lastID uint64, capacity int) (sub *Subscription, backlog []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// An ID ahead of the bus means it was issued before a restart
+	complete = lastID <= b.nextID
+	if lastID < b.nextID {
+		if len(b.buffer) == 0 || b.buffer[0].ID > lastID+1 {
+			complete = false
+		}
+		for _, event := range b.buffer {
+			if event.ID > lastID {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	return b.subscribeLocked(capacity), backlog, complete
+}
+
+// subscribeLocked registers a new subscription; the caller must hold the lock
+func (b *Bus) subscribeLocked(capacity int) *Subscription {
+	ch := make(chan Event, capacity)
+	sub := &Subscription{C: ch, ch: ch}
+	b.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe stops delivery to the subscription and closes its channel
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeLocked(sub)
+}
+
+// LastID returns the ID of the most recently published event
+func (b *Bus) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nextID
+}
+
+// closeLocked removes a subscription; the caller must hold the lock
+func (b *Bus) closeLocked(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(b.subscriptions, sub)
+	close(sub.ch)
+}
+
+// Filter matches event types against a list of patterns such as "transaction.created" or "category.*"
+type Filter []string
+
+// ParseFilter parses a comma-separated list of event type patterns
+func ParseFilter(raw string) Filter {
+	var filter Filter
+	for _, pattern := range strings.Split(raw, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			filter = append(filter, pattern)
+		}
+	}
+	return filter
+}
+
+// Matches reports whether the event type is selected; an empty filter selects everything
+func (f Filter) Matches(eventType string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, pattern := range f {
+		if pattern == "*" || pattern == eventType {
+			return true
+		}
+		if strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
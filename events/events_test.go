@@ -0,0 +1,88 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishFansOutToSubscribersAndListeners(t *testing.T) {
+	bus := NewBus(10)
+
+	var heard []string
+	bus.Listen(func(event Event) {
+		heard = append(heard, event.Type)
+	})
+
+	sub := bus.Subscribe(4)
+	first := bus.Publish(TransactionCreated, map[string]int{"id": 1})
+	second := bus.Publish(CategoryDeleted, map[string]int{"id": 2})
+
+	assert.Equal(t, uint64(1), first.ID)
+	assert.Equal(t, uint64(2), second.ID)
+	assert.Equal(t, first, <-sub.C)
+	assert.Equal(t, second, <-sub.C)
+	assert.Equal(t, []string{TransactionCreated, CategoryDeleted}, heard)
+
+	bus.Unsubscribe(sub)
+	_, ok := <-sub.C
+	assert.False(t, ok)
+}
+
+func TestSubscribeSinceReplaysBufferedEvents(t *testing.T) {
+	bus := NewBus(3)
+	for i := 0; i < 5; i++ {
+		bus.Publish(TransactionUpdated, i)
+	}
+
+	// Events 3-5 are still buffered
+	_, backlog, complete := bus.SubscribeSince(3, 1)
+	assert.True(t, complete)
+	assert.Len(t, backlog, 2)
+	assert.Equal(t, uint64(4), backlog[0].ID)
+
+	// Event 2 has been evicted, so resuming after 1 is incomplete
+	_, backlog, complete = bus.SubscribeSince(1, 1)
+	assert.False(t, complete)
+	assert.Len(t, backlog, 3)
+
+	// An ID from before a restart is also incomplete
+	_, backlog, complete = bus.SubscribeSince(99, 1)
+	assert.False(t, complete)
+	assert.Empty(t, backlog)
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	bus := NewBus(10)
+	sub := bus.Subscribe(1)
+
+	bus.Publish(AccountCreated, nil)
+	bus.Publish(AccountUpdated, nil)
+
+	event, ok := <-sub.C
+	assert.True(t, ok)
+	assert.Equal(t, AccountCreated, event.Type)
+
+	_, ok = <-sub.C
+	assert.False(t, ok)
+}
+
+func TestFilterMatches(t *testing.T) {
+	assert.True(t, ParseFilter("").Matches(TransactionCreated))
+	assert.True(t, ParseFilter("transaction.*").Matches(TransactionDeleted))
+	assert.False(t, ParseFilter("transaction.*").Matches(TransferCreated))
+	assert.True(t, ParseFilter("transfer.created, category.updated").Matches(CategoryUpdated))
+	assert.False(t, ParseFilter("account.deleted").Matches(AccountCreated))
+}
+
+func TestEventVisibleTo(t *testing.T) {
+	bus := NewBus(10)
+	anonymous := bus.Publish(CategoryCreated, nil)
+	own := bus.PublishAs(7, CategoryUpdated, nil)
+
+	assert.True(t, anonymous.VisibleTo(0))
+	assert.True(t, anonymous.VisibleTo(7))
+	assert.True(t, own.VisibleTo(7))
+	assert.False(t, own.VisibleTo(8))
+	assert.False(t, own.VisibleTo(0))
+}
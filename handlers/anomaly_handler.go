@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/services"
+	"expense-api/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// convertToAnomalyResponse converts an Anomaly to AnomalyResponse
+func convertToAnomalyResponse(anomaly services.Anomaly) models.AnomalyResponse {
+	return models.AnomalyResponse{
+		Transaction: convertToTransactionResponse(anomaly.Transaction),
+		Score:       anomaly.Score,
+		Reasons:     anomaly.Reasons,
+	}
+}
+
+// maxAnomalyWindowDays is the longest history GET /insights/anomalies compares
+// with, ten years, which keeps the window from overflowing a time.Duration
+const maxAnomalyWindowDays = 3650
+
+// GetAnomalies handles GET /insights/anomalies, which lists the transactions in
+// an optional date range whose amount is out of character for their category or payee
+func GetAnomalies(svc services.AnomalyService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		filter := repository.TransactionFilter{Type: c.Query("type", "expense")}
+		if err := validation.Var("type", filter.Type, "category_type"); err != nil {
+			return apperrors.Validation(err)
+		}
+
+		settings := services.DefaultAnomalySettings()
+		if value := c.Query("window_days"); value != "" {
+			days, err := strconv.Atoi(value)
+			if err != nil || days < 1 || days > maxAnomalyWindowDays {
+				return apperrors.BadRequest(apperrors.CodeInvalidParameter, fmt.Sprintf("window_days must be an integer from 1 to %d", maxAnomalyWindowDays))
+			}
+			settings.Window = time.Duration(days) * 24 * time.Hour
+		}
+		if value := c.Query("threshold"); value != "" {
+			threshold, err := strconv.ParseFloat(value, 64)
+			if err != nil || threshold <= 0 {
+				return apperrors.BadRequest(apperrors.CodeInvalidParameter, "threshold must be a positive number")
+			}
+			settings.Threshold = threshold
+		}
+
+		var err error
+		filter.From, filter.To, err = optionalDateRange(c)
+		if err != nil {
+			return err
+		}
+
+		anomalies, err := svc.Anomalies(filter, settings)
+		if err != nil {
+			return err
+		}
+
+		response := models.AnomaliesResponse{
+			DateRange: models.DateRange{
+				StartDate: c.Query("start_date"),
+				EndDate:   c.Query("end_date"),
+			},
+			Type:       filter.Type,
+			WindowDays: int(settings.Window / (24 * time.Hour)),
+			Threshold:  settings.Threshold,
+			Anomalies:  make([]models.AnomalyResponse, 0, len(anomalies)),
+		}
+		for _, anomaly := range anomalies {
+			response.Anomalies = append(response.Anomalies, convertToAnomalyResponse(anomaly))
+		}
+
+		return c.JSON(response)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"expense-api/container"
+	"expense-api/events"
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestAnomalies(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+
+	app := newTestApp()
+	app.Get("/insights/anomalies", GetAnomalies(deps.Anomalies))
+	app.Post("/transactions", CreateTransaction(deps.Transactions, deps.Anomalies))
+
+	send := func(method, path, body string, out interface{}) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		if out != nil && resp.StatusCode < 300 {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	// Food (1) is seeded; a few weeks of groceries around 20
+	food := uint(1)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i, amount := range []float64{20, 22, 25, 18, 21, 24} {
+		_, err := deps.Transactions.Create(models.Transaction{Amount: amount, Type: "expense", CategoryID: &food, BankAccountID: 1,
+			Description: "Groceries", Date: models.FlexibleDate{Time: today.AddDate(0, 0, -30+i*5)}})
+		assert.NoError(t, err)
+	}
+
+	sub := events.Default.Subscribe(events.DefaultBufferSize)
+	defer events.Default.Unsubscribe(sub)
+
+	body := `{"amount": 150, "type": "expense", "category_id": 1, "bank_account_id": 1, "description": "Catering", "date": "` + today.Format("2006-01-02") + `"}`
+	var created models.TransactionResponse
+	assert.Equal(t, 201, send("POST", "/transactions", body, &created))
+
+	// Other tests publish to the same bus, so look for this transaction's event
+	var flagged *models.AnomalyResponse
+	for len(sub.C) > 0 {
+		event := <-sub.C
+		if anomaly, ok := event.Data.(models.AnomalyResponse); ok && event.Type == events.TransactionAnomaly && anomaly.Transaction.ID == created.ID {
+			flagged = &anomaly
+		}
+	}
+	if assert.NotNil(t, flagged) {
+		assert.Equal(t, 21.5, flagged.Reasons[0].Median)
+	}
+
+	var response models.AnomaliesResponse
+	assert.Equal(t, 200, send("GET", "/insights/anomalies", "", &response))
+	assert.Equal(t, "expense", response.Type)
+	assert.Equal(t, 180, response.WindowDays)
+	if assert.Len(t, response.Anomalies, 1) {
+		assert.Equal(t, created.ID, response.Anomalies[0].Transaction.ID)
+		assert.Equal(t, models.AnomalyScopeCategory, response.Anomalies[0].Reasons[0].Scope)
+	}
+
+	// A high enough threshold tolerates the catering bill
+	response = models.AnomaliesResponse{}
+	assert.Equal(t, 200, send("GET", "/insights/anomalies?threshold=100", "", &response))
+	assert.Empty(t, response.Anomalies)
+
+	assert.Equal(t, 400, send("GET", "/insights/anomalies?threshold=-1", "", nil))
+	assert.Equal(t, 400, send("GET", "/insights/anomalies?window_days=0", "", nil))
+	assert.Equal(t, 400, send("GET", "/insights/anomalies?window_days=3651", "", nil))
+	assert.Equal(t, 400, send("GET", "/insights/anomalies?window_days=9223372036854775807", "", nil))
+	assert.Equal(t, 400, send("GET", "/insights/anomalies?type=transfer", "", nil))
+}
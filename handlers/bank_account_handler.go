@@ -1,110 +1,50 @@
 package handlers
 
 import (
-	"strconv"
-
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 
+	"expense-api/apperrors"
+	"expense-api/events"
 	"expense-api/models"
+	"expense-api/services"
 )
 
 // CreateBankAccount creates a new bank account
-func CreateBankAccount(db *gorm.DB) fiber.Handler {
+func CreateBankAccount(svc services.AccountService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var bankAccount models.BankAccount
-		
-		if err := c.BodyParser(&bankAccount); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Cannot parse JSON",
-			})
-		}
-
-		// Validate required fields
-		if bankAccount.Name == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Bank account name is required",
-			})
-		}
+		svc := svc.WithContext(c.UserContext())
+		var bankAccount models.BankAccountRequest
 
-		if bankAccount.BankName == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Bank name is required",
-			})
-		}
-
-		if bankAccount.AccountType == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Account type is required",
-			})
-		}
-
-		// Validate account type
-		validTypes := []string{"checking", "savings", "credit", "investment", "other"}
-		isValidType := false
-		for _, validType := range validTypes {
-			if bankAccount.AccountType == validType {
-				isValidType = true
-				break
-			}
-		}
-		if !isValidType {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid account type. Must be one of: checking, savings, credit, investment, other",
-			})
+		if err := c.BodyParser(&bankAccount); err != nil {
+			return apperrors.InvalidBody(err)
 		}
 
-		// Create bank account
-		if err := db.Create(&bankAccount).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to create bank account",
-			})
+		created, err := svc.Create(bankAccount)
+		if err != nil {
+			return err
 		}
 
-		// Return response
-		response := models.BankAccountResponse{
-			ID:            bankAccount.ID,
-			Name:          bankAccount.Name,
-			AccountNumber: bankAccount.AccountNumber,
-			BankName:      bankAccount.BankName,
-			AccountType:   bankAccount.AccountType,
-			Balance:       bankAccount.Balance,
-			IsActive:      bankAccount.IsActive,
-		}
+		response := convertToBankAccountResponse(created)
+		publish(c, events.AccountCreated, response)
 
 		return c.Status(fiber.StatusCreated).JSON(response)
 	}
 }
 
 // GetBankAccounts retrieves all bank accounts
-func GetBankAccounts(db *gorm.DB) fiber.Handler {
+func GetBankAccounts(svc services.AccountService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var bankAccounts []models.BankAccount
-		
+		svc := svc.WithContext(c.UserContext())
 		// Get active accounts by default, unless include_inactive=true
-		query := db.Where("is_active = ?", true)
-		if c.Query("include_inactive") == "true" {
-			query = db
-		}
-
-		if err := query.Find(&bankAccounts).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to retrieve bank accounts",
-			})
+		bankAccounts, err := svc.List(c.Query("include_inactive") == "true")
+		if err != nil {
+			return err
 		}
 
 		// Convert to response format
 		var responses []models.BankAccountResponse
 		for _, account := range bankAccounts {
-			responses = append(responses, models.BankAccountResponse{
-				ID:            account.ID,
-				Name:          account.Name,
-				AccountNumber: account.AccountNumber,
-				BankName:      account.BankName,
-				AccountType:   account.AccountType,
-				Balance:       account.Balance,
-				IsActive:      account.IsActive,
-			})
+			responses = append(responses, convertToBankAccountResponse(account))
 		}
 
 		return c.JSON(responses)
@@ -112,169 +52,63 @@ func GetBankAccounts(db *gorm.DB) fiber.Handler {
 }
 
 // GetBankAccount retrieves a specific bank account by ID
-func GetBankAccount(db *gorm.DB) fiber.Handler {
+func GetBankAccount(svc services.AccountService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		id, err := strconv.Atoi(c.Params("id"))
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid bank account ID",
-			})
-		}
-
-		var bankAccount models.BankAccount
-		if err := db.First(&bankAccount, id).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-					"error": "Bank account not found",
-				})
-			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to retrieve bank account",
-			})
+			return err
 		}
 
-		response := models.BankAccountResponse{
-			ID:            bankAccount.ID,
-			Name:          bankAccount.Name,
-			AccountNumber: bankAccount.AccountNumber,
-			BankName:      bankAccount.BankName,
-			AccountType:   bankAccount.AccountType,
-			Balance:       bankAccount.Balance,
-			IsActive:      bankAccount.IsActive,
+		bankAccount, err := svc.Get(id)
+		if err != nil {
+			return err
 		}
 
-		return c.JSON(response)
+		return c.JSON(convertToBankAccountResponse(bankAccount))
 	}
 }
 
 // UpdateBankAccount updates a bank account
-func UpdateBankAccount(db *gorm.DB) fiber.Handler {
+func UpdateBankAccount(svc services.AccountService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		id, err := strconv.Atoi(c.Params("id"))
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid bank account ID",
-			})
-		}
-
-		var existingAccount models.BankAccount
-		if err := db.First(&existingAccount, id).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-					"error": "Bank account not found",
-				})
-			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to retrieve bank account",
-			})
+			return err
 		}
 
-		var updateData models.BankAccount
+		var updateData models.BankAccountRequest
 		if err := c.BodyParser(&updateData); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Cannot parse JSON",
-			})
-		}
-
-		// Validate account type if provided
-		if updateData.AccountType != "" {
-			validTypes := []string{"checking", "savings", "credit", "investment", "other"}
-			isValidType := false
-			for _, validType := range validTypes {
-				if updateData.AccountType == validType {
-					isValidType = true
-					break
-				}
-			}
-			if !isValidType {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"error": "Invalid account type. Must be one of: checking, savings, credit, investment, other",
-				})
-			}
-		}
-
-		// Update fields
-		if updateData.Name != "" {
-			existingAccount.Name = updateData.Name
-		}
-		if updateData.AccountNumber != "" {
-			existingAccount.AccountNumber = updateData.AccountNumber
-		}
-		if updateData.BankName != "" {
-			existingAccount.BankName = updateData.BankName
+			return apperrors.InvalidBody(err)
 		}
-		if updateData.AccountType != "" {
-			existingAccount.AccountType = updateData.AccountType
-		}
-		if updateData.Balance != 0 {
-			existingAccount.Balance = updateData.Balance
-		}
-		// Handle IsActive explicitly since it's a boolean
-		existingAccount.IsActive = updateData.IsActive
 
-		if err := db.Save(&existingAccount).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to update bank account",
-			})
+		updated, err := svc.Update(id, updateData)
+		if err != nil {
+			return err
 		}
 
-		response := models.BankAccountResponse{
-			ID:            existingAccount.ID,
-			Name:          existingAccount.Name,
-			AccountNumber: existingAccount.AccountNumber,
-			BankName:      existingAccount.BankName,
-			AccountType:   existingAccount.AccountType,
-			Balance:       existingAccount.Balance,
-			IsActive:      existingAccount.IsActive,
-		}
+		response := convertToBankAccountResponse(updated)
+		publish(c, events.AccountUpdated, response)
 
 		return c.JSON(response)
 	}
 }
 
 // DeleteBankAccount soft deletes a bank account
-func DeleteBankAccount(db *gorm.DB) fiber.Handler {
+func DeleteBankAccount(svc services.AccountService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		id, err := strconv.Atoi(c.Params("id"))
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid bank account ID",
-			})
+			return err
 		}
 
-		// Check if bank account exists
-		var bankAccount models.BankAccount
-		if err := db.First(&bankAccount, id).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-					"error": "Bank account not found",
-				})
-			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to retrieve bank account",
-			})
+		if err := svc.Delete(id); err != nil {
+			return err
 		}
 
-		// Check if there are any transactions associated with this account
-		var transactionCount int64
-		if err := db.Model(&models.Transaction{}).Where("bank_account_id = ? OR destination_bank_account_id = ?", id, id).Count(&transactionCount).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to check for associated transactions",
-			})
-		}
-
-		if transactionCount > 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Cannot delete bank account with associated transactions. Consider deactivating instead.",
-			})
-		}
-
-		// Soft delete the bank account
-		if err := db.Delete(&bankAccount).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to delete bank account",
-			})
-		}
+		publish(c, events.AccountDeleted, fiber.Map{"id": id})
 
 		return c.Status(fiber.StatusNoContent).Send(nil)
 	}
@@ -1,165 +1,215 @@
 package handlers
 
 import (
-	"expense-api/database"
+	"strconv"
+
+	"expense-api/apperrors"
+	"expense-api/events"
 	"expense-api/models"
+	"expense-api/services"
+	"expense-api/validation"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// CreateCategory handles POST /categories
-func CreateCategory(c *fiber.Ctx) error {
-	var category models.Category
-
-	if err := c.BodyParser(&category); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+// convertToCategoryResponse converts a Category model to CategoryResponse
+func convertToCategoryResponse(category models.Category) models.CategoryResponse {
+	return models.CategoryResponse{
+		ID:       category.ID,
+		Name:     category.Name,
+		Type:     category.Type,
+		ParentID: category.ParentID,
 	}
+}
 
-	// Validate category type
-	if category.Type != "expense" && category.Type != "income" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Type must be either 'expense' or 'income'",
-		})
-	}
+// CreateCategory handles POST /categories
+func CreateCategory(svc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		var category models.Category
+		if err := c.BodyParser(&category); err != nil {
+			return apperrors.InvalidBody(err)
+		}
 
-	// Check if category name already exists
-	var existingCategory models.Category
-	if err := database.DB.Where("name = ?", category.Name).First(&existingCategory).Error; err == nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Category with this name already exists",
-		})
-	}
+		created, err := svc.Create(category)
+		if err != nil {
+			return err
+		}
 
-	if err := database.DB.Create(&category).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to create category",
-		})
-	}
+		publish(c, events.CategoryCreated, created)
 
-	return c.Status(201).JSON(category)
+		return c.Status(201).JSON(created)
+	}
 }
 
-// GetCategories handles GET /categories
-func GetCategories(c *fiber.Ctx) error {
-	var categories []models.Category
+// GetCategories handles GET /categories. With ?tree=true the categories are
+// nested under their parents.
+func GetCategories(svc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		if c.QueryBool("tree") {
+			tree, err := svc.Tree()
+			if err != nil {
+				return err
+			}
+			return c.JSON(tree.Nodes())
+		}
 
-	if err := database.DB.Find(&categories).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to fetch categories",
-		})
-	}
+		categories, err := svc.List()
+		if err != nil {
+			return err
+		}
 
-	// Convert to response format
-	var response []models.CategoryResponse
-	for _, c := range categories {
-		response = append(response, models.CategoryResponse{
-			ID:   c.ID,
-			Name: c.Name,
-			Type: c.Type,
-		})
-	}
+		// Convert to response format
+		var response []models.CategoryResponse
+		for _, category := range categories {
+			response = append(response, convertToCategoryResponse(category))
+		}
 
-	return c.JSON(response)
+		return c.JSON(response)
+	}
 }
 
 // GetCategory handles GET /categories/:id
-func GetCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
+func GetCategory(svc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
 
-	var category models.Category
-	if err := database.DB.First(&category, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Category not found",
-		})
-	}
+		category, err := svc.Get(id)
+		if err != nil {
+			return err
+		}
 
-	response := models.CategoryResponse{
-		ID:   category.ID,
-		Name: category.Name,
-		Type: category.Type,
+		return c.JSON(convertToCategoryResponse(category))
 	}
-
-	return c.JSON(response)
 }
 
-// DeleteCategory handles DELETE /categories/:id
-func DeleteCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
+// DeleteCategory handles DELETE /categories/:id. With ?reassign_to=<id> the
+// category is merged into that category instead of refusing when it is in use.
+func DeleteCategory(svc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		var reassigned int64
+		if value := c.Query("reassign_to"); value != "" {
+			targetID, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || targetID == 0 {
+				return apperrors.BadRequest(apperrors.CodeInvalidParameter, "reassign_to must be a positive integer")
+			}
+			result, err := svc.Merge(id, uint(targetID))
+			if err != nil {
+				return err
+			}
+			reassigned = result.ReassignedTransactions
+			publishMerge(c, id, result)
+		} else {
+			if err := svc.Delete(id); err != nil {
+				return err
+			}
+			publish(c, events.CategoryDeleted, fiber.Map{"id": id})
+		}
 
-	var category models.Category
-	if err := database.DB.First(&category, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Category not found",
+		return c.Status(200).JSON(fiber.Map{
+			"message":                 "Category deleted successfully",
+			"reassigned_transactions": reassigned,
 		})
 	}
+}
 
-	// Check if category is being used by any transactions
-	var count int64
-	database.DB.Model(&models.Transaction{}).Where("category_id = ?", id).Count(&count)
-	if count > 0 {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Cannot delete category that has associated transactions",
-		})
-	}
+// MergeCategory handles POST /categories/:id/merge
+func MergeCategory(svc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
 
-	if err := database.DB.Delete(&category).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to delete category",
+		var request models.CategoryMergeRequest
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
+		}
+		if err := validation.Struct(&request); err != nil {
+			return apperrors.Validation(err)
+		}
+
+		result, err := svc.Merge(id, request.TargetID)
+		if err != nil {
+			return err
+		}
+
+		publishMerge(c, id, result)
+
+		return c.JSON(models.CategoryMergeResponse{
+			Target:                 convertToCategoryResponse(result.Target),
+			ReassignedTransactions: result.ReassignedTransactions,
+			MovedSubcategories:     result.MovedSubcategories,
 		})
 	}
+}
 
-	return c.Status(200).JSON(fiber.Map{
-		"message": "Category deleted successfully",
-	})
+// publishMerge announces a category merged into another and each live transaction it moved
+func publishMerge(c *fiber.Ctx, id uint, result services.MergeResult) {
+	for _, t := range result.Transactions {
+		publish(c, events.TransactionUpdated, convertToTransactionResponse(t))
+	}
+	publish(c, events.CategoryDeleted, fiber.Map{"id": id, "merged_into": result.Target.ID})
 }
 
 // UpdateCategory handles PUT /categories/:id
-func UpdateCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
+func UpdateCategory(svc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
 
-	var category models.Category
-	if err := database.DB.First(&category, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Category not found",
-		})
-	}
+		var updateData map[string]interface{}
+		if err := c.BodyParser(&updateData); err != nil {
+			return apperrors.InvalidBody(err)
+		}
 
-	var updateData map[string]interface{}
-	if err := c.BodyParser(&updateData); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		category, err := svc.Update(id, updateData)
+		if err != nil {
+			return err
+		}
+
+		publish(c, events.CategoryUpdated, category)
+
+		return c.JSON(category)
 	}
+}
 
-	// Validate category type if provided
-	if categoryType, exists := updateData["type"]; exists {
-		if categoryType != "expense" && categoryType != "income" {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Type must be either 'expense' or 'income'",
-			})
+// MoveCategory handles POST /categories/:id/move
+func MoveCategory(svc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
 		}
-	}
 
-	// Check if name already exists (excluding current category)
-	if name, exists := updateData["name"]; exists {
-		var existingCategory models.Category
-		if err := database.DB.Where("name = ? AND id != ?", name, id).First(&existingCategory).Error; err == nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Category with this name already exists",
-			})
+		var request models.CategoryMoveRequest
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
 		}
-	}
 
-	if err := database.DB.Model(&category).Updates(updateData).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to update category",
-		})
-	}
+		category, err := svc.Move(id, request.ParentID)
+		if err != nil {
+			return err
+		}
 
-	// Load updated category
-	database.DB.First(&category, id)
+		publish(c, events.CategoryUpdated, category)
 
-	return c.JSON(category)
+		return c.JSON(category)
+	}
 }
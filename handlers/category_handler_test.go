@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"expense-api/container"
+	"expense-api/events"
+	"expense-api/models"
+	"expense-api/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCategoryTreeAndRollup(t *testing.T) {
+	t.Parallel()
+	deps := setupTestServices(t)
+
+	// Food (1) and Salary (2) are seeded
+	food := uint(1)
+	groceries, err := deps.Categories.Create(models.Category{Name: "Groceries", Type: "expense", ParentID: &food})
+	assert.NoError(t, err)
+	restaurants, err := deps.Categories.Create(models.Category{Name: "Restaurants", Type: "expense", ParentID: &food})
+	assert.NoError(t, err)
+	coffee, err := deps.Categories.Create(models.Category{Name: "Coffee", Type: "expense", ParentID: &restaurants.ID})
+	assert.NoError(t, err)
+
+	date := models.FlexibleDate{Time: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	for _, transaction := range []models.Transaction{
+		{Amount: 30, Type: "expense", CategoryID: &groceries.ID, BankAccountID: 1, Description: "Market", Date: date},
+		{Amount: 5, Type: "expense", CategoryID: &coffee.ID, BankAccountID: 1, Description: "Espresso", Date: date},
+		{Amount: 10, Type: "expense", CategoryID: &food, BankAccountID: 1, Description: "Snacks", Date: date},
+	} {
+		_, err := deps.Transactions.Create(transaction)
+		assert.NoError(t, err)
+	}
+
+	app := newTestApp()
+	app.Get("/categories", GetCategories(deps.Categories))
+	app.Post("/categories/:id/move", MoveCategory(deps.Categories))
+	app.Get("/transactions/aggregate", GetTransactionsAggregate(deps.Transactions, deps.Categories))
+	app.Get("/transactions/aggregate-table", GetTransactionsAggregateTable(deps.Transactions, deps.Categories))
+
+	get := func(path string, out interface{}) int {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		if resp.StatusCode == 200 {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	var tree []models.CategoryTreeNode
+	assert.Equal(t, 200, get("/categories?tree=true", &tree))
+	if assert.Len(t, tree, 2) && assert.Len(t, tree[0].Children, 2) {
+		assert.Equal(t, "Food", tree[0].Name)
+		assert.Equal(t, "Groceries", tree[0].Children[0].Name)
+		assert.Equal(t, "Coffee", tree[0].Children[1].Children[0].Name)
+	}
+
+	aggregate := func(query string) map[string]float64 {
+		var response models.AggregateResponse
+		assert.Equal(t, 200, get("/transactions/aggregate"+query, &response))
+		return response.Categories
+	}
+	move := func(id uint, body string) int {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/categories/%d/move", id), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// Without depth every category is reported on its own, keyed by its path
+	assert.Equal(t, map[string]float64{"Food": 10, "Food / Groceries": 30, "Food / Restaurants / Coffee": 5}, aggregate(""))
+	assert.Equal(t, map[string]float64{"Food": 10, "Food / Groceries": 30, "Food / Restaurants": 5}, aggregate("?depth=1"))
+
+	var table models.AggregateTableResponse
+	assert.Equal(t, 200, get("/transactions/aggregate-table?start_date=2024-01-01&end_date=2024-01-31&depth=0", &table))
+	if assert.Len(t, table.Expenses.Categories, 1) {
+		assert.Equal(t, models.CategoryAggregate{CategoryID: food, CategoryName: "Food", CategoryPath: "Food", TotalAmount: 45, TransactionCount: 3}, table.Expenses.Categories[0])
+	}
+
+	assert.Equal(t, 400, get("/transactions/aggregate?depth=-1", nil))
+
+	// Moving Coffee to the top level takes its totals out of Food
+	assert.Equal(t, 200, move(coffee.ID, `{"parent_id": null}`))
+	assert.Equal(t, map[string]float64{"Food": 40, "Coffee": 5}, aggregate("?depth=0"))
+
+	// Once Food sits under Coffee, Coffee cannot move under Restaurants
+	assert.Equal(t, 200, move(food, fmt.Sprintf(`{"parent_id": %d}`, coffee.ID)))
+	assert.Equal(t, 400, move(coffee.ID, fmt.Sprintf(`{"parent_id": %d}`, restaurants.ID)))
+	assert.Equal(t, map[string]float64{"Coffee": 45}, aggregate("?depth=0"))
+}
+
+func TestMergeCategory(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+
+	// Food (1) and Salary (2) are seeded
+	food := uint(1)
+	groceries, err := deps.Categories.Create(models.Category{Name: "Groceries", Type: "expense"})
+	assert.NoError(t, err)
+	organic, err := deps.Categories.Create(models.Category{Name: "Organic", Type: "expense", ParentID: &groceries.ID})
+	assert.NoError(t, err)
+
+	date := models.FlexibleDate{Time: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	var trashed models.Transaction
+	for i, amount := range []float64{30, 12, 8} {
+		transaction, err := deps.Transactions.Create(models.Transaction{Amount: amount, Type: "expense", CategoryID: &groceries.ID, BankAccountID: 1, Description: "Merged market", Date: date})
+		assert.NoError(t, err)
+		if i == 2 {
+			trashed = transaction
+		}
+	}
+	assert.NoError(t, deps.Transactions.Delete(trashed.ID))
+
+	app := newTestApp()
+	app.Post("/categories/:id/merge", MergeCategory(deps.Categories))
+	app.Delete("/categories/:id", DeleteCategory(deps.Categories))
+	post := func(path, body string) *http.Response {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Categories of another type cannot be merged
+	resp := post(fmt.Sprintf("/categories/%d/merge", groceries.ID), `{"target_id": 2}`)
+	assert.Equal(t, 400, resp.StatusCode)
+	resp = post(fmt.Sprintf("/categories/%d/merge", groceries.ID), `{}`)
+	assert.Equal(t, 400, resp.StatusCode)
+
+	sub := events.Default.Subscribe(events.DefaultBufferSize)
+	defer events.Default.Unsubscribe(sub)
+
+	resp = post(fmt.Sprintf("/categories/%d/merge", groceries.ID), `{"target_id": 1}`)
+	assert.Equal(t, 200, resp.StatusCode)
+	var merged models.CategoryMergeResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&merged))
+	assert.Equal(t, "Food", merged.Target.Name)
+	// Transactions in the trash move too, so undeleting them keeps a valid category
+	assert.Equal(t, int64(3), merged.ReassignedTransactions)
+	assert.Equal(t, 1, merged.MovedSubcategories)
+
+	// Other tests publish to the same bus, so look for this test's transactions
+	var updated []models.TransactionResponse
+	for len(sub.C) > 0 {
+		event := <-sub.C
+		if response, ok := event.Data.(models.TransactionResponse); ok && event.Type == events.TransactionUpdated && response.Description == "Merged market" {
+			updated = append(updated, response)
+		}
+	}
+	// Only live transactions are announced
+	if assert.Len(t, updated, 2) {
+		for _, response := range updated {
+			assert.NotEqual(t, trashed.ID, response.ID)
+			assert.Equal(t, &food, response.CategoryID)
+			assert.Equal(t, "Food", response.Category)
+		}
+	}
+
+	var count int64
+	db.Unscoped().Model(&models.Transaction{}).Where("category_id = ?", food).Count(&count)
+	assert.Equal(t, int64(3), count)
+	moved, err := deps.Categories.Get(organic.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &food, moved.ParentID)
+	_, err = deps.Categories.Get(groceries.ID)
+	assert.Error(t, err)
+
+	// The reassignment is recorded in the history of each transaction
+	var versions []models.TransactionVersion
+	db.Where("record_id = ?", trashed.ID).Order("version").Find(&versions)
+	if assert.Len(t, versions, 3) {
+		assert.Equal(t, &food, versions[2].CategoryID)
+		assert.True(t, versions[2].Deleted)
+	}
+
+	// Deleting with reassign_to merges the same way
+	req := httptest.NewRequest("DELETE", fmt.Sprintf("/categories/%d?reassign_to=%d", organic.ID, food), nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	var deleted map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deleted))
+	assert.Equal(t, float64(0), deleted["reassigned_transactions"])
+
+	req = httptest.NewRequest("DELETE", "/categories/1?reassign_to=1", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
+
+func TestCategoryNamesOfDeletedCategoriesCanBeReused(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+
+	app := newTestApp()
+	app.Post("/categories", CreateCategory(deps.Categories))
+	app.Post("/categories/:id/merge", MergeCategory(deps.Categories))
+	app.Delete("/categories/:id", DeleteCategory(deps.Categories))
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// Food (1) is seeded at the top level
+	assert.Equal(t, 409, send("POST", "/categories", `{"name": "Food", "type": "expense"}`))
+
+	assert.Equal(t, 201, send("POST", "/categories", `{"name": "Snacks", "type": "expense"}`))
+	assert.Equal(t, 200, send("DELETE", "/categories/3", ""))
+	assert.Equal(t, 201, send("POST", "/categories", `{"name": "Snacks", "type": "expense"}`))
+	assert.Equal(t, 200, send("POST", "/categories/4/merge", `{"target_id": 1}`))
+	assert.Equal(t, 201, send("POST", "/categories", `{"name": "Snacks", "type": "expense"}`))
+
+	// A top-level duplicate that slips past the service's check is still rejected
+	err := repository.NewGormCategories(func() *gorm.DB { return db }).Create(&models.Category{Name: "Food", Type: "expense"})
+	assert.ErrorIs(t, err, repository.ErrDuplicate)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"sync"
+
+	"expense-api/openapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// openAPIDocument is generated once from the route descriptions and model types
+var openAPIDocument = sync.OnceValue(func() openapi.Document {
+	return openapi.Build("Expense API", "1.0.0", openapi.Operations())
+})
+
+// swaggerUIPage renders the OpenAPI document with Swagger UI
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Expense API Documentation</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// docsContentSecurityPolicy replaces the API's policy on the docs page so Swagger UI can load
+const docsContentSecurityPolicy = "default-src 'none'; script-src 'unsafe-inline' https://unpkg.com; style-src 'unsafe-inline' https://unpkg.com; img-src 'self' data: https:; connect-src 'self'; frame-ancestors 'none'"
+
+// GetOpenAPISpec handles GET /openapi.json
+func GetOpenAPISpec(c *fiber.Ctx) error {
+	return c.JSON(openAPIDocument())
+}
+
+// GetAPIDocs handles GET /docs
+func GetAPIDocs(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentSecurityPolicy, docsContentSecurityPolicy)
+	c.Type("html")
+	return c.SendString(swaggerUIPage)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/events"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// StreamHeartbeatInterval is how often an idle event stream sends a keep-alive comment
+var StreamHeartbeatInterval = 15 * time.Second
+
+// streamSubscriptionCapacity is how many events may queue for a slow stream before it is dropped
+const streamSubscriptionCapacity = 256
+
+// StreamResetEvent tells a resuming client that events were missed and it should refetch
+const StreamResetEvent = "stream.reset"
+
+// writeStreamEvent writes one event in text/event-stream format
+func writeStreamEvent(w *bufio.Writer, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return w.Flush()
+}
+
+// publish publishes an event caused by the user making the request
+func publish(c *fiber.Ctx, eventType string, data interface{}) {
+	events.PublishAs(requestUser(c), eventType, data)
+}
+
+// StreamEvents handles GET /events/stream. A client sees the events its own
+// requests caused and those no user caused, never those of other users.
+func StreamEvents(c *fiber.Ctx) error {
+	filter := events.ParseFilter(c.Query("types"))
+	user := requestUser(c)
+
+	// Resume from the Last-Event-ID header, or the query parameter for clients that cannot set headers
+	lastEventID := c.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	var lastID uint64
+	resuming := lastEventID != ""
+	if resuming {
+		parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return apperrors.BadRequest(apperrors.CodeInvalidParameter, "Last-Event-ID must be a non-negative integer")
+		}
+		lastID = parsed
+	}
+
+	var subscription *events.Subscription
+	var backlog []events.Event
+	complete := true
+	if resuming {
+		subscription, backlog, complete = events.Default.SubscribeSince(lastID, streamSubscriptionCapacity)
+	} else {
+		subscription = events.Default.Subscribe(streamSubscriptionCapacity)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer events.Default.Unsubscribe(subscription)
+
+		fmt.Fprintf(w, "retry: 3000\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		if !complete {
+			fmt.Fprintf(w, "event: %s\ndata: {\"last_event_id\":%d}\n\n", StreamResetEvent, lastID)
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+
+		for _, event := range backlog {
+			if !event.VisibleTo(user) || !filter.Matches(event.Type) {
+				continue
+			}
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(StreamHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-subscription.C:
+				// A closed channel means the stream fell behind; the client reconnects with Last-Event-ID
+				if !ok {
+					return
+				}
+				if !event.VisibleTo(user) || !filter.Matches(event.Type) {
+					continue
+				}
+				if err := writeStreamEvent(w, event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				fmt.Fprintf(w, ": heartbeat %d\n\n", time.Now().Unix())
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	}))
+
+	return nil
+}
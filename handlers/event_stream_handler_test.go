@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"expense-api/events"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// readStreamFrame reads lines up to the next blank line of an event stream
+func readStreamFrame(t *testing.T, reader *bufio.Reader) []string {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	// Short heartbeats let the stream notice the client hanging up quickly
+	defer func(interval time.Duration) { StreamHeartbeatInterval = interval }(StreamHeartbeatInterval)
+	StreamHeartbeatInterval = 50 * time.Millisecond
+
+	// The stream belongs to user 7, as if Authenticate had verified its token
+	app := newTestApp()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(UserLocal, uint(7))
+		return c.Next()
+	})
+	app.Get("/events/stream", StreamEvents)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	before := events.Publish(events.CategoryCreated, fiber.Map{"id": 1})
+
+	req, _ := http.NewRequest("GET", "http://"+ln.Addr().String()+"/events/stream?types=category.*", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, []string{"retry: 3000"}, readStreamFrame(t, reader))
+
+	// Events published before connecting are replayed from the buffer
+	var replayed []string
+	for {
+		frame := readStreamFrame(t, reader)
+		if frame[0] == "id: "+strconv.FormatUint(before.ID, 10) {
+			replayed = frame
+			break
+		}
+	}
+	assert.Equal(t, "event: category.created", replayed[1])
+
+	// Filtered out by type, then by user, then delivered live
+	events.Publish(events.AccountCreated, fiber.Map{"id": 2})
+	events.PublishAs(8, events.CategoryUpdated, fiber.Map{"id": 1})
+	live := events.PublishAs(7, events.CategoryDeleted, fiber.Map{"id": 1})
+
+	done := make(chan []string)
+	go func() { done <- readStreamFrame(t, reader) }()
+	select {
+	case frame := <-done:
+		assert.Equal(t, "id: "+strconv.FormatUint(live.ID, 10), frame[0])
+		assert.Equal(t, "event: category.deleted", frame[1])
+		assert.Contains(t, frame[2], `"type":"category.deleted"`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for live event")
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"expense-api/health"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Health handles GET /livez and GET /readyz, answering 503 when any check of the registry fails
+func Health(registry *health.Registry) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report := registry.Run(c.UserContext())
+
+		status := fiber.StatusOK
+		if report.Status != health.StatusOK {
+			status = fiber.StatusServiceUnavailable
+		}
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.Status(status).JSON(report)
+	}
+}
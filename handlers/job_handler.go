@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"expense-api/apperrors"
+	"expense-api/events"
+	"expense-api/jobs"
+	"expense-api/logging"
+	"expense-api/metrics"
+	"expense-api/models"
+	"expense-api/services"
+	"expense-api/tracing"
+	"expense-api/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// bulkJobChunkSize is the number of rows a bulk job creates between progress updates
+const bulkJobChunkSize = 500
+
+// convertToJobResponse converts a Job model to JobResponse
+func convertToJobResponse(job models.Job) models.JobResponse {
+	response := models.JobResponse{
+		ID:         job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Attempts:   job.Attempts,
+		LastError:  job.LastError,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+		CreatedAt:  job.CreatedAt,
+	}
+
+	if job.Result != "" {
+		var result models.BulkTransactionResponse
+		if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+			logging.For(logging.ComponentJobs).Error("Failed to decode job result", "job_id", job.ID, "error", err)
+		} else {
+			response.Result = &result
+		}
+	}
+
+	return response
+}
+
+// bulkJobResult is the result a bulk job saves after each chunk. It holds the
+// IDs of the created rows instead of the rows, so saving progress stays cheap;
+// the rows are loaded once, when the last chunk is done.
+type bulkJobResult struct {
+	models.BulkTransactionResponse
+	CreatedIDs []uint `json:"created_ids,omitempty"`
+}
+
+// BulkCreateJob performs bulk transaction jobs in chunks, saving progress after each one.
+// A retried job resumes after the rows it already processed.
+func BulkCreateJob(svc services.TransactionService) jobs.Handler {
+	return func(ctx context.Context, run *jobs.Run) (err error) {
+		ctx, span := tracing.Tracer().Start(ctx, "job "+jobs.TypeBulkCreate, trace.WithAttributes(attribute.Int("job.id", int(run.Job.ID))))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "job failed")
+			}
+			span.End()
+		}()
+
+		var payload models.BulkJobPayload
+		if err := run.Payload(&payload); err != nil {
+			return err
+		}
+
+		response := bulkJobResult{BulkTransactionResponse: models.BulkTransactionResponse{
+			Success:    []models.TransactionResponse{},
+			Failed:     []models.BulkTransactionError{},
+			TotalCount: len(payload.Transactions),
+		}}
+		if err := run.PreviousResult(&response); err != nil {
+			return err
+		}
+		// Results saved by earlier versions list the created rows instead of their IDs
+		if len(response.CreatedIDs) == 0 {
+			for _, created := range response.Success {
+				response.CreatedIDs = append(response.CreatedIDs, created.ID)
+			}
+		}
+		response.Success = []models.TransactionResponse{}
+
+		// Atomic jobs create every row in a single step
+		chunkSize := bulkJobChunkSize
+		if payload.Atomic {
+			chunkSize = len(payload.Transactions)
+		}
+
+		for start := run.Job.Processed; start < len(payload.Transactions); start += chunkSize {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			// The chunk is stored in the same database transaction as the progress,
+			// so a retry after a crash or cancellation never inserts it twice
+			end := min(start+chunkSize, len(payload.Transactions))
+			var result services.BulkCreateResult
+			var created []models.TransactionResponse
+			err := run.Step(ctx, func(ctx context.Context) (int, interface{}, error) {
+				var err error
+				result, err = svc.WithContext(ctx).CreateBulk(models.BulkTransactionRequest{Transactions: payload.Transactions[start:end]}, payload.Atomic)
+				if err != nil {
+					return 0, nil, err
+				}
+
+				for _, failed := range result.Failed {
+					failed.Index += start
+					response.Failed = append(response.Failed, failed)
+				}
+				for _, transaction := range result.Created {
+					response.CreatedIDs = append(response.CreatedIDs, transaction.ID)
+				}
+				created = convertToTransactionResponses(result.Created)
+				response.SuccessCount = len(response.CreatedIDs)
+				response.FailedCount = len(response.Failed)
+				return end, response, nil
+			})
+			if err != nil {
+				return err
+			}
+
+			metrics.RecordBulkFailures("job", result.Failed)
+			for _, transaction := range created {
+				events.PublishAs(run.Job.UserID, events.TransactionCreated, transaction)
+			}
+		}
+
+		transactions, err := svc.WithContext(ctx).GetMany(response.CreatedIDs)
+		if err != nil {
+			return err
+		}
+		response.Success = append([]models.TransactionResponse{}, convertToTransactionResponses(transactions)...)
+		return run.Progress(len(payload.Transactions), response.BulkTransactionResponse)
+	}
+}
+
+// enqueueBulkJob validates a bulk request and queues it, responding 202 with the job
+func enqueueBulkJob(c *fiber.Ctx, queue *jobs.Runner, request models.BulkTransactionRequest, atomic bool) error {
+	if queue == nil {
+		return apperrors.New(fiber.StatusServiceUnavailable, apperrors.CodeAsyncUnavailable, "Background jobs are not available")
+	}
+
+	if err := validation.Struct(&request); err != nil {
+		return apperrors.Validation(err)
+	}
+
+	job, err := queue.Enqueue(jobs.TypeBulkCreate, requestUser(c), models.BulkJobPayload{Transactions: request.Transactions, Atomic: atomic}, len(request.Transactions))
+	if err != nil {
+		return apperrors.Internal("Failed to queue job", err)
+	}
+
+	c.Location(fmt.Sprintf("/api/jobs/%d", job.ID))
+	return c.Status(fiber.StatusAccepted).JSON(convertToJobResponse(job))
+}
+
+// ImportTransactions handles POST /transactions/import
+func ImportTransactions(queue *jobs.Runner) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if len(c.Body()) > MaxBulkBodySize {
+			return apperrors.BodyTooLarge(MaxBulkBodySize)
+		}
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			return apperrors.BadRequest(apperrors.CodeInvalidFile, "A CSV file must be uploaded in the 'file' form field")
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return apperrors.Internal("Failed to read uploaded file", err)
+		}
+		defer file.Close()
+
+		_, parse := tracing.Tracer().Start(c.UserContext(), "import.parse")
+		transactions, err := parseTransactionsCSV(file)
+		parse.SetAttributes(attribute.Int("import.rows", len(transactions)))
+		parse.End()
+		if err != nil {
+			return apperrors.BadRequest(apperrors.CodeInvalidFile, err.Error())
+		}
+
+		return enqueueBulkJob(c, queue, models.BulkTransactionRequest{Transactions: transactions}, c.Query("atomic") == "true")
+	}
+}
+
+// parseTransactionsCSV reads transactions from a CSV file whose header row names the columns.
+// Columns match the JSON fields of a transaction; only amount, type, bank_account_id and description are required.
+func parseTransactionsCSV(r io.Reader) ([]models.Transaction, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "transaction_id", "amount", "type", "category_id", "bank_account_id", "destination_bank_account_id", "payee_id", "description", "date":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	for _, required := range []string{"amount", "type", "bank_account_id", "description"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	var transactions []models.Transaction
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		transaction, err := parseTransactionRecord(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, nil
+}
+
+// parseTransactionRecord converts one CSV record to a transaction
+func parseTransactionRecord(record []string, columns map[string]int) (models.Transaction, error) {
+	var transaction models.Transaction
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	optionalID := func(name string) (*uint, error) {
+		if value(name) == "" {
+			return nil, nil
+		}
+		id, err := strconv.ParseUint(value(name), 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a positive integer", name)
+		}
+		result := uint(id)
+		return &result, nil
+	}
+
+	var err error
+	if transaction.Amount, err = strconv.ParseFloat(value("amount"), 64); err != nil {
+		return transaction, errors.New("amount must be a number")
+	}
+	bankAccountID, err := strconv.ParseUint(value("bank_account_id"), 10, 0)
+	if err != nil {
+		return transaction, errors.New("bank_account_id must be a positive integer")
+	}
+	transaction.BankAccountID = uint(bankAccountID)
+	if transaction.CategoryID, err = optionalID("category_id"); err != nil {
+		return transaction, err
+	}
+	if transaction.DestinationBankAccountID, err = optionalID("destination_bank_account_id"); err != nil {
+		return transaction, err
+	}
+	if transaction.PayeeID, err = optionalID("payee_id"); err != nil {
+		return transaction, err
+	}
+	if date := value("date"); date != "" {
+		if err := transaction.Date.UnmarshalJSON([]byte(strconv.Quote(date))); err != nil {
+			return transaction, errors.New("date is not a recognised date")
+		}
+	}
+
+	transaction.TransactionID = value("transaction_id")
+	transaction.Type = value("type")
+	transaction.Description = value("description")
+	return transaction, nil
+}
+
+// GetJob handles GET /jobs/:id
+func GetJob(queue *jobs.Runner) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		job, err := queue.Get(id, requestUser(c))
+		if err != nil {
+			return apperrors.Lookup(err, jobNotFound())
+		}
+
+		return c.JSON(convertToJobResponse(job))
+	}
+}
+
+// CancelJob handles POST /jobs/:id/cancel
+func CancelJob(queue *jobs.Runner) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		job, err := queue.Cancel(id, requestUser(c))
+		if errors.Is(err, jobs.ErrNotCancellable) {
+			return apperrors.Conflict(apperrors.CodeJobNotCancellable, "Only queued or running jobs can be cancelled")
+		}
+		if err != nil {
+			return apperrors.Lookup(err, jobNotFound())
+		}
+
+		return c.JSON(convertToJobResponse(job))
+	}
+}
+
+// RetryJob handles POST /jobs/:id/retry
+func RetryJob(queue *jobs.Runner) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		job, err := queue.Retry(id, requestUser(c))
+		if errors.Is(err, jobs.ErrNotRetryable) {
+			return apperrors.Conflict(apperrors.CodeJobNotRetryable, "Only failed or cancelled jobs can be retried")
+		}
+		if err != nil {
+			return apperrors.Lookup(err, jobNotFound())
+		}
+
+		return c.JSON(convertToJobResponse(job))
+	}
+}
+
+// jobNotFound is returned when a job ID does not exist
+func jobNotFound() *apperrors.Error {
+	return apperrors.NotFound(apperrors.CodeJobNotFound, "Job not found")
+}
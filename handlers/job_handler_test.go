@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"expense-api/apperrors"
+	"expense-api/container"
+	"expense-api/jobs"
+	"expense-api/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestAsyncBulkAndImportJobs(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+	deps.Jobs.Register(jobs.TypeBulkCreate, BulkCreateJob(deps.Transactions))
+
+	app := newTestApp()
+	app.Post("/transactions/bulk", CreateBulkTransactions(deps.Transactions, deps.Jobs))
+	app.Post("/transactions/import", ImportTransactions(deps.Jobs))
+	app.Get("/jobs/:id", GetJob(deps.Jobs))
+	app.Post("/jobs/:id/cancel", CancelJob(deps.Jobs))
+
+	getJob := func(location string) models.JobResponse {
+		resp, err := app.Test(httptest.NewRequest("GET", strings.TrimPrefix(location, "/api"), nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var job models.JobResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+		return job
+	}
+
+	// Async bulk requests are queued and answered with the job
+	payloadBytes, _ := json.Marshal(map[string]interface{}{"transactions": []map[string]interface{}{
+		{"amount": 10.0, "type": "expense", "category_id": 1, "bank_account_id": 1, "description": "Lunch"},
+		{"amount": 5.0, "type": "expense", "category_id": 2, "bank_account_id": 1, "description": "Wrong category"},
+	}})
+	req := httptest.NewRequest("POST", "/transactions/bulk?async=true", bytes.NewReader(payloadBytes))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 202, resp.StatusCode)
+
+	var queued models.JobResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&queued))
+	assert.Equal(t, jobs.StatusQueued, queued.Status)
+	assert.Equal(t, 2, queued.Total)
+	assert.Equal(t, "/api/jobs/1", resp.Header.Get("Location"))
+
+	ran, err := deps.Jobs.RunNext(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ran)
+
+	job := getJob(resp.Header.Get("Location"))
+	assert.Equal(t, jobs.StatusSucceeded, job.Status)
+	assert.Equal(t, 2, job.Processed)
+	if assert.NotNil(t, job.Result) {
+		assert.Equal(t, 1, job.Result.SuccessCount)
+		assert.Equal(t, "Lunch", job.Result.Success[0].Description)
+		if assert.Len(t, job.Result.Failed, 1) {
+			assert.Equal(t, 1, job.Result.Failed[0].Index)
+			assert.Equal(t, apperrors.CodeCategoryTypeMismatch, job.Result.Failed[0].Code)
+		}
+	}
+
+	// Jobs of other users are not found
+	other := newTestApp()
+	other.Use(func(c *fiber.Ctx) error {
+		c.Locals(UserLocal, uint(2))
+		return c.Next()
+	})
+	other.Get("/jobs/:id", GetJob(deps.Jobs))
+	other.Post("/jobs/:id/cancel", CancelJob(deps.Jobs))
+	for _, req := range []*http.Request{httptest.NewRequest("GET", "/jobs/1", nil), httptest.NewRequest("POST", "/jobs/1/cancel", nil)} {
+		resp, err := other.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode, req.Method)
+	}
+
+	// Finished jobs cannot be cancelled
+	resp, err = app.Test(httptest.NewRequest("POST", "/jobs/1/cancel", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 409, resp.StatusCode)
+
+	// CSV imports always run as jobs
+	upload := func(csv string) (*multipart.Writer, *bytes.Buffer) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "transactions.csv")
+		part.Write([]byte(csv))
+		writer.Close()
+		return writer, body
+	}
+
+	writer, body := upload("amount,type,category_id,bank_account_id,destination_bank_account_id,description,date\n" +
+		"1200,income,2,1,,Salary,2024-01-31\n" +
+		"300,transfer,,1,2,To savings,31-01-2024\n")
+	req = httptest.NewRequest("POST", "/transactions/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 202, resp.StatusCode)
+
+	_, err = deps.Jobs.RunNext(context.Background())
+	assert.NoError(t, err)
+
+	job = getJob(resp.Header.Get("Location"))
+	assert.Equal(t, jobs.StatusSucceeded, job.Status)
+	if assert.NotNil(t, job.Result) {
+		assert.Equal(t, 2, job.Result.SuccessCount)
+		assert.Equal(t, "transfer", job.Result.Success[1].Type)
+	}
+
+	// Malformed files are rejected before a job is queued
+	writer, body = upload("amount,type,bank_account_id,description\nten,expense,1,Lunch\n")
+	req = httptest.NewRequest("POST", "/transactions/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+
+	var problem models.Problem
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, apperrors.CodeInvalidFile, problem.Code)
+	assert.Equal(t, "line 2: amount must be a number", problem.Detail)
+}
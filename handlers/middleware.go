@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"expense-api/apperrors"
+	"expense-api/idempotency"
+	"expense-api/logging"
+	"expense-api/ratelimit"
+	"expense-api/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireReady rejects requests with 503 until ready reports that storage can be used
+func RequireReady(ready func() bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !ready() {
+			return apperrors.Unavailable()
+		}
+		return c.Next()
+	}
+}
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to retry.
+// The first response for a key is stored and replayed for retries of the same request;
+// server errors are not stored so the request can be retried for real. Keys are
+// scoped by client as identified by clientKey, so it must run after Authenticate.
+func Idempotency(store *idempotency.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(idempotency.Header)
+		if store == nil || key == "" || c.Method() != fiber.MethodPost {
+			return c.Next()
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			return apperrors.BadRequest(apperrors.CodeInvalidParameter, fmt.Sprintf("%s must be at most %d characters", idempotency.Header, idempotency.MaxKeyLength))
+		}
+
+		client := clientKey(c)
+		record, err := store.Begin(client, key, idempotency.Fingerprint(c.Method(), c.OriginalURL(), c.Body()))
+		switch {
+		case errors.Is(err, idempotency.ErrKeyReused):
+			return apperrors.Conflict(apperrors.CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request")
+		case errors.Is(err, idempotency.ErrInProgress):
+			return apperrors.Conflict(apperrors.CodeIdempotencyKeyInProgress, "A request with this Idempotency-Key is still being processed")
+		case err != nil:
+			return apperrors.Internal("Failed to check Idempotency-Key", err)
+		case record != nil:
+			// Replay the stored response
+			c.Set(idempotency.ReplayedHeader, "true")
+			c.Set(fiber.HeaderContentType, record.ContentType)
+			if record.Location != "" {
+				c.Location(record.Location)
+			}
+			return c.Status(record.ResponseCode).SendString(record.ResponseBody)
+		}
+
+		stop := store.Heartbeat(c.UserContext(), client, key)
+		// Render errors here so the final response can be stored
+		err = c.Next()
+		if err != nil {
+			err = c.App().Config().ErrorHandler(c, err)
+		}
+		stop()
+		if err != nil {
+			store.Release(client, key)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			if err := store.Release(client, key); err != nil {
+				logging.For(logging.ComponentIdempotency).ErrorContext(c.UserContext(), "Failed to release idempotency key", "error", err)
+			}
+			return nil
+		}
+
+		response := c.Response()
+		if err := store.Complete(client, key, status, string(response.Header.ContentType()), string(response.Header.Peek(fiber.HeaderLocation)), response.Body()); err != nil {
+			logging.For(logging.ComponentIdempotency).ErrorContext(c.UserContext(), "Failed to store idempotent response", "error", err)
+		}
+		return nil
+	}
+}
+
+// APIKeyHeader carries the API key of a client. A bearer token in the Authorization header is used the same way.
+const APIKeyHeader = "X-API-Key"
+
+// UserLocal is the Fiber local under which authentication stores the ID of the user making the request
+const UserLocal = "user_id"
+
+// Authenticate identifies the user making a request from its API key or bearer
+// token and stores the user's ID under UserLocal. Requests without a token, with
+// an unknown one, or sent before the database is ready continue anonymously.
+func Authenticate(users services.UserService, ready func() bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := requestToken(c)
+		if users == nil || token == "" || !ready() {
+			return c.Next()
+		}
+
+		user, ok, err := users.WithContext(c.UserContext()).Authenticate(token)
+		if err != nil {
+			logging.For(logging.ComponentHTTP).ErrorContext(c.UserContext(), "Failed to verify API token", "error", err)
+		} else if ok {
+			c.Locals(UserLocal, user.ID)
+		}
+		return c.Next()
+	}
+}
+
+// requestToken returns the API key of a request, or else its bearer token
+func requestToken(c *fiber.Ctx) string {
+	if key := c.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok {
+		return token
+	}
+	return ""
+}
+
+// RateLimit limits the requests of each client with separate limits for
+// reads, writes and bulk routes, and reports the state of the client's bucket
+// in RateLimit-* headers. A nil limiter allows every request.
+func RateLimit(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if limiter == nil {
+			return c.Next()
+		}
+
+		class := ratelimit.Classify(c.Method(), c.Path())
+		result, limited, err := limiter.Take(c.UserContext(), class, clientKey(c))
+		if err != nil {
+			// An unreachable shared store should not take the API down with it
+			logging.For(logging.ComponentHTTP).ErrorContext(c.UserContext(), "Rate limit store error", "error", err)
+			return c.Next()
+		}
+		if !limited {
+			return c.Next()
+		}
+
+		c.Set(ratelimit.HeaderLimit, strconv.Itoa(result.Limit))
+		c.Set(ratelimit.HeaderRemaining, strconv.Itoa(result.Remaining))
+		c.Set(ratelimit.HeaderReset, strconv.Itoa(ceilSeconds(result.Reset.Seconds())))
+		c.Set(ratelimit.HeaderPolicy, limiter.Limits[class].Policy())
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter.Seconds())))
+			return apperrors.TooManyRequests(fmt.Sprintf("Too many %s requests, retry later", class))
+		}
+		return c.Next()
+	}
+}
+
+// clientKey identifies who a request counts against: the user verified by
+// Authenticate, otherwise the client IP address. Unverified keys are ignored,
+// so sending a new key on every request does not get a new bucket.
+func clientKey(c *fiber.Ctx) string {
+	if user := c.Locals(UserLocal); user != nil {
+		return fmt.Sprintf("user:%v", user)
+	}
+	return "ip:" + c.IP()
+}
+
+// requestUser returns the ID of the user verified by Authenticate, or 0 for anonymous requests
+func requestUser(c *fiber.Ctx) uint {
+	user, _ := c.Locals(UserLocal).(uint)
+	return user
+}
+
+// ceilSeconds rounds a number of seconds up, as the RateLimit and Retry-After headers expect
+func ceilSeconds(seconds float64) int {
+	return int(math.Ceil(seconds))
+}
+
+// BodyLimit rejects request bodies larger than limit with 413. Bulk creates
+// and imports are left to their handlers, which allow up to MaxBulkBodySize.
+func BodyLimit(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() == fiber.MethodPost && ratelimit.Classify(c.Method(), c.Path()) == ratelimit.ClassBulk {
+			return c.Next()
+		}
+		if len(c.Body()) > limit {
+			return apperrors.BodyTooLarge(limit)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"expense-api/admin"
+	"expense-api/apperrors"
+	"expense-api/container"
+	"expense-api/idempotency"
+	"expense-api/models"
+	"expense-api/ratelimit"
+	"expense-api/repository"
+	"expense-api/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestIdempotencyKeyReplaysResponses(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+
+	// Requests are anonymous until user is set, as if Authenticate had verified its token
+	var user uint
+	app := newTestApp()
+	app.Use(func(c *fiber.Ctx) error {
+		if user != 0 {
+			c.Locals(UserLocal, user)
+		}
+		return c.Next()
+	})
+	app.Use(Idempotency(deps.Idempotency))
+	app.Post("/transactions", CreateTransaction(deps.Transactions, nil))
+	app.Post("/transactions/transfer", CreateTransfer(deps.Transactions))
+
+	post := func(url, key string, payload map[string]interface{}) (int, string, string) {
+		payloadBytes, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", url, bytes.NewReader(payloadBytes))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(idempotency.Header, key)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), resp.Header.Get(idempotency.ReplayedHeader)
+	}
+	count := func() int64 {
+		var n int64
+		db.Model(&models.Transaction{}).Count(&n)
+		return n
+	}
+
+	transfer := map[string]interface{}{"amount": 100.0, "bank_account_id": 1, "destination_bank_account_id": 2, "description": "To savings"}
+
+	status, first, replayed := post("/transactions/transfer", "retry-1", transfer)
+	assert.Equal(t, 201, status)
+	assert.Empty(t, replayed)
+
+	// The retry gets the stored response and creates nothing
+	status, second, replayed := post("/transactions/transfer", "retry-1", transfer)
+	assert.Equal(t, 201, status)
+	assert.Equal(t, "true", replayed)
+	assert.Equal(t, first, second)
+	assert.Equal(t, int64(1), count())
+
+	// Reusing the key for a different request is a conflict
+	transfer["amount"] = 200.0
+	status, body, _ := post("/transactions/transfer", "retry-1", transfer)
+	assert.Equal(t, 409, status)
+	assert.Contains(t, body, apperrors.CodeIdempotencyKeyReused)
+
+	// Client errors are stored and replayed too
+	invalid := map[string]interface{}{"amount": 5.0, "type": "expense", "category_id": 2, "bank_account_id": 1, "description": "Wrong category"}
+	status, _, _ = post("/transactions", "retry-2", invalid)
+	assert.Equal(t, 400, status)
+	status, body, replayed = post("/transactions", "retry-2", invalid)
+	assert.Equal(t, 400, status)
+	assert.Equal(t, "true", replayed)
+	assert.Contains(t, body, apperrors.CodeCategoryTypeMismatch)
+
+	// Requests without a key are never deduplicated
+	post("/transactions/transfer", "", transfer)
+	post("/transactions/transfer", "", transfer)
+	assert.Equal(t, int64(3), count())
+
+	// Keys are scoped by client, so another client's key is not replayed
+	user = 2
+	status, _, replayed = post("/transactions/transfer", "retry-1", transfer)
+	assert.Equal(t, 201, status)
+	assert.Empty(t, replayed)
+	assert.Equal(t, int64(4), count())
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassRead:  {Requests: 2, Period: time.Minute},
+		ratelimit.ClassWrite: {Requests: 1, Period: time.Minute},
+	})
+
+	store := repository.NewMemoryStore()
+	assert.NoError(t, store.Users().Create(&models.User{Name: "alice", TokenHash: admin.HashToken("key-1")}))
+
+	app := newTestApp()
+	app.Use(Authenticate(services.NewUserService(store.Users()), func() bool { return true }))
+	app.Use(RateLimit(limiter))
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/transactions", ok)
+	app.Post("/transactions", ok)
+	app.Post("/transactions/bulk", ok)
+
+	send := func(method, path, apiKey string) *http.Response {
+		req := httptest.NewRequest(method, path, nil)
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	resp := send("GET", "/transactions", "")
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get(ratelimit.HeaderLimit))
+	assert.Equal(t, "1", resp.Header.Get(ratelimit.HeaderRemaining))
+	assert.Equal(t, "30", resp.Header.Get(ratelimit.HeaderReset))
+	assert.Equal(t, "2;w=60", resp.Header.Get(ratelimit.HeaderPolicy))
+
+	assert.Equal(t, 200, send("GET", "/transactions", "").StatusCode)
+	resp = send("GET", "/transactions", "")
+	assert.Equal(t, 429, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get(fiber.HeaderRetryAfter))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), apperrors.CodeRateLimited)
+
+	// Writes have a bucket of their own, and routes without a limit send no headers
+	assert.Equal(t, 200, send("POST", "/transactions", "").StatusCode)
+	assert.Equal(t, 429, send("POST", "/transactions", "").StatusCode)
+	resp = send("POST", "/transactions/bulk", "")
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(ratelimit.HeaderLimit))
+
+	// Requests with a verified API key count against its user instead of the IP address
+	assert.Equal(t, 200, send("GET", "/transactions", "key-1").StatusCode)
+	assert.Equal(t, 200, send("GET", "/transactions", "key-1").StatusCode)
+	assert.Equal(t, 429, send("GET", "/transactions", "key-1").StatusCode)
+
+	// Unknown keys do not get a bucket of their own
+	assert.Equal(t, 429, send("GET", "/transactions", "key-2").StatusCode)
+	assert.Equal(t, 429, send("GET", "/transactions", "key-3").StatusCode)
+}
+
+func TestClientKey(t *testing.T) {
+	store := repository.NewMemoryStore()
+	user := models.User{Name: "alice", TokenHash: admin.HashToken("secret")}
+	assert.NoError(t, store.Users().Create(&user))
+
+	app := fiber.New()
+	var keys []string
+	app.Use(Authenticate(services.NewUserService(store.Users()), func() bool { return true }))
+	app.Use(func(c *fiber.Ctx) error {
+		keys = append(keys, clientKey(c))
+		return nil
+	})
+
+	for _, headers := range []map[string]string{
+		{APIKeyHeader: "secret"},
+		{fiber.HeaderAuthorization: "Bearer secret"},
+		{APIKeyHeader: "forged"},
+		{fiber.HeaderAuthorization: "Basic secret"},
+		{},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		_, err := app.Test(req)
+		assert.NoError(t, err)
+	}
+
+	if assert.Len(t, keys, 5) {
+		assert.Equal(t, fmt.Sprintf("user:%d", user.ID), keys[0])
+		assert.Equal(t, keys[0], keys[1])
+		assert.Equal(t, "ip:0.0.0.0", keys[2])
+		assert.Equal(t, "ip:0.0.0.0", keys[3])
+		assert.Equal(t, "ip:0.0.0.0", keys[4])
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	deps := setupTestServices(t)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperrors.Handler, BodyLimit: 2 * MaxBulkBodySize})
+	app.Use(BodyLimit(100))
+	app.Post("/transactions", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Post("/transactions/bulk", CreateBulkTransactions(deps.Transactions, nil))
+
+	send := func(path string, size int) (int, string) {
+		req := httptest.NewRequest("POST", path, bytes.NewReader(bytes.Repeat([]byte(" "), size)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, _ := send("/transactions", 100)
+	assert.Equal(t, 200, status)
+	status, body := send("/transactions", 101)
+	assert.Equal(t, 413, status)
+	assert.Contains(t, body, apperrors.CodeBodyTooLarge)
+
+	// Bulk creates may send up to MaxBulkBodySize, which the handler enforces
+	status, body = send("/transactions/bulk", 1000)
+	assert.Equal(t, 400, status)
+	assert.Contains(t, body, apperrors.CodeInvalidBody)
+	status, body = send("/transactions/bulk", MaxBulkBodySize+1)
+	assert.Equal(t, 413, status)
+	assert.Contains(t, body, apperrors.CodeBodyTooLarge)
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"strconv"
+
+	"expense-api/apperrors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// paramID parses the :id path parameter, rejecting anything that is not a positive integer
+func paramID(c *fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, apperrors.BadRequest(apperrors.CodeInvalidID, "id must be a positive integer")
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"expense-api/apperrors"
+	"expense-api/events"
+	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/services"
+	"expense-api/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// convertToPayeeResponse converts a Payee model to PayeeResponse
+func convertToPayeeResponse(payee models.Payee) models.PayeeResponse {
+	aliases := make([]string, 0, len(payee.Aliases))
+	for _, alias := range payee.Aliases {
+		aliases = append(aliases, alias.Pattern)
+	}
+	return models.PayeeResponse{
+		ID:                payee.ID,
+		Name:              payee.Name,
+		DefaultCategoryID: payee.DefaultCategoryID,
+		Aliases:           aliases,
+	}
+}
+
+// CreatePayee handles POST /payees
+func CreatePayee(svc services.PayeeService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		var request models.PayeeRequest
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
+		}
+
+		created, err := svc.Create(request)
+		if err != nil {
+			return err
+		}
+
+		response := convertToPayeeResponse(created)
+		publish(c, events.PayeeCreated, response)
+
+		return c.Status(201).JSON(response)
+	}
+}
+
+// GetPayees handles GET /payees
+func GetPayees(svc services.PayeeService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		payees, err := svc.List()
+		if err != nil {
+			return err
+		}
+
+		response := make([]models.PayeeResponse, 0, len(payees))
+		for _, payee := range payees {
+			response = append(response, convertToPayeeResponse(payee))
+		}
+
+		return c.JSON(response)
+	}
+}
+
+// GetPayee handles GET /payees/:id
+func GetPayee(svc services.PayeeService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		payee, err := svc.Get(id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(convertToPayeeResponse(payee))
+	}
+}
+
+// UpdatePayee handles PUT /payees/:id, which replaces the name, default category and aliases
+func UpdatePayee(svc services.PayeeService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		var request models.PayeeRequest
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
+		}
+
+		payee, err := svc.Update(id, request)
+		if err != nil {
+			return err
+		}
+
+		response := convertToPayeeResponse(payee)
+		publish(c, events.PayeeUpdated, response)
+
+		return c.JSON(response)
+	}
+}
+
+// DeletePayee handles DELETE /payees/:id
+func DeletePayee(svc services.PayeeService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		if err := svc.Delete(id); err != nil {
+			return err
+		}
+
+		publish(c, events.PayeeDeleted, fiber.Map{"id": id})
+
+		return c.Status(200).JSON(fiber.Map{
+			"message": "Payee deleted successfully",
+		})
+	}
+}
+
+// MergePayee handles POST /payees/:id/merge
+func MergePayee(svc services.PayeeService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		var request models.PayeeMergeRequest
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
+		}
+		if err := validation.Struct(&request); err != nil {
+			return apperrors.Validation(err)
+		}
+
+		result, err := svc.Merge(id, request.TargetID)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range result.Transactions {
+			publish(c, events.TransactionUpdated, convertToTransactionResponse(t))
+		}
+		publish(c, events.PayeeDeleted, fiber.Map{"id": id, "merged_into": result.Target.ID})
+
+		return c.JSON(models.PayeeMergeResponse{
+			Target:                 convertToPayeeResponse(result.Target),
+			ReassignedTransactions: result.ReassignedTransactions,
+		})
+	}
+}
+
+// GetPayeeReport handles GET /reports/payees, which ranks payees by the amount
+// spent with them, or received from them with ?type=income, in an optional date range
+func GetPayeeReport(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		filter := repository.TransactionFilter{Type: c.Query("type", "expense")}
+		if err := validation.Var("type", filter.Type, "category_type"); err != nil {
+			return apperrors.Validation(err)
+		}
+
+		limit := 0
+		if value := c.Query("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				return apperrors.BadRequest(apperrors.CodeInvalidParameter, "limit must be a positive integer")
+			}
+			limit = parsed
+		}
+
+		var err error
+		filter.From, filter.To, err = optionalDateRange(c)
+		if err != nil {
+			return err
+		}
+
+		transactions, err := svc.List(filter)
+		if err != nil {
+			return err
+		}
+
+		response := models.PayeeReportResponse{
+			DateRange: models.DateRange{
+				StartDate: c.Query("start_date"),
+				EndDate:   c.Query("end_date"),
+			},
+			Type:   filter.Type,
+			Payees: []models.PayeeSpend{},
+		}
+
+		byPayee := make(map[uint]*models.PayeeSpend)
+		for _, t := range transactions {
+			response.TotalAmount += t.Amount
+			if t.PayeeID == nil {
+				response.UnassignedAmount += t.Amount
+				response.UnassignedCount++
+				continue
+			}
+			spend, exists := byPayee[*t.PayeeID]
+			if !exists {
+				spend = &models.PayeeSpend{PayeeID: *t.PayeeID, PayeeName: t.Payee.Name}
+				byPayee[*t.PayeeID] = spend
+			}
+			spend.TotalAmount += t.Amount
+			spend.TransactionCount++
+		}
+
+		for _, spend := range byPayee {
+			response.Payees = append(response.Payees, *spend)
+		}
+		sort.Slice(response.Payees, func(i, j int) bool {
+			a, b := response.Payees[i], response.Payees[j]
+			if a.TotalAmount != b.TotalAmount {
+				return a.TotalAmount > b.TotalAmount
+			}
+			return a.PayeeName < b.PayeeName
+		})
+		if limit > 0 && len(response.Payees) > limit {
+			response.Payees = response.Payees[:limit]
+		}
+		for i := range response.Payees {
+			response.Payees[i].Rank = i + 1
+			if response.TotalAmount > 0 {
+				response.Payees[i].Share = math.Round(response.Payees[i].TotalAmount/response.TotalAmount*10000) / 100
+			}
+		}
+
+		return c.JSON(response)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"expense-api/container"
+	"expense-api/events"
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPayeesAndReport(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+
+	app := newTestApp()
+	app.Post("/payees", CreatePayee(deps.Payees))
+	app.Get("/payees/:id", GetPayee(deps.Payees))
+	app.Post("/payees/:id/merge", MergePayee(deps.Payees))
+	app.Delete("/payees/:id", DeletePayee(deps.Payees))
+	app.Get("/reports/payees", GetPayeeReport(deps.Transactions))
+
+	send := func(method, path, body string, out interface{}) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		if out != nil && resp.StatusCode < 300 {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	// Food (1) and Salary (2) are seeded
+	var amazon, uber models.PayeeResponse
+	assert.Equal(t, 201, send("POST", "/payees", `{"name": "Amazon", "default_category_id": 1, "aliases": ["AMZN Mktp", "amazon.in"]}`, &amazon))
+	assert.Equal(t, []string{"AMZN MKTP", "AMAZON IN"}, amazon.Aliases)
+	assert.Equal(t, 201, send("POST", "/payees", `{"name": "Uber", "aliases": ["UBER *TRIP"]}`, &uber))
+	assert.Equal(t, 409, send("POST", "/payees", `{"name": "Amazon"}`, nil))
+	assert.Equal(t, 400, send("POST", "/payees", `{"aliases": ["X"]}`, nil))
+
+	// Imported rows are matched by description and take the payee's default category
+	food := uint(1)
+	date := models.FlexibleDate{Time: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)}
+	result, err := deps.Transactions.CreateBulk(models.BulkTransactionRequest{Transactions: []models.Transaction{
+		{Amount: 30, Type: "expense", BankAccountID: 1, Description: "AMZN Mktp IN*2K3", Date: date},
+		{Amount: 20, Type: "expense", BankAccountID: 1, Description: "Amazon.in order", Date: date},
+		{Amount: 15, Type: "expense", CategoryID: &food, BankAccountID: 1, Description: "UBER *TRIP HELP.UBER.COM", Date: date},
+		{Amount: 10, Type: "expense", CategoryID: &food, BankAccountID: 1, Description: "Market", Date: date},
+		{Amount: 500, Type: "income", CategoryID: func() *uint { id := uint(2); return &id }(), BankAccountID: 1, Description: "Salary", Date: date},
+	}}, false)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Failed)
+	if assert.Len(t, result.Created, 5) {
+		response := convertToTransactionResponse(result.Created[0])
+		assert.Equal(t, "Amazon", response.Payee)
+		assert.Equal(t, &food, response.CategoryID)
+		assert.Nil(t, result.Created[3].PayeeID)
+	}
+
+	var report models.PayeeReportResponse
+	assert.Equal(t, 200, send("GET", "/reports/payees?start_date=2024-03-01&end_date=2024-03-31", "", &report))
+	assert.Equal(t, "expense", report.Type)
+	assert.Equal(t, float64(75), report.TotalAmount)
+	assert.Equal(t, float64(10), report.UnassignedAmount)
+	assert.Equal(t, []models.PayeeSpend{
+		{Rank: 1, PayeeID: amazon.ID, PayeeName: "Amazon", TotalAmount: 50, TransactionCount: 2, Share: 66.67},
+		{Rank: 2, PayeeID: uber.ID, PayeeName: "Uber", TotalAmount: 15, TransactionCount: 1, Share: 20},
+	}, report.Payees)
+
+	report = models.PayeeReportResponse{}
+	assert.Equal(t, 200, send("GET", "/reports/payees?start_date=2024-03-01&limit=1", "", &report))
+	assert.Len(t, report.Payees, 1)
+	report = models.PayeeReportResponse{}
+	assert.Equal(t, 200, send("GET", "/reports/payees?end_date=2024-02-29", "", &report))
+	assert.Empty(t, report.Payees)
+	assert.Equal(t, 400, send("GET", "/reports/payees?type=transfer", "", nil))
+	assert.Equal(t, 400, send("GET", "/reports/payees?limit=0", "", nil))
+
+	// Merging moves transactions in the trash too
+	assert.NoError(t, deps.Transactions.Delete(result.Created[2].ID))
+	uberTrip, err := deps.Transactions.Create(models.Transaction{Amount: 12, Type: "expense", CategoryID: &food, BankAccountID: 1, Description: "UBER *TRIP AIRPORT", Date: date})
+	assert.NoError(t, err)
+	sub := events.Default.Subscribe(events.DefaultBufferSize)
+	defer events.Default.Unsubscribe(sub)
+
+	var merged models.PayeeMergeResponse
+	assert.Equal(t, 400, send("POST", fmt.Sprintf("/payees/%d/merge", uber.ID), `{"target_id": 99}`, nil))
+	assert.Equal(t, 200, send("POST", fmt.Sprintf("/payees/%d/merge", uber.ID), fmt.Sprintf(`{"target_id": %d}`, amazon.ID), &merged))
+	assert.Equal(t, int64(2), merged.ReassignedTransactions)
+	assert.Equal(t, []string{"AMZN MKTP", "AMAZON IN", "UBER *TRIP"}, merged.Target.Aliases)
+	assert.Equal(t, 404, send("GET", fmt.Sprintf("/payees/%d", uber.ID), "", nil))
+
+	// Other tests publish to the same bus, so look for this test's transaction.
+	// Only live transactions are announced.
+	var updated []models.TransactionResponse
+	for len(sub.C) > 0 {
+		event := <-sub.C
+		if response, ok := event.Data.(models.TransactionResponse); ok && event.Type == events.TransactionUpdated && strings.HasPrefix(response.Description, "UBER *TRIP") {
+			updated = append(updated, response)
+		}
+	}
+	if assert.Len(t, updated, 1) {
+		assert.Equal(t, uberTrip.ID, updated[0].ID)
+		assert.Equal(t, "Amazon", updated[0].Payee)
+	}
+
+	var count int64
+	db.Unscoped().Model(&models.Transaction{}).Where("payee_id = ?", amazon.ID).Count(&count)
+	assert.Equal(t, int64(4), count)
+
+	// The reassignment is recorded in the history of each transaction, even in the trash
+	var versions []models.TransactionVersion
+	db.Where("record_id = ?", result.Created[2].ID).Order("version").Find(&versions)
+	if assert.Len(t, versions, 3) {
+		assert.Equal(t, models.VersionOperationUpdate, versions[2].Operation)
+		assert.True(t, versions[2].Deleted)
+	}
+
+	// Deleting a payee keeps its transactions without a payee
+	assert.Equal(t, 200, send("DELETE", fmt.Sprintf("/payees/%d", amazon.ID), "", nil))
+	db.Unscoped().Model(&models.Transaction{}).Where("payee_id IS NOT NULL").Count(&count)
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", fmt.Sprintf("/payees/%d", amazon.ID), "", nil))
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/events"
+	"expense-api/logging"
+	"expense-api/models"
+	"expense-api/services"
+	"expense-api/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// convertToRecurringResponse converts a RecurringTransaction model to RecurringTransactionResponse
+func convertToRecurringResponse(schedule models.RecurringTransaction) models.RecurringTransactionResponse {
+	return models.RecurringTransactionResponse{
+		ID:              schedule.ID,
+		Description:     schedule.Description,
+		Amount:          schedule.Amount,
+		CategoryID:      schedule.CategoryID,
+		BankAccountID:   schedule.BankAccountID,
+		PayeeID:         schedule.PayeeID,
+		Cadence:         schedule.Cadence,
+		NextDate:        schedule.NextDate,
+		SubscriptionKey: schedule.SubscriptionKey,
+	}
+}
+
+// GetSubscriptions handles GET /insights/subscriptions, which lists the expenses
+// charged at a regular cadence, optionally only the active or stopped ones
+func GetSubscriptions(svc services.RecurringService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		active := c.Query("active")
+		if active != "" && active != "true" && active != "false" {
+			return apperrors.BadRequest(apperrors.CodeInvalidParameter, "active must be true or false")
+		}
+
+		subscriptions, err := svc.Subscriptions(time.Now())
+		if err != nil {
+			return err
+		}
+
+		response := models.SubscriptionsResponse{Subscriptions: []models.Subscription{}}
+		for _, subscription := range subscriptions {
+			if subscription.Active {
+				response.ActiveCount++
+				response.ActiveAnnualCost += subscription.AnnualCost
+			}
+			if active == "" || (active == "true") == subscription.Active {
+				response.Subscriptions = append(response.Subscriptions, subscription)
+			}
+		}
+		response.ActiveAnnualCost = services.RoundCents(response.ActiveAnnualCost)
+
+		return c.JSON(response)
+	}
+}
+
+// ScheduleSubscription handles POST /insights/subscriptions/schedule, which turns
+// a detected subscription into a recurring transaction
+func ScheduleSubscription(svc services.RecurringService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		var request models.SubscriptionScheduleRequest
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
+		}
+		if err := validation.Struct(&request); err != nil {
+			return apperrors.Validation(err)
+		}
+
+		schedule, err := svc.Schedule(request.Key, time.Now())
+		if err != nil {
+			return err
+		}
+
+		response := convertToRecurringResponse(schedule)
+		publish(c, events.RecurringCreated, response)
+
+		return c.Status(201).JSON(response)
+	}
+}
+
+// GetRecurringTransactions handles GET /recurring
+func GetRecurringTransactions(svc services.RecurringService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		schedules, err := svc.List()
+		if err != nil {
+			return err
+		}
+
+		response := make([]models.RecurringTransactionResponse, 0, len(schedules))
+		for _, schedule := range schedules {
+			response = append(response, convertToRecurringResponse(schedule))
+		}
+
+		return c.JSON(response)
+	}
+}
+
+// GetRecurringTransaction handles GET /recurring/:id
+func GetRecurringTransaction(svc services.RecurringService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		schedule, err := svc.Get(id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(convertToRecurringResponse(schedule))
+	}
+}
+
+// DeleteRecurringTransaction handles DELETE /recurring/:id
+func DeleteRecurringTransaction(svc services.RecurringService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		if err := svc.Delete(id); err != nil {
+			return err
+		}
+
+		publish(c, events.RecurringDeleted, fiber.Map{"id": id})
+
+		return c.Status(200).JSON(fiber.Map{
+			"message": "Recurring transaction deleted successfully",
+		})
+	}
+}
+
+// PostRecurringTransactions posts the due recurring transactions every interval
+// until ctx is cancelled
+func PostRecurringTransactions(ctx context.Context, svc services.RecurringService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := svc.WithContext(ctx).PostDue(time.Now())
+		for _, transaction := range convertToTransactionResponses(created) {
+			events.Publish(events.TransactionCreated, transaction)
+		}
+		if err != nil {
+			logging.For(logging.ComponentServices).ErrorContext(ctx, "Failed to post recurring transactions", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
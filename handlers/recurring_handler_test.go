@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"expense-api/container"
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSubscriptionsAndRecurringTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+
+	app := newTestApp()
+	app.Get("/insights/subscriptions", GetSubscriptions(deps.Recurring))
+	app.Post("/insights/subscriptions/schedule", ScheduleSubscription(deps.Recurring))
+	app.Get("/recurring", GetRecurringTransactions(deps.Recurring))
+	app.Delete("/recurring/:id", DeleteRecurringTransaction(deps.Recurring))
+
+	send := func(method, path, body string, out interface{}) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		if out != nil && resp.StatusCode < 300 {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	// Food (1) is seeded; the last charge was a month ago
+	food := uint(1)
+	now := time.Now().UTC().Truncate(time.Second)
+	for months := 3; months >= 1; months-- {
+		_, err := deps.Transactions.Create(models.Transaction{Amount: 15.99, Type: "expense", CategoryID: &food, BankAccountID: 1,
+			Description: fmt.Sprintf("MEAL KIT %d", months), Date: models.FlexibleDate{Time: now.AddDate(0, -months, 0)}})
+		assert.NoError(t, err)
+	}
+
+	var subscriptions models.SubscriptionsResponse
+	assert.Equal(t, 200, send("GET", "/insights/subscriptions", "", &subscriptions))
+	if assert.Len(t, subscriptions.Subscriptions, 1) {
+		assert.Equal(t, "description:MEAL KIT", subscriptions.Subscriptions[0].Key)
+		assert.Equal(t, models.CadenceMonthly, subscriptions.Subscriptions[0].Cadence)
+	}
+	assert.Equal(t, 1, subscriptions.ActiveCount)
+	assert.Equal(t, 191.88, subscriptions.ActiveAnnualCost)
+
+	subscriptions = models.SubscriptionsResponse{}
+	assert.Equal(t, 200, send("GET", "/insights/subscriptions?active=false", "", &subscriptions))
+	assert.Empty(t, subscriptions.Subscriptions)
+	assert.Equal(t, 400, send("GET", "/insights/subscriptions?active=maybe", "", nil))
+
+	var schedule models.RecurringTransactionResponse
+	assert.Equal(t, 400, send("POST", "/insights/subscriptions/schedule", `{}`, nil))
+	assert.Equal(t, 201, send("POST", "/insights/subscriptions/schedule", `{"key": "description:MEAL KIT"}`, &schedule))
+	assert.Equal(t, models.CadenceMonthly, schedule.Cadence)
+	assert.Equal(t, 409, send("POST", "/insights/subscriptions/schedule", `{"key": "description:MEAL KIT"}`, nil))
+
+	// The schedule posts the next charge once it is due
+	due := schedule.NextDate.Add(time.Minute)
+	created, err := deps.Recurring.PostDue(due)
+	assert.NoError(t, err)
+	assert.Len(t, created, 1)
+	created, err = deps.Recurring.PostDue(due)
+	assert.NoError(t, err)
+	assert.Empty(t, created)
+
+	var schedules []models.RecurringTransactionResponse
+	assert.Equal(t, 200, send("GET", "/recurring", "", &schedules))
+	if assert.Len(t, schedules, 1) {
+		assert.True(t, schedules[0].NextDate.After(due))
+	}
+
+	assert.Equal(t, 200, send("DELETE", fmt.Sprintf("/recurring/%d", schedule.ID), "", nil))
+	assert.Equal(t, 404, send("DELETE", fmt.Sprintf("/recurring/%d", schedule.ID), "", nil))
+}
@@ -1,33 +1,49 @@
 package handlers
 
 import (
+	"strconv"
 	"time"
 
-	"expense-api/database"
+	"expense-api/apperrors"
+	"expense-api/events"
+	"expense-api/jobs"
+	"expense-api/logging"
+	"expense-api/metrics"
 	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/services"
+	"expense-api/tracing"
+	"expense-api/validation"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// convertToBankAccountResponse converts a BankAccount model to BankAccountResponse
+func convertToBankAccountResponse(a models.BankAccount) models.BankAccountResponse {
+	return models.BankAccountResponse{
+		ID:             a.ID,
+		Name:           a.Name,
+		AccountNumber:  a.AccountNumber,
+		BankName:       a.BankName,
+		AccountType:    a.AccountType,
+		Balance:        a.Balance,
+		OpeningBalance: a.OpeningBalance,
+		IsActive:       a.IsActive,
+	}
+}
+
 // convertToTransactionResponse converts a Transaction model to TransactionResponse
 func convertToTransactionResponse(t models.Transaction) models.TransactionResponse {
 	response := models.TransactionResponse{
-		ID:            t.ID,
-		TransactionID: t.TransactionID,
-		Amount:        t.Amount,
-		Type:          t.Type,
-		CategoryID:    t.CategoryID,
-		BankAccountID: t.BankAccountID,
-		BankAccount: models.BankAccountResponse{
-			ID:            t.BankAccount.ID,
-			Name:          t.BankAccount.Name,
-			AccountNumber: t.BankAccount.AccountNumber,
-			BankName:      t.BankAccount.BankName,
-			AccountType:   t.BankAccount.AccountType,
-			Balance:       t.BankAccount.Balance,
-			IsActive:      t.BankAccount.IsActive,
-		},
+		ID:                       t.ID,
+		TransactionID:            t.TransactionID,
+		Amount:                   t.Amount,
+		Type:                     t.Type,
+		CategoryID:               t.CategoryID,
+		BankAccountID:            t.BankAccountID,
+		BankAccount:              convertToBankAccountResponse(t.BankAccount),
 		DestinationBankAccountID: t.DestinationBankAccountID,
+		PayeeID:                  t.PayeeID,
 		Description:              t.Description,
 		Date:                     t.Date.Time,
 		CreatedAt:                t.CreatedAt,
@@ -38,905 +54,672 @@ func convertToTransactionResponse(t models.Transaction) models.TransactionRespon
 		response.Category = t.Category.Name
 	}
 
+	// Set payee name if payee exists
+	if t.PayeeID != nil {
+		response.Payee = t.Payee.Name
+	}
+
 	// Set destination bank account if it exists
 	if t.DestinationBankAccountID != nil {
-		response.DestinationBankAccount = &models.BankAccountResponse{
-			ID:            t.DestinationBankAccount.ID,
-			Name:          t.DestinationBankAccount.Name,
-			AccountNumber: t.DestinationBankAccount.AccountNumber,
-			BankName:      t.DestinationBankAccount.BankName,
-			AccountType:   t.DestinationBankAccount.AccountType,
-			Balance:       t.DestinationBankAccount.Balance,
-			IsActive:      t.DestinationBankAccount.IsActive,
-		}
+		destination := convertToBankAccountResponse(t.DestinationBankAccount)
+		response.DestinationBankAccount = &destination
 	}
 
 	return response
 }
 
-// CreateTransaction handles POST /transactions
-func CreateTransaction(c *fiber.Ctx) error {
-	var transaction models.Transaction
+// convertToTransactionResponses converts a list of transactions
+func convertToTransactionResponses(transactions []models.Transaction) []models.TransactionResponse {
+	var response []models.TransactionResponse
+	for _, t := range transactions {
+		response = append(response, convertToTransactionResponse(t))
+	}
+	return response
+}
 
-	if err := c.BodyParser(&transaction); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+// convertToTransferResponse converts a transfer Transaction model to TransferResponse
+func convertToTransferResponse(t models.Transaction) models.TransferResponse {
+	return models.TransferResponse{
+		ID:                     t.ID,
+		TransactionID:          t.TransactionID,
+		Amount:                 t.Amount,
+		BankAccount:            convertToBankAccountResponse(t.BankAccount),
+		DestinationBankAccount: convertToBankAccountResponse(t.DestinationBankAccount),
+		Description:            t.Description,
+		Date:                   t.Date.Time,
+		CreatedAt:              t.CreatedAt,
 	}
+}
 
-	// Validate transaction type
-	if transaction.Type != "expense" && transaction.Type != "income" && transaction.Type != "transfer" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Type must be either 'expense', 'income', or 'transfer'",
-		})
+// accountFilter reads the optional bank_account_id query parameter
+func accountFilter(c *fiber.Ctx) (*uint, error) {
+	if c.Query("bank_account_id") == "" {
+		return nil, nil
 	}
 
-	// Set default date if not provided
-	if transaction.Date.IsZero() {
-		transaction.Date = models.FlexibleDate{Time: time.Now()}
+	id := c.QueryInt("bank_account_id")
+	if id <= 0 {
+		return nil, apperrors.BadRequest(apperrors.CodeInvalidParameter, "bank_account_id must be a positive integer")
 	}
+	accountID := uint(id)
+	return &accountID, nil
+}
 
-	// Validate bank account exists
-	var bankAccount models.BankAccount
-	if err := database.DB.First(&bankAccount, transaction.BankAccountID).Error; err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Bank account not found",
-		})
+// parseDateRange reads the required start_date and end_date query parameters.
+// The end date is moved to the end of its day so the range is inclusive.
+func parseDateRange(c *fiber.Ctx) (time.Time, time.Time, error) {
+	startDateStr := c.Query("start_date")
+	endDateStr := c.Query("end_date")
+
+	if startDateStr == "" || endDateStr == "" {
+		return time.Time{}, time.Time{}, apperrors.BadRequest(apperrors.CodeInvalidDate, "Both start_date and end_date query parameters are required")
 	}
 
-	// Validate based on transaction type
-	if transaction.Type == "transfer" {
-		// For transfers, category is not required but destination account is
-		if transaction.DestinationBankAccountID == nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Destination bank account is required for transfers",
-			})
-		}
+	startDate, err := time.Parse("2006-01-02", startDateStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, apperrors.BadRequest(apperrors.CodeInvalidDate, "Invalid start_date format. Use YYYY-MM-DD")
+	}
 
-		// Validate destination bank account exists
-		var destBankAccount models.BankAccount
-		if err := database.DB.First(&destBankAccount, *transaction.DestinationBankAccountID).Error; err != nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Destination bank account not found",
-			})
-		}
+	endDate, err := time.Parse("2006-01-02", endDateStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, apperrors.BadRequest(apperrors.CodeInvalidDate, "Invalid end_date format. Use YYYY-MM-DD")
+	}
 
-		// Cannot transfer to the same account
-		if transaction.BankAccountID == *transaction.DestinationBankAccountID {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Cannot transfer to the same bank account",
-			})
-		}
+	// Set end date to end of day
+	endDate = endDate.Add(24*time.Hour - time.Second)
 
-		// Set category to nil for transfers
-		transaction.CategoryID = nil
-	} else {
-		// For expense/income, category is required
-		if transaction.CategoryID == nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Category is required for expense and income transactions",
-			})
-		}
+	return startDate, endDate, nil
+}
 
-		// Verify category exists and matches type
-		var category models.Category
-		if err := database.DB.First(&category, *transaction.CategoryID).Error; err != nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Category not found",
-			})
-		}
+// optionalDateRange reads the optional start_date and end_date query parameters.
+// Either bound may be left out; the end date includes its whole day.
+func optionalDateRange(c *fiber.Ctx) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
 
-		if category.Type != transaction.Type {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Category type does not match transaction type",
-			})
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			return nil, nil, apperrors.BadRequest(apperrors.CodeInvalidDate, "Invalid start_date format. Use YYYY-MM-DD")
 		}
-
-		// Destination account should not be set for expense/income
-		transaction.DestinationBankAccountID = nil
+		from = &startDate
 	}
 
-	if err := database.DB.Create(&transaction).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to create transaction",
-		})
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			return nil, nil, apperrors.BadRequest(apperrors.CodeInvalidDate, "Invalid end_date format. Use YYYY-MM-DD")
+		}
+		endDate = endDate.Add(24*time.Hour - time.Second)
+		to = &endDate
 	}
 
-	// Load related data for response
-	database.DB.Preload("Category").Preload("BankAccount").Preload("DestinationBankAccount").First(&transaction, transaction.ID)
-
-	// Convert to response format
-	response := convertToTransactionResponse(transaction)
-
-	return c.Status(201).JSON(response)
+	return from, to, nil
 }
 
-// GetTransactions handles GET /transactions
-func GetTransactions(c *fiber.Ctx) error {
-	var transactions []models.Transaction
-	query := database.DB.Preload("Category").Preload("BankAccount").Preload("DestinationBankAccount")
+// CreateTransaction handles POST /transactions. With anomalies, the new transaction
+// is judged against the earlier ones of its category and payee and
+// transaction.anomaly is published if it is out of character; the response is the same.
+func CreateTransaction(svc services.TransactionService, anomalies services.AnomalyService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		var transaction models.Transaction
+		if err := c.BodyParser(&transaction); err != nil {
+			return apperrors.InvalidBody(err)
+		}
 
-	// Apply type filter if provided
-	if transactionType := c.Query("type"); transactionType != "" {
-		if transactionType != "expense" && transactionType != "income" && transactionType != "transfer" {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Type must be either 'expense', 'income', or 'transfer'",
-			})
+		created, err := svc.Create(transaction)
+		if err != nil {
+			return err
 		}
-		query = query.Where("type = ?", transactionType)
-	}
 
-	// Apply bank account filter if provided
-	if bankAccountID := c.Query("bank_account_id"); bankAccountID != "" {
-		query = query.Where("bank_account_id = ? OR destination_bank_account_id = ?", bankAccountID, bankAccountID)
-	}
+		response := convertToTransactionResponse(created)
+		publish(c, events.TransactionCreated, response)
 
-	if err := query.Order("date DESC").Find(&transactions).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to fetch transactions",
-		})
-	}
+		if anomalies != nil {
+			// The transaction is stored, so a failed check is logged rather than returned
+			anomaly, found, err := anomalies.WithContext(c.UserContext()).Check(created, services.DefaultAnomalySettings())
+			if err != nil {
+				logging.For(logging.ComponentServices).ErrorContext(c.UserContext(), "Failed to check transaction for anomalies", "transaction_id", created.ID, "error", err)
+			} else if found {
+				publish(c, events.TransactionAnomaly, convertToAnomalyResponse(anomaly))
+			}
+		}
 
-	// Convert to response format
-	var response []models.TransactionResponse
-	for _, t := range transactions {
-		response = append(response, convertToTransactionResponse(t))
+		return c.Status(201).JSON(response)
 	}
-
-	return c.JSON(response)
 }
 
-// GetTransactionsAggregate handles GET /transactions/aggregate
-func GetTransactionsAggregate(c *fiber.Ctx) error {
-	var transactions []models.Transaction
-
-	// Exclude transfers from aggregation
-	if err := database.DB.Preload("Category").Where("type != ?", "transfer").Order("date DESC").Find(&transactions).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to fetch transactions",
-		})
-	}
+// GetTransactions handles GET /transactions
+func GetTransactions(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		var filter repository.TransactionFilter
+
+		// Apply type filter if provided
+		if transactionType := c.Query("type"); transactionType != "" {
+			if err := validation.Var("type", transactionType, "transaction_type"); err != nil {
+				return apperrors.Validation(err)
+			}
+			filter.Type = transactionType
+		}
 
-	// Calculate aggregates
-	categories := make(map[string]float64)
-	var totalIncome, totalExpenses float64
+		// Apply bank account filter if provided
+		accountID, err := accountFilter(c)
+		if err != nil {
+			return err
+		}
+		filter.BankAccountID = accountID
 
-	for _, t := range transactions {
-		categoryName := t.Category.Name
-		categories[categoryName] += t.Amount
+		// Apply date filters if provided
+		filter.From, filter.To, err = optionalDateRange(c)
+		if err != nil {
+			return err
+		}
 
-		if t.Type == "income" {
-			totalIncome += t.Amount
-		} else if t.Type == "expense" {
-			totalExpenses += t.Amount
+		transactions, err := svc.List(filter)
+		if err != nil {
+			return err
 		}
-	}
 
-	response := models.AggregateResponse{
-		Categories:    categories,
-		TotalIncome:   totalIncome,
-		TotalExpenses: totalExpenses,
-		NetAmount:     totalIncome - totalExpenses,
+		return c.JSON(convertToTransactionResponses(transactions))
 	}
-
-	return c.JSON(response)
 }
 
-// GetTransaction handles GET /transactions/:id
-func GetTransaction(c *fiber.Ctx) error {
-	id := c.Params("id")
+// categoryRollup picks the category a transaction is reported under by the aggregate endpoints
+type categoryRollup struct {
+	tree *services.CategoryTree
+	// depth, when set, adds the totals of deeper subcategories to their ancestor at that depth
+	depth *int
+}
 
-	var transaction models.Transaction
-	if err := database.DB.Preload("Category").Preload("BankAccount").Preload("DestinationBankAccount").First(&transaction, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Transaction not found",
-		})
+// newCategoryRollup reads the optional depth query parameter, where top level categories are at depth 0
+func newCategoryRollup(c *fiber.Ctx, categories services.CategoryService) (categoryRollup, error) {
+	var rollup categoryRollup
+	if value := c.Query("depth"); value != "" {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return rollup, apperrors.BadRequest(apperrors.CodeInvalidParameter, "depth must be a non-negative integer")
+		}
+		rollup.depth = &depth
 	}
 
-	response := convertToTransactionResponse(transaction)
-	return c.JSON(response)
+	tree, err := categories.Tree()
+	if err != nil {
+		return rollup, err
+	}
+	rollup.tree = tree
+	return rollup, nil
 }
 
-// UpdateTransaction handles PUT /transactions/:id
-func UpdateTransaction(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	var transaction models.Transaction
-	if err := database.DB.First(&transaction, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Transaction not found",
-		})
+// category returns the category the transaction's totals count towards and its path, e.g. "Bills / Electricity"
+func (r categoryRollup) category(t models.Transaction) (models.Category, string) {
+	if t.CategoryID == nil {
+		return t.Category, t.Category.Name
 	}
-
-	var updateData map[string]interface{}
-	if err := c.BodyParser(&updateData); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	category, ok := r.tree.Get(*t.CategoryID)
+	if r.depth != nil {
+		category, ok = r.tree.AtDepth(*t.CategoryID, *r.depth)
+	}
+	if !ok {
+		// e.g. a deleted category, reported on its own
+		category = t.Category
+		category.ID = *t.CategoryID
+		return category, category.Name
 	}
+	return category, r.tree.Path(category.ID)
+}
 
-	// Validate transaction type if provided
-	if transactionType, exists := updateData["type"]; exists {
-		if transactionType != "expense" && transactionType != "income" {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Type must be either 'expense' or 'income'",
-			})
+// GetTransactionsAggregate handles GET /transactions/aggregate. Subcategories
+// are keyed by their path; ?depth=n rolls them up into their ancestor at depth n.
+func GetTransactionsAggregate(svc services.TransactionService, categorySvc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		rollup, err := newCategoryRollup(c, categorySvc.WithContext(c.UserContext()))
+		if err != nil {
+			return err
 		}
-	}
 
-	// Verify category exists and matches type if category_id is being updated
-	if categoryID, exists := updateData["category_id"]; exists {
-		var category models.Category
-		if err := database.DB.First(&category, categoryID).Error; err != nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Category not found",
-			})
+		// Exclude transfers from aggregation
+		transactions, err := svc.List(repository.TransactionFilter{ExcludeType: "transfer"})
+		if err != nil {
+			return err
 		}
 
-		// Check if type is being updated and matches category type
-		transactionType := transaction.Type
-		if t, exists := updateData["type"]; exists {
-			transactionType = t.(string)
+		// Calculate aggregates
+		categories := make(map[string]float64)
+		var totalIncome, totalExpenses float64
+
+		for _, t := range transactions {
+			_, categoryPath := rollup.category(t)
+			categories[categoryPath] += t.Amount
+
+			if t.Type == "income" {
+				totalIncome += t.Amount
+			} else if t.Type == "expense" {
+				totalExpenses += t.Amount
+			}
 		}
 
-		if category.Type != transactionType {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Category type does not match transaction type",
-			})
+		response := models.AggregateResponse{
+			Categories:    categories,
+			TotalIncome:   totalIncome,
+			TotalExpenses: totalExpenses,
+			NetAmount:     totalIncome - totalExpenses,
 		}
-	}
 
-	if err := database.DB.Model(&transaction).Updates(updateData).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to update transaction",
-		})
+		return c.JSON(response)
 	}
-
-	// Load updated transaction with category
-	database.DB.Preload("Category").First(&transaction, transaction.ID)
-
-	return c.JSON(transaction)
 }
 
-// DeleteTransaction handles DELETE /transactions/:id
-func DeleteTransaction(c *fiber.Ctx) error {
-	id := c.Params("id")
+// GetTransaction handles GET /transactions/:id
+func GetTransaction(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
 
-	var transaction models.Transaction
-	if err := database.DB.First(&transaction, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Transaction not found",
-		})
-	}
+		transaction, err := svc.Get(id)
+		if err != nil {
+			return err
+		}
 
-	if err := database.DB.Delete(&transaction).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to delete transaction",
-		})
+		return c.JSON(convertToTransactionResponse(transaction))
 	}
-
-	return c.Status(200).JSON(fiber.Map{
-		"message": "Transaction deleted successfully",
-	})
 }
 
-// GetTransactionsByDateRange handles GET /transactions/date-range
-func GetTransactionsByDateRange(c *fiber.Ctx) error {
-	startDateStr := c.Query("start_date")
-	endDateStr := c.Query("end_date")
+// UpdateTransaction handles PUT /transactions/:id
+func UpdateTransaction(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
 
-	if startDateStr == "" || endDateStr == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Both start_date and end_date query parameters are required",
-		})
-	}
+		var updateData map[string]interface{}
+		if err := c.BodyParser(&updateData); err != nil {
+			return apperrors.InvalidBody(err)
+		}
 
-	startDate, err := time.Parse("2006-01-02", startDateStr)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid start_date format. Use YYYY-MM-DD",
-		})
-	}
+		transaction, err := svc.Update(id, updateData)
+		if err != nil {
+			return err
+		}
 
-	endDate, err := time.Parse("2006-01-02", endDateStr)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid end_date format. Use YYYY-MM-DD",
-		})
-	}
+		publish(c, events.TransactionUpdated, transaction)
 
-	// Set end date to end of day
-	endDate = endDate.Add(24*time.Hour - time.Second)
+		return c.JSON(transaction)
+	}
+}
 
-	var transactions []models.Transaction
-	query := database.DB.Preload("Category").Where("date BETWEEN ? AND ?", startDate, endDate)
+// DeleteTransaction handles DELETE /transactions/:id
+func DeleteTransaction(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
 
-	// Apply type filter if provided
-	if transactionType := c.Query("type"); transactionType != "" {
-		if transactionType != "expense" && transactionType != "income" {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Type must be either 'expense' or 'income'",
-			})
+		if err := svc.Delete(id); err != nil {
+			return err
 		}
-		query = query.Where("type = ?", transactionType)
-	}
 
-	if err := query.Order("date DESC").Find(&transactions).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to fetch transactions",
-		})
-	}
+		publish(c, events.TransactionDeleted, fiber.Map{"id": id})
 
-	// Convert to response format
-	var response []models.TransactionResponse
-	for _, t := range transactions {
-		response = append(response, models.TransactionResponse{
-			ID:            t.ID,
-			TransactionID: t.TransactionID,
-			Amount:        t.Amount,
-			Type:          t.Type,
-			CategoryID:    t.CategoryID,
-			Category:      t.Category.Name,
-			Description:   t.Description,
-			Date:          t.Date.Time,
-			CreatedAt:     t.CreatedAt,
+		return c.Status(200).JSON(fiber.Map{
+			"message": "Transaction deleted successfully",
 		})
 	}
-
-	return c.JSON(response)
 }
 
-// CreateBulkTransactions handles POST /transactions/bulk
-func CreateBulkTransactions(c *fiber.Ctx) error {
-	var request models.BulkTransactionRequest
+// GetTransactionsByDateRange handles GET /transactions/date-range
+func GetTransactionsByDateRange(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		startDate, endDate, err := parseDateRange(c)
+		if err != nil {
+			return err
+		}
 
-	if err := c.BodyParser(&request); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
+		filter := repository.TransactionFilter{From: &startDate, To: &endDate}
 
-	// Validate request
-	if len(request.Transactions) == 0 {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "At least one transaction is required",
-		})
-	}
+		// Apply type filter if provided
+		if transactionType := c.Query("type"); transactionType != "" {
+			if err := validation.Var("type", transactionType, "category_type"); err != nil {
+				return apperrors.Validation(err)
+			}
+			filter.Type = transactionType
+		}
 
-	if len(request.Transactions) > 5000 {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Maximum 5000 transactions allowed per bulk request",
-		})
-	}
+		transactions, err := svc.List(filter)
+		if err != nil {
+			return err
+		}
 
-	var response models.BulkTransactionResponse
-	response.TotalCount = len(request.Transactions)
-
-	// Process each transaction
-	for i, transaction := range request.Transactions {
-		// Set default date if not provided
-		if transaction.Date.IsZero() {
-			transaction.Date = models.FlexibleDate{Time: time.Now()}
-		}
-
-		// Validate transaction type
-		if transaction.Type != "expense" && transaction.Type != "income" {
-			response.Failed = append(response.Failed, models.BulkTransactionError{
-				Index:       i,
-				Transaction: transaction,
-				Error:       "Type must be either 'expense' or 'income'",
-			})
-			continue
-		}
-
-		// Verify category exists and matches type
-		var category models.Category
-		if err := database.DB.First(&category, transaction.CategoryID).Error; err != nil {
-			response.Failed = append(response.Failed, models.BulkTransactionError{
-				Index:       i,
-				Transaction: transaction,
-				Error:       "Category not found",
-			})
-			continue
-		}
-
-		if category.Type != transaction.Type {
-			response.Failed = append(response.Failed, models.BulkTransactionError{
-				Index:       i,
-				Transaction: transaction,
-				Error:       "Category type does not match transaction type",
-			})
-			continue
-		}
-
-		// Create transaction
-		if err := database.DB.Create(&transaction).Error; err != nil {
-			response.Failed = append(response.Failed, models.BulkTransactionError{
-				Index:       i,
-				Transaction: transaction,
-				Error:       "Failed to create transaction: " + err.Error(),
-			})
-			continue
-		}
-
-		// Load category for response
-		database.DB.Preload("Category").First(&transaction, transaction.ID)
-
-		// Add to success list
-		response.Success = append(response.Success, models.TransactionResponse{
-			ID:            transaction.ID,
-			TransactionID: transaction.TransactionID,
-			Amount:        transaction.Amount,
-			Type:          transaction.Type,
-			CategoryID:    transaction.CategoryID,
-			Category:      transaction.Category.Name,
-			Description:   transaction.Description,
-			Date:          transaction.Date.Time,
-			CreatedAt:     transaction.CreatedAt,
-		})
+		return c.JSON(convertToTransactionResponses(transactions))
 	}
+}
 
-	response.SuccessCount = len(response.Success)
-	response.FailedCount = len(response.Failed)
+// Request body size limits. Bulk bodies may hold MaxBulkTransactions rows of up to MaxBulkRowSize bytes each.
+const (
+	MaxBodySize     = 1 << 20
+	MaxBulkRowSize  = 1 << 10
+	MaxBulkBodySize = models.MaxBulkTransactions * MaxBulkRowSize
+)
 
-	// Return appropriate status code
-	statusCode := 201
-	if response.FailedCount > 0 {
-		if response.SuccessCount == 0 {
-			statusCode = 400 // All failed
-		} else {
-			statusCode = 207 // Partial success (Multi-Status)
+// CreateBulkTransactions handles POST /transactions/bulk
+func CreateBulkTransactions(svc services.TransactionService, queue *jobs.Runner) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if len(c.Body()) > MaxBulkBodySize {
+			return apperrors.BodyTooLarge(MaxBulkBodySize)
 		}
-	}
 
-	return c.Status(statusCode).JSON(response)
-}
+		svc := svc.WithContext(c.UserContext())
+		var request models.BulkTransactionRequest
+		_, parse := tracing.Tracer().Start(c.UserContext(), "bulk.parse")
+		err := c.BodyParser(&request)
+		parse.End()
+		if err != nil {
+			return apperrors.InvalidBody(err)
+		}
 
-// UpdateTransactionCategory handles PATCH /transactions/:id/category
-func UpdateTransactionCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
+		// With atomic=true either every transaction is created or none is
+		atomic := c.Query("atomic") == "true"
 
-	var transaction models.Transaction
-	if err := database.DB.First(&transaction, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Transaction not found",
-		})
-	}
+		// With async=true the rows are created by a background job
+		if c.Query("async") == "true" {
+			return enqueueBulkJob(c, queue, request, atomic)
+		}
 
-	var request struct {
-		CategoryID uint `json:"category_id" validate:"required"`
-	}
+		result, err := svc.CreateBulk(request, atomic)
+		if err != nil {
+			return err
+		}
 
-	if err := c.BodyParser(&request); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
+		response := models.BulkTransactionResponse{
+			Success:    convertToTransactionResponses(result.Created),
+			Failed:     result.Failed,
+			TotalCount: len(request.Transactions),
+		}
 
-	if request.CategoryID == 0 {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "category_id is required",
-		})
-	}
+		for _, created := range response.Success {
+			publish(c, events.TransactionCreated, created)
+		}
+		metrics.RecordBulkFailures("request", response.Failed)
 
-	// Verify new category exists and matches transaction type
-	var newCategory models.Category
-	if err := database.DB.First(&newCategory, request.CategoryID).Error; err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Category not found",
-		})
-	}
+		response.SuccessCount = len(response.Success)
+		response.FailedCount = len(response.Failed)
 
-	if newCategory.Type != transaction.Type {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Category type does not match transaction type",
-		})
-	}
+		// Return appropriate status code
+		statusCode := 201
+		if response.FailedCount > 0 {
+			if response.SuccessCount == 0 {
+				statusCode = 400 // All failed
+			} else {
+				statusCode = 207 // Partial success (Multi-Status)
+			}
+		}
 
-	// Update the category
-	if err := database.DB.Model(&transaction).Update("category_id", request.CategoryID).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to update transaction category",
-		})
+		return c.Status(statusCode).JSON(response)
 	}
-
-	// Load updated transaction with new category
-	database.DB.Preload("Category").First(&transaction, transaction.ID)
-
-	response := models.TransactionResponse{
-		ID:            transaction.ID,
-		TransactionID: transaction.TransactionID,
-		Amount:        transaction.Amount,
-		Type:          transaction.Type,
-		CategoryID:    transaction.CategoryID,
-		Category:      transaction.Category.Name,
-		Description:   transaction.Description,
-		Date:          transaction.Date.Time,
-		CreatedAt:     transaction.CreatedAt,
-	}
-
-	return c.JSON(response)
 }
 
-// GetSummary handles GET /transactions/summary
-func GetSummary(c *fiber.Ctx) error {
-	// Get total counts
-	var totalTransactions int64
-	var totalExpenses int64
-	var totalIncome int64
-
-	database.DB.Model(&models.Transaction{}).Count(&totalTransactions)
-	database.DB.Model(&models.Transaction{}).Where("type = ?", "expense").Count(&totalExpenses)
-	database.DB.Model(&models.Transaction{}).Where("type = ?", "income").Count(&totalIncome)
-
-	// Get total amounts
-	var expenseSum float64
-	var incomeSum float64
-
-	database.DB.Model(&models.Transaction{}).Where("type = ?", "expense").Select("COALESCE(SUM(amount), 0)").Scan(&expenseSum)
-	database.DB.Model(&models.Transaction{}).Where("type = ?", "income").Select("COALESCE(SUM(amount), 0)").Scan(&incomeSum)
-
-	// Get recent transactions (last 5)
-	var recentTransactions []models.Transaction
-	database.DB.Preload("Category").Order("created_at DESC").Limit(5).Find(&recentTransactions)
-
-	// Convert to response format
-	var recentResponse []models.TransactionResponse
-	for _, t := range recentTransactions {
-		recentResponse = append(recentResponse, models.TransactionResponse{
-			ID:            t.ID,
-			TransactionID: t.TransactionID,
-			Amount:        t.Amount,
-			Type:          t.Type,
-			CategoryID:    t.CategoryID,
-			Category:      t.Category.Name,
-			Description:   t.Description,
-			Date:          t.Date.Time,
-			CreatedAt:     t.CreatedAt,
-		})
-	}
+// UpdateTransactionCategory handles PATCH /transactions/:id/category
+func UpdateTransactionCategory(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
 
-	summary := fiber.Map{
-		"overview": fiber.Map{
-			"total_transactions": totalTransactions,
-			"total_expenses":     totalExpenses,
-			"total_income":       totalIncome,
-		},
-		"totals": fiber.Map{
-			"total_expense_amount": expenseSum,
-			"total_income_amount":  incomeSum,
-			"net_amount":           incomeSum - expenseSum,
-		},
-		"recent_transactions": recentResponse,
-	}
+		var request struct {
+			CategoryID uint `json:"category_id"`
+		}
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
+		}
 
-	return c.JSON(summary)
-}
+		transaction, err := svc.UpdateCategory(id, request.CategoryID)
+		if err != nil {
+			return err
+		}
 
-// DeleteBulkTransactions handles DELETE /transactions/bulk
-func DeleteBulkTransactions(c *fiber.Ctx) error {
-	var request models.BulkDeleteRequest
+		response := convertToTransactionResponse(transaction)
+		publish(c, events.TransactionUpdated, response)
 
-	if err := c.BodyParser(&request); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return c.JSON(response)
 	}
+}
 
-	// Validate request
-	if len(request.TransactionIDs) == 0 {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "At least one transaction ID is required",
-		})
-	}
+// GetSummary handles GET /transactions/summary
+func GetSummary(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		// Get counts, totals and the last 5 transactions
+		summary, err := svc.Summary(5)
+		if err != nil {
+			return err
+		}
 
-	if len(request.TransactionIDs) > 1000 {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Maximum 1000 transaction IDs allowed per bulk delete request",
+		return c.JSON(fiber.Map{
+			"overview": fiber.Map{
+				"total_transactions": summary.All.Count,
+				"total_expenses":     summary.Expenses.Count,
+				"total_income":       summary.Income.Count,
+			},
+			"totals": fiber.Map{
+				"total_expense_amount": summary.Expenses.Amount,
+				"total_income_amount":  summary.Income.Amount,
+				"net_amount":           summary.Income.Amount - summary.Expenses.Amount,
+			},
+			"recent_transactions": convertToTransactionResponses(summary.Recent),
 		})
 	}
+}
 
-	var response models.BulkDeleteResponse
-	response.TotalCount = len(request.TransactionIDs)
-
-	// Process each transaction ID
-	for _, transactionID := range request.TransactionIDs {
-		// Check if transaction exists
-		var transaction models.Transaction
-		if err := database.DB.First(&transaction, transactionID).Error; err != nil {
-			response.Failed = append(response.Failed, models.BulkDeleteError{
-				TransactionID: transactionID,
-				Error:         "Transaction not found",
-			})
-			continue
+// DeleteBulkTransactions handles DELETE /transactions/bulk
+func DeleteBulkTransactions(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		var request models.BulkDeleteRequest
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
 		}
 
-		// Delete the transaction
-		if err := database.DB.Delete(&transaction).Error; err != nil {
-			response.Failed = append(response.Failed, models.BulkDeleteError{
-				TransactionID: transactionID,
-				Error:         "Failed to delete transaction: " + err.Error(),
-			})
-			continue
+		// With atomic=true either every transaction is deleted or none is
+		result, err := svc.DeleteBulk(request, c.Query("atomic") == "true")
+		if err != nil {
+			return err
 		}
 
-		// Add to success list
-		response.Deleted = append(response.Deleted, transactionID)
-	}
-
-	response.DeletedCount = len(response.Deleted)
-	response.FailedCount = len(response.Failed)
-
-	// Return appropriate status code
-	statusCode := 200
-	if response.FailedCount > 0 {
-		if response.DeletedCount == 0 {
-			statusCode = 400 // All failed
-		} else {
-			statusCode = 207 // Partial success (Multi-Status)
+		response := models.BulkDeleteResponse{
+			Deleted:    result.Deleted,
+			Failed:     result.Failed,
+			TotalCount: len(request.TransactionIDs),
 		}
-	}
-
-	return c.Status(statusCode).JSON(response)
-}
-// GetTransactionsAggregateTable handles GET /transactions/aggregate-table
-func GetTransactionsAggregateTable(c *fiber.Ctx) error {
-	startDateStr := c.Query("start_date")
-	endDateStr := c.Query("end_date")
-
-	if startDateStr == "" || endDateStr == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Both start_date and end_date query parameters are required",
-		})
-	}
 
-	startDate, err := time.Parse("2006-01-02", startDateStr)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid start_date format. Use YYYY-MM-DD",
-		})
-	}
+		for _, deletedID := range response.Deleted {
+			publish(c, events.TransactionDeleted, fiber.Map{"id": deletedID})
+		}
 
-	endDate, err := time.Parse("2006-01-02", endDateStr)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid end_date format. Use YYYY-MM-DD",
-		})
-	}
+		response.DeletedCount = len(response.Deleted)
+		response.FailedCount = len(response.Failed)
 
-	// Set end date to end of day
-	endDate = endDate.Add(24*time.Hour - time.Second)
+		// Return appropriate status code
+		statusCode := 200
+		if response.FailedCount > 0 {
+			if response.DeletedCount == 0 {
+				statusCode = 400 // All failed
+			} else {
+				statusCode = 207 // Partial success (Multi-Status)
+			}
+		}
 
-	// Query transactions within date range
-	var transactions []models.Transaction
-	if err := database.DB.Preload("Category").Where("date BETWEEN ? AND ?", startDate, endDate).Find(&transactions).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to fetch transactions",
-		})
+		return c.Status(statusCode).JSON(response)
 	}
+}
 
-	// Initialize response
-	response := models.AggregateTableResponse{
-		DateRange: models.DateRange{
-			StartDate: startDateStr,
-			EndDate:   endDateStr,
-		},
-	}
+// GetTransactionsAggregateTable handles GET /transactions/aggregate-table;
+// ?depth=n rolls subcategories up into their ancestor at depth n
+func GetTransactionsAggregateTable(svc services.TransactionService, categorySvc services.CategoryService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		startDate, endDate, err := parseDateRange(c)
+		if err != nil {
+			return err
+		}
 
-	// Maps to aggregate data by category
-	incomeCategories := make(map[uint]*models.CategoryAggregate)
-	expenseCategories := make(map[uint]*models.CategoryAggregate)
+		rollup, err := newCategoryRollup(c, categorySvc.WithContext(c.UserContext()))
+		if err != nil {
+			return err
+		}
 
-	var totalIncome, totalExpenses float64
-	var incomeTransactionCount, expenseTransactionCount int
+		// Query transactions within date range
+		transactions, err := svc.List(repository.TransactionFilter{From: &startDate, To: &endDate})
+		if err != nil {
+			return err
+		}
 
-	// Process each transaction
-	for _, t := range transactions {
-		// Skip transfers as they don't have categories
-		if t.Type == "transfer" || t.CategoryID == nil {
-			continue
+		// Initialize response
+		response := models.AggregateTableResponse{
+			DateRange: models.DateRange{
+				StartDate: c.Query("start_date"),
+				EndDate:   c.Query("end_date"),
+			},
 		}
 
-		categoryID := *t.CategoryID
-		
-		if t.Type == "income" {
-			totalIncome += t.Amount
-			incomeTransactionCount++
+		// Maps to aggregate data by category
+		incomeCategories := make(map[uint]*models.CategoryAggregate)
+		expenseCategories := make(map[uint]*models.CategoryAggregate)
 
-			if agg, exists := incomeCategories[categoryID]; exists {
-				agg.TotalAmount += t.Amount
-				agg.TransactionCount++
-			} else {
-				incomeCategories[categoryID] = &models.CategoryAggregate{
-					CategoryID:       categoryID,
-					CategoryName:     t.Category.Name,
-					TotalAmount:      t.Amount,
-					TransactionCount: 1,
-				}
+		var totalIncome, totalExpenses float64
+		var incomeTransactionCount, expenseTransactionCount int
+
+		// Process each transaction
+		for _, t := range transactions {
+			// Skip transfers as they don't have categories
+			if t.Type == "transfer" || t.CategoryID == nil {
+				continue
 			}
-		} else if t.Type == "expense" {
-			totalExpenses += t.Amount
-			expenseTransactionCount++
 
-			if agg, exists := expenseCategories[categoryID]; exists {
-				agg.TotalAmount += t.Amount
-				agg.TransactionCount++
-			} else {
-				expenseCategories[categoryID] = &models.CategoryAggregate{
-					CategoryID:       categoryID,
-					CategoryName:     t.Category.Name,
-					TotalAmount:      t.Amount,
-					TransactionCount: 1,
+			category, categoryPath := rollup.category(t)
+			categoryID := category.ID
+
+			if t.Type == "income" {
+				totalIncome += t.Amount
+				incomeTransactionCount++
+
+				if agg, exists := incomeCategories[categoryID]; exists {
+					agg.TotalAmount += t.Amount
+					agg.TransactionCount++
+				} else {
+					incomeCategories[categoryID] = &models.CategoryAggregate{
+						CategoryID:       categoryID,
+						CategoryName:     category.Name,
+						CategoryPath:     categoryPath,
+						ParentID:         category.ParentID,
+						TotalAmount:      t.Amount,
+						TransactionCount: 1,
+					}
+				}
+			} else if t.Type == "expense" {
+				totalExpenses += t.Amount
+				expenseTransactionCount++
+
+				if agg, exists := expenseCategories[categoryID]; exists {
+					agg.TotalAmount += t.Amount
+					agg.TransactionCount++
+				} else {
+					expenseCategories[categoryID] = &models.CategoryAggregate{
+						CategoryID:       categoryID,
+						CategoryName:     category.Name,
+						CategoryPath:     categoryPath,
+						ParentID:         category.ParentID,
+						TotalAmount:      t.Amount,
+						TransactionCount: 1,
+					}
 				}
 			}
 		}
-	}
 
-	// Convert maps to slices for JSON response
-	for _, agg := range incomeCategories {
-		response.Income.Categories = append(response.Income.Categories, *agg)
-	}
-	for _, agg := range expenseCategories {
-		response.Expenses.Categories = append(response.Expenses.Categories, *agg)
-	}
+		// Convert maps to slices for JSON response
+		for _, agg := range incomeCategories {
+			response.Income.Categories = append(response.Income.Categories, *agg)
+		}
+		for _, agg := range expenseCategories {
+			response.Expenses.Categories = append(response.Expenses.Categories, *agg)
+		}
 
-	// Set totals
-	response.Income.TotalAmount = totalIncome
-	response.Income.TotalTransactions = incomeTransactionCount
-	response.Expenses.TotalAmount = totalExpenses
-	response.Expenses.TotalTransactions = expenseTransactionCount
+		// Set totals
+		response.Income.TotalAmount = totalIncome
+		response.Income.TotalTransactions = incomeTransactionCount
+		response.Expenses.TotalAmount = totalExpenses
+		response.Expenses.TotalTransactions = expenseTransactionCount
 
-	// Set summary
-	response.Summary.TotalIncome = totalIncome
-	response.Summary.TotalExpenses = totalExpenses
-	response.Summary.NetAmount = totalIncome - totalExpenses
+		// Set summary
+		response.Summary.TotalIncome = totalIncome
+		response.Summary.TotalExpenses = totalExpenses
+		response.Summary.NetAmount = totalIncome - totalExpenses
 
-	return c.JSON(response)
+		return c.JSON(response)
+	}
 }
 
 // CreateTransfer handles POST /transactions/transfer
-func CreateTransfer(c *fiber.Ctx) error {
-	var transferRequest models.TransferRequest
-
-	if err := c.BodyParser(&transferRequest); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
-
-	// Validate amount
-	if transferRequest.Amount <= 0 {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Amount must be greater than 0",
-		})
-	}
-
-	// Validate source and destination accounts are different
-	if transferRequest.BankAccountID == transferRequest.DestinationBankAccountID {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Cannot transfer to the same bank account",
-		})
-	}
-
-	// Validate source bank account exists
-	var sourceBankAccount models.BankAccount
-	if err := database.DB.First(&sourceBankAccount, transferRequest.BankAccountID).Error; err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Source bank account not found",
-		})
-	}
+func CreateTransfer(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		var transferRequest models.TransferRequest
+		if err := c.BodyParser(&transferRequest); err != nil {
+			return apperrors.InvalidBody(err)
+		}
 
-	// Validate destination bank account exists
-	var destBankAccount models.BankAccount
-	if err := database.DB.First(&destBankAccount, transferRequest.DestinationBankAccountID).Error; err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Destination bank account not found",
-		})
-	}
+		transaction, err := svc.CreateTransfer(transferRequest)
+		if err != nil {
+			return err
+		}
 
-	// Set default date if not provided
-	if transferRequest.Date.IsZero() {
-		transferRequest.Date = models.FlexibleDate{Time: time.Now()}
-	}
+		response := convertToTransferResponse(transaction)
+		publish(c, events.TransferCreated, response)
 
-	// Create transfer transaction
-	transaction := models.Transaction{
-		TransactionID:           transferRequest.TransactionID,
-		Amount:                  transferRequest.Amount,
-		Type:                    "transfer",
-		CategoryID:              nil, // Transfers don't have categories
-		BankAccountID:           transferRequest.BankAccountID,
-		DestinationBankAccountID: &transferRequest.DestinationBankAccountID,
-		Description:             transferRequest.Description,
-		Date:                    transferRequest.Date,
+		return c.Status(201).JSON(response)
 	}
-
-	if err := database.DB.Create(&transaction).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to create transfer",
-		})
-	}
-
-	// Load related data for response
-	database.DB.Preload("BankAccount").Preload("DestinationBankAccount").First(&transaction, transaction.ID)
-
-	// Convert to transfer response format
-	response := models.TransferResponse{
-		ID:            transaction.ID,
-		TransactionID: transaction.TransactionID,
-		Amount:        transaction.Amount,
-		BankAccount: models.BankAccountResponse{
-			ID:            transaction.BankAccount.ID,
-			Name:          transaction.BankAccount.Name,
-			AccountNumber: transaction.BankAccount.AccountNumber,
-			BankName:      transaction.BankAccount.BankName,
-			AccountType:   transaction.BankAccount.AccountType,
-			Balance:       transaction.BankAccount.Balance,
-			IsActive:      transaction.BankAccount.IsActive,
-		},
-		DestinationBankAccount: models.BankAccountResponse{
-			ID:            transaction.DestinationBankAccount.ID,
-			Name:          transaction.DestinationBankAccount.Name,
-			AccountNumber: transaction.DestinationBankAccount.AccountNumber,
-			BankName:      transaction.DestinationBankAccount.BankName,
-			AccountType:   transaction.DestinationBankAccount.AccountType,
-			Balance:       transaction.DestinationBankAccount.Balance,
-			IsActive:      transaction.DestinationBankAccount.IsActive,
-		},
-		Description: transaction.Description,
-		Date:        transaction.Date.Time,
-		CreatedAt:   transaction.CreatedAt,
-	}
-
-	return c.Status(201).JSON(response)
 }
 
 // GetTransfers handles GET /transactions/transfers
-func GetTransfers(c *fiber.Ctx) error {
-	var transactions []models.Transaction
-	query := database.DB.Preload("BankAccount").Preload("DestinationBankAccount").Where("type = ?", "transfer")
+func GetTransfers(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		filter := repository.TransactionFilter{Type: "transfer"}
+
+		// Apply bank account filter if provided
+		accountID, err := accountFilter(c)
+		if err != nil {
+			return err
+		}
+		filter.BankAccountID = accountID
 
-	// Apply bank account filter if provided
-	if bankAccountID := c.Query("bank_account_id"); bankAccountID != "" {
-		query = query.Where("bank_account_id = ? OR destination_bank_account_id = ?", bankAccountID, bankAccountID)
-	}
+		// Apply date filters if provided
+		filter.From, filter.To, err = optionalDateRange(c)
+		if err != nil {
+			return err
+		}
 
-	if err := query.Order("date DESC").Find(&transactions).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to fetch transfers",
-		})
-	}
+		transactions, err := svc.List(filter)
+		if err != nil {
+			return err
+		}
 
-	// Convert to transfer response format
-	var response []models.TransferResponse
-	for _, t := range transactions {
-		response = append(response, models.TransferResponse{
-			ID:            t.ID,
-			TransactionID: t.TransactionID,
-			Amount:        t.Amount,
-			BankAccount: models.BankAccountResponse{
-				ID:            t.BankAccount.ID,
-				Name:          t.BankAccount.Name,
-				AccountNumber: t.BankAccount.AccountNumber,
-				BankName:      t.BankAccount.BankName,
-				AccountType:   t.BankAccount.AccountType,
-				Balance:       t.BankAccount.Balance,
-				IsActive:      t.BankAccount.IsActive,
-			},
-			DestinationBankAccount: models.BankAccountResponse{
-				ID:            t.DestinationBankAccount.ID,
-				Name:          t.DestinationBankAccount.Name,
-				AccountNumber: t.DestinationBankAccount.AccountNumber,
-				BankName:      t.DestinationBankAccount.BankName,
-				AccountType:   t.DestinationBankAccount.AccountType,
-				Balance:       t.DestinationBankAccount.Balance,
-				IsActive:      t.DestinationBankAccount.IsActive,
-			},
-			Description: t.Description,
-			Date:        t.Date.Time,
-			CreatedAt:   t.CreatedAt,
-		})
-	}
+		// Convert to transfer response format
+		var response []models.TransferResponse
+		for _, t := range transactions {
+			response = append(response, convertToTransferResponse(t))
+		}
 
-	return c.JSON(response)
+		return c.JSON(response)
+	}
 }
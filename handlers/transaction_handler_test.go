@@ -7,10 +7,14 @@ import (
 	"testing"
 	"time"
 
-	"expense-api/database"
+	"expense-api/apperrors"
+	"expense-api/container"
+	"expense-api/database/migrations"
 	"expense-api/models"
+	"expense-api/repository"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -20,46 +24,57 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
 
-	// Migrate tables
-	err = db.AutoMigrate(&models.Category{}, &models.BankAccount{}, &models.Transaction{})
+	// Create tables with the same migrations the server runs
+	migrator, err := migrations.New(db)
 	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	seedTestData(t, repository.NewGormCategories(func() *gorm.DB { return db }), repository.NewGormAccounts(func() *gorm.DB { return db }))
+	return db
+}
 
-	// Seed test categories
+// setupTestServices creates services over an in-memory store holding the test data
+func setupTestServices(t *testing.T) *container.Container {
+	store := repository.NewMemoryStore()
+	seedTestData(t, store.Categories(), store.Accounts())
+	return container.NewInMemory(store)
+}
+
+// seedTestData adds the categories and bank accounts the handler tests refer to by ID
+func seedTestData(t *testing.T, categories repository.CategoryRepository, accounts repository.AccountRepository) {
 	testCategories := []models.Category{
 		{Name: "Food", Type: "expense"},
 		{Name: "Salary", Type: "income"},
 	}
 
 	for _, category := range testCategories {
-		err = db.Create(&category).Error
-		assert.NoError(t, err)
+		assert.NoError(t, categories.Create(&category))
 	}
 
-	// Seed test bank accounts
 	testBankAccounts := []models.BankAccount{
 		{Name: "Test Checking", BankName: "Test Bank", AccountType: "checking", Balance: 1000.0, IsActive: true},
 		{Name: "Test Savings", BankName: "Test Bank", AccountType: "savings", Balance: 5000.0, IsActive: true},
 	}
 
 	for _, account := range testBankAccounts {
-		err = db.Create(&account).Error
-		assert.NoError(t, err)
+		assert.NoError(t, accounts.Create(&account))
 	}
+}
 
-	// Set the test database
-	database.DB = db
-	return db
+// newTestApp creates an app that renders errors the same way as the server
+func newTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: apperrors.Handler})
+	app.Use(requestid.New())
+	return app
 }
 
 func TestCreateTransaction(t *testing.T) {
-	db := setupTestDB(t)
-	defer func() {
-		sqlDB, _ := db.DB()
-		sqlDB.Close()
-	}()
+	t.Parallel()
+	deps := setupTestServices(t)
 
-	app := fiber.New()
-	app.Post("/transactions", CreateTransaction)
+	app := newTestApp()
+	app.Post("/transactions", CreateTransaction(deps.Transactions, nil))
 
 	tests := []struct {
 		name           string
@@ -106,6 +121,19 @@ func TestCreateTransaction(t *testing.T) {
 			expectedStatus: 400,
 			checkResponse:  false,
 		},
+		{
+			name: "Non-positive amount",
+			payload: map[string]interface{}{
+				"amount":          0,
+				"type":            "expense",
+				"category_id":     1,
+				"bank_account_id": 1,
+				"description":     "Test",
+				"date":            time.Now().Format(time.RFC3339),
+			},
+			expectedStatus: 400,
+			checkResponse:  false,
+		},
 		{
 			name: "Invalid category ID",
 			payload: map[string]interface{}{
@@ -145,11 +173,8 @@ func TestCreateTransaction(t *testing.T) {
 }
 
 func TestGetTransactions(t *testing.T) {
-	db := setupTestDB(t)
-	defer func() {
-		sqlDB, _ := db.DB()
-		sqlDB.Close()
-	}()
+	t.Parallel()
+	deps := setupTestServices(t)
 
 	// Create test transactions
 	// Helper function to create uint pointers
@@ -175,12 +200,12 @@ func TestGetTransactions(t *testing.T) {
 	}
 
 	for _, transaction := range testTransactions {
-		err := db.Create(&transaction).Error
+		_, err := deps.Transactions.Create(transaction)
 		assert.NoError(t, err)
 	}
 
-	app := fiber.New()
-	app.Get("/transactions", GetTransactions)
+	app := newTestApp()
+	app.Get("/transactions", GetTransactions(deps.Transactions))
 
 	tests := []struct {
 		name           string
@@ -221,4 +246,206 @@ func TestGetTransactions(t *testing.T) {
 			assert.Len(t, response, tt.expectedCount)
 		})
 	}
-} 
\ No newline at end of file
+} 
+func TestCreateTransferValidation(t *testing.T) {
+	t.Parallel()
+	deps := setupTestServices(t)
+
+	app := newTestApp()
+	app.Post("/transactions/transfer", CreateTransfer(deps.Transactions))
+
+	payloadBytes, _ := json.Marshal(map[string]interface{}{
+		"amount":          -10.0,
+		"bank_account_id": 1,
+	})
+	req := httptest.NewRequest("POST", "/transactions/transfer", bytes.NewReader(payloadBytes))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+
+	assert.Equal(t, apperrors.ProblemContentType, resp.Header.Get("Content-Type"))
+
+	var response models.Problem
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, apperrors.CodeValidationFailed, response.Code)
+	assert.Equal(t, "Validation failed", response.Detail)
+
+	codes := map[string]string{}
+	for _, field := range response.Errors {
+		codes[field.Field] = field.Code
+	}
+	assert.Equal(t, map[string]string{
+		"amount":                      "positive",
+		"destination_bank_account_id": "required",
+		"description":                 "required",
+	}, codes)
+}
+
+func TestErrorsAreProblemDetails(t *testing.T) {
+	t.Parallel()
+	deps := setupTestServices(t)
+
+	app := newTestApp()
+	app.Post("/transactions", CreateTransaction(deps.Transactions, nil))
+	app.Get("/transactions/:id", GetTransaction(deps.Transactions))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		payload        interface{}
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "Missing transaction",
+			method:         "GET",
+			path:           "/transactions/999",
+			expectedStatus: 404,
+			expectedCode:   apperrors.CodeTransactionNotFound,
+		},
+		{
+			name:           "Non-numeric id",
+			method:         "GET",
+			path:           "/transactions/abc",
+			expectedStatus: 400,
+			expectedCode:   apperrors.CodeInvalidID,
+		},
+		{
+			name:   "Category type mismatch",
+			method: "POST",
+			path:   "/transactions",
+			payload: map[string]interface{}{
+				"amount":          10.0,
+				"type":            "expense",
+				"category_id":     2, // Salary is an income category
+				"bank_account_id": 1,
+			},
+			expectedStatus: 400,
+			expectedCode:   apperrors.CodeCategoryTypeMismatch,
+		},
+		{
+			name:   "Unknown bank account",
+			method: "POST",
+			path:   "/transactions",
+			payload: map[string]interface{}{
+				"amount":          10.0,
+				"type":            "expense",
+				"category_id":     1,
+				"bank_account_id": 99,
+			},
+			expectedStatus: 400,
+			expectedCode:   apperrors.CodeBankAccountNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body *bytes.Reader
+			if tt.payload != nil {
+				payloadBytes, _ := json.Marshal(tt.payload)
+				body = bytes.NewReader(payloadBytes)
+			} else {
+				body = bytes.NewReader(nil)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, apperrors.ProblemContentType, resp.Header.Get("Content-Type"))
+
+			var problem models.Problem
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+			assert.Equal(t, tt.expectedCode, problem.Code)
+			assert.Equal(t, tt.expectedStatus, problem.Status)
+			assert.Equal(t, tt.path, problem.Instance)
+			assert.NotEmpty(t, problem.RequestID)
+			assert.Equal(t, resp.Header.Get("X-Request-ID"), problem.RequestID)
+		})
+	}
+}
+
+func TestBulkTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+
+	app := newTestApp()
+	app.Post("/transactions/bulk", CreateBulkTransactions(deps.Transactions, deps.Jobs))
+	app.Delete("/transactions/bulk", DeleteBulkTransactions(deps.Transactions))
+
+	send := func(method, url string, payload interface{}, response interface{}) int {
+		payloadBytes, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewReader(payloadBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(response))
+		return resp.StatusCode
+	}
+	count := func() int64 {
+		var n int64
+		db.Model(&models.Transaction{}).Count(&n)
+		return n
+	}
+
+	rows := []map[string]interface{}{
+		{"amount": 10.0, "type": "expense", "category_id": 1, "bank_account_id": 1, "description": "Lunch"},
+		{"amount": 250.0, "type": "transfer", "bank_account_id": 1, "destination_bank_account_id": 2, "description": "To savings"},
+		{"amount": 5.0, "type": "expense", "category_id": 2, "bank_account_id": 1, "description": "Wrong category"},
+	}
+
+	// Atomic mode stores nothing when one row is invalid
+	var created models.BulkTransactionResponse
+	status := send("POST", "/transactions/bulk?atomic=true", map[string]interface{}{"transactions": rows}, &created)
+	assert.Equal(t, 400, status)
+	assert.Empty(t, created.Success)
+	if assert.Len(t, created.Failed, 1) {
+		assert.Equal(t, 2, created.Failed[0].Index)
+		assert.Equal(t, apperrors.CodeCategoryTypeMismatch, created.Failed[0].Code)
+	}
+	assert.Equal(t, int64(0), count())
+
+	// Default mode stores the valid rows, transfers included
+	created = models.BulkTransactionResponse{}
+	status = send("POST", "/transactions/bulk", map[string]interface{}{"transactions": rows}, &created)
+	assert.Equal(t, 207, status)
+	assert.Equal(t, 2, created.SuccessCount)
+	assert.Equal(t, "Food", created.Success[0].Category)
+	assert.Equal(t, "transfer", created.Success[1].Type)
+	assert.Equal(t, "Test Savings", created.Success[1].DestinationBankAccount.Name)
+	assert.Equal(t, int64(2), count())
+
+	// Atomic mode succeeds when every row is valid
+	created = models.BulkTransactionResponse{}
+	status = send("POST", "/transactions/bulk?atomic=true", map[string]interface{}{"transactions": rows[:2]}, &created)
+	assert.Equal(t, 201, status)
+	assert.Equal(t, 2, created.SuccessCount)
+	assert.Equal(t, int64(4), count())
+
+	// Atomic delete keeps everything when one ID is missing
+	var deleted models.BulkDeleteResponse
+	status = send("DELETE", "/transactions/bulk?atomic=true", map[string]interface{}{"transaction_ids": []uint{1, 2, 999}}, &deleted)
+	assert.Equal(t, 400, status)
+	assert.Empty(t, deleted.Deleted)
+	assert.Equal(t, int64(4), count())
+
+	deleted = models.BulkDeleteResponse{}
+	status = send("DELETE", "/transactions/bulk?atomic=true", map[string]interface{}{"transaction_ids": []uint{1, 2}}, &deleted)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, []uint{1, 2}, deleted.Deleted)
+	assert.Equal(t, int64(2), count())
+
+	// Every batched write is still recorded in the version history
+	var versions int64
+	db.Model(&models.TransactionVersion{}).Where("operation = ?", models.VersionOperationDelete).Count(&versions)
+	assert.Equal(t, int64(2), versions)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"strconv"
+
+	"expense-api/apperrors"
+	"expense-api/events"
+	"expense-api/models"
+	"expense-api/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetTransactionHistory handles GET /transactions/:id/history
+func GetTransactionHistory(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		versions, err := svc.History(id)
+		if err != nil {
+			return err
+		}
+
+		if versions == nil {
+			versions = []models.TransactionVersion{}
+		}
+
+		return c.JSON(versions)
+	}
+}
+
+// RestoreTransaction handles POST /transactions/:id/restore?version=
+func RestoreTransaction(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		versionNumber, err := strconv.Atoi(c.Query("version"))
+		if err != nil || versionNumber <= 0 {
+			return apperrors.BadRequest(apperrors.CodeInvalidParameter, "version query parameter must be a positive integer")
+		}
+
+		transaction, err := svc.Restore(id, versionNumber)
+		if err != nil {
+			return err
+		}
+
+		response := convertToTransactionResponse(transaction)
+		publish(c, events.TransactionUpdated, response)
+
+		return c.JSON(response)
+	}
+}
+
+// GetDeletedTransactions handles GET /transactions/trash
+func GetDeletedTransactions(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		transactions, err := svc.Deleted()
+		if err != nil {
+			return err
+		}
+
+		// Convert to response format
+		response := []models.TransactionResponse{}
+		for _, t := range transactions {
+			response = append(response, convertToTransactionResponse(t))
+		}
+
+		return c.JSON(response)
+	}
+}
+
+// UndeleteTransaction handles POST /transactions/:id/undelete
+func UndeleteTransaction(svc services.TransactionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		transaction, err := svc.Undelete(id)
+		if err != nil {
+			return err
+		}
+
+		response := convertToTransactionResponse(transaction)
+		publish(c, events.TransactionUpdated, response)
+
+		return c.JSON(response)
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"expense-api/container"
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// historyBackends returns containers over the in-memory store and over SQLite,
+// which must keep the same history
+func historyBackends(t *testing.T) map[string]*container.Container {
+	db := setupTestDB(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	return map[string]*container.Container{
+		"memory": setupTestServices(t),
+		"gorm":   container.New(func() *gorm.DB { return db }),
+	}
+}
+
+func TestTransactionHistoryAndRestore(t *testing.T) {
+	for name, deps := range historyBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			categoryID := uint(1)
+			_, err := deps.Transactions.Create(models.Transaction{
+				Amount:        50.0,
+				Type:          "expense",
+				CategoryID:    &categoryID,
+				BankAccountID: 1,
+				Description:   "Lunch",
+				Date:          models.FlexibleDate{Time: time.Now()},
+			})
+			assert.NoError(t, err)
+
+			app := newTestApp()
+			app.Put("/transactions/:id", UpdateTransaction(deps.Transactions))
+			app.Get("/transactions/:id/history", GetTransactionHistory(deps.Transactions))
+			app.Post("/transactions/:id/restore", RestoreTransaction(deps.Transactions))
+
+			payload, _ := json.Marshal(map[string]interface{}{"amount": 75.0, "description": "Lunch with dessert"})
+			req := httptest.NewRequest("PUT", "/transactions/1", bytes.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+
+			resp, err = app.Test(httptest.NewRequest("GET", "/transactions/1/history", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+
+			var versions []models.TransactionVersion
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&versions))
+			if assert.Len(t, versions, 2) {
+				assert.Equal(t, models.VersionOperationCreate, versions[0].Operation)
+				assert.Equal(t, 50.0, versions[0].Amount)
+				assert.Equal(t, models.VersionOperationUpdate, versions[1].Operation)
+				assert.Equal(t, 75.0, versions[1].Amount)
+			}
+
+			resp, err = app.Test(httptest.NewRequest("POST", "/transactions/1/restore?version=1", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+
+			var restored models.TransactionResponse
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&restored))
+			assert.Equal(t, 50.0, restored.Amount)
+			assert.Equal(t, "Lunch", restored.Description)
+
+			versions, err = deps.Transactions.History(1)
+			assert.NoError(t, err)
+			if assert.Len(t, versions, 3) {
+				assert.Equal(t, 3, versions[2].Version)
+				assert.Equal(t, models.VersionOperationRestore, versions[2].Operation)
+			}
+
+			resp, err = app.Test(httptest.NewRequest("POST", "/transactions/1/restore?version=9", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, 404, resp.StatusCode)
+
+			resp, err = app.Test(httptest.NewRequest("GET", "/transactions/9/history", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, 404, resp.StatusCode)
+		})
+	}
+}
+
+func TestBulkCreateRecordsVersions(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	deps := container.New(func() *gorm.DB { return db })
+
+	categoryID := uint(1)
+	request := models.BulkTransactionRequest{}
+	for _, amount := range []float64{10, 20, 30} {
+		request.Transactions = append(request.Transactions, models.Transaction{Amount: amount, Type: "expense", CategoryID: &categoryID,
+			BankAccountID: 1, Description: "Snack", Date: models.FlexibleDate{Time: time.Now()}})
+	}
+	result, err := deps.Transactions.CreateBulk(request, true)
+	assert.NoError(t, err)
+	assert.Len(t, result.Created, 3)
+
+	var versions []models.TransactionVersion
+	assert.NoError(t, db.Order("record_id").Find(&versions).Error)
+	if assert.Len(t, versions, 3) {
+		for i, version := range versions {
+			assert.Equal(t, result.Created[i].ID, version.RecordID)
+			assert.Equal(t, 1, version.Version)
+			assert.Equal(t, models.VersionOperationCreate, version.Operation)
+			assert.Equal(t, request.Transactions[i].Amount, version.Amount)
+		}
+	}
+
+	// A version number is used once per transaction
+	duplicate := versions[0]
+	duplicate.ID = 0
+	assert.Error(t, db.Create(&duplicate).Error)
+}
+
+func TestTransactionTrashAndUndelete(t *testing.T) {
+	for name, deps := range historyBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			categoryID := uint(1)
+			_, err := deps.Transactions.Create(models.Transaction{
+				Amount:        20.0,
+				Type:          "expense",
+				CategoryID:    &categoryID,
+				BankAccountID: 1,
+				Description:   "Coffee",
+				Date:          models.FlexibleDate{Time: time.Now()},
+			})
+			assert.NoError(t, err)
+
+			app := newTestApp()
+			app.Get("/transactions", GetTransactions(deps.Transactions))
+			app.Get("/transactions/trash", GetDeletedTransactions(deps.Transactions))
+			app.Delete("/transactions/:id", DeleteTransaction(deps.Transactions))
+			app.Post("/transactions/:id/undelete", UndeleteTransaction(deps.Transactions))
+
+			list := func(path string) []models.TransactionResponse {
+				resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+				assert.NoError(t, err)
+				var transactions []models.TransactionResponse
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&transactions))
+				return transactions
+			}
+
+			resp, err := app.Test(httptest.NewRequest("DELETE", "/transactions/1", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+
+			// Soft-deleted rows are hidden from listings but kept in the trash
+			assert.Empty(t, list("/transactions"))
+			if trash := list("/transactions/trash"); assert.Len(t, trash, 1) {
+				assert.Equal(t, "Coffee", trash[0].Description)
+			}
+
+			resp, err = app.Test(httptest.NewRequest("POST", "/transactions/1/undelete", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+
+			assert.Len(t, list("/transactions"), 1)
+			assert.Empty(t, list("/transactions/trash"))
+
+			resp, err = app.Test(httptest.NewRequest("POST", "/transactions/1/undelete", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, 400, resp.StatusCode)
+
+			versions, err := deps.Transactions.History(1)
+			assert.NoError(t, err)
+			operations := []string{}
+			for _, version := range versions {
+				operations = append(operations, version.Operation)
+			}
+			assert.Equal(t, []string{models.VersionOperationCreate, models.VersionOperationDelete, models.VersionOperationUndelete}, operations)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+	"expense-api/services"
+	"expense-api/webhooks"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// convertToWebhookResponse converts a WebhookSubscription model to WebhookSubscriptionResponse
+func convertToWebhookResponse(s models.WebhookSubscription) models.WebhookSubscriptionResponse {
+	return models.WebhookSubscriptionResponse{
+		ID:        s.ID,
+		URL:       s.URL,
+		Events:    webhooks.Events(s),
+		IsActive:  s.IsActive,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// CreateWebhook handles POST /webhooks
+func CreateWebhook(svc services.WebhookService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		var request models.WebhookSubscriptionRequest
+
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
+		}
+
+		subscription, err := svc.Create(requestUser(c), request)
+		if err != nil {
+			return err
+		}
+
+		// The secret is only ever returned once, on creation
+		response := convertToWebhookResponse(subscription)
+		response.Secret = subscription.Secret
+
+		return c.Status(201).JSON(response)
+	}
+}
+
+// GetWebhooks handles GET /webhooks
+func GetWebhooks(svc services.WebhookService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		subscriptions, err := svc.List(requestUser(c))
+		if err != nil {
+			return err
+		}
+
+		response := []models.WebhookSubscriptionResponse{}
+		for _, s := range subscriptions {
+			response = append(response, convertToWebhookResponse(s))
+		}
+
+		return c.JSON(response)
+	}
+}
+
+// GetWebhook handles GET /webhooks/:id
+func GetWebhook(svc services.WebhookService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		subscription, err := svc.Get(requestUser(c), id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(convertToWebhookResponse(subscription))
+	}
+}
+
+// UpdateWebhook handles PUT /webhooks/:id
+func UpdateWebhook(svc services.WebhookService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		var request models.WebhookSubscriptionRequest
+		if err := c.BodyParser(&request); err != nil {
+			return apperrors.InvalidBody(err)
+		}
+
+		subscription, err := svc.Update(requestUser(c), id, request)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(convertToWebhookResponse(subscription))
+	}
+}
+
+// DeleteWebhook handles DELETE /webhooks/:id
+func DeleteWebhook(svc services.WebhookService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		if err := svc.Delete(requestUser(c), id); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusNoContent).Send(nil)
+	}
+}
+
+// Page sizes of GET /webhooks/:id/deliveries
+const (
+	defaultDeliveryLimit = 100
+	maxDeliveryLimit     = 1000
+)
+
+// GetWebhookDeliveries handles GET /webhooks/:id/deliveries
+func GetWebhookDeliveries(svc services.WebhookService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		limit := defaultDeliveryLimit
+		if value := c.Query("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxDeliveryLimit {
+				return apperrors.BadRequest(apperrors.CodeInvalidParameter, fmt.Sprintf("limit must be an integer from 1 to %d", maxDeliveryLimit))
+			}
+			limit = parsed
+		}
+
+		deliveries, err := svc.Deliveries(requestUser(c), id, c.Query("status"), limit)
+		if err != nil {
+			return err
+		}
+
+		if deliveries == nil {
+			deliveries = []models.WebhookDelivery{}
+		}
+
+		return c.JSON(deliveries)
+	}
+}
+
+// TestWebhook handles POST /webhooks/:id/test
+func TestWebhook(svc services.WebhookService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		svc := svc.WithContext(c.UserContext())
+		id, err := paramID(c)
+		if err != nil {
+			return err
+		}
+
+		delivery, err := svc.Test(requestUser(c), id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(delivery)
+	}
+}
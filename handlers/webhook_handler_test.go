@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/services"
+	"expense-api/webhooks"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	t.Parallel()
+	store := repository.NewMemoryStore()
+	hooks := store.Webhooks()
+
+	var tested []models.WebhookSubscription
+	sendTest := func(ctx context.Context, subscription models.WebhookSubscription) (models.WebhookDelivery, error) {
+		tested = append(tested, subscription)
+		delivery := models.WebhookDelivery{SubscriptionID: subscription.ID, Event: webhooks.EventTest, Status: webhooks.StatusSucceeded}
+		return delivery, hooks.CreateDelivery(&delivery)
+	}
+	svc := services.NewWebhookService(hooks, sendTest)
+
+	app := newTestApp()
+	app.Post("/webhooks", CreateWebhook(svc))
+	app.Get("/webhooks", GetWebhooks(svc))
+	app.Get("/webhooks/:id", GetWebhook(svc))
+	app.Put("/webhooks/:id", UpdateWebhook(svc))
+	app.Delete("/webhooks/:id", DeleteWebhook(svc))
+	app.Get("/webhooks/:id/deliveries", GetWebhookDeliveries(svc))
+	app.Post("/webhooks/:id/test", TestWebhook(svc))
+
+	send := func(method, path, body string, out interface{}) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		if out != nil && resp.StatusCode < 300 {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	var created models.WebhookSubscriptionResponse
+	assert.Equal(t, 201, send("POST", "/webhooks", `{"url": "https://example.com/hook", "events": ["transaction.created"]}`, &created))
+	assert.NotEmpty(t, created.Secret)
+	assert.True(t, created.IsActive)
+	assert.Equal(t, 400, send("POST", "/webhooks", `{"url": "https://example.com/hook", "events": ["budget.exceeded"]}`, nil))
+	assert.Equal(t, 400, send("POST", "/webhooks", `{"url": "not a url", "events": ["*"]}`, nil))
+
+	// Omitted fields keep their values, and the secret is never shown again
+	var updated models.WebhookSubscriptionResponse
+	assert.Equal(t, 200, send("PUT", "/webhooks/1", `{"is_active": false}`, &updated))
+	assert.Equal(t, "https://example.com/hook", updated.URL)
+	assert.Equal(t, []string{"transaction.created"}, updated.Events)
+	assert.False(t, updated.IsActive)
+	assert.Empty(t, updated.Secret)
+
+	var listed []models.WebhookSubscriptionResponse
+	assert.Equal(t, 200, send("GET", "/webhooks", "", &listed))
+	assert.Len(t, listed, 1)
+	assert.Equal(t, 404, send("GET", "/webhooks/9", "", nil))
+
+	var delivery models.WebhookDelivery
+	assert.Equal(t, 200, send("POST", "/webhooks/1/test", "", &delivery))
+	assert.Equal(t, webhooks.EventTest, delivery.Event)
+	if assert.Len(t, tested, 1) {
+		assert.Equal(t, created.Secret, tested[0].Secret)
+	}
+	failed := models.WebhookDelivery{SubscriptionID: 1, Event: "transaction.created", Status: webhooks.StatusFailed}
+	assert.NoError(t, hooks.CreateDelivery(&failed))
+
+	for query, want := range map[string]int{"": 2, "?limit=1": 1, "?status=failed": 1, "?status=pending": 0} {
+		var deliveries []models.WebhookDelivery
+		assert.Equal(t, 200, send("GET", "/webhooks/1/deliveries"+query, "", &deliveries), query)
+		assert.Len(t, deliveries, want, query)
+	}
+	for _, query := range []string{"?limit=0", "?limit=-5", "?limit=1001", "?limit=lots", "?status=done"} {
+		assert.Equal(t, 400, send("GET", "/webhooks/1/deliveries"+query, "", nil), query)
+	}
+
+	// Other users neither see nor manage the subscription
+	other := newTestApp()
+	other.Use(func(c *fiber.Ctx) error {
+		c.Locals(UserLocal, uint(2))
+		return c.Next()
+	})
+	other.Get("/webhooks", GetWebhooks(svc))
+	other.Get("/webhooks/:id", GetWebhook(svc))
+	other.Delete("/webhooks/:id", DeleteWebhook(svc))
+	resp, err := other.Test(httptest.NewRequest("GET", "/webhooks", nil))
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	assert.Empty(t, listed)
+	for _, method := range []string{"GET", "DELETE"} {
+		resp, err := other.Test(httptest.NewRequest(method, "/webhooks/1", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode, method)
+	}
+
+	assert.Equal(t, 204, send("DELETE", "/webhooks/1", "", nil))
+	assert.Equal(t, 404, send("GET", "/webhooks/1", "", nil))
+	assert.Equal(t, 404, send("GET", "/webhooks/1/deliveries", "", nil))
+}
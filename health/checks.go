@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"expense-api/database/migrations"
+
+	"gorm.io/gorm"
+)
+
+// errNotConnected is reported by the database checks while the provider returns nil
+var errNotConnected = errors.New("database is not ready")
+
+// errRollback undoes the probe write of Writable
+var errRollback = errors.New("rollback")
+
+// Database pings the database returned by db
+func Database(db func() *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		conn := db()
+		if conn == nil {
+			return errNotConnected
+		}
+		sqlDB, err := conn.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Migrations fails while the database has migrations this build has not applied
+func Migrations(db func() *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		conn := db()
+		if conn == nil {
+			return errNotConnected
+		}
+		migrator, err := migrations.New(conn.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d migration(s) pending, the next is %d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
+}
+
+// Writable fails when the database rejects writes, e.g. on a read-only replica or a full disk.
+// The probe write changes no rows and is rolled back.
+func Writable(db func() *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		conn := db()
+		if conn == nil {
+			return errNotConnected
+		}
+		err := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE schema_migrations SET version = version WHERE version < 0").Error; err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if errors.Is(err, errRollback) {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense-api/database/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRegistryRun(t *testing.T) {
+	registry := NewRegistry()
+	registry.Timeout = 20 * time.Millisecond
+
+	report := registry.Run(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Empty(t, report.Checks)
+
+	block := make(chan struct{})
+	defer close(block)
+	registry.Register("ok", func(ctx context.Context) error { return nil })
+	registry.Register("broken", func(ctx context.Context) error { return errors.New("boom") })
+	registry.Register("stuck", func(ctx context.Context) error { <-block; return nil })
+	registry.Register("panics", func(ctx context.Context) error { panic("bad check") })
+	registry.Register("ok", func(ctx context.Context) error { return nil })
+
+	report = registry.Run(context.Background())
+	assert.Equal(t, StatusFail, report.Status)
+	if assert.Len(t, report.Checks, 4) {
+		assert.Equal(t, "ok", report.Checks[0].Name)
+		assert.Equal(t, StatusOK, report.Checks[0].Status)
+		assert.Equal(t, "boom", report.Checks[1].Error)
+		assert.Equal(t, "timed out after 20ms", report.Checks[2].Error)
+		assert.GreaterOrEqual(t, report.Checks[2].LatencyMS, 20.0)
+		assert.Contains(t, report.Checks[3].Error, "bad check")
+	}
+}
+
+func openDB(t *testing.T, dsn string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestDatabaseChecks(t *testing.T) {
+	ctx := context.Background()
+	var db *gorm.DB
+	provider := func() *gorm.DB { return db }
+
+	// Nothing passes before the database is connected
+	for _, check := range []Check{Database(provider), Migrations(provider), Writable(provider)} {
+		assert.ErrorIs(t, check(ctx), errNotConnected)
+	}
+
+	path := filepath.Join(t.TempDir(), "health.db")
+	db = openDB(t, path)
+	assert.NoError(t, Database(provider)(ctx))
+	assert.ErrorContains(t, Migrations(provider)(ctx), "migration(s) pending, the next is 1_initial_schema")
+
+	migrator, err := migrations.New(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.NoError(t, Migrations(provider)(ctx))
+	assert.NoError(t, Writable(provider)(ctx))
+
+	db = openDB(t, "file:"+path+"?mode=ro")
+	assert.NoError(t, Database(provider)(ctx))
+	assert.NoError(t, Migrations(provider)(ctx))
+	assert.Error(t, Writable(provider)(ctx))
+}
@@ -0,0 +1,206 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"expense-api/logging"
+	"expense-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Header is the request header carrying the client's idempotency key
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from a stored record
+const ReplayedHeader = "Idempotent-Replayed"
+
+// MaxKeyLength is the longest key accepted
+const MaxKeyLength = 255
+
+// Record statuses
+const (
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+var (
+	// ErrKeyReused is returned when a key is sent again with a different request
+	ErrKeyReused = errors.New("idempotency key was used with a different request")
+	// ErrInProgress is returned while the first request with a key has not finished
+	ErrInProgress = errors.New("a request with this idempotency key is in progress")
+)
+
+// Fingerprint identifies a request by its method, URL and body
+func Fingerprint(method, url string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + url + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Store keeps idempotency records in the database. Keys are scoped by client,
+// so clients that happen to send the same key never see each other's responses.
+type Store struct {
+	DB func() *gorm.DB
+	// TTL is how long a completed response is replayed
+	TTL time.Duration
+	// LockTimeout is how long an unfinished request holds its key without a
+	// heartbeat before it is considered abandoned, e.g. because the server crashed
+	LockTimeout time.Duration
+}
+
+// NewStore creates a store with default settings
+func NewStore(db func() *gorm.DB) *Store {
+	return &Store{
+		DB:          db,
+		TTL:         24 * time.Hour,
+		LockTimeout: time.Minute,
+	}
+}
+
+// Begin claims a client's key for a request. It returns nil when the caller
+// should process the request, holding the key with Heartbeat, and then call
+// Complete or Release, or the stored record when a response for the same
+// request can be replayed. The claim is a single insert, so only one of
+// several concurrent requests with a key proceeds.
+func (s *Store) Begin(client, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now().UTC()
+		record := models.IdempotencyRecord{
+			Client:      client,
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      StatusInProgress,
+			ExpiresAt:   now.Add(s.TTL),
+		}
+
+		insert := s.DB().Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if insert.Error != nil {
+			return nil, insert.Error
+		}
+		if insert.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existing models.IdempotencyRecord
+		err := s.DB().Where("client = ? AND idempotency_key = ?", client, key).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released between our insert and lookup; try again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if existing.ExpiresAt.Before(now) || (existing.Status == StatusInProgress && existing.UpdatedAt.Before(now.Add(-s.LockTimeout))) {
+			if err := s.deleteStale(client, key, now); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if existing.Fingerprint != fingerprint {
+			return nil, ErrKeyReused
+		}
+		if existing.Status == StatusInProgress {
+			return nil, ErrInProgress
+		}
+		return &existing, nil
+	}
+
+	return nil, ErrInProgress
+}
+
+// deleteStale removes a record if it has expired or its request was abandoned
+func (s *Store) deleteStale(client, key string, now time.Time) error {
+	return s.DB().Where("client = ? AND idempotency_key = ? AND (expires_at < ? OR (status = ? AND updated_at < ?))",
+		client, key, now, StatusInProgress, now.Add(-s.LockTimeout)).Delete(&models.IdempotencyRecord{}).Error
+}
+
+// Heartbeat keeps a claimed key locked while its request runs by refreshing
+// it every third of LockTimeout, so a request that takes longer than
+// LockTimeout is not taken for abandoned and run again by a retry. Call stop
+// once the request has finished.
+func (s *Store) Heartbeat(ctx context.Context, client, key string) (stop func()) {
+	if s.LockTimeout <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.LockTimeout / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.touch(client, key); err != nil {
+					logging.For(logging.ComponentIdempotency).ErrorContext(ctx, "Failed to extend idempotency key lock", "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// touch refreshes the lock of an unfinished request
+func (s *Store) touch(client, key string) error {
+	return s.DB().Model(&models.IdempotencyRecord{}).Where("client = ? AND idempotency_key = ? AND status = ?", client, key, StatusInProgress).
+		Update("updated_at", time.Now().UTC()).Error
+}
+
+// Complete stores the response of a claimed key so retries can replay it
+func (s *Store) Complete(client, key string, status int, contentType, location string, body []byte) error {
+	return s.DB().Model(&models.IdempotencyRecord{}).Where("client = ? AND idempotency_key = ?", client, key).Updates(map[string]interface{}{
+		"status":        StatusCompleted,
+		"response_code": status,
+		"content_type":  contentType,
+		"location":      location,
+		"response_body": string(body),
+	}).Error
+}
+
+// Release gives up a claimed key without storing a response, so the request can be retried
+func (s *Store) Release(client, key string) error {
+	return s.DB().Where("client = ? AND idempotency_key = ? AND status = ?", client, key, StatusInProgress).Delete(&models.IdempotencyRecord{}).Error
+}
+
+// Purge deletes expired records and returns how many were removed
+func (s *Store) Purge() (int64, error) {
+	result := s.DB().Where("expires_at < ?", time.Now().UTC()).Delete(&models.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// Run purges expired records every interval until the context is cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Purge(); err != nil {
+			logging.For(logging.ComponentIdempotency).ErrorContext(ctx, "Idempotency purge error", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestStore(t *testing.T) *Store {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	// Every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(&models.IdempotencyRecord{})
+	assert.NoError(t, err)
+
+	return NewStore(func() *gorm.DB { return db })
+}
+
+func TestBeginCompleteAndReplay(t *testing.T) {
+	store := setupTestStore(t)
+	fingerprint := Fingerprint("POST", "/api/transactions", []byte(`{"amount":10}`))
+
+	record, err := store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	// A retry while the first request runs is rejected
+	_, err = store.Begin("user:1", "key-1", fingerprint)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	assert.NoError(t, store.Complete("user:1", "key-1", 201, "application/json", "", []byte(`{"id":1}`)))
+
+	record, err = store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	if assert.NotNil(t, record) {
+		assert.Equal(t, 201, record.ResponseCode)
+		assert.Equal(t, `{"id":1}`, record.ResponseBody)
+	}
+
+	// The same key with another body is a client error
+	_, err = store.Begin("user:1", "key-1", Fingerprint("POST", "/api/transactions", []byte(`{"amount":20}`)))
+	assert.ErrorIs(t, err, ErrKeyReused)
+}
+
+func TestReleaseAndExpiryFreeTheKey(t *testing.T) {
+	store := setupTestStore(t)
+	fingerprint := Fingerprint("POST", "/api/transactions", nil)
+
+	_, err := store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Release("user:1", "key-1"))
+
+	record, err := store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	assert.NoError(t, store.Complete("user:1", "key-1", 201, "application/json", "", []byte(`{}`)))
+
+	// Expired responses are no longer replayed, even for a different body
+	store.DB().Model(&models.IdempotencyRecord{}).Where("idempotency_key = ?", "key-1").Update("expires_at", time.Now().UTC().Add(-time.Minute))
+	record, err = store.Begin("user:1", "key-1", Fingerprint("POST", "/api/transactions", []byte(`{}`)))
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	// Requests abandoned for longer than the lock timeout give up their key
+	store.DB().Model(&models.IdempotencyRecord{}).Where("idempotency_key = ?", "key-1").Update("updated_at", time.Now().UTC().Add(-2*store.LockTimeout))
+	record, err = store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	store.DB().Model(&models.IdempotencyRecord{}).Where("idempotency_key = ?", "key-1").Update("expires_at", time.Now().UTC().Add(-time.Minute))
+	removed, err := store.Purge()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+}
+
+func TestKeysAreScopedByClient(t *testing.T) {
+	store := setupTestStore(t)
+	fingerprint := Fingerprint("POST", "/api/transactions", []byte(`{"amount":10}`))
+
+	_, err := store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Complete("user:1", "key-1", 201, "application/json", "", []byte(`{"id":1}`)))
+
+	// Another client with the same key runs its own request, even with another body
+	record, err := store.Begin("ip:203.0.113.7", "key-1", Fingerprint("POST", "/api/transactions", []byte(`{"amount":20}`)))
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	assert.NoError(t, store.Release("ip:203.0.113.7", "key-1"))
+
+	// Releasing it left the first client's response alone
+	record, err = store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	if assert.NotNil(t, record) {
+		assert.Equal(t, `{"id":1}`, record.ResponseBody)
+	}
+}
+
+func TestHeartbeatKeepsLongRequestsLocked(t *testing.T) {
+	store := setupTestStore(t)
+	store.LockTimeout = 60 * time.Millisecond
+	fingerprint := Fingerprint("POST", "/api/transactions", nil)
+
+	_, err := store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	stop := store.Heartbeat(context.Background(), "user:1", "key-1")
+
+	// The request outlives the lock timeout, yet a retry still waits for it
+	time.Sleep(4 * store.LockTimeout)
+	_, err = store.Begin("user:1", "key-1", fingerprint)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	// Without heartbeats the lock runs out, e.g. after a crash
+	stop()
+	time.Sleep(2 * store.LockTimeout)
+	record, err := store.Begin("user:1", "key-1", fingerprint)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func TestConcurrentBeginClaimsOnce(t *testing.T) {
+	store := setupTestStore(t)
+	fingerprint := Fingerprint("POST", "/api/transactions/transfer", []byte(`{"amount":10}`))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claimed, inProgress := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, err := store.Begin("user:1", "key-1", fingerprint)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil && record == nil {
+				claimed++
+			} else if err == ErrInProgress {
+				inProgress++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, claimed)
+	assert.Equal(t, 9, inProgress)
+}
@@ -0,0 +1,412 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"expense-api/logging"
+	"expense-api/models"
+	"expense-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// Job types
+const (
+	TypeBulkCreate = "transactions.bulk_create"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	// ErrCancelled is returned by Run.Progress once the job has been cancelled
+	ErrCancelled = errors.New("job cancelled")
+	// ErrNotCancellable is returned when cancelling a job that already finished
+	ErrNotCancellable = errors.New("only queued or running jobs can be cancelled")
+	// ErrNotRetryable is returned when retrying a job that has not failed or been cancelled
+	ErrNotRetryable = errors.New("only failed or cancelled jobs can be retried")
+	// errLeaseLost stops a run whose worker could not report in, since the job
+	// may be queued again and picked up by another worker
+	errLeaseLost = errors.New("job heartbeat failed")
+)
+
+// Handler performs a job. It reports progress through run and returns an error to fail the job.
+type Handler func(ctx context.Context, run *Run) error
+
+// Run is a job being performed by a worker
+type Run struct {
+	Job models.Job
+	db  *gorm.DB
+}
+
+// Payload decodes the job input into v
+func (r *Run) Payload(v interface{}) error {
+	return json.Unmarshal([]byte(r.Job.Payload), v)
+}
+
+// PreviousResult decodes the result saved by an earlier attempt into v, if there is one
+func (r *Run) PreviousResult(v interface{}) error {
+	if r.Job.Result == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(r.Job.Result), v)
+}
+
+// Progress records how many items are done and the result so far.
+// It returns ErrCancelled if the job was cancelled, in which case the handler should stop.
+func (r *Run) Progress(processed int, result interface{}) error {
+	return r.save(r.db, processed, result)
+}
+
+// Step runs fn in a database transaction and records the progress it returns
+// in the same transaction, so the items of a step are never stored without
+// the progress that says they are done, or the other way round. Repositories
+// used with the context passed to fn take part in the transaction. If the job
+// was cancelled meanwhile, the step is rolled back and Step returns ErrCancelled.
+func (r *Run) Step(ctx context.Context, fn func(ctx context.Context) (processed int, result interface{}, err error)) error {
+	saved := r.Job
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		processed, result, err := fn(repository.ContextWithTx(ctx, tx))
+		if err != nil {
+			return err
+		}
+		return r.save(tx, processed, result)
+	})
+	if err != nil {
+		r.Job = saved
+	}
+	return err
+}
+
+// save writes the progress of a running job with db
+func (r *Run) save(db *gorm.DB, processed int, result interface{}) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	update := db.Model(&models.Job{}).Where("id = ? AND status = ?", r.Job.ID, StatusRunning).
+		Updates(map[string]interface{}{"processed": processed, "result": string(body)})
+	if update.Error != nil {
+		return update.Error
+	}
+	if update.RowsAffected == 0 {
+		return ErrCancelled
+	}
+
+	r.Job.Processed = processed
+	r.Job.Result = string(body)
+	return nil
+}
+
+// Runner stores jobs in the database and performs them on a pool of workers.
+// Because jobs are persisted, work interrupted by a restart or a crash is picked up again.
+type Runner struct {
+	DB           func() *gorm.DB
+	Workers      int
+	PollInterval time.Duration
+	// Lease is how long a running job is left alone after its worker last
+	// reported in. Workers report in every third of it; a job whose worker
+	// stopped reporting is queued again, even if it ran on another replica.
+	// A worker that fails to report in stops the job, so it never runs twice.
+	Lease time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	wake     chan struct{}
+	workers  sync.WaitGroup
+	running  atomic.Int32
+}
+
+// NewRunner creates a runner with default settings
+func NewRunner(db func() *gorm.DB) *Runner {
+	return &Runner{
+		DB:           db,
+		Workers:      2,
+		PollInterval: 5 * time.Second,
+		Lease:        time.Minute,
+		handlers:     map[string]Handler{},
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler that performs jobs of the given type
+func (r *Runner) Register(jobType string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = handler
+}
+
+// handler returns the handler registered for a job type
+func (r *Runner) handler(jobType string) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, ok := r.handlers[jobType]
+	return handler, ok
+}
+
+// Enqueue stores a new job of total items queued by userID and wakes a worker
+func (r *Runner) Enqueue(jobType string, userID uint, payload interface{}, total int) (models.Job, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	job := models.Job{
+		UserID:  userID,
+		Type:    jobType,
+		Status:  StatusQueued,
+		Payload: string(body),
+		Total:   total,
+	}
+	if err := r.DB().Create(&job).Error; err != nil {
+		return models.Job{}, err
+	}
+
+	r.notify()
+	return job, nil
+}
+
+// Get returns a job queued by userID; jobs of other users are not found
+func (r *Runner) Get(id, userID uint) (models.Job, error) {
+	var job models.Job
+	err := r.DB().Where("user_id = ?", userID).First(&job, id).Error
+	return job, err
+}
+
+// Cancel stops a queued job from starting, or asks a running job to stop after its current step.
+// Items already processed by a running job are kept.
+func (r *Runner) Cancel(id, userID uint) (models.Job, error) {
+	return r.transition(id, userID, []string{StatusQueued, StatusRunning}, ErrNotCancellable, map[string]interface{}{
+		"status":      StatusCancelled,
+		"finished_at": time.Now().UTC(),
+	})
+}
+
+// Retry queues a failed or cancelled job again. It resumes after the items already processed.
+func (r *Runner) Retry(id, userID uint) (models.Job, error) {
+	job, err := r.transition(id, userID, []string{StatusFailed, StatusCancelled}, ErrNotRetryable, map[string]interface{}{
+		"status":      StatusQueued,
+		"last_error":  "",
+		"finished_at": nil,
+	})
+	if err == nil {
+		r.notify()
+	}
+	return job, err
+}
+
+// transition applies changes to a job of userID if it is in one of the from statuses
+func (r *Runner) transition(id, userID uint, from []string, invalid error, changes map[string]interface{}) (models.Job, error) {
+	job, err := r.Get(id, userID)
+	if err != nil {
+		return job, err
+	}
+
+	update := r.DB().Model(&models.Job{}).Where("id = ? AND status IN ?", id, from).Updates(changes)
+	if update.Error != nil {
+		return job, update.Error
+	}
+	if update.RowsAffected == 0 {
+		return job, invalid
+	}
+
+	return r.Get(id, userID)
+}
+
+// notify wakes an idle worker without blocking
+func (r *Runner) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start requeues jobs whose worker stopped and starts the workers.
+// Workers stop when the context is cancelled.
+func (r *Runner) Start(ctx context.Context) error {
+	if err := r.requeueStale(); err != nil {
+		return err
+	}
+
+	for i := 0; i < r.Workers; i++ {
+		r.workers.Add(1)
+		r.running.Add(1)
+		go func() {
+			defer r.workers.Done()
+			defer r.running.Add(-1)
+			r.work(ctx)
+		}()
+	}
+	return nil
+}
+
+// Check fails unless every worker is running. It suits a readiness check.
+func (r *Runner) Check(ctx context.Context) error {
+	if running := int(r.running.Load()); running < r.Workers {
+		return fmt.Errorf("%d of %d job workers running", running, r.Workers)
+	}
+	return nil
+}
+
+// Wait blocks until the workers started by Start have returned after their context was cancelled
+func (r *Runner) Wait() {
+	r.workers.Wait()
+}
+
+// work runs queued jobs until the context is cancelled
+func (r *Runner) work(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before waiting again
+		for {
+			ran, err := r.RunNext(ctx)
+			if err != nil {
+				logging.For(logging.ComponentJobs).ErrorContext(ctx, "Job worker error", "error", err)
+			}
+			if !ran || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+			if err := r.requeueStale(); err != nil {
+				logging.For(logging.ComponentJobs).ErrorContext(ctx, "Failed to requeue stale jobs", "error", err)
+			}
+		}
+	}
+}
+
+// requeueStale queues the running jobs whose worker has not reported in for
+// longer than the lease, such as jobs of a replica that crashed
+func (r *Runner) requeueStale() error {
+	stale := time.Now().UTC().Add(-r.Lease)
+	return r.DB().Model(&models.Job{}).
+		Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", StatusRunning, stale).
+		Update("status", StatusQueued).Error
+}
+
+// heartbeat reports in for a running job until stop is closed. If the job
+// cannot be reported in, or is no longer running, it stops the run with cancel.
+func (r *Runner) heartbeat(id uint, stop <-chan struct{}, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(r.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			update := r.DB().Model(&models.Job{}).Where("id = ? AND status = ?", id, StatusRunning).
+				Update("heartbeat_at", time.Now().UTC())
+			if update.Error != nil {
+				logging.For(logging.ComponentJobs).Warn("Job heartbeat failed, stopping the job", "job_id", id, "error", update.Error)
+				cancel(errLeaseLost)
+				return
+			}
+			if update.RowsAffected == 0 {
+				cancel(ErrCancelled)
+				return
+			}
+		}
+	}
+}
+
+// RunNext claims the oldest queued job and performs it. It reports whether a job was run.
+func (r *Runner) RunNext(ctx context.Context) (bool, error) {
+	var job models.Job
+	err := r.DB().Where("status = ?", StatusQueued).Order("id ASC").First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Another worker may claim the same job; only one update succeeds
+	now := time.Now().UTC()
+	claim := r.DB().Model(&models.Job{}).Where("id = ? AND status = ?", job.ID, StatusQueued).
+		Updates(map[string]interface{}{"status": StatusRunning, "attempts": gorm.Expr("attempts + 1"), "started_at": now, "heartbeat_at": now})
+	if claim.Error != nil {
+		return false, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return true, nil
+	}
+
+	job.Status = StatusRunning
+	job.Attempts++
+	job.StartedAt = &now
+
+	return true, r.perform(ctx, job)
+}
+
+// perform runs the handler of a claimed job and records how it ended
+func (r *Runner) perform(ctx context.Context, job models.Job) (err error) {
+	run := &Run{Job: job, db: r.DB()}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = r.finish(run.Job.ID, fmt.Errorf("job panicked: %v", recovered))
+		}
+	}()
+
+	handler, ok := r.handler(job.Type)
+	if !ok {
+		return r.finish(job.ID, fmt.Errorf("no handler registered for job type %q", job.Type))
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := make(chan struct{})
+	defer close(stop)
+	go r.heartbeat(job.ID, stop, cancel)
+
+	runErr := handler(runCtx, run)
+	if errors.Is(runErr, ErrCancelled) || errors.Is(context.Cause(runCtx), ErrCancelled) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		// Shutting down: hand the job back so any worker can resume it
+		return r.DB().Model(&models.Job{}).Where("id = ? AND status = ?", job.ID, StatusRunning).
+			Update("status", StatusQueued).Error
+	}
+	if errors.Is(context.Cause(runCtx), errLeaseLost) {
+		// The job is left running, so it is queued again once its lease runs out
+		return fmt.Errorf("job %d stopped: %w", job.ID, errLeaseLost)
+	}
+	return r.finish(job.ID, runErr)
+}
+
+// finish marks a running job as succeeded or failed, leaving cancelled jobs alone
+func (r *Runner) finish(id uint, runErr error) error {
+	changes := map[string]interface{}{
+		"status":      StatusSucceeded,
+		"finished_at": time.Now().UTC(),
+	}
+	if runErr != nil {
+		logging.For(logging.ComponentJobs).Warn("Job failed", "job_id", id, "error", runErr)
+		changes["status"] = StatusFailed
+		changes["last_error"] = runErr.Error()
+	}
+
+	return r.DB().Model(&models.Job{}).Where("id = ? AND status = ?", id, StatusRunning).Updates(changes).Error
+}
@@ -0,0 +1,261 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"expense-api/models"
+	"expense-api/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestRunner(t *testing.T) *Runner {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(&models.Job{})
+	assert.NoError(t, err)
+
+	// Every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return NewRunner(func() *gorm.DB { return db })
+}
+
+type countPayload struct {
+	Items []int `json:"items"`
+}
+
+// countHandler sums items one at a time, failing on negative numbers
+func countHandler(ctx context.Context, run *Run) error {
+	var payload countPayload
+	if err := run.Payload(&payload); err != nil {
+		return err
+	}
+	var sum int
+	if err := run.PreviousResult(&sum); err != nil {
+		return err
+	}
+
+	for i := run.Job.Processed; i < len(payload.Items); i++ {
+		if payload.Items[i] < 0 {
+			return errors.New("negative item")
+		}
+		sum += payload.Items[i]
+		if err := run.Progress(i+1, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRunNextRecordsProgressAndResult(t *testing.T) {
+	runner := setupTestRunner(t)
+	runner.Register("count", countHandler)
+
+	job, err := runner.Enqueue("count", 0, countPayload{Items: []int{1, 2, 3}}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusQueued, job.Status)
+
+	ran, err := runner.RunNext(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ran)
+
+	job, err = runner.Get(job.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "6", job.Result)
+	assert.NotNil(t, job.FinishedAt)
+
+	ran, err = runner.RunNext(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ran)
+}
+
+func TestRetryResumesAfterProcessedItems(t *testing.T) {
+	runner := setupTestRunner(t)
+	runner.Register("count", countHandler)
+
+	job, err := runner.Enqueue("count", 0, countPayload{Items: []int{1, 2, -1, 4}}, 4)
+	assert.NoError(t, err)
+
+	_, err = runner.RunNext(context.Background())
+	assert.NoError(t, err)
+
+	job, _ = runner.Get(job.ID, 0)
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.Equal(t, "negative item", job.LastError)
+	assert.Equal(t, 2, job.Processed)
+
+	_, err = runner.Cancel(job.ID, 0)
+	assert.ErrorIs(t, err, ErrNotCancellable)
+
+	// Fix the bad item the way an operator would, then retry
+	runner.DB().Model(&models.Job{}).Where("id = ?", job.ID).Update("payload", `{"items":[1,2,3,4]}`)
+	job, err = runner.Retry(job.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusQueued, job.Status)
+	assert.Empty(t, job.LastError)
+
+	_, err = runner.RunNext(context.Background())
+	assert.NoError(t, err)
+
+	job, _ = runner.Get(job.ID, 0)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "10", job.Result)
+
+	_, err = runner.Retry(job.ID, 0)
+	assert.ErrorIs(t, err, ErrNotRetryable)
+}
+
+func TestCancelStopsRunningJob(t *testing.T) {
+	runner := setupTestRunner(t)
+	runner.Register("cancel-self", func(ctx context.Context, run *Run) error {
+		if _, err := runner.Cancel(run.Job.ID, 0); err != nil {
+			return err
+		}
+		return run.Progress(1, nil)
+	})
+
+	job, err := runner.Enqueue("cancel-self", 0, nil, 1)
+	assert.NoError(t, err)
+
+	_, err = runner.RunNext(context.Background())
+	assert.NoError(t, err)
+
+	job, _ = runner.Get(job.ID, 0)
+	assert.Equal(t, StatusCancelled, job.Status)
+	assert.Equal(t, 0, job.Processed)
+}
+
+func TestStepIsRolledBackWhenCancelled(t *testing.T) {
+	runner := setupTestRunner(t)
+	var steps int
+	runner.Register("insert", func(ctx context.Context, run *Run) error {
+		if steps > 0 {
+			if _, err := runner.Cancel(run.Job.ID, 0); err != nil {
+				return err
+			}
+		}
+		steps++
+
+		// The step stores a row through the context, as services do
+		return run.Step(ctx, func(ctx context.Context) (int, interface{}, error) {
+			db := repository.Provider(runner.DB).WithContext(ctx)()
+			err := db.Create(&models.Job{Type: "marker", Status: StatusSucceeded, Payload: "{}"}).Error
+			return 1, nil, err
+		})
+	})
+	markers := func() int64 {
+		var count int64
+		runner.DB().Model(&models.Job{}).Where("type = ?", "marker").Count(&count)
+		return count
+	}
+
+	job, err := runner.Enqueue("insert", 0, nil, 1)
+	assert.NoError(t, err)
+	_, err = runner.RunNext(context.Background())
+	assert.NoError(t, err)
+	job, _ = runner.Get(job.ID, 0)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.Equal(t, 1, job.Processed)
+	assert.Equal(t, int64(1), markers())
+
+	// A step of a cancelled job is not stored
+	job, err = runner.Enqueue("insert", 0, nil, 1)
+	assert.NoError(t, err)
+	_, err = runner.RunNext(context.Background())
+	assert.NoError(t, err)
+	job, _ = runner.Get(job.ID, 0)
+	assert.Equal(t, StatusCancelled, job.Status)
+	assert.Equal(t, 0, job.Processed)
+	assert.Equal(t, int64(1), markers())
+}
+
+func TestHeartbeatFailureStopsTheRun(t *testing.T) {
+	runner := setupTestRunner(t)
+	runner.Lease = 30 * time.Millisecond
+
+	// Once the job starts, the heartbeat talks to a database that is gone
+	broken, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, err := broken.DB()
+	assert.NoError(t, err)
+	sqlDB.Close()
+	db := runner.DB()
+	var failing atomic.Bool
+	runner.DB = func() *gorm.DB {
+		if failing.Load() {
+			return broken
+		}
+		return db
+	}
+
+	runner.Register("wait", func(ctx context.Context, run *Run) error {
+		failing.Store(true)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+			return errors.New("the run was not stopped")
+		}
+	})
+
+	job, err := runner.Enqueue("wait", 0, nil, 1)
+	assert.NoError(t, err)
+	_, err = runner.RunNext(context.Background())
+	assert.ErrorIs(t, err, errLeaseLost)
+
+	// The job is left for requeueStale rather than failed
+	failing.Store(false)
+	job, _ = runner.Get(job.ID, 0)
+	assert.Equal(t, StatusRunning, job.Status)
+	assert.Empty(t, job.LastError)
+}
+
+func TestStartRequeuesInterruptedJobs(t *testing.T) {
+	runner := setupTestRunner(t)
+	runner.Register("count", countHandler)
+	runner.Workers = 1
+
+	job, err := runner.Enqueue("count", 0, countPayload{Items: []int{5}}, 1)
+	assert.NoError(t, err)
+	busy, err := runner.Enqueue("count", 0, countPayload{Items: []int{7}}, 1)
+	assert.NoError(t, err)
+
+	// Simulate a crash while the job was running, and a job another replica is still running
+	stale := time.Now().UTC().Add(-2 * runner.Lease)
+	runner.DB().Model(&models.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{"status": StatusRunning, "heartbeat_at": stale})
+	runner.DB().Model(&models.Job{}).Where("id = ?", busy.ID).Updates(map[string]interface{}{"status": StatusRunning, "heartbeat_at": time.Now().UTC()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.Error(t, runner.Check(ctx))
+	assert.NoError(t, runner.Start(ctx))
+	assert.NoError(t, runner.Check(ctx))
+
+	assert.Eventually(t, func() bool {
+		job, _ = runner.Get(job.ID, 0)
+		return job.Status == StatusSucceeded
+	}, 2*time.Second, 10*time.Millisecond)
+
+	busy, _ = runner.Get(busy.ID, 0)
+	assert.Equal(t, StatusRunning, busy.Status)
+
+	// Workers return once the context is cancelled
+	cancel()
+	runner.Wait()
+	assert.Error(t, runner.Check(context.Background()))
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultSlowQueryThreshold is how long a query may take before it is logged as slow
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// GormLogger logs GORM statements through the db component: failed queries as
+// errors, queries slower than SlowThreshold as warnings and every other query
+// at debug level. Statements are logged without their parameter values.
+type GormLogger struct {
+	Logger *slog.Logger
+	// SlowThreshold is the duration above which a query is logged as slow; 0 disables it
+	SlowThreshold time.Duration
+}
+
+// NewGormLogger creates a GORM logger with the threshold from SLOW_QUERY_THRESHOLD (default 200ms)
+func NewGormLogger() *GormLogger {
+	threshold := DefaultSlowQueryThreshold
+	if value, ok := os.LookupEnv("SLOW_QUERY_THRESHOLD"); ok {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			threshold = parsed
+		}
+	}
+	return &GormLogger{Logger: For(ComponentDB), SlowThreshold: threshold}
+}
+
+// LogMode implements logger.Interface. Levels come from LOG_LEVEL_DB instead.
+func (l *GormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.Logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.Logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.Logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+// Trace logs a finished statement
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+
+	var level slog.Level
+	var msg string
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "query failed"
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold:
+		level, msg = slog.LevelWarn, "slow query"
+	default:
+		level, msg = slog.LevelDebug, "query"
+	}
+	if !l.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter keeps parameter values, which may hold personal data, out of the logged statements
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,129 @@
+// Package logging configures structured logging with log/slog.
+//
+// Records are written as JSON to stdout. Every component logs through its own
+// logger from For, whose level can be set on its own, e.g. LOG_LEVEL_DB=debug
+// to see every SQL statement while the rest stays at LOG_LEVEL.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Components that log, each configurable with LOG_LEVEL_<COMPONENT>
+const (
+	ComponentHTTP        = "http"
+	ComponentDB          = "db"
+	ComponentJobs        = "jobs"
+	ComponentWebhooks    = "webhooks"
+	ComponentIdempotency = "idempotency"
+	ComponentServer      = "server"
+	ComponentServices    = "services"
+)
+
+var (
+	mu     sync.RWMutex
+	base   slog.Handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
+	root                = slog.LevelInfo
+	levels              = map[string]slog.Level{}
+)
+
+// Setup installs the default logger from the environment:
+//   - LOG_LEVEL sets the level of every component: debug, info, warn or error (default info)
+//   - LOG_LEVEL_<COMPONENT>, e.g. LOG_LEVEL_DB, overrides it for one component
+//   - LOG_FORMAT=text writes human-readable lines instead of JSON
+//
+// Output of the standard log package goes through the default logger as well.
+func Setup() {
+	Configure(os.Stdout, os.Getenv("LOG_FORMAT"), os.Environ())
+}
+
+// Configure installs the default logger writing to w in the given format ("json" or "text"),
+// reading the levels from environ, a list of KEY=value pairs as returned by os.Environ
+func Configure(w io.Writer, format string, environ []string) {
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler = slog.NewJSONHandler(w, options)
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, options)
+	}
+
+	rootLevel := slog.LevelInfo
+	componentLevels := map[string]slog.Level{}
+	for _, entry := range environ {
+		key, value, _ := strings.Cut(entry, "=")
+		var level slog.Level
+		if !strings.HasPrefix(key, "LOG_LEVEL") || level.UnmarshalText([]byte(value)) != nil {
+			continue
+		}
+		if key == "LOG_LEVEL" {
+			rootLevel = level
+		} else if component, ok := strings.CutPrefix(key, "LOG_LEVEL_"); ok {
+			componentLevels[strings.ToLower(component)] = level
+		}
+	}
+
+	mu.Lock()
+	base, root, levels = handler, rootLevel, componentLevels
+	mu.Unlock()
+
+	slog.SetDefault(slog.New(levelHandler{level: rootLevel, Handler: handler}))
+}
+
+// For returns the logger of a component. Records carry the component and,
+// when logged with a request context, the request ID.
+func For(component string) *slog.Logger {
+	mu.RLock()
+	level, ok := levels[component]
+	if !ok {
+		level = root
+	}
+	handler := base
+	mu.RUnlock()
+
+	return slog.New(levelHandler{level: level, Handler: handler}).With("component", component)
+}
+
+// levelHandler drops records below its level and adds the request ID of the context
+type levelHandler struct {
+	level slog.Level
+	slog.Handler
+}
+
+func (h levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{level: h.level, Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{level: h.level, Handler: h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the request it belongs to
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of ctx, if any
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,157 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupLogging captures the records logged during the test
+func setupLogging(t *testing.T, environ ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+	Configure(&buf, "json", environ)
+	t.Cleanup(func() {
+		Configure(os.Stdout, "json", nil)
+	})
+	return &buf
+}
+
+// records decodes the JSON lines written to buf
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		result = append(result, record)
+	}
+	return result
+}
+
+func TestComponentLevels(t *testing.T) {
+	buf := setupLogging(t, "LOG_LEVEL=warn", "LOG_LEVEL_DB=debug", "LOG_LEVEL_JOBS=bogus", "PATH=/bin")
+
+	For(ComponentDB).Debug("db debug")
+	For(ComponentJobs).Info("jobs info")
+	For(ComponentJobs).Warn("jobs warn")
+
+	logged := records(t, buf)
+	if assert.Len(t, logged, 2) {
+		assert.Equal(t, "db debug", logged[0]["msg"])
+		assert.Equal(t, "db", logged[0]["component"])
+		// An invalid level falls back to LOG_LEVEL
+		assert.Equal(t, "jobs warn", logged[1]["msg"])
+		assert.Equal(t, "WARN", logged[1]["level"])
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	buf := setupLogging(t)
+
+	ctx := WithRequestID(context.Background(), "abc-123")
+	For(ComponentHTTP).With("extra", 1).InfoContext(ctx, "with id")
+	For(ComponentHTTP).Info("without id")
+
+	logged := records(t, buf)
+	if assert.Len(t, logged, 2) {
+		assert.Equal(t, "abc-123", logged[0]["request_id"])
+		assert.NotContains(t, logged[1], "request_id")
+	}
+	assert.Equal(t, "", RequestID(nil))
+}
+
+func TestMiddleware(t *testing.T) {
+	buf := setupLogging(t)
+
+	app := fiber.New()
+	app.Use(requestid.New())
+	app.Use(Middleware())
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		For(ComponentDB).InfoContext(c.UserContext(), "inside handler")
+		return c.SendString("ok")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return errors.New("boom")
+	})
+
+	req := httptest.NewRequest("GET", "/items/1", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "req-1")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/fail", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 500, resp.StatusCode)
+
+	logged := records(t, buf)
+	if !assert.Len(t, logged, 3) {
+		return
+	}
+	assert.Equal(t, "inside handler", logged[0]["msg"])
+	assert.Equal(t, "req-1", logged[0]["request_id"])
+
+	assert.Equal(t, "request", logged[1]["msg"])
+	assert.Equal(t, "http", logged[1]["component"])
+	assert.Equal(t, "req-1", logged[1]["request_id"])
+	assert.Equal(t, "/items/1", logged[1]["path"])
+	assert.Equal(t, float64(200), logged[1]["status"])
+
+	// Errors are rendered before the access log is written, so it has the final status
+	assert.Equal(t, float64(500), logged[2]["status"])
+	assert.NotEmpty(t, logged[2]["request_id"])
+}
+
+func TestGormLogger(t *testing.T) {
+	buf := setupLogging(t)
+
+	gormLogger := NewGormLogger()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger})
+	assert.NoError(t, err)
+	ctx := WithRequestID(context.Background(), "req-2")
+
+	// Fast queries are only logged at debug level
+	assert.NoError(t, db.WithContext(ctx).Exec("CREATE TABLE items (name TEXT)").Error)
+	assert.Empty(t, buf.String())
+
+	assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing").Error)
+	gormLogger.SlowThreshold = time.Nanosecond
+	assert.NoError(t, db.WithContext(ctx).Exec("INSERT INTO items (name) VALUES (?)", "secret").Error)
+
+	logged := records(t, buf)
+	if !assert.Len(t, logged, 2) {
+		return
+	}
+	assert.Equal(t, "query failed", logged[0]["msg"])
+	assert.Equal(t, "ERROR", logged[0]["level"])
+	assert.Equal(t, "db", logged[0]["component"])
+	assert.Equal(t, "req-2", logged[0]["request_id"])
+	assert.Contains(t, logged[0]["error"], "no such table")
+
+	assert.Equal(t, "slow query", logged[1]["msg"])
+	assert.Equal(t, "WARN", logged[1]["level"])
+	// Parameter values stay out of the log
+	assert.NotContains(t, logged[1]["sql"], "secret")
+}
+
+func TestNewGormLoggerThreshold(t *testing.T) {
+	t.Setenv("SLOW_QUERY_THRESHOLD", "1s")
+	assert.Equal(t, time.Second, NewGormLogger().SlowThreshold)
+
+	t.Setenv("SLOW_QUERY_THRESHOLD", "soon")
+	assert.Equal(t, DefaultSlowQueryThreshold, NewGormLogger().SlowThreshold)
+}
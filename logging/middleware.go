@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware writes an access log record for every request. It runs after the
+// requestid middleware and puts the request ID on c.UserContext(), so every
+// record logged with that context, including GORM's, carries it.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		id := c.GetRespHeader(fiber.HeaderXRequestID)
+		if id != "" {
+			c.SetUserContext(WithRequestID(c.UserContext(), id))
+		}
+
+		// Render the error here, like Fiber's logger does, so the status code is final
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		For(ComponentHTTP).LogAttrs(c.UserContext(), slog.LevelInfo, "request",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", c.Response().StatusCode()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", len(c.Response().Body())),
+			slog.String("ip", c.IP()),
+		)
+		return nil
+	}
+}
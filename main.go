@@ -1,164 +1,52 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
-	"time"
 
-	"expense-api/database"
-	"expense-api/handlers"
+	"expense-api/logging"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/joho/godotenv"
 )
 
-func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
-	}
-
-	// Create Fiber app
-	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
-	})
-
-	// Middleware
-	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,PATCH,DELETE,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-	}))
-
-	// Health check endpoint (works without database)
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":    "healthy",
-			"message":   "Expense API is running",
-			"timestamp": time.Now().UTC(),
-			"database":  "connecting...",
-		})
-	})
-
-	// Database status endpoint
-	app.Get("/db-status", func(c *fiber.Ctx) error {
-		if database.GetDB() != nil {
-			sqlDB, err := database.GetDB().DB()
-			if err == nil {
-				err = sqlDB.Ping()
-				if err == nil {
-					return c.JSON(fiber.Map{
-						"status":    "connected",
-						"message":   "Database is connected and responding",
-						"timestamp": time.Now().UTC(),
-					})
-				}
-			}
-		}
-		return c.JSON(fiber.Map{
-			"status":    "disconnected",
-			"message":   "Database is not connected",
-			"timestamp": time.Now().UTC(),
-		})
-	})
-
-	// API routes
-	api := app.Group("/api")
-
-	// Bank Account routes
-	bankAccounts := api.Group("/bank-accounts")
-	bankAccounts.Post("/", func(c *fiber.Ctx) error {
-		if database.GetDB() == nil {
-			return c.Status(503).JSON(fiber.Map{"error": "Database not ready"})
-		}
-		return handlers.CreateBankAccount(database.GetDB())(c)
-	})
-	bankAccounts.Get("/", func(c *fiber.Ctx) error {
-		if database.GetDB() == nil {
-			return c.Status(503).JSON(fiber.Map{"error": "Database not ready"})
-		}
-		return handlers.GetBankAccounts(database.GetDB())(c)
-	})
-	bankAccounts.Get("/:id", func(c *fiber.Ctx) error {
-		if database.GetDB() == nil {
-			return c.Status(503).JSON(fiber.Map{"error": "Database not ready"})
-		}
-		return handlers.GetBankAccount(database.GetDB())(c)
-	})
-	bankAccounts.Put("/:id", func(c *fiber.Ctx) error {
-		if database.GetDB() == nil {
-			return c.Status(503).JSON(fiber.Map{"error": "Database not ready"})
-		}
-		return handlers.UpdateBankAccount(database.GetDB())(c)
-	})
-	bankAccounts.Delete("/:id", func(c *fiber.Ctx) error {
-		if database.GetDB() == nil {
-			return c.Status(503).JSON(fiber.Map{"error": "Database not ready"})
-		}
-		return handlers.DeleteBankAccount(database.GetDB())(c)
-	})
+const usage = `usage: expense-api [command]
 
-	// Transaction routes
-	transactions := api.Group("/transactions")
-	transactions.Post("/", handlers.CreateTransaction)
-	transactions.Post("/bulk", handlers.CreateBulkTransactions)
-	transactions.Post("/transfer", handlers.CreateTransfer)
-	transactions.Delete("/bulk", handlers.DeleteBulkTransactions)
-	transactions.Get("/", handlers.GetTransactions)
-	transactions.Get("/transfers", handlers.GetTransfers)
-	transactions.Get("/aggregate", handlers.GetTransactionsAggregate)
-	transactions.Get("/aggregate-table", handlers.GetTransactionsAggregateTable)
-	transactions.Get("/date-range", handlers.GetTransactionsByDateRange)
-	transactions.Get("/:id", handlers.GetTransaction)
-	transactions.Put("/:id", handlers.UpdateTransaction)
-	transactions.Patch("/:id/category", handlers.UpdateTransactionCategory)
-	transactions.Delete("/:id", handlers.DeleteTransaction)
+commands:
+  serve       run the API server (default)
+  migrate     apply, roll back or list database migrations
+  seed        load default or demo data
+  admin       create users and check or repair stored data`
 
-	// Category routes
-	categories := api.Group("/categories")
-	categories.Post("/", handlers.CreateCategory)
-	categories.Get("/", handlers.GetCategories)
-	categories.Get("/:id", handlers.GetCategory)
-	categories.Put("/:id", handlers.UpdateCategory)
-	categories.Delete("/:id", handlers.DeleteCategory)
+// commands maps each subcommand to the function that runs it with the remaining arguments
+var commands = map[string]func(args []string) error{
+	"serve":   runServe,
+	"migrate": runMigrate,
+	"seed":    runSeed,
+	"admin":   runAdmin,
+}
 
-	// Get port from environment variable
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+func main() {
+	// Load environment variables
+	envErr := godotenv.Load()
+	// Logging is configured from the environment, so set it up once .env is loaded
+	logging.Setup()
+	if envErr != nil {
+		slog.Info("No .env file found, using system environment variables")
 	}
 
-	log.Printf("Server starting on port %s", port)
-
-	// Start server in a goroutine
-	go func() {
-		log.Fatal(app.Listen(":" + port))
-	}()
-
-	// Wait a moment for server to start
-	time.Sleep(2 * time.Second)
-
-	// Initialize database in background (non-blocking)
-	go func() {
-		log.Println("Attempting to connect to database...")
-		database.Connect()
-		database.Migrate()
-		database.SeedDefaultCategories()
-		database.SeedDefaultBankAccounts()
-		log.Println("Database initialization completed")
-	}()
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
 
-	// Keep main thread alive
-	select {}
+	if err := command(args); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// queryStartKey stores when a statement started on its instance
+const queryStartKey = "metrics:query_start"
+
+// GormPlugin returns a GORM plugin that observes the duration of every query
+func (m *Metrics) GormPlugin() gorm.Plugin {
+	return &gormPlugin{metrics: m}
+}
+
+type gormPlugin struct {
+	metrics *Metrics
+}
+
+// Name implements gorm.Plugin
+func (p *gormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize registers callbacks around every kind of statement
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, r := range registrations {
+		if err := r.before("metrics:before_"+r.operation, p.start); err != nil {
+			return err
+		}
+		if err := r.after("metrics:after_"+r.operation, p.observe(r.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *gormPlugin) start(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (p *gormPlugin) observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		p.metrics.dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveDB observes the queries on db and reports its connection pool stats
+func (m *Metrics) ObserveDB(db *gorm.DB) error {
+	if err := db.Use(m.GormPlugin()); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return m.Registry.Register(collectors.NewDBStatsCollector(sqlDB, Namespace))
+}
@@ -0,0 +1,154 @@
+// Package metrics collects Prometheus metrics for the expense API and serves them on /metrics.
+package metrics
+
+import (
+	"expense-api/events"
+	"expense-api/jobs"
+	"expense-api/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric name
+const Namespace = "expense"
+
+// Metrics holds the collectors of one registry
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpDuration        *prometheus.HistogramVec
+	dbQueryDuration     *prometheus.HistogramVec
+	transactionsCreated *prometheus.CounterVec
+	bulkRowsFailed      *prometheus.CounterVec
+}
+
+// Default holds the metrics served by the API
+var Default = New()
+
+// New creates a registry with the API metrics and the Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database query duration by operation and table.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		transactionsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "transactions_created_total",
+			Help:      "Transactions created by type.",
+		}, []string{"type"}),
+		bulkRowsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "bulk_rows_failed_total",
+			Help:      "Rows of bulk creates that failed, by source (request or job) and error code.",
+		}, []string{"source", "code"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbQueryDuration,
+		m.transactionsCreated,
+		m.bulkRowsFailed,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{}))
+}
+
+// Listen counts the transactions and transfers created, as published on the bus
+func (m *Metrics) Listen(bus *events.Bus) {
+	bus.Listen(func(event events.Event) {
+		switch event.Type {
+		case events.TransactionCreated:
+			if transaction, ok := event.Data.(models.TransactionResponse); ok {
+				m.transactionsCreated.WithLabelValues(transaction.Type).Inc()
+			}
+		case events.TransferCreated:
+			m.transactionsCreated.WithLabelValues("transfer").Inc()
+		}
+	})
+}
+
+// RecordBulkFailures counts the failed rows of a bulk create on the default metrics
+func RecordBulkFailures(source string, failed []models.BulkTransactionError) {
+	Default.RecordBulkFailures(source, failed)
+}
+
+// RecordBulkFailures counts the failed rows of a bulk create by error code
+func (m *Metrics) RecordBulkFailures(source string, failed []models.BulkTransactionError) {
+	for _, row := range failed {
+		m.bulkRowsFailed.WithLabelValues(source, row.Code).Inc()
+	}
+}
+
+// ObserveJobs reports the number of queued and running background jobs on every scrape
+func (m *Metrics) ObserveJobs(runner *jobs.Runner) error {
+	return m.Registry.Register(&jobQueueCollector{runner: runner})
+}
+
+var jobQueueDepth = prometheus.NewDesc(
+	prometheus.BuildFQName(Namespace, "jobs", "queue_depth"),
+	"Background jobs waiting or being performed, by status.",
+	[]string{"status"}, nil,
+)
+
+// jobQueueCollector counts jobs in the database when scraped
+type jobQueueCollector struct {
+	runner *jobs.Runner
+}
+
+func (c *jobQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jobQueueDepth
+}
+
+func (c *jobQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	db := c.runner.DB()
+	if db == nil {
+		return
+	}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := db.Model(&models.Job{}).Select("status, COUNT(*) AS count").
+		Where("status IN ?", []string{jobs.StatusQueued, jobs.StatusRunning}).
+		Group("status").Scan(&rows).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(jobQueueDepth, err)
+		return
+	}
+
+	counts := map[string]int64{jobs.StatusQueued: 0, jobs.StatusRunning: 0}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(jobQueueDepth, prometheus.GaugeValue, float64(count), status)
+	}
+}
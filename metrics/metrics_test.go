@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"expense-api/apperrors"
+	"expense-api/events"
+	"expense-api/jobs"
+	"expense-api/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	assert.NoError(t, db.AutoMigrate(&models.Job{}))
+	return db
+}
+
+// scrape returns the metrics as served on /metrics
+func scrape(t *testing.T, m *Metrics) string {
+	app := fiber.New()
+	app.Get("/metrics", m.Handler())
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestMiddlewareLabelsRequestsByRoutePattern(t *testing.T) {
+	m := New()
+	app := fiber.New(fiber.Config{ErrorHandler: apperrors.Handler})
+	app.Use(m.Middleware())
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "0" {
+			return apperrors.NotFound(apperrors.CodeTransactionNotFound, "Item not found")
+		}
+		return c.SendString("item")
+	})
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/0", "/unknown/1", "/unknown/2"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/items/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/items/:id", "404")))
+	// Unknown paths share one series instead of one per path
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.httpRequests))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpDuration))
+
+	metrics := scrape(t, m)
+	assert.Contains(t, metrics, `expense_http_requests_total{method="GET",route="/items/:id",status="200"} 2`)
+	assert.Contains(t, metrics, "expense_http_request_duration_seconds_bucket")
+	assert.Contains(t, metrics, "go_goroutines")
+}
+
+func TestObserveDB(t *testing.T) {
+	m := New()
+	db := setupTestDB(t)
+	assert.NoError(t, m.ObserveDB(db))
+
+	job := models.Job{Type: "test", Status: jobs.StatusQueued, Payload: "{}"}
+	assert.NoError(t, db.Create(&job).Error)
+	assert.NoError(t, db.First(&models.Job{}, job.ID).Error)
+	assert.NoError(t, db.Model(&job).Update("status", jobs.StatusRunning).Error)
+
+	count, err := testutil.GatherAndCount(m.Registry, "expense_db_query_duration_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	metrics := scrape(t, m)
+	for _, operation := range []string{"create", "query", "update"} {
+		assert.Contains(t, metrics, `expense_db_query_duration_seconds_count{operation="`+operation+`",table="jobs"} 1`)
+	}
+
+	count, err = testutil.GatherAndCount(m.Registry, "go_sql_open_connections")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestObserveJobs(t *testing.T) {
+	m := New()
+	db := setupTestDB(t)
+	runner := jobs.NewRunner(func() *gorm.DB { return db })
+	assert.NoError(t, m.ObserveJobs(runner))
+
+	for i := 0; i < 3; i++ {
+		_, err := runner.Enqueue("test", 0, nil, 1)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, db.Model(&models.Job{}).Where("id = ?", 1).Update("status", jobs.StatusRunning).Error)
+	assert.NoError(t, db.Model(&models.Job{}).Where("id = ?", 2).Update("status", jobs.StatusSucceeded).Error)
+
+	expected := `
+# HELP expense_jobs_queue_depth Background jobs waiting or being performed, by status.
+# TYPE expense_jobs_queue_depth gauge
+expense_jobs_queue_depth{status="queued"} 1
+expense_jobs_queue_depth{status="running"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry, strings.NewReader(expected), "expense_jobs_queue_depth"))
+}
+
+func TestBusinessMetrics(t *testing.T) {
+	m := New()
+	bus := events.NewBus(10)
+	m.Listen(bus)
+
+	bus.Publish(events.TransactionCreated, models.TransactionResponse{Type: "expense"})
+	bus.Publish(events.TransactionCreated, models.TransactionResponse{Type: "expense"})
+	bus.Publish(events.TransactionCreated, models.TransactionResponse{Type: "income"})
+	bus.Publish(events.TransferCreated, models.TransferResponse{})
+	bus.Publish(events.TransactionDeleted, fiber.Map{"id": 1})
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.transactionsCreated.WithLabelValues("expense")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.transactionsCreated.WithLabelValues("income")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.transactionsCreated.WithLabelValues("transfer")))
+
+	m.RecordBulkFailures("request", []models.BulkTransactionError{
+		{Index: 0, Code: apperrors.CodeValidationFailed},
+		{Index: 2, Code: apperrors.CodeValidationFailed},
+		{Index: 3, Code: apperrors.CodeCategoryNotFound},
+	})
+	m.RecordBulkFailures("job", nil)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.bulkRowsFailed.WithLabelValues("request", apperrors.CodeValidationFailed)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.bulkRowsFailed.WithLabelValues("request", apperrors.CodeCategoryNotFound)))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.bulkRowsFailed))
+}
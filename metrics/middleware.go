@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// unmatchedRoute labels requests no route matched, so unknown paths do not add series
+const unmatchedRoute = "unmatched"
+
+// Middleware counts requests and observes their latency, labelled by the
+// route pattern (e.g. /api/transactions/:id) rather than the raw path.
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		// Render the error here, like the logger does, so the status code is final
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		route := c.Route().Path
+		// The router answers unknown paths with a plain fiber 404
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusNotFound {
+			route = unmatchedRoute
+		}
+
+		status := strconv.Itoa(c.Response().StatusCode())
+		m.httpRequests.WithLabelValues(c.Method(), route, status).Inc()
+		m.httpDuration.WithLabelValues(c.Method(), route).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+// Scopes a transaction is compared within for anomalies
+const (
+	AnomalyScopeCategory = "category"
+	AnomalyScopePayee    = "payee"
+)
+
+// AnomalyReason compares the amount of a transaction with the earlier ones of
+// its category or payee
+type AnomalyReason struct {
+	Scope string `json:"scope"`
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	// Samples is the number of earlier transactions in the trailing window
+	Samples int     `json:"samples"`
+	Median  float64 `json:"median"`
+	// MAD is the median absolute deviation of the earlier amounts from their median
+	MAD float64 `json:"mad"`
+	// Score is the robust z-score of the amount
+	Score float64 `json:"score"`
+	// Ratio is the amount divided by the median, e.g. 5 for five times the usual
+	Ratio float64 `json:"ratio"`
+}
+
+// AnomalyResponse is a transaction whose amount is out of character
+type AnomalyResponse struct {
+	Transaction TransactionResponse `json:"transaction"`
+	// Score is the highest score among the reasons
+	Score   float64         `json:"score"`
+	Reasons []AnomalyReason `json:"reasons"`
+}
+
+// AnomaliesResponse lists the anomalous transactions found by GET /insights/anomalies
+type AnomaliesResponse struct {
+	DateRange  DateRange         `json:"date_range"`
+	Type       string            `json:"type"`
+	WindowDays int               `json:"window_days"`
+	Threshold  float64           `json:"threshold"`
+	Anomalies  []AnomalyResponse `json:"anomalies"`
+}
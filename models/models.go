@@ -74,55 +74,77 @@ func (fd *FlexibleDate) Scan(value interface{}) error {
 
 // BankAccount represents a bank account
 type BankAccount struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	Name          string         `json:"name" gorm:"not null"`
-	AccountNumber string         `json:"account_number"`
-	BankName      string         `json:"bank_name" gorm:"not null"`
-	AccountType   string         `json:"account_type" gorm:"not null;check:account_type IN ('checking', 'savings', 'credit', 'investment', 'other')"`
-	Balance       float64        `json:"balance" gorm:"default:0"`
-	IsActive      bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Name           string         `json:"name" gorm:"not null" validate:"required"`
+	AccountNumber  string         `json:"account_number"`
+	BankName       string         `json:"bank_name" gorm:"not null" validate:"required"`
+	AccountType    string         `json:"account_type" gorm:"not null;check:account_type IN ('checking', 'savings', 'credit', 'investment', 'other')" validate:"required,account_type"`
+	Balance        float64        `json:"balance" gorm:"default:0"`
+	OpeningBalance float64        `json:"opening_balance" gorm:"default:0"` // Balance before the first recorded transaction
+	IsActive       bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 // Transaction represents an expense, income, or transfer transaction
 type Transaction struct {
 	ID                      uint         `json:"id" gorm:"primaryKey"`
 	TransactionID           string       `json:"transaction_id" gorm:"index"`
-	Amount                  float64      `json:"amount" gorm:"not null"`
-	Type                    string       `json:"type" gorm:"not null;check:type IN ('expense', 'income', 'transfer')"`
+	Amount                  float64      `json:"amount" gorm:"not null" validate:"positive"`
+	Type                    string       `json:"type" gorm:"not null;check:type IN ('expense', 'income', 'transfer')" validate:"required,transaction_type"`
 	CategoryID              *uint        `json:"category_id"` // Nullable for transfers
-	Category                Category     `json:"category" gorm:"foreignKey:CategoryID"`
-	BankAccountID           uint         `json:"bank_account_id" gorm:"not null"`
-	BankAccount             BankAccount  `json:"bank_account" gorm:"foreignKey:BankAccountID"`
+	Category                Category     `json:"category" gorm:"foreignKey:CategoryID" validate:"-"`
+	BankAccountID           uint         `json:"bank_account_id" gorm:"not null" validate:"required"`
+	BankAccount             BankAccount  `json:"bank_account" gorm:"foreignKey:BankAccountID" validate:"-"`
 	DestinationBankAccountID *uint       `json:"destination_bank_account_id"` // For transfers
-	DestinationBankAccount  BankAccount  `json:"destination_bank_account" gorm:"foreignKey:DestinationBankAccountID"`
+	DestinationBankAccount  BankAccount  `json:"destination_bank_account" gorm:"foreignKey:DestinationBankAccountID" validate:"-"`
+	PayeeID                 *uint        `json:"payee_id" gorm:"index"` // Matched from the description when not given
+	Payee                   Payee        `json:"payee" gorm:"foreignKey:PayeeID" validate:"-"`
 	Description             string       `json:"description" gorm:"not null"`
 	Date                    FlexibleDate `json:"date" gorm:"not null"`
 	CreatedAt               time.Time    `json:"created_at"`
 	UpdatedAt               time.Time    `json:"updated_at"`
+	DeletedAt               gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 // Category represents a transaction category
 type Category struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"not null;unique"`
-	Type      string         `json:"type" gorm:"not null;check:type IN ('expense', 'income')"`
+	Name      string         `json:"name" gorm:"not null;uniqueIndex:idx_categories_live_parent_name,priority:2" validate:"required"`
+	Type      string         `json:"type" gorm:"not null;check:type IN ('expense', 'income')" validate:"required,category_type"`
+	// ParentID nests the category under another category of the same type; names are unique among
+	// live siblings, with top-level categories counted as siblings under parent 0. The comma in
+	// COALESCE is escaped for both levels of GORM's tag parsing.
+	ParentID  *uint          `json:"parent_id" gorm:"uniqueIndex:idx_categories_live_parent_name,priority:1,expression:COALESCE(parent_id\\,0),where:deleted_at IS NULL"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// BankAccountRequest is the body of bank account create and update requests.
+// The balances are pointers so an explicit 0 can be told apart from an omitted field.
+// Updates only check the account type.
+type BankAccountRequest struct {
+	Name           string   `json:"name" validate:"required"`
+	AccountNumber  string   `json:"account_number"`
+	BankName       string   `json:"bank_name" validate:"required"`
+	AccountType    string   `json:"account_type" validate:"required,account_type"`
+	Balance        *float64 `json:"balance"`
+	OpeningBalance *float64 `json:"opening_balance"`
+	IsActive       bool     `json:"is_active"`
+}
+
 // BankAccountResponse represents the response structure for bank accounts
 type BankAccountResponse struct {
-	ID            uint    `json:"id"`
-	Name          string  `json:"name"`
-	AccountNumber string  `json:"account_number"`
-	BankName      string  `json:"bank_name"`
-	AccountType   string  `json:"account_type"`
-	Balance       float64 `json:"balance"`
-	IsActive      bool    `json:"is_active"`
+	ID             uint    `json:"id"`
+	Name           string  `json:"name"`
+	AccountNumber  string  `json:"account_number"`
+	BankName       string  `json:"bank_name"`
+	AccountType    string  `json:"account_type"`
+	Balance        float64 `json:"balance"`
+	OpeningBalance float64 `json:"opening_balance"`
+	IsActive       bool    `json:"is_active"`
 }
 
 // TransactionResponse represents the response structure for transactions
@@ -137,6 +159,8 @@ type TransactionResponse struct {
 	BankAccount             BankAccountResponse   `json:"bank_account"`
 	DestinationBankAccountID *uint                `json:"destination_bank_account_id"`
 	DestinationBankAccount  *BankAccountResponse  `json:"destination_bank_account"`
+	PayeeID                 *uint                 `json:"payee_id"`
+	Payee                   string                `json:"payee"`
 	Description             string                `json:"description"`
 	Date                    time.Time             `json:"date"`
 	CreatedAt               time.Time             `json:"created_at"`
@@ -144,9 +168,33 @@ type TransactionResponse struct {
 
 // CategoryResponse represents the response structure for categories
 type CategoryResponse struct {
-	ID   uint   `json:"id"`
-	Name string `json:"name"`
-	Type string `json:"type"`
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// CategoryTreeNode is a category with its subcategories, as listed by GET /categories?tree=true
+type CategoryTreeNode struct {
+	CategoryResponse
+	Children []CategoryTreeNode `json:"children"`
+}
+
+// CategoryMergeRequest names the category another one is merged into
+type CategoryMergeRequest struct {
+	TargetID uint `json:"target_id" validate:"required"`
+}
+
+// CategoryMergeResponse reports what POST /categories/:id/merge changed
+type CategoryMergeResponse struct {
+	Target                 CategoryResponse `json:"target"`
+	ReassignedTransactions int64            `json:"reassigned_transactions"`
+	MovedSubcategories     int              `json:"moved_subcategories"`
+}
+
+// CategoryMoveRequest moves a category under a new parent, or to the top level when ParentID is null
+type CategoryMoveRequest struct {
+	ParentID *uint `json:"parent_id"`
 }
 
 // AggregateResponse represents the aggregation response
@@ -157,6 +205,9 @@ type AggregateResponse struct {
 	NetAmount     float64            `json:"net_amount"`
 }
 
+// MaxBulkTransactions is the most transactions a bulk request may create; it matches the max of BulkTransactionRequest.Transactions
+const MaxBulkTransactions = 5000
+
 // BulkTransactionRequest represents a request to create multiple transactions
 type BulkTransactionRequest struct {
 	Transactions []Transaction `json:"transactions" validate:"required,min=1,max=5000"`
@@ -175,7 +226,9 @@ type BulkTransactionResponse struct {
 type BulkTransactionError struct {
 	Index       int    `json:"index"`
 	Transaction Transaction `json:"transaction"`
+	Code        string `json:"code"`
 	Error       string `json:"error"`
+	Fields      []FieldError `json:"fields,omitempty"`
 }
 
 // BulkDeleteRequest represents a request to delete multiple transactions
@@ -195,6 +248,7 @@ type BulkDeleteResponse struct {
 // BulkDeleteError represents an error for a specific transaction ID in bulk delete operation
 type BulkDeleteError struct {
 	TransactionID uint   `json:"transaction_id"`
+	Code          string `json:"code"`
 	Error         string `json:"error"`
 }
 
@@ -202,6 +256,9 @@ type BulkDeleteError struct {
 type CategoryAggregate struct {
 	CategoryID       uint    `json:"category_id"`
 	CategoryName     string  `json:"category_name"`
+	// CategoryPath names the category with its ancestors, e.g. "Bills / Electricity"
+	CategoryPath     string  `json:"category_path"`
+	ParentID         *uint   `json:"parent_id"`
 	TotalAmount      float64 `json:"total_amount"`
 	TransactionCount int     `json:"transaction_count"`
 }
@@ -233,7 +290,7 @@ type AggregateTableResponse struct {
 
 // TransferRequest represents a request to create a transfer between accounts
 type TransferRequest struct {
-	Amount                  float64      `json:"amount" validate:"required,gt=0"`
+	Amount                  float64      `json:"amount" validate:"required,positive"`
 	BankAccountID           uint         `json:"bank_account_id" validate:"required"`
 	DestinationBankAccountID uint        `json:"destination_bank_account_id" validate:"required"`
 	Description             string       `json:"description" validate:"required"`
@@ -251,4 +308,124 @@ type TransferResponse struct {
 	Description             string               `json:"description"`
 	Date                    time.Time            `json:"date"`
 	CreatedAt               time.Time            `json:"created_at"`
-} 
\ No newline at end of file
+} 
+// WebhookSubscription represents an outgoing webhook endpoint and the events it receives
+type WebhookSubscription struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"-" gorm:"not null;default:0;index"` // Owner, who only receives the events they may see; 0 when authentication is off
+	URL       string         `json:"url" gorm:"not null"`
+	Secret    string         `json:"-" gorm:"not null"`
+	Events    string         `json:"events" gorm:"not null"` // Comma-separated event names, "*" for all
+	IsActive  bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// WebhookDelivery represents a queued or attempted webhook delivery
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null;index"`
+	Event          string     `json:"event" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:text;not null"`
+	Status         string     `json:"status" gorm:"not null;index;check:status IN ('pending', 'succeeded', 'failed')"`
+	Attempts       int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookSubscriptionRequest represents a request to create or update a webhook subscription
+type WebhookSubscriptionRequest struct {
+	URL      string   `json:"url" validate:"required,http_url"`
+	Secret   string   `json:"secret"`
+	Events   []string `json:"events" validate:"required,min=1"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// WebhookSubscriptionResponse represents the response structure for webhook subscriptions
+type WebhookSubscriptionResponse struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the subscription is created
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Job represents a background job and its progress
+type Job struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"-" gorm:"not null;default:0;index"` // User who queued the job; 0 when authentication is off
+	Type        string     `json:"type" gorm:"not null;index"`
+	Status      string     `json:"status" gorm:"not null;index;check:status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')"`
+	Payload     string     `json:"-" gorm:"type:text;not null"` // JSON input of the job
+	Result      string     `json:"-" gorm:"type:text"`          // JSON output, updated as the job progresses
+	Total       int        `json:"total"`
+	Processed   int        `json:"processed"`
+	Attempts    int        `json:"attempts" gorm:"default:0"`
+	LastError   string     `json:"last_error"`
+	StartedAt   *time.Time `json:"started_at"`
+	HeartbeatAt *time.Time `json:"-"` // Last time the worker running the job reported in
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// BulkJobPayload is the input of a bulk transaction import job
+type BulkJobPayload struct {
+	Transactions []Transaction `json:"transactions"`
+	Atomic       bool          `json:"atomic"`
+}
+
+// JobResponse represents the response structure for background jobs
+type JobResponse struct {
+	ID         uint                     `json:"id"`
+	Type       string                   `json:"type"`
+	Status     string                   `json:"status"`
+	Total      int                      `json:"total"`
+	Processed  int                      `json:"processed"`
+	Attempts   int                      `json:"attempts"`
+	LastError  string                   `json:"last_error,omitempty"`
+	Result     *BulkTransactionResponse `json:"result,omitempty"` // Counts and rejected rows so far; created rows once finished
+	StartedAt  *time.Time               `json:"started_at"`
+	FinishedAt *time.Time               `json:"finished_at"`
+	CreatedAt  time.Time                `json:"created_at"`
+}
+
+// IdempotencyRecord stores the response to a request sent with an Idempotency-Key header
+type IdempotencyRecord struct {
+	Client       string    `json:"client" gorm:"primaryKey;size:64"` // Who sent the key: a verified user, or else an IP address
+	Key          string    `json:"key" gorm:"primaryKey;column:idempotency_key;size:255"`
+	Fingerprint  string    `json:"fingerprint" gorm:"not null"` // Hash of the method, URL and body
+	Status       string    `json:"status" gorm:"not null;check:status IN ('in_progress', 'completed')"`
+	ResponseCode int       `json:"response_code"`
+	ContentType  string    `json:"content_type"`
+	Location     string    `json:"location"`
+	ResponseBody string    `json:"response_body" gorm:"type:text"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// FieldError describes one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 error response with a stable machine-readable code
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	RequestID string       `json:"request_id"`
+	Errors    []FieldError `json:"errors,omitempty"` // Every invalid field when Code is VALIDATION_FAILED
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Payee is a merchant or person transactions are paid to or received from.
+// Raw bank descriptions such as "AMZN Mktp IN*2K3" are linked to a payee by its aliases.
+type Payee struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"not null;uniqueIndex" validate:"required"`
+	// DefaultCategoryID is used for new transactions of the payee that have no category
+	DefaultCategoryID *uint        `json:"default_category_id"`
+	Aliases           []PayeeAlias `json:"aliases" gorm:"foreignKey:PayeeID;constraint:OnDelete:CASCADE" validate:"-"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+}
+
+// PayeeAlias is a pattern matched against transaction descriptions, e.g. "AMZN MKTP*".
+// A "*" matches the rest of a word.
+type PayeeAlias struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PayeeID   uint      `json:"payee_id" gorm:"not null;index"`
+	Pattern   string    `json:"pattern" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PayeeRequest creates a payee or replaces every field of one
+type PayeeRequest struct {
+	Name              string   `json:"name" validate:"required,max=100"`
+	DefaultCategoryID *uint    `json:"default_category_id"`
+	Aliases           []string `json:"aliases" validate:"max=100,dive,required,max=100"`
+}
+
+// PayeeResponse represents the response structure for payees
+type PayeeResponse struct {
+	ID                uint     `json:"id"`
+	Name              string   `json:"name"`
+	DefaultCategoryID *uint    `json:"default_category_id"`
+	Aliases           []string `json:"aliases"`
+}
+
+// PayeeMergeRequest names the payee another one is merged into
+type PayeeMergeRequest struct {
+	TargetID uint `json:"target_id" validate:"required"`
+}
+
+// PayeeMergeResponse reports what POST /payees/:id/merge changed
+type PayeeMergeResponse struct {
+	Target                 PayeeResponse `json:"target"`
+	ReassignedTransactions int64         `json:"reassigned_transactions"`
+}
+
+// PayeeSpend is the spending with one payee in GET /reports/payees
+type PayeeSpend struct {
+	Rank             int     `json:"rank"`
+	PayeeID          uint    `json:"payee_id"`
+	PayeeName        string  `json:"payee_name"`
+	TotalAmount      float64 `json:"total_amount"`
+	TransactionCount int     `json:"transaction_count"`
+	// Share is the percentage of the period's total going to this payee
+	Share float64 `json:"share"`
+}
+
+// PayeeReportResponse ranks payees by the amount spent with them in a period
+type PayeeReportResponse struct {
+	DateRange   DateRange    `json:"date_range"`
+	Type        string       `json:"type"`
+	Payees      []PayeeSpend `json:"payees"`
+	TotalAmount float64      `json:"total_amount"`
+	// Unassigned totals the transactions without a payee
+	UnassignedAmount float64 `json:"unassigned_amount"`
+	UnassignedCount  int     `json:"unassigned_count"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// Cadences of subscriptions and recurring transactions
+const (
+	CadenceWeekly  = "weekly"
+	CadenceMonthly = "monthly"
+	CadenceYearly  = "yearly"
+)
+
+// RecurringTransaction posts the same expense at a regular cadence, e.g. a
+// subscription detected by GET /insights/subscriptions
+type RecurringTransaction struct {
+	ID            uint    `json:"id" gorm:"primaryKey"`
+	Description   string  `json:"description" gorm:"not null"`
+	Amount        float64 `json:"amount" gorm:"not null"`
+	CategoryID    *uint   `json:"category_id"`
+	BankAccountID uint    `json:"bank_account_id" gorm:"not null"`
+	PayeeID       *uint   `json:"payee_id"`
+	Cadence       string  `json:"cadence" gorm:"not null"`
+	// NextDate is the date of the next transaction to post
+	NextDate time.Time `json:"next_date" gorm:"not null;index"`
+	// AnchorDay is the day of the month monthly and yearly charges fall on; a
+	// shorter month moves a charge to its last day, but not the ones after it
+	AnchorDay int `json:"anchor_day"`
+	// SubscriptionKey is the key of the detected subscription the schedule was created from
+	SubscriptionKey string    `json:"subscription_key" gorm:"index"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// RecurringTransactionResponse represents the response structure for recurring transactions
+type RecurringTransactionResponse struct {
+	ID              uint      `json:"id"`
+	Description     string    `json:"description"`
+	Amount          float64   `json:"amount"`
+	CategoryID      *uint     `json:"category_id"`
+	BankAccountID   uint      `json:"bank_account_id"`
+	PayeeID         *uint     `json:"payee_id"`
+	Cadence         string    `json:"cadence"`
+	NextDate        time.Time `json:"next_date"`
+	SubscriptionKey string    `json:"subscription_key"`
+}
+
+// Subscription is a series of expenses with the same payee or description and
+// a similar amount at a regular cadence
+type Subscription struct {
+	// Key identifies the series in POST /insights/subscriptions/schedule
+	Key           string        `json:"key"`
+	Name          string        `json:"name"`
+	PayeeID       *uint         `json:"payee_id"`
+	CategoryID    *uint         `json:"category_id"`
+	BankAccountID uint          `json:"bank_account_id"`
+	Cadence       string        `json:"cadence"`
+	Occurrences   int           `json:"occurrences"`
+	AverageAmount float64       `json:"average_amount"`
+	LastAmount    float64       `json:"last_amount"`
+	FirstCharge   time.Time     `json:"first_charge"`
+	LastCharge    time.Time     `json:"last_charge"`
+	NextExpected  time.Time     `json:"next_expected"`
+	PriceChanges  []PriceChange `json:"price_changes"`
+	// Active is false once the next charge is overdue by 3 days for weekly, 10 days
+	// for monthly or 31 days for yearly subscriptions
+	Active bool `json:"active"`
+	// AnnualCost is the last amount charged for a year at the cadence
+	AnnualCost float64 `json:"annual_cost"`
+	// ScheduleID is the recurring transaction created from the series, if any
+	ScheduleID *uint `json:"schedule_id"`
+}
+
+// PriceChange is a charge of a subscription whose amount differs from the previous one
+type PriceChange struct {
+	Date      time.Time `json:"date"`
+	OldAmount float64   `json:"old_amount"`
+	NewAmount float64   `json:"new_amount"`
+}
+
+// SubscriptionsResponse lists the subscriptions found in GET /insights/subscriptions
+type SubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+	ActiveCount   int            `json:"active_count"`
+	// ActiveAnnualCost totals the annual cost of the active subscriptions
+	ActiveAnnualCost float64 `json:"active_annual_cost"`
+}
+
+// SubscriptionScheduleRequest names the detected subscription to turn into a recurring transaction
+type SubscriptionScheduleRequest struct {
+	Key string `json:"key" validate:"required"`
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Version operations recorded in the transaction history
+const (
+	VersionOperationCreate   = "create"
+	VersionOperationUpdate   = "update"
+	VersionOperationDelete   = "delete"
+	VersionOperationRestore  = "restore"
+	VersionOperationUndelete = "undelete"
+)
+
+// VersionOperationKey is the GORM setting used to label the operation of the next recorded version
+const VersionOperationKey = "transaction_version:operation"
+
+// VersionBatchKey is the GORM setting that stops AfterCreate from recording versions one row at a time.
+// Batch inserts that set it record the versions with RecordCreatedVersions instead.
+const VersionBatchKey = "transaction_version:batch"
+
+// TransactionVersion is a full snapshot of a Transaction row after a change
+type TransactionVersion struct {
+	ID                       uint         `json:"id" gorm:"primaryKey"`
+	RecordID                 uint         `json:"record_id" gorm:"not null;uniqueIndex:idx_transaction_versions_record_version"`
+	Version                  int          `json:"version" gorm:"not null;uniqueIndex:idx_transaction_versions_record_version"`
+	Operation                string       `json:"operation" gorm:"not null"`
+	TransactionID            string       `json:"transaction_id"`
+	Amount                   float64      `json:"amount"`
+	Type                     string       `json:"type"`
+	CategoryID               *uint        `json:"category_id"`
+	BankAccountID            uint         `json:"bank_account_id"`
+	DestinationBankAccountID *uint        `json:"destination_bank_account_id"`
+	Description              string       `json:"description"`
+	Date                     FlexibleDate `json:"date"`
+	Deleted                  bool         `json:"deleted"`
+	CreatedAt                time.Time    `json:"created_at"`
+}
+
+// AfterCreate records the initial version of a transaction
+func (t *Transaction) AfterCreate(tx *gorm.DB) error {
+	if batch, ok := tx.Get(VersionBatchKey); ok && batch == true {
+		return nil
+	}
+	return recordTransactionVersion(tx, t.ID, VersionOperationCreate)
+}
+
+// AfterUpdate records a new version after a transaction is modified
+func (t *Transaction) AfterUpdate(tx *gorm.DB) error {
+	return recordTransactionVersion(tx, t.ID, VersionOperationUpdate)
+}
+
+// AfterDelete records the soft-deleted state of a transaction
+func (t *Transaction) AfterDelete(tx *gorm.DB) error {
+	return recordTransactionVersion(tx, t.ID, VersionOperationDelete)
+}
+
+// recordTransactionVersion snapshots the current row for the given transaction ID
+func recordTransactionVersion(tx *gorm.DB, id uint, operation string) error {
+	if id == 0 {
+		return nil
+	}
+
+	// Allow callers to label restore/undelete updates explicitly
+	if op, ok := tx.Get(VersionOperationKey); ok {
+		if label, ok := op.(string); ok && label != "" {
+			operation = label
+		}
+	}
+
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	var current Transaction
+	if err := db.Unscoped().First(&current, id).Error; err != nil {
+		return err
+	}
+
+	var latest int
+	if err := db.Model(&TransactionVersion{}).Where("record_id = ?", id).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	version := NewTransactionVersion(current, latest+1, operation)
+	return db.Create(&version).Error
+}
+
+// RecordCreatedVersions records the first version of transactions that were just inserted
+// with VersionBatchKey set, in inserts of up to batchSize rows
+func RecordCreatedVersions(tx *gorm.DB, transactions []Transaction, batchSize int) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	versions := make([]TransactionVersion, len(transactions))
+	for i, transaction := range transactions {
+		versions[i] = NewTransactionVersion(transaction, 1, VersionOperationCreate)
+	}
+
+	return tx.Session(&gorm.Session{NewDB: true}).CreateInBatches(&versions, batchSize).Error
+}
+
+// NewTransactionVersion snapshots a transaction as the given version
+func NewTransactionVersion(current Transaction, version int, operation string) TransactionVersion {
+	return TransactionVersion{
+		RecordID:                 current.ID,
+		Version:                  version,
+		Operation:                operation,
+		TransactionID:            current.TransactionID,
+		Amount:                   current.Amount,
+		Type:                     current.Type,
+		CategoryID:               current.CategoryID,
+		BankAccountID:            current.BankAccountID,
+		DestinationBankAccountID: current.DestinationBankAccountID,
+		Description:              current.Description,
+		Date:                     current.Date,
+		Deleted:                  current.DeletedAt.Valid,
+	}
+}
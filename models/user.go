@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// User is a person or service that calls the API with its own token
+type User struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex"`
+	TokenHash string    `json:"-" gorm:"not null;uniqueIndex"` // SHA-256 of the API token, which is never stored
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
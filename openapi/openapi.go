@@ -0,0 +1,317 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"expense-api/models"
+
+	"gorm.io/gorm"
+)
+
+// Version of the OpenAPI specification produced by Build
+const Version = "3.1.0"
+
+// Operation describes one route of the API
+type Operation struct {
+	Method      string
+	Path        string // Fiber-style path, e.g. /api/transactions/:id
+	Summary     string
+	Description string
+	Tag         string
+	Query       []Param
+	Headers     []Param
+	Body        interface{} // Zero value of the request body type, nil if none
+	BodyType    string      // Request content type, application/json if empty
+	ContentType string      // Response content type, application/json if empty
+	Responses   []Response
+}
+
+// Param describes a query parameter
+type Param struct {
+	Name        string
+	Type        string // string, integer, number or boolean
+	Format      string
+	Description string
+	Required    bool
+}
+
+// Response describes one possible response of an operation
+type Response struct {
+	Status      int
+	Description string
+	Body        interface{} // Zero value of the response body type, nil if none
+	ContentType string      // Overrides the operation content type, e.g. for problem responses
+}
+
+// File is a file field of a multipart request body
+type File struct{}
+
+// Document is an OpenAPI document
+type Document map[string]interface{}
+
+var pathParamPattern = regexp.MustCompile(`:(\w+)`)
+
+// PathTemplate converts a Fiber path such as /api/transactions/:id to /api/transactions/{id}
+func PathTemplate(path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return pathParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+// Build generates an OpenAPI document for the given operations
+func Build(title, version string, operations []Operation) Document {
+	g := &generator{components: map[string]interface{}{}}
+	paths := map[string]map[string]interface{}{}
+
+	for _, op := range operations {
+		path := PathTemplate(op.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(op.Method)] = g.operation(op)
+	}
+
+	return Document{
+		"openapi": Version,
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.components,
+		},
+	}
+}
+
+// Has reports whether the document describes the given method and Fiber path
+func (d Document) Has(method, path string) bool {
+	paths, _ := d["paths"].(map[string]map[string]interface{})
+	_, ok := paths[PathTemplate(path)][strings.ToLower(method)]
+	return ok
+}
+
+type generator struct {
+	components map[string]interface{}
+}
+
+// operation renders a single operation object
+func (g *generator) operation(op Operation) map[string]interface{} {
+	result := map[string]interface{}{
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+	}
+	if op.Description != "" {
+		result["description"] = op.Description
+	}
+	if op.Tag != "" {
+		result["tags"] = []string{op.Tag}
+	}
+
+	var parameters []interface{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "integer"},
+		})
+	}
+	for _, param := range op.Query {
+		parameters = append(parameters, parameter(param, "query"))
+	}
+	for _, param := range op.Headers {
+		parameters = append(parameters, parameter(param, "header"))
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	if op.Body != nil {
+		bodyType := op.BodyType
+		if bodyType == "" {
+			bodyType = "application/json"
+		}
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				bodyType: map[string]interface{}{
+					"schema": g.schema(reflect.TypeOf(op.Body)),
+				},
+			},
+		}
+	}
+
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	responses := map[string]interface{}{}
+	for _, response := range op.Responses {
+		rendered := map[string]interface{}{"description": response.Description}
+		if response.Body != nil {
+			responseType := contentType
+			if response.ContentType != "" {
+				responseType = response.ContentType
+			}
+			rendered["content"] = map[string]interface{}{
+				responseType: map[string]interface{}{
+					"schema": g.schema(reflect.TypeOf(response.Body)),
+				},
+			}
+		}
+		responses[strconv.Itoa(response.Status)] = rendered
+	}
+	result["responses"] = responses
+
+	return result
+}
+
+// parameter renders a query or header parameter
+func parameter(param Param, in string) map[string]interface{} {
+	schema := map[string]interface{}{"type": param.Type}
+	if param.Format != "" {
+		schema["format"] = param.Format
+	}
+	result := map[string]interface{}{
+		"name":     param.Name,
+		"in":       in,
+		"required": param.Required,
+		"schema":   schema,
+	}
+	if param.Description != "" {
+		result["description"] = param.Description
+	}
+	return result
+}
+
+// operationID derives a stable identifier from the method and path
+func operationID(op Operation) string {
+	var parts []string
+	for _, segment := range strings.Split(strings.Trim(op.Path, "/"), "/") {
+		if segment == "" || segment == "api" {
+			continue
+		}
+		segment = strings.TrimPrefix(segment, ":")
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }) {
+			parts = append(parts, strings.ToUpper(word[:1])+word[1:])
+		}
+	}
+	return strings.ToLower(op.Method) + strings.Join(parts, "")
+}
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	flexibleDateType = reflect.TypeOf(models.FlexibleDate{})
+	deletedAtType    = reflect.TypeOf(gorm.DeletedAt{})
+	fileType         = reflect.TypeOf(File{})
+)
+
+// schema returns the JSON schema for a Go type, registering named structs as components
+func (g *generator) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType, flexibleDateType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case deletedAtType:
+		return map[string]interface{}{"type": []string{"string", "null"}, "format": "date-time"}
+	case fileType:
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		inner := g.schema(t.Elem())
+		if ref, ok := inner["$ref"]; ok {
+			return map[string]interface{}{"oneOf": []interface{}{map[string]interface{}{"$ref": ref}, map[string]interface{}{"type": "null"}}}
+		}
+		if typ, ok := inner["type"].(string); ok {
+			inner["type"] = []string{typ, "null"}
+		}
+		return inner
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := t.Name()
+		if _, exists := g.components[name]; !exists {
+			// Reserve the name first so self-referencing types terminate
+			g.components[name] = map[string]interface{}{}
+			g.components[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	return map[string]interface{}{}
+}
+
+// structSchema renders the properties of a struct from its json tags
+func (g *generator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a json name are flattened
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			embedded := g.structSchema(field.Type)
+			if props, ok := embedded["properties"].(map[string]interface{}); ok {
+				for key, value := range props {
+					properties[key] = value
+				}
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+
+		if strings.Contains(field.Tag.Get("validate"), "required") && !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	result := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		result["required"] = required
+	}
+	return result
+}
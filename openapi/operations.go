@@ -0,0 +1,364 @@
+package openapi
+
+import (
+	"strings"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/events"
+	"expense-api/health"
+	"expense-api/models"
+)
+
+// MessageBody is the confirmation returned by delete endpoints
+type MessageBody struct {
+	Message string `json:"message"`
+}
+
+// HealthStatus is the response of the health and database status endpoints
+type HealthStatus struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	Database  string    `json:"database,omitempty"`
+}
+
+// CategoryDeleted is the confirmation returned by DELETE /api/categories/:id
+type CategoryDeleted struct {
+	Message                string `json:"message"`
+	ReassignedTransactions int64  `json:"reassigned_transactions"`
+}
+
+// TransactionCategoryUpdate is the request body of PATCH /api/transactions/:id/category
+type TransactionCategoryUpdate struct {
+	CategoryID uint `json:"category_id" validate:"required"`
+}
+
+// TransactionUpdate lists the fields accepted by PUT /api/transactions/:id
+type TransactionUpdate struct {
+	TransactionID string  `json:"transaction_id,omitempty"`
+	Amount        float64 `json:"amount,omitempty"`
+	Type          string  `json:"type,omitempty"`
+	CategoryID    uint    `json:"category_id,omitempty"`
+	// PayeeID links the transaction to a payee; null unlinks it
+	PayeeID     *uint               `json:"payee_id,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Date        models.FlexibleDate `json:"date,omitempty"`
+}
+
+// CategoryUpdate lists the fields accepted by PUT /api/categories/:id
+type CategoryUpdate struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+	// ParentID moves the category under another one; POST /api/categories/:id/move also moves to the top level
+	ParentID *uint `json:"parent_id,omitempty"`
+}
+
+// SummaryOverview holds the transaction counts of the summary endpoint
+type SummaryOverview struct {
+	TotalTransactions int64 `json:"total_transactions"`
+	TotalExpenses     int64 `json:"total_expenses"`
+	TotalIncome       int64 `json:"total_income"`
+}
+
+// SummaryTotals holds the amounts of the summary endpoint
+type SummaryTotals struct {
+	TotalExpenseAmount float64 `json:"total_expense_amount"`
+	TotalIncomeAmount  float64 `json:"total_income_amount"`
+	NetAmount          float64 `json:"net_amount"`
+}
+
+// Summary is the response of GET /api/transactions/summary
+type Summary struct {
+	Overview           SummaryOverview              `json:"overview"`
+	Totals             SummaryTotals                `json:"totals"`
+	RecentTransactions []models.TransactionResponse `json:"recent_transactions"`
+}
+
+// ImportUpload is the multipart body of POST /api/transactions/import
+type ImportUpload struct {
+	File File `json:"file" validate:"required"`
+}
+
+var (
+	errorResponses = map[int]string{
+		400: "Invalid request; validation failures list every invalid field",
+		404: "Resource not found",
+		409: "Conflict with the current state of the resource, or an Idempotency-Key reused with a different request",
+		413: "Request body too large",
+		429: "Rate limit exceeded; Retry-After says when to try again",
+		500: "Internal server error",
+		503: "Database not ready",
+	}
+
+	typeParam           = Param{Name: "type", Type: "string", Description: "Filter by transaction type"}
+	accountParam        = Param{Name: "bank_account_id", Type: "integer", Description: "Filter by source or destination bank account"}
+	startDateParam      = Param{Name: "start_date", Type: "string", Format: "date", Description: "Start date (YYYY-MM-DD)", Required: true}
+	endDateParam        = Param{Name: "end_date", Type: "string", Format: "date", Description: "End date (YYYY-MM-DD)", Required: true}
+	fromParam           = Param{Name: "start_date", Type: "string", Format: "date", Description: "Only transactions on or after this date (YYYY-MM-DD)"}
+	toParam             = Param{Name: "end_date", Type: "string", Format: "date", Description: "Only transactions on or before this date (YYYY-MM-DD)"}
+	idempotencyKeyParam = Param{Name: "Idempotency-Key", Type: "string", Description: "Retries with the same key and body replay the first response instead of repeating the request"}
+	asyncParam          = Param{Name: "async", Type: "boolean", Description: "Queue the rows as a background job and respond 202 with the job"}
+	atomicParam         = Param{Name: "atomic", Type: "boolean", Description: "Apply every item in one database transaction, or none if any item fails"}
+	depthParam          = Param{Name: "depth", Type: "integer", Description: "Roll subcategory totals up into their ancestor at this depth; 0 reports top level categories only"}
+)
+
+// ok builds a success response followed by the given error responses
+func ok(status int, description string, body interface{}, errorStatuses ...int) []Response {
+	responses := []Response{{Status: status, Description: description, Body: body}}
+	for _, code := range errorStatuses {
+		responses = append(responses, problem(code))
+	}
+	return responses
+}
+
+// problem builds a problem details response for an error status
+func problem(status int) Response {
+	return Response{
+		Status:      status,
+		Description: errorResponses[status],
+		Body:        models.Problem{},
+		ContentType: apperrors.ProblemContentType,
+	}
+}
+
+// Operations describes every route registered by the server
+func Operations() []Operation {
+	operations := []Operation{
+		// Health
+		{Method: "GET", Path: "/health", Tag: "Health", Summary: "Check that the API is running",
+			Responses: ok(200, "API is running", HealthStatus{})},
+		{Method: "GET", Path: "/db-status", Tag: "Health", Summary: "Check the database connection",
+			Responses: ok(200, "Database status", HealthStatus{})},
+		{Method: "GET", Path: "/livez", Tag: "Health", Summary: "Check that the process is alive",
+			Description: "Runs the liveness checks. Suited for restarting a stuck process; it does not depend on the database.",
+			Responses: []Response{
+				{Status: 200, Description: "Every check passed", Body: health.Report{}},
+				{Status: 503, Description: "A check failed", Body: health.Report{}},
+			}},
+		{Method: "GET", Path: "/readyz", Tag: "Health", Summary: "Check that the API can serve requests",
+			Description: "Pings the database, checks that every migration is applied, that the database accepts writes and that the job workers run. Answers 503 until the server has connected and migrated the database.",
+			Responses: []Response{
+				{Status: 200, Description: "Every check passed", Body: health.Report{}},
+				{Status: 503, Description: "A check failed", Body: health.Report{}},
+			}},
+		{Method: "GET", Path: "/metrics", Tag: "Health", Summary: "Prometheus metrics",
+			Description: "Request counts and latency by route pattern, database query durations and pool stats, job queue depth, transactions created and failed bulk rows, in the Prometheus text format.",
+			ContentType: "text/plain",
+			Responses:   ok(200, "Metrics", "")},
+
+		// Bank accounts
+		{Method: "POST", Path: "/api/bank-accounts", Tag: "Bank Accounts", Summary: "Create a bank account",
+			Body: models.BankAccountRequest{}, Responses: ok(201, "Bank account created", models.BankAccountResponse{}, 400, 500, 503)},
+		{Method: "GET", Path: "/api/bank-accounts", Tag: "Bank Accounts", Summary: "List bank accounts",
+			Query:     []Param{{Name: "include_inactive", Type: "boolean", Description: "Include deactivated accounts"}},
+			Responses: ok(200, "Bank accounts", []models.BankAccountResponse{}, 500, 503)},
+		{Method: "GET", Path: "/api/bank-accounts/:id", Tag: "Bank Accounts", Summary: "Get a bank account",
+			Responses: ok(200, "Bank account", models.BankAccountResponse{}, 400, 404, 500, 503)},
+		{Method: "PUT", Path: "/api/bank-accounts/:id", Tag: "Bank Accounts", Summary: "Update a bank account",
+			Body: models.BankAccountRequest{}, Responses: ok(200, "Updated bank account", models.BankAccountResponse{}, 400, 404, 500, 503)},
+		{Method: "DELETE", Path: "/api/bank-accounts/:id", Tag: "Bank Accounts", Summary: "Delete a bank account without transactions",
+			Responses: ok(204, "Bank account deleted", nil, 400, 404, 409, 500, 503)},
+
+		// Transactions
+		{Method: "POST", Path: "/api/transactions", Tag: "Transactions", Summary: "Create a transaction",
+			Description: "When the server runs with ANOMALY_CHECKS=true, a transaction whose amount is out of character also publishes a transaction.anomaly event.",
+			Body:        models.Transaction{}, Responses: ok(201, "Transaction created", models.TransactionResponse{}, 400, 500)},
+		{Method: "POST", Path: "/api/transactions/bulk", Tag: "Transactions", Summary: "Create up to 5000 transactions",
+			Query: []Param{atomicParam, asyncParam}, Body: models.BulkTransactionRequest{},
+			Responses: append(ok(201, "All transactions created", models.BulkTransactionResponse{}),
+				Response{Status: 202, Description: "Job queued (async=true)", Body: models.JobResponse{}},
+				Response{Status: 207, Description: "Some transactions failed", Body: models.BulkTransactionResponse{}},
+				Response{Status: 400, Description: "Invalid request, every transaction failed, or any transaction failed in atomic mode", Body: models.BulkTransactionResponse{}},
+				Response{Status: 500, Description: "The atomic insert was rolled back", Body: models.Problem{}, ContentType: apperrors.ProblemContentType})},
+		{Method: "POST", Path: "/api/transactions/import", Tag: "Jobs", Summary: "Import transactions from a CSV file as a background job",
+			Description: "The header row names the columns: amount, type, bank_account_id and description are required; transaction_id, category_id, destination_bank_account_id, payee_id and date are optional. Rows without payee_id are matched to a payee by description.",
+			Query:       []Param{atomicParam}, BodyType: "multipart/form-data", Body: ImportUpload{},
+			Responses: ok(202, "Job queued", models.JobResponse{}, 400, 500, 503)},
+		{Method: "POST", Path: "/api/transactions/transfer", Tag: "Transfers", Summary: "Transfer between bank accounts",
+			Body: models.TransferRequest{}, Responses: ok(201, "Transfer created", models.TransferResponse{}, 400, 500)},
+		{Method: "DELETE", Path: "/api/transactions/bulk", Tag: "Transactions", Summary: "Delete up to 1000 transactions",
+			Query: []Param{atomicParam}, Body: models.BulkDeleteRequest{},
+			Responses: append(ok(200, "All transactions deleted", models.BulkDeleteResponse{}),
+				Response{Status: 207, Description: "Some deletions failed", Body: models.BulkDeleteResponse{}},
+				Response{Status: 400, Description: "Invalid request, every deletion failed, or any deletion failed in atomic mode", Body: models.BulkDeleteResponse{}},
+				Response{Status: 500, Description: "The atomic delete was rolled back", Body: models.Problem{}, ContentType: apperrors.ProblemContentType})},
+		{Method: "GET", Path: "/api/transactions", Tag: "Transactions", Summary: "List transactions",
+			Query: []Param{typeParam, accountParam, fromParam, toParam}, Responses: ok(200, "Transactions", []models.TransactionResponse{}, 400, 500)},
+		{Method: "GET", Path: "/api/transactions/transfers", Tag: "Transfers", Summary: "List transfers",
+			Query: []Param{accountParam, fromParam, toParam}, Responses: ok(200, "Transfers", []models.TransferResponse{}, 400, 500)},
+		{Method: "GET", Path: "/api/transactions/summary", Tag: "Reports", Summary: "Transaction counts, totals and recent activity",
+			Responses: ok(200, "Summary", Summary{})},
+		{Method: "GET", Path: "/api/transactions/aggregate", Tag: "Reports", Summary: "Totals per category",
+			Description: "Subcategories are keyed by their path, e.g. \"Bills / Electricity\".",
+			Query:       []Param{depthParam}, Responses: ok(200, "Aggregate", models.AggregateResponse{}, 400, 500)},
+		{Method: "GET", Path: "/api/transactions/aggregate-table", Tag: "Reports", Summary: "Income and expenses per category in a date range",
+			Query: []Param{startDateParam, endDateParam, depthParam}, Responses: ok(200, "Aggregate table", models.AggregateTableResponse{}, 400, 500)},
+		{Method: "GET", Path: "/api/transactions/date-range", Tag: "Transactions", Summary: "List transactions in a date range",
+			Query:     []Param{startDateParam, endDateParam, typeParam},
+			Responses: ok(200, "Transactions", []models.TransactionResponse{}, 400, 500)},
+		{Method: "GET", Path: "/api/transactions/trash", Tag: "Transactions", Summary: "List soft-deleted transactions",
+			Responses: ok(200, "Deleted transactions", []models.TransactionResponse{}, 500)},
+		{Method: "GET", Path: "/api/transactions/:id", Tag: "Transactions", Summary: "Get a transaction",
+			Responses: ok(200, "Transaction", models.TransactionResponse{}, 400, 404, 500)},
+		{Method: "GET", Path: "/api/transactions/:id/history", Tag: "Transactions", Summary: "List recorded versions of a transaction",
+			Responses: ok(200, "Versions, oldest first", []models.TransactionVersion{}, 400, 404, 500)},
+		{Method: "PUT", Path: "/api/transactions/:id", Tag: "Transactions", Summary: "Update a transaction",
+			Body: TransactionUpdate{}, Responses: ok(200, "Updated transaction", models.Transaction{}, 400, 404, 500)},
+		{Method: "PATCH", Path: "/api/transactions/:id/category", Tag: "Transactions", Summary: "Change the category of a transaction",
+			Body: TransactionCategoryUpdate{}, Responses: ok(200, "Updated transaction", models.TransactionResponse{}, 400, 404, 500)},
+		{Method: "POST", Path: "/api/transactions/:id/restore", Tag: "Transactions", Summary: "Restore a transaction to a recorded version",
+			Query:     []Param{{Name: "version", Type: "integer", Description: "Version to restore", Required: true}},
+			Responses: ok(200, "Restored transaction", models.TransactionResponse{}, 400, 404, 500)},
+		{Method: "POST", Path: "/api/transactions/:id/undelete", Tag: "Transactions", Summary: "Undelete a soft-deleted transaction",
+			Responses: ok(200, "Undeleted transaction", models.TransactionResponse{}, 400, 404, 500)},
+		{Method: "DELETE", Path: "/api/transactions/:id", Tag: "Transactions", Summary: "Soft-delete a transaction",
+			Responses: ok(200, "Transaction deleted", MessageBody{}, 400, 404, 500)},
+
+		// Categories
+		{Method: "POST", Path: "/api/categories", Tag: "Categories", Summary: "Create a category",
+			Body: models.Category{}, Responses: ok(201, "Category created", models.Category{}, 400, 409, 500)},
+		{Method: "GET", Path: "/api/categories", Tag: "Categories", Summary: "List categories",
+			Description: "With tree=true the response lists the top level categories, each with its subcategories in children (CategoryTreeNode).",
+			Query:       []Param{{Name: "tree", Type: "boolean", Description: "Nest subcategories under their parents"}},
+			Responses:   ok(200, "Categories", []models.CategoryResponse{}, 500)},
+		{Method: "GET", Path: "/api/categories/:id", Tag: "Categories", Summary: "Get a category",
+			Responses: ok(200, "Category", models.CategoryResponse{}, 400, 404, 500)},
+		{Method: "PUT", Path: "/api/categories/:id", Tag: "Categories", Summary: "Update a category",
+			Body: CategoryUpdate{}, Responses: ok(200, "Updated category", models.Category{}, 400, 404, 409, 500)},
+		{Method: "POST", Path: "/api/categories/:id/move", Tag: "Categories", Summary: "Move a category under another parent",
+			Description: "The parent must have the same type and cannot be the category or one of its subcategories. A null parent_id moves the category to the top level.",
+			Body:        models.CategoryMoveRequest{}, Responses: ok(200, "Moved category", models.Category{}, 400, 404, 409, 500)},
+		{Method: "POST", Path: "/api/categories/:id/merge", Tag: "Categories", Summary: "Merge a category into another one",
+			Description: "Moves every transaction and subcategory of the category to the target, which must have the same type, and deletes the category, all in one database transaction.",
+			Body:        models.CategoryMergeRequest{}, Responses: ok(200, "Categories merged", models.CategoryMergeResponse{}, 400, 404, 409, 500)},
+		{Method: "DELETE", Path: "/api/categories/:id", Tag: "Categories", Summary: "Delete a category without transactions or subcategories",
+			Description: "With reassign_to the category is merged into that category first, as POST /api/categories/:id/merge does, so it may be in use.",
+			Query:       []Param{{Name: "reassign_to", Type: "integer", Description: "Move the transactions and subcategories to this category before deleting"}},
+			Responses:   ok(200, "Category deleted", CategoryDeleted{}, 400, 404, 409, 500)},
+
+		// Payees
+		{Method: "POST", Path: "/api/payees", Tag: "Payees", Summary: "Create a payee",
+			Description: "Aliases are patterns matched against the descriptions of new and imported transactions, ignoring case and punctuation; a \"*\" matches the rest of a word. The most specific matching alias wins.",
+			Body:        models.PayeeRequest{}, Responses: ok(201, "Payee created", models.PayeeResponse{}, 400, 409, 500)},
+		{Method: "GET", Path: "/api/payees", Tag: "Payees", Summary: "List payees",
+			Responses: ok(200, "Payees ordered by name", []models.PayeeResponse{}, 500)},
+		{Method: "GET", Path: "/api/payees/:id", Tag: "Payees", Summary: "Get a payee",
+			Responses: ok(200, "Payee", models.PayeeResponse{}, 400, 404, 500)},
+		{Method: "PUT", Path: "/api/payees/:id", Tag: "Payees", Summary: "Replace the name, default category and aliases of a payee",
+			Body: models.PayeeRequest{}, Responses: ok(200, "Updated payee", models.PayeeResponse{}, 400, 404, 409, 500)},
+		{Method: "POST", Path: "/api/payees/:id/merge", Tag: "Payees", Summary: "Merge a payee into another one",
+			Description: "Moves every transaction and alias of the payee to the target and deletes the payee, all in one database transaction. The target keeps its default category, or takes the payee's if it has none.",
+			Body:        models.PayeeMergeRequest{}, Responses: ok(200, "Payees merged", models.PayeeMergeResponse{}, 400, 404, 500)},
+		{Method: "DELETE", Path: "/api/payees/:id", Tag: "Payees", Summary: "Delete a payee",
+			Description: "Transactions of the payee are kept without a payee.",
+			Responses:   ok(200, "Payee deleted", MessageBody{}, 400, 404, 500)},
+
+		// Reports
+		{Method: "GET", Path: "/api/reports/payees", Tag: "Reports", Summary: "Rank payees by spending in a date range",
+			Query: []Param{
+				{Name: "type", Type: "string", Description: "expense (default) or income"},
+				fromParam, toParam,
+				{Name: "limit", Type: "integer", Description: "Only the top payees"},
+			},
+			Responses: ok(200, "Payee report", models.PayeeReportResponse{}, 400, 500)},
+
+		// Insights
+		{Method: "GET", Path: "/api/insights/subscriptions", Tag: "Insights", Summary: "Detect subscriptions and other recurring charges",
+			Description: "Finds expenses with the same payee, or the same description once reference numbers are ignored, charged weekly, monthly or yearly at a similar amount. Subscriptions are ordered by annual cost.",
+			Query:       []Param{{Name: "active", Type: "boolean", Description: "Only active (true) or stopped (false) subscriptions"}},
+			Responses:   ok(200, "Detected subscriptions", models.SubscriptionsResponse{}, 400, 500)},
+		{Method: "POST", Path: "/api/insights/subscriptions/schedule", Tag: "Insights", Summary: "Create a recurring transaction from a detected subscription",
+			Description: "The schedule posts the last amount of the subscription at its cadence, starting with its next expected charge that is not in the past.",
+			Body:        models.SubscriptionScheduleRequest{}, Responses: ok(201, "Recurring transaction created", models.RecurringTransactionResponse{}, 400, 409, 500)},
+
+		{Method: "GET", Path: "/api/insights/anomalies", Tag: "Insights", Summary: "Find transactions with unusual amounts",
+			Description: "Judges each transaction against the earlier ones of its category and of its payee in a trailing window. An amount is flagged when its robust z-score, 0.6745 × (amount − median) / MAD, is above the threshold; only amounts above the usual are flagged.",
+			Query: []Param{
+				{Name: "type", Type: "string", Description: "expense (default) or income"},
+				fromParam, toParam,
+				{Name: "window_days", Type: "integer", Description: "Days of earlier transactions to compare with (default 180)"},
+				{Name: "threshold", Type: "number", Description: "Score above which an amount is anomalous (default 3.5)"},
+			},
+			Responses: ok(200, "Anomalous transactions, the highest score first", models.AnomaliesResponse{}, 400, 500)},
+
+		// Recurring transactions
+		{Method: "GET", Path: "/api/recurring", Tag: "Recurring Transactions", Summary: "List recurring transactions",
+			Responses: ok(200, "Recurring transactions, the next one due first", []models.RecurringTransactionResponse{}, 500)},
+		{Method: "GET", Path: "/api/recurring/:id", Tag: "Recurring Transactions", Summary: "Get a recurring transaction",
+			Responses: ok(200, "Recurring transaction", models.RecurringTransactionResponse{}, 400, 404, 500)},
+		{Method: "DELETE", Path: "/api/recurring/:id", Tag: "Recurring Transactions", Summary: "Delete a recurring transaction",
+			Description: "The transactions it already posted are kept.",
+			Responses:   ok(200, "Recurring transaction deleted", MessageBody{}, 400, 404, 500)},
+
+		// Webhooks
+		{Method: "POST", Path: "/api/webhooks", Tag: "Webhooks", Summary: "Create a webhook subscription",
+			Body: models.WebhookSubscriptionRequest{}, Responses: ok(201, "Subscription created, including its secret", models.WebhookSubscriptionResponse{}, 400, 500)},
+		{Method: "GET", Path: "/api/webhooks", Tag: "Webhooks", Summary: "List webhook subscriptions",
+			Responses: ok(200, "Subscriptions", []models.WebhookSubscriptionResponse{}, 500)},
+		{Method: "GET", Path: "/api/webhooks/:id", Tag: "Webhooks", Summary: "Get a webhook subscription",
+			Responses: ok(200, "Subscription", models.WebhookSubscriptionResponse{}, 400, 404, 500)},
+		{Method: "PUT", Path: "/api/webhooks/:id", Tag: "Webhooks", Summary: "Update a webhook subscription",
+			Body: models.WebhookSubscriptionRequest{}, Responses: ok(200, "Updated subscription", models.WebhookSubscriptionResponse{}, 400, 404, 500)},
+		{Method: "DELETE", Path: "/api/webhooks/:id", Tag: "Webhooks", Summary: "Delete a webhook subscription",
+			Responses: ok(204, "Subscription deleted", nil, 400, 404, 500)},
+		{Method: "GET", Path: "/api/webhooks/:id/deliveries", Tag: "Webhooks", Summary: "List recent deliveries of a subscription",
+			Query: []Param{
+				{Name: "status", Type: "string", Description: "pending, succeeded or failed"},
+				{Name: "limit", Type: "integer", Description: "Maximum number of deliveries, 1 to 1000 (default 100)"},
+			},
+			Responses: ok(200, "Deliveries, newest first", []models.WebhookDelivery{}, 400, 404, 500)},
+		{Method: "POST", Path: "/api/webhooks/:id/test", Tag: "Webhooks", Summary: "Send a test event to a subscription",
+			Responses: ok(200, "Delivery attempt", models.WebhookDelivery{}, 400, 404, 500)},
+
+		// Jobs
+		{Method: "GET", Path: "/api/jobs/:id", Tag: "Jobs", Summary: "Get the status, progress and result of a background job",
+			Responses: ok(200, "Job", models.JobResponse{}, 400, 404, 500)},
+		{Method: "POST", Path: "/api/jobs/:id/cancel", Tag: "Jobs", Summary: "Cancel a queued or running job",
+			Description: "A running job stops after its current chunk; rows it already created are kept.",
+			Responses:   ok(200, "Cancelled job", models.JobResponse{}, 400, 404, 409, 500)},
+		{Method: "POST", Path: "/api/jobs/:id/retry", Tag: "Jobs", Summary: "Queue a failed or cancelled job again",
+			Description: "The job resumes after the rows it already processed.",
+			Responses:   ok(200, "Queued job", models.JobResponse{}, 400, 404, 409, 500)},
+
+		// Events
+		{Method: "GET", Path: "/api/events/stream", Tag: "Events", Summary: "Stream live change events (Server-Sent Events)",
+			Description: "Each frame carries an Event as JSON. Resume with the Last-Event-ID header. Authenticated clients receive their own events and those no user caused; anonymous clients only the latter.",
+			ContentType: "text/event-stream",
+			Query: []Param{
+				{Name: "types", Type: "string", Description: "Comma-separated event types, e.g. transaction.*,category.created"},
+				{Name: "last_event_id", Type: "integer", Description: "Resume after this event ID"},
+			},
+			Responses: ok(200, "Event stream", events.Event{}, 400)},
+	}
+
+	for i, op := range operations {
+		if !strings.HasPrefix(op.Path, "/api/") {
+			continue
+		}
+		// Every API POST accepts an Idempotency-Key
+		if op.Method == "POST" {
+			operations[i].Headers = append(operations[i].Headers, idempotencyKeyParam)
+			if !hasStatus(op.Responses, 409) {
+				operations[i].Responses = append(operations[i].Responses, problem(409))
+			}
+		}
+		// Request bodies are size limited and every API route is rate limited
+		if op.Body != nil {
+			operations[i].Responses = append(operations[i].Responses, problem(413))
+		}
+		operations[i].Responses = append(operations[i].Responses, problem(429))
+	}
+
+	return operations
+}
+
+// hasStatus reports whether responses include the given status
+func hasStatus(responses []Response, status int) bool {
+	for _, response := range responses {
+		if response.Status == status {
+			return true
+		}
+	}
+	return false
+}
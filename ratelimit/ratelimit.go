@@ -0,0 +1,148 @@
+// Package ratelimit limits how many requests each client may make, with
+// token-bucket semantics: a bucket holds up to Limit.Requests tokens, every
+// request takes one and tokens are refilled evenly over Limit.Period. Clients
+// can therefore burst up to the full limit and then continue at the refill rate.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response headers, following the IETF RateLimit header fields draft
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+	HeaderPolicy    = "RateLimit-Policy"
+)
+
+// Class groups routes that share a limit
+type Class string
+
+// Route classes, each with a limit of its own
+const (
+	ClassRead  Class = "read"
+	ClassWrite Class = "write"
+	ClassBulk  Class = "bulk"
+)
+
+// Classify returns the class of a request: bulk creates, deletes and imports
+// are bulk, other GET, HEAD and OPTIONS requests are reads and everything else is a write
+func Classify(method, path string) Class {
+	path = strings.TrimSuffix(path, "/")
+	if strings.HasSuffix(path, "/bulk") || strings.HasSuffix(path, "/import") {
+		return ClassBulk
+	}
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return ClassRead
+	}
+	return ClassWrite
+}
+
+// Limit allows Requests requests per Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses a limit such as "100/1m". "0" and "off" return a zero Limit, which allows everything.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "0" || strings.EqualFold(value, "off") {
+		return Limit{}, nil
+	}
+	count, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must look like 100/1m", value)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid request count", value)
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid period", value)
+	}
+	return Limit{Requests: requests, Period: duration}, nil
+}
+
+// Unlimited reports whether the limit allows every request
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// Policy describes the limit for the RateLimit-Policy header, e.g. "100;w=60"
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Period.Seconds())))
+}
+
+// String formats the limit the way ParseLimit reads it
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// rate is the number of tokens added per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of requests that can be made right away
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, zero when Allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. MemoryStore keeps them in the process; an
+// implementation backed by a shared store such as Redis lets several
+// instances of the API enforce one limit.
+type Store interface {
+	// Take takes a token from the bucket identified by key, creating a full bucket if there is none
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter applies a limit per class to every client
+type Limiter struct {
+	Store  Store
+	Limits map[Class]Limit
+}
+
+// NewLimiter creates a limiter keeping its buckets in store
+func NewLimiter(store Store, limits map[Class]Limit) *Limiter {
+	return &Limiter{Store: store, Limits: limits}
+}
+
+// DefaultLimits are the limits used when none are configured
+func DefaultLimits() map[Class]Limit {
+	return map[Class]Limit{
+		ClassRead:  {Requests: 600, Period: time.Minute},
+		ClassWrite: {Requests: 120, Period: time.Minute},
+		ClassBulk:  {Requests: 10, Period: time.Minute},
+	}
+}
+
+// Take takes a token for a request of the given class made by client.
+// It returns ok=false when the class has no limit.
+func (l *Limiter) Take(ctx context.Context, class Class, client string) (result Result, ok bool, err error) {
+	limit := l.Limits[class]
+	if limit.Unlimited() {
+		return Result{}, false, nil
+	}
+	result, err = l.Store.Take(ctx, string(class)+":"+client, limit)
+	return result, true, err
+}
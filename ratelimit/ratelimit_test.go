@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestStore returns a store whose clock only moves when advance is called
+func newTestStore() (*MemoryStore, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	return store, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	store, advance := newTestStore()
+	ctx := context.Background()
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	// A new client can burst up to the full limit
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := store.Take(ctx, "client", limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// Other keys have buckets of their own
+	result, _ = store.Take(ctx, "other", limit)
+	assert.True(t, result.Allowed)
+
+	// Tokens come back at the refill rate, one per second here
+	advance(time.Second)
+	result, _ = store.Take(ctx, "client", limit)
+	assert.True(t, result.Allowed)
+	result, _ = store.Take(ctx, "client", limit)
+	assert.False(t, result.Allowed)
+
+	// and never beyond the size of the bucket
+	advance(time.Hour)
+	result, _ = store.Take(ctx, "client", limit)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	store, advance := newTestStore()
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+
+	store.Take(context.Background(), "busy", limit)
+	store.Take(context.Background(), "busy", limit)
+	store.Take(context.Background(), "quiet", limit)
+	assert.Equal(t, 0, store.Purge())
+
+	// The quiet bucket is full again after a second, the busy one after two
+	advance(time.Second)
+	assert.Equal(t, 1, store.Purge())
+	advance(time.Second)
+	assert.Equal(t, 1, store.Purge())
+	assert.Empty(t, store.buckets)
+}
+
+func TestLimiterTake(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), map[Class]Limit{
+		ClassWrite: {Requests: 1, Period: time.Minute},
+		ClassBulk:  {},
+	})
+	ctx := context.Background()
+
+	result, limited, err := limiter.Take(ctx, ClassWrite, "ip:1.2.3.4")
+	assert.NoError(t, err)
+	assert.True(t, limited)
+	assert.True(t, result.Allowed)
+	result, _, _ = limiter.Take(ctx, ClassWrite, "ip:1.2.3.4")
+	assert.False(t, result.Allowed)
+
+	// Classes without a limit are not counted
+	for _, class := range []Class{ClassRead, ClassBulk} {
+		_, limited, err = limiter.Take(ctx, class, "ip:1.2.3.4")
+		assert.NoError(t, err)
+		assert.False(t, limited)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		method, path string
+		class        Class
+	}{
+		{"GET", "/api/transactions", ClassRead},
+		{"HEAD", "/api/transactions/1", ClassRead},
+		{"POST", "/api/transactions", ClassWrite},
+		{"DELETE", "/api/categories/1", ClassWrite},
+		{"POST", "/api/transactions/bulk", ClassBulk},
+		{"POST", "/api/transactions/bulk/", ClassBulk},
+		{"DELETE", "/api/transactions/bulk", ClassBulk},
+		{"POST", "/api/transactions/import", ClassBulk},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.class, Classify(tt.method, tt.path), "%s %s", tt.method, tt.path)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("100/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 100, Period: time.Minute}, limit)
+	assert.Equal(t, "100;w=60", limit.Policy())
+	assert.Equal(t, "100/1m0s", limit.String())
+
+	for _, off := range []string{"0", "off"} {
+		limit, err = ParseLimit(off)
+		assert.NoError(t, err)
+		assert.True(t, limit.Unlimited())
+	}
+
+	for _, invalid := range []string{"100", "many/1m", "-1/1m", "100/soon", "100/0s"} {
+		_, err = ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,651 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"expense-api/models"
+
+	"gorm.io/gorm"
+)
+
+// Provider returns the current database connection, which is nil until the database is ready
+type Provider func() *gorm.DB
+
+// txKey is the context key of the database transaction stored by ContextWithTx
+type txKey struct{}
+
+// ContextWithTx returns a context under which the repositories run their
+// queries in tx, so work done through services commits or rolls back with it
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// WithContext returns a provider whose connection runs queries with ctx, in
+// the transaction stored by ContextWithTx if there is one
+func (p Provider) WithContext(ctx context.Context) Provider {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return func() *gorm.DB { return tx.WithContext(ctx) }
+	}
+	return func() *gorm.DB {
+		db := p()
+		if db == nil {
+			return nil
+		}
+		return db.WithContext(ctx)
+	}
+}
+
+// translate converts a driver error into one of GORM's portable errors, such
+// as ErrDuplicate, when the database dialect knows how
+func translate(db *gorm.DB, err error) error {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok && err != nil {
+		return translator.Translate(err)
+	}
+	return err
+}
+
+// GormTransactions is a TransactionRepository backed by GORM
+type GormTransactions struct {
+	db Provider
+}
+
+// NewGormTransactions creates a GORM transaction repository
+func NewGormTransactions(db Provider) *GormTransactions {
+	return &GormTransactions{db: db}
+}
+
+// withRelations preloads the category, accounts and payee of transactions
+func withRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Category").Preload("BankAccount").Preload("DestinationBankAccount").Preload("Payee")
+}
+
+// Create stores a new transaction and sets its ID. Inside an enclosing
+// transaction a failed insert only rolls back to a savepoint, so the
+// enclosing transaction can go on.
+func (r *GormTransactions) Create(transaction *models.Transaction) error {
+	return r.db().Transaction(func(tx *gorm.DB) error {
+		return tx.Create(transaction).Error
+	})
+}
+
+// CreateBatch stores transactions in inserts of up to batchSize rows and sets their IDs.
+// Their first versions are inserted in batches too, rather than by a hook per row.
+// The rows are stored all or none, using a savepoint inside an enclosing transaction.
+func (r *GormTransactions) CreateBatch(transactions []models.Transaction, batchSize int) error {
+	return r.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Set(models.VersionBatchKey, true).CreateInBatches(&transactions, batchSize).Error; err != nil {
+			return err
+		}
+		return models.RecordCreatedVersions(tx, transactions, batchSize)
+	})
+}
+
+// FindByID returns a transaction with its category and accounts loaded
+func (r *GormTransactions) FindByID(id uint) (models.Transaction, error) {
+	var transaction models.Transaction
+	err := withRelations(r.db()).First(&transaction, id).Error
+	return transaction, err
+}
+
+// FindByIDs returns the transactions that exist among ids, with relations loaded
+func (r *GormTransactions) FindByIDs(ids []uint) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := withRelations(r.db()).Where("id IN ?", ids).Find(&transactions).Error
+	return transactions, err
+}
+
+// List returns matching transactions with relations loaded, newest date first
+func (r *GormTransactions) List(filter TransactionFilter) ([]models.Transaction, error) {
+	query := withRelations(r.db())
+
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.ExcludeType != "" {
+		query = query.Where("type != ?", filter.ExcludeType)
+	}
+	if filter.BankAccountID != nil {
+		query = query.Where("bank_account_id = ? OR destination_bank_account_id = ?", *filter.BankAccountID, *filter.BankAccountID)
+	}
+	if filter.From != nil {
+		query = query.Where("date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("date <= ?", *filter.To)
+	}
+
+	var transactions []models.Transaction
+	err := query.Order("date DESC").Find(&transactions).Error
+	return transactions, err
+}
+
+// Recent returns the most recently created transactions
+func (r *GormTransactions) Recent(limit int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := withRelations(r.db()).Order("created_at DESC").Limit(limit).Find(&transactions).Error
+	return transactions, err
+}
+
+// Totals counts and sums transactions, optionally of one type
+func (r *GormTransactions) Totals(transactionType string) (TransactionTotals, error) {
+	query := r.db().Model(&models.Transaction{})
+	if transactionType != "" {
+		query = query.Where("type = ?", transactionType)
+	}
+
+	var totals TransactionTotals
+	err := query.Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").Scan(&totals).Error
+	return totals, err
+}
+
+// Update applies the given column values to a transaction
+func (r *GormTransactions) Update(id uint, fields map[string]interface{}) error {
+	return r.db().Model(&models.Transaction{ID: id}).Updates(fields).Error
+}
+
+// Delete soft deletes a transaction
+func (r *GormTransactions) Delete(id uint) error {
+	return r.db().Delete(&models.Transaction{ID: id}).Error
+}
+
+// DeleteBatch soft deletes several transactions in one statement.
+// The records are passed as a slice so the version hook still runs for each of them.
+func (r *GormTransactions) DeleteBatch(ids []uint) error {
+	transactions := make([]models.Transaction, len(ids))
+	for i, id := range ids {
+		transactions[i].ID = id
+	}
+	return r.db().Delete(&transactions).Error
+}
+
+// FindWithDeleted returns a transaction whether or not it is deleted, with relations loaded
+func (r *GormTransactions) FindWithDeleted(id uint) (models.Transaction, error) {
+	var transaction models.Transaction
+	err := withRelations(r.db().Unscoped()).First(&transaction, id).Error
+	return transaction, err
+}
+
+// ListDeleted returns the deleted transactions with relations loaded, most recently deleted first
+func (r *GormTransactions) ListDeleted() ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := withRelations(r.db().Unscoped()).Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&transactions).Error
+	return transactions, err
+}
+
+// Versions returns the recorded versions of a transaction, oldest first
+func (r *GormTransactions) Versions(id uint) ([]models.TransactionVersion, error) {
+	var versions []models.TransactionVersion
+	err := r.db().Where("record_id = ?", id).Order("version ASC").Find(&versions).Error
+	return versions, err
+}
+
+// FindVersion returns one recorded version of a transaction
+func (r *GormTransactions) FindVersion(id uint, version int) (models.TransactionVersion, error) {
+	var found models.TransactionVersion
+	err := r.db().Where("record_id = ? AND version = ?", id, version).First(&found).Error
+	return found, err
+}
+
+// Restore writes the fields of a version back to its transaction and undeletes it
+func (r *GormTransactions) Restore(version models.TransactionVersion) error {
+	return r.db().Unscoped().Set(models.VersionOperationKey, models.VersionOperationRestore).
+		Model(&models.Transaction{ID: version.RecordID}).Updates(map[string]interface{}{
+		"transaction_id":              version.TransactionID,
+		"amount":                      version.Amount,
+		"type":                        version.Type,
+		"category_id":                 version.CategoryID,
+		"bank_account_id":             version.BankAccountID,
+		"destination_bank_account_id": version.DestinationBankAccountID,
+		"description":                 version.Description,
+		"date":                        version.Date,
+		"deleted_at":                  nil,
+	}).Error
+}
+
+// Undelete brings back a deleted transaction
+func (r *GormTransactions) Undelete(id uint) error {
+	return r.db().Unscoped().Set(models.VersionOperationKey, models.VersionOperationUndelete).
+		Model(&models.Transaction{ID: id}).Update("deleted_at", nil).Error
+}
+
+// Atomic runs fn inside a database transaction
+func (r *GormTransactions) Atomic(fn func(TransactionRepository) error) error {
+	return r.db().Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormTransactions(func() *gorm.DB { return tx }))
+	})
+}
+
+// CountByCategory counts transactions using a category
+func (r *GormTransactions) CountByCategory(categoryID uint) (int64, error) {
+	var count int64
+	err := r.db().Model(&models.Transaction{}).Where("category_id = ?", categoryID).Count(&count).Error
+	return count, err
+}
+
+// CountByAccount counts transactions from or to a bank account
+func (r *GormTransactions) CountByAccount(accountID uint) (int64, error) {
+	var count int64
+	err := r.db().Model(&models.Transaction{}).Where("bank_account_id = ? OR destination_bank_account_id = ?", accountID, accountID).Count(&count).Error
+	return count, err
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *GormTransactions) WithContext(ctx context.Context) TransactionRepository {
+	return NewGormTransactions(r.db.WithContext(ctx))
+}
+
+// GormCategories is a CategoryRepository backed by GORM
+type GormCategories struct {
+	db Provider
+}
+
+// NewGormCategories creates a GORM category repository
+func NewGormCategories(db Provider) *GormCategories {
+	return &GormCategories{db: db}
+}
+
+// Create stores a new category and sets its ID
+func (r *GormCategories) Create(category *models.Category) error {
+	db := r.db()
+	return translate(db, db.Create(category).Error)
+}
+
+// FindByID returns a category
+func (r *GormCategories) FindByID(id uint) (models.Category, error) {
+	var category models.Category
+	err := r.db().First(&category, id).Error
+	return category, err
+}
+
+// FindByName returns the category with the given name among the children of
+// parentID, ignoring the category excludeID
+func (r *GormCategories) FindByName(name string, parentID *uint, excludeID uint) (models.Category, error) {
+	var category models.Category
+	query := r.db().Where("name = ? AND id != ?", name, excludeID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	err := query.First(&category).Error
+	return category, err
+}
+
+// List returns every category
+func (r *GormCategories) List() ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db().Find(&categories).Error
+	return categories, err
+}
+
+// Update applies the given column values to a category
+func (r *GormCategories) Update(id uint, fields map[string]interface{}) error {
+	db := r.db()
+	return translate(db, db.Model(&models.Category{ID: id}).Updates(fields).Error)
+}
+
+// Delete soft deletes a category
+func (r *GormCategories) Delete(id uint) error {
+	return r.db().Delete(&models.Category{ID: id}).Error
+}
+
+// Merge moves the transactions and subcategories of sourceID to targetID and deletes sourceID in one transaction
+func (r *GormCategories) Merge(sourceID, targetID uint) ([]uint, error) {
+	var ids []uint
+	db := r.db()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Transaction{}).Where("category_id = ?", sourceID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			// The records are passed as a slice so the version hook runs for each of them
+			transactions := make([]models.Transaction, len(ids))
+			for i, id := range ids {
+				transactions[i].ID = id
+			}
+			if err := tx.Unscoped().Model(&transactions).Update("category_id", targetID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", sourceID).Update("parent_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Payee{}).Where("default_category_id = ?", sourceID).Update("default_category_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RecurringTransaction{}).Where("category_id = ?", sourceID).Update("category_id", targetID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Category{ID: sourceID}).Error
+	})
+	if err != nil {
+		return nil, translate(db, err)
+	}
+	return ids, nil
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *GormCategories) WithContext(ctx context.Context) CategoryRepository {
+	return NewGormCategories(r.db.WithContext(ctx))
+}
+
+// GormPayees is a PayeeRepository backed by GORM
+type GormPayees struct {
+	db Provider
+}
+
+// NewGormPayees creates a GORM payee repository
+func NewGormPayees(db Provider) *GormPayees {
+	return &GormPayees{db: db}
+}
+
+// withAliases preloads the aliases of payees in the order they were added
+func withAliases(db *gorm.DB) *gorm.DB {
+	return db.Preload("Aliases", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
+
+// Create stores a new payee with its aliases and sets their IDs
+func (r *GormPayees) Create(payee *models.Payee) error {
+	return r.db().Create(payee).Error
+}
+
+// FindByID returns a payee with its aliases
+func (r *GormPayees) FindByID(id uint) (models.Payee, error) {
+	var payee models.Payee
+	err := withAliases(r.db()).First(&payee, id).Error
+	return payee, err
+}
+
+// FindByName returns the payee with the given name, ignoring the payee excludeID
+func (r *GormPayees) FindByName(name string, excludeID uint) (models.Payee, error) {
+	var payee models.Payee
+	err := r.db().Where("name = ? AND id != ?", name, excludeID).First(&payee).Error
+	return payee, err
+}
+
+// List returns every payee with its aliases, ordered by name
+func (r *GormPayees) List() ([]models.Payee, error) {
+	var payees []models.Payee
+	err := withAliases(r.db()).Order("name").Find(&payees).Error
+	return payees, err
+}
+
+// Save writes the name and default category of a payee and replaces its aliases in one transaction
+func (r *GormPayees) Save(payee *models.Payee) error {
+	return r.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Payee{ID: payee.ID}).Select("name", "default_category_id").Updates(payee).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("payee_id = ?", payee.ID).Delete(&models.PayeeAlias{}).Error; err != nil {
+			return err
+		}
+		if len(payee.Aliases) == 0 {
+			return nil
+		}
+		for i := range payee.Aliases {
+			payee.Aliases[i].ID = 0
+			payee.Aliases[i].PayeeID = payee.ID
+		}
+		return tx.Create(&payee.Aliases).Error
+	})
+}
+
+// Delete removes a payee with its aliases and unlinks its transactions, including
+// deleted ones, and its recurring transactions
+func (r *GormPayees) Delete(id uint) error {
+	return r.db().Transaction(func(tx *gorm.DB) error {
+		// The payee is not part of the transaction history, so no version is recorded
+		if err := tx.Unscoped().Model(&models.Transaction{}).Where("payee_id = ?", id).UpdateColumn("payee_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RecurringTransaction{}).Where("payee_id = ?", id).Update("payee_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("payee_id = ?", id).Delete(&models.PayeeAlias{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Payee{ID: id}).Error
+	})
+}
+
+// Merge moves the transactions and aliases of sourceID to targetID and deletes sourceID in one transaction
+func (r *GormPayees) Merge(sourceID, targetID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Transaction{}).Where("payee_id = ?", sourceID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			// The records are passed as a slice so the version hook runs for each of them
+			transactions := make([]models.Transaction, len(ids))
+			for i, id := range ids {
+				transactions[i].ID = id
+			}
+			if err := tx.Unscoped().Model(&transactions).Update("payee_id", targetID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.RecurringTransaction{}).Where("payee_id = ?", sourceID).Update("payee_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PayeeAlias{}).Where("payee_id = ?", sourceID).Update("payee_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Payee{}).Where("id = ? AND default_category_id IS NULL", targetID).
+			Update("default_category_id", tx.Model(&models.Payee{}).Select("default_category_id").Where("id = ?", sourceID)).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Payee{ID: sourceID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *GormPayees) WithContext(ctx context.Context) PayeeRepository {
+	return NewGormPayees(r.db.WithContext(ctx))
+}
+
+// GormRecurring is a RecurringRepository backed by GORM
+type GormRecurring struct {
+	db Provider
+}
+
+// NewGormRecurring creates a GORM recurring transaction repository
+func NewGormRecurring(db Provider) *GormRecurring {
+	return &GormRecurring{db: db}
+}
+
+// Create stores a new schedule and sets its ID
+func (r *GormRecurring) Create(schedule *models.RecurringTransaction) error {
+	return r.db().Create(schedule).Error
+}
+
+// FindByID returns a schedule
+func (r *GormRecurring) FindByID(id uint) (models.RecurringTransaction, error) {
+	var schedule models.RecurringTransaction
+	err := r.db().First(&schedule, id).Error
+	return schedule, err
+}
+
+// FindBySubscriptionKey returns the schedule created from a detected subscription
+func (r *GormRecurring) FindBySubscriptionKey(key string) (models.RecurringTransaction, error) {
+	var schedule models.RecurringTransaction
+	err := r.db().Where("subscription_key = ?", key).First(&schedule).Error
+	return schedule, err
+}
+
+// List returns every schedule, the next one due first
+func (r *GormRecurring) List() ([]models.RecurringTransaction, error) {
+	var schedules []models.RecurringTransaction
+	err := r.db().Order("next_date, id").Find(&schedules).Error
+	return schedules, err
+}
+
+// Due returns the schedules whose next date is on or before now
+func (r *GormRecurring) Due(now time.Time) ([]models.RecurringTransaction, error) {
+	var schedules []models.RecurringTransaction
+	err := r.db().Where("next_date <= ?", now).Order("next_date, id").Find(&schedules).Error
+	return schedules, err
+}
+
+// Advance moves the next date of a schedule from from to to. The update only
+// applies while the next date is still from, so of two servers posting the
+// same charge only one succeeds.
+func (r *GormRecurring) Advance(id uint, from, to time.Time) (bool, error) {
+	update := r.db().Model(&models.RecurringTransaction{}).Where("id = ? AND next_date = ?", id, from).Update("next_date", to)
+	return update.RowsAffected > 0, update.Error
+}
+
+// Delete removes a schedule; the transactions it posted are kept
+func (r *GormRecurring) Delete(id uint) error {
+	return r.db().Delete(&models.RecurringTransaction{ID: id}).Error
+}
+
+// Atomic runs fn in a database transaction
+func (r *GormRecurring) Atomic(ctx context.Context, fn func(ctx context.Context, schedules RecurringRepository) error) error {
+	return r.db().Transaction(func(tx *gorm.DB) error {
+		return fn(ContextWithTx(ctx, tx), NewGormRecurring(func() *gorm.DB { return tx }))
+	})
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *GormRecurring) WithContext(ctx context.Context) RecurringRepository {
+	return NewGormRecurring(r.db.WithContext(ctx))
+}
+
+// GormAccounts is an AccountRepository backed by GORM
+type GormAccounts struct {
+	db Provider
+}
+
+// NewGormAccounts creates a GORM bank account repository
+func NewGormAccounts(db Provider) *GormAccounts {
+	return &GormAccounts{db: db}
+}
+
+// Create stores a new bank account and sets its ID
+func (r *GormAccounts) Create(account *models.BankAccount) error {
+	return r.db().Create(account).Error
+}
+
+// FindByID returns a bank account
+func (r *GormAccounts) FindByID(id uint) (models.BankAccount, error) {
+	var account models.BankAccount
+	err := r.db().First(&account, id).Error
+	return account, err
+}
+
+// List returns active accounts, or every account when includeInactive is set
+func (r *GormAccounts) List(includeInactive bool) ([]models.BankAccount, error) {
+	query := r.db()
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var accounts []models.BankAccount
+	err := query.Find(&accounts).Error
+	return accounts, err
+}
+
+// Save writes every field of a bank account
+func (r *GormAccounts) Save(account *models.BankAccount) error {
+	return r.db().Save(account).Error
+}
+
+// Delete soft deletes a bank account
+func (r *GormAccounts) Delete(id uint) error {
+	return r.db().Delete(&models.BankAccount{ID: id}).Error
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *GormAccounts) WithContext(ctx context.Context) AccountRepository {
+	return NewGormAccounts(r.db.WithContext(ctx))
+}
+
+// GormUsers is a UserRepository backed by GORM
+type GormUsers struct {
+	db Provider
+}
+
+// NewGormUsers creates a GORM user repository
+func NewGormUsers(db Provider) *GormUsers {
+	return &GormUsers{db: db}
+}
+
+// Create stores a new user and sets its ID
+func (r *GormUsers) Create(user *models.User) error {
+	return r.db().Create(user).Error
+}
+
+// FindByTokenHash returns the user whose API token has the given hash
+func (r *GormUsers) FindByTokenHash(hash string) (models.User, error) {
+	var user models.User
+	err := r.db().Where("token_hash = ?", hash).First(&user).Error
+	return user, err
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *GormUsers) WithContext(ctx context.Context) UserRepository {
+	return NewGormUsers(r.db.WithContext(ctx))
+}
+
+// GormWebhooks is a WebhookRepository backed by GORM
+type GormWebhooks struct {
+	db Provider
+}
+
+// NewGormWebhooks creates a GORM webhook repository
+func NewGormWebhooks(db Provider) *GormWebhooks {
+	return &GormWebhooks{db: db}
+}
+
+// Create stores a new subscription and sets its ID
+func (r *GormWebhooks) Create(subscription *models.WebhookSubscription) error {
+	return r.db().Create(subscription).Error
+}
+
+// FindByID returns a subscription
+func (r *GormWebhooks) FindByID(id uint) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.db().First(&subscription, id).Error
+	return subscription, err
+}
+
+// List returns the subscriptions of a user ordered by ID
+func (r *GormWebhooks) List(userID uint) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db().Where("user_id = ?", userID).Order("id ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Save writes every field of a subscription
+func (r *GormWebhooks) Save(subscription *models.WebhookSubscription) error {
+	return r.db().Save(subscription).Error
+}
+
+// Delete soft deletes a subscription; the dispatcher fails its pending deliveries
+func (r *GormWebhooks) Delete(id uint) error {
+	return r.db().Delete(&models.WebhookSubscription{ID: id}).Error
+}
+
+// Deliveries returns up to limit deliveries of a subscription, newest first
+func (r *GormWebhooks) Deliveries(subscriptionID uint, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := r.db().Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *GormWebhooks) WithContext(ctx context.Context) WebhookRepository {
+	return NewGormWebhooks(r.db.WithContext(ctx))
+}
@@ -0,0 +1,1129 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"expense-api/models"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore keeps transactions, categories, payees, bank accounts, recurring transactions, users and webhooks in memory.
+// It backs the in-memory repositories used by tests and alternative deployments.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	atomic       sync.Mutex // serialises Atomic blocks
+	transactions map[uint]models.Transaction
+	versions     map[uint][]models.TransactionVersion // by transaction ID, oldest first
+	categories   map[uint]models.Category
+	accounts     map[uint]models.BankAccount
+	payees       map[uint]models.Payee
+	recurring    map[uint]models.RecurringTransaction
+	users        map[uint]models.User
+	webhooks     map[uint]models.WebhookSubscription
+	deliveries   map[uint]models.WebhookDelivery
+	lastIDs      map[string]uint
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		transactions: map[uint]models.Transaction{},
+		versions:     map[uint][]models.TransactionVersion{},
+		categories:   map[uint]models.Category{},
+		accounts:     map[uint]models.BankAccount{},
+		payees:       map[uint]models.Payee{},
+		recurring:    map[uint]models.RecurringTransaction{},
+		users:        map[uint]models.User{},
+		webhooks:     map[uint]models.WebhookSubscription{},
+		deliveries:   map[uint]models.WebhookDelivery{},
+		lastIDs:      map[string]uint{},
+	}
+}
+
+// Transactions returns a TransactionRepository over the store
+func (s *MemoryStore) Transactions() *MemoryTransactions {
+	return &MemoryTransactions{store: s}
+}
+
+// Categories returns a CategoryRepository over the store
+func (s *MemoryStore) Categories() *MemoryCategories {
+	return &MemoryCategories{store: s}
+}
+
+// Accounts returns an AccountRepository over the store
+func (s *MemoryStore) Accounts() *MemoryAccounts {
+	return &MemoryAccounts{store: s}
+}
+
+// Payees returns a PayeeRepository over the store
+func (s *MemoryStore) Payees() *MemoryPayees {
+	return &MemoryPayees{store: s}
+}
+
+// Recurring returns a RecurringRepository over the store
+func (s *MemoryStore) Recurring() *MemoryRecurring {
+	return &MemoryRecurring{store: s}
+}
+
+// Users returns a UserRepository over the store
+func (s *MemoryStore) Users() *MemoryUsers {
+	return &MemoryUsers{store: s}
+}
+
+// Webhooks returns a WebhookRepository over the store
+func (s *MemoryStore) Webhooks() *MemoryWebhooks {
+	return &MemoryWebhooks{store: s}
+}
+
+// newID allocates the next ID of a table, starting at 1 like the database does
+func (s *MemoryStore) newID(table string) uint {
+	s.lastIDs[table]++
+	return s.lastIDs[table]
+}
+
+// recordVersion appends a snapshot of a transaction to its history, as the
+// version hooks of the GORM models do; the caller holds the lock
+func (s *MemoryStore) recordVersion(transaction models.Transaction, operation string) {
+	version := models.NewTransactionVersion(transaction, len(s.versions[transaction.ID])+1, operation)
+	version.ID = s.newID("transaction_versions")
+	version.CreatedAt = time.Now()
+	s.versions[transaction.ID] = append(s.versions[transaction.ID], version)
+}
+
+// applyFields overlays column values onto a record by round-tripping it through
+// its JSON form. It decodes into a fresh value so pointer fields the record
+// shares with callers, such as a parent or category ID, are never written through.
+func applyFields(record interface{}, fields map[string]interface{}) error {
+	current, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	var merged map[string]interface{}
+	if err := json.Unmarshal(current, &merged); err != nil {
+		return err
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	updated, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	fresh := reflect.New(reflect.TypeOf(record).Elem())
+	if err := json.Unmarshal(updated, fresh.Interface()); err != nil {
+		return err
+	}
+	reflect.ValueOf(record).Elem().Set(fresh.Elem())
+	return nil
+}
+
+// MemoryTransactions is an in-memory TransactionRepository
+type MemoryTransactions struct {
+	store *MemoryStore
+}
+
+// withRelations fills in the category, accounts and payee of a transaction; the caller holds the lock
+func (r *MemoryTransactions) withRelations(t models.Transaction) models.Transaction {
+	t.Category = models.Category{}
+	if t.CategoryID != nil {
+		t.Category = r.store.categories[*t.CategoryID]
+	}
+	t.BankAccount = r.store.accounts[t.BankAccountID]
+	t.DestinationBankAccount = models.BankAccount{}
+	if t.DestinationBankAccountID != nil {
+		t.DestinationBankAccount = r.store.accounts[*t.DestinationBankAccountID]
+	}
+	t.Payee = models.Payee{}
+	if t.PayeeID != nil {
+		t.Payee = r.store.payees[*t.PayeeID]
+		t.Payee.Aliases = nil
+	}
+	return t
+}
+
+// Create stores a new transaction and sets its ID
+func (r *MemoryTransactions) Create(transaction *models.Transaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	transaction.ID = r.store.newID("transactions")
+	transaction.CreatedAt = now
+	transaction.UpdatedAt = now
+	r.store.transactions[transaction.ID] = *transaction
+	r.store.recordVersion(*transaction, models.VersionOperationCreate)
+	return nil
+}
+
+// CreateBatch stores transactions and sets their IDs
+func (r *MemoryTransactions) CreateBatch(transactions []models.Transaction, batchSize int) error {
+	for i := range transactions {
+		if err := r.Create(&transactions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByID returns a transaction with its category and accounts loaded
+func (r *MemoryTransactions) FindByID(id uint) (models.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	transaction, ok := r.store.transactions[id]
+	if !ok || transaction.DeletedAt.Valid {
+		return models.Transaction{}, ErrNotFound
+	}
+	return r.withRelations(transaction), nil
+}
+
+// FindWithDeleted returns a transaction whether or not it is deleted, with relations loaded
+func (r *MemoryTransactions) FindWithDeleted(id uint) (models.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	transaction, ok := r.store.transactions[id]
+	if !ok {
+		return models.Transaction{}, ErrNotFound
+	}
+	return r.withRelations(transaction), nil
+}
+
+// FindByIDs returns the transactions that exist among ids, with relations loaded
+func (r *MemoryTransactions) FindByIDs(ids []uint) ([]models.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var transactions []models.Transaction
+	for _, id := range ids {
+		if transaction, ok := r.store.transactions[id]; ok && !transaction.DeletedAt.Valid {
+			transactions = append(transactions, r.withRelations(transaction))
+		}
+	}
+	return transactions, nil
+}
+
+// List returns matching transactions with relations loaded, newest date first
+func (r *MemoryTransactions) List(filter TransactionFilter) ([]models.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var transactions []models.Transaction
+	for _, t := range r.store.transactions {
+		if t.DeletedAt.Valid {
+			continue
+		}
+		if filter.Type != "" && t.Type != filter.Type {
+			continue
+		}
+		if filter.ExcludeType != "" && t.Type == filter.ExcludeType {
+			continue
+		}
+		if filter.BankAccountID != nil && t.BankAccountID != *filter.BankAccountID &&
+			(t.DestinationBankAccountID == nil || *t.DestinationBankAccountID != *filter.BankAccountID) {
+			continue
+		}
+		if filter.From != nil && t.Date.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && t.Date.After(*filter.To) {
+			continue
+		}
+		transactions = append(transactions, r.withRelations(t))
+	}
+
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].Date.Equal(transactions[j].Date.Time) {
+			return transactions[i].ID > transactions[j].ID
+		}
+		return transactions[i].Date.After(transactions[j].Date.Time)
+	})
+	return transactions, nil
+}
+
+// Recent returns the most recently created transactions
+func (r *MemoryTransactions) Recent(limit int) ([]models.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var transactions []models.Transaction
+	for _, t := range r.store.transactions {
+		if !t.DeletedAt.Valid {
+			transactions = append(transactions, r.withRelations(t))
+		}
+	}
+
+	// IDs increase with creation time and break ties between equal timestamps
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID > transactions[j].ID })
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+	return transactions, nil
+}
+
+// Totals counts and sums transactions, optionally of one type
+func (r *MemoryTransactions) Totals(transactionType string) (TransactionTotals, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var totals TransactionTotals
+	for _, t := range r.store.transactions {
+		if t.DeletedAt.Valid || (transactionType != "" && t.Type != transactionType) {
+			continue
+		}
+		totals.Count++
+		totals.Amount += t.Amount
+	}
+	return totals, nil
+}
+
+// Update applies the given column values to a transaction
+func (r *MemoryTransactions) Update(id uint, fields map[string]interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	transaction, ok := r.store.transactions[id]
+	if !ok || transaction.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	if err := applyFields(&transaction, fields); err != nil {
+		return err
+	}
+	transaction.UpdatedAt = time.Now()
+	r.store.transactions[id] = transaction
+	r.store.recordVersion(transaction, models.VersionOperationUpdate)
+	return nil
+}
+
+// Delete soft deletes a transaction
+func (r *MemoryTransactions) Delete(id uint) error {
+	return r.DeleteBatch([]uint{id})
+}
+
+// DeleteBatch soft deletes several transactions, failing without changes if one does not exist
+func (r *MemoryTransactions) DeleteBatch(ids []uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, id := range ids {
+		if transaction, ok := r.store.transactions[id]; !ok || transaction.DeletedAt.Valid {
+			return ErrNotFound
+		}
+	}
+	now := time.Now()
+	for _, id := range ids {
+		transaction := r.store.transactions[id]
+		transaction.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		r.store.transactions[id] = transaction
+		r.store.recordVersion(transaction, models.VersionOperationDelete)
+	}
+	return nil
+}
+
+// ListDeleted returns the deleted transactions with relations loaded, most recently deleted first
+func (r *MemoryTransactions) ListDeleted() ([]models.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var transactions []models.Transaction
+	for _, t := range r.store.transactions {
+		if t.DeletedAt.Valid {
+			transactions = append(transactions, r.withRelations(t))
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].DeletedAt.Time.Equal(transactions[j].DeletedAt.Time) {
+			return transactions[i].ID > transactions[j].ID
+		}
+		return transactions[i].DeletedAt.Time.After(transactions[j].DeletedAt.Time)
+	})
+	return transactions, nil
+}
+
+// Versions returns the recorded versions of a transaction, oldest first
+func (r *MemoryTransactions) Versions(id uint) ([]models.TransactionVersion, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return slices.Clone(r.store.versions[id]), nil
+}
+
+// FindVersion returns one recorded version of a transaction
+func (r *MemoryTransactions) FindVersion(id uint, version int) (models.TransactionVersion, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	versions := r.store.versions[id]
+	if version < 1 || version > len(versions) {
+		return models.TransactionVersion{}, ErrNotFound
+	}
+	return versions[version-1], nil
+}
+
+// Restore writes the fields of a version back to its transaction and undeletes it
+func (r *MemoryTransactions) Restore(version models.TransactionVersion) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	transaction, ok := r.store.transactions[version.RecordID]
+	if !ok {
+		return ErrNotFound
+	}
+	transaction.TransactionID = version.TransactionID
+	transaction.Amount = version.Amount
+	transaction.Type = version.Type
+	transaction.CategoryID = version.CategoryID
+	transaction.BankAccountID = version.BankAccountID
+	transaction.DestinationBankAccountID = version.DestinationBankAccountID
+	transaction.Description = version.Description
+	transaction.Date = version.Date
+	transaction.DeletedAt = gorm.DeletedAt{}
+	transaction.UpdatedAt = time.Now()
+	r.store.transactions[transaction.ID] = transaction
+	r.store.recordVersion(transaction, models.VersionOperationRestore)
+	return nil
+}
+
+// Undelete brings back a deleted transaction
+func (r *MemoryTransactions) Undelete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	transaction, ok := r.store.transactions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	transaction.DeletedAt = gorm.DeletedAt{}
+	transaction.UpdatedAt = time.Now()
+	r.store.transactions[id] = transaction
+	r.store.recordVersion(transaction, models.VersionOperationUndelete)
+	return nil
+}
+
+// Atomic runs fn and restores the stored transactions if it fails.
+// Writes made outside Atomic blocks while fn runs are not isolated from it.
+func (r *MemoryTransactions) Atomic(fn func(TransactionRepository) error) error {
+	r.store.atomic.Lock()
+	defer r.store.atomic.Unlock()
+
+	r.store.mu.RLock()
+	transactions := maps.Clone(r.store.transactions)
+	versions := maps.Clone(r.store.versions)
+	lastID, lastVersionID := r.store.lastIDs["transactions"], r.store.lastIDs["transaction_versions"]
+	r.store.mu.RUnlock()
+
+	if err := fn(r); err != nil {
+		r.store.mu.Lock()
+		r.store.transactions = transactions
+		r.store.versions = versions
+		r.store.lastIDs["transactions"] = lastID
+		r.store.lastIDs["transaction_versions"] = lastVersionID
+		r.store.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// CountByCategory counts transactions using a category
+func (r *MemoryTransactions) CountByCategory(categoryID uint) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var count int64
+	for _, t := range r.store.transactions {
+		if !t.DeletedAt.Valid && t.CategoryID != nil && *t.CategoryID == categoryID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountByAccount counts transactions from or to a bank account
+func (r *MemoryTransactions) CountByAccount(accountID uint) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var count int64
+	for _, t := range r.store.transactions {
+		if !t.DeletedAt.Valid && (t.BankAccountID == accountID || (t.DestinationBankAccountID != nil && *t.DestinationBankAccountID == accountID)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// WithContext returns the repository itself; the in-memory store does not use contexts
+func (r *MemoryTransactions) WithContext(ctx context.Context) TransactionRepository {
+	return r
+}
+
+// MemoryCategories is an in-memory CategoryRepository
+type MemoryCategories struct {
+	store *MemoryStore
+}
+
+// Create stores a new category and sets its ID
+func (r *MemoryCategories) Create(category *models.Category) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	category.ID = r.store.newID("categories")
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	r.store.categories[category.ID] = *category
+	return nil
+}
+
+// FindByID returns a category
+func (r *MemoryCategories) FindByID(id uint) (models.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	category, ok := r.store.categories[id]
+	if !ok {
+		return models.Category{}, ErrNotFound
+	}
+	return category, nil
+}
+
+// FindByName returns the category with the given name among the children of
+// parentID, ignoring the category excludeID
+func (r *MemoryCategories) FindByName(name string, parentID *uint, excludeID uint) (models.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, category := range r.store.categories {
+		sameParent := (category.ParentID == nil && parentID == nil) ||
+			(category.ParentID != nil && parentID != nil && *category.ParentID == *parentID)
+		if category.Name == name && sameParent && category.ID != excludeID {
+			return category, nil
+		}
+	}
+	return models.Category{}, ErrNotFound
+}
+
+// List returns every category ordered by ID
+func (r *MemoryCategories) List() ([]models.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var categories []models.Category
+	for _, category := range r.store.categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories, nil
+}
+
+// Update applies the given column values to a category
+func (r *MemoryCategories) Update(id uint, fields map[string]interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	category, ok := r.store.categories[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := applyFields(&category, fields); err != nil {
+		return err
+	}
+	category.UpdatedAt = time.Now()
+	r.store.categories[id] = category
+	return nil
+}
+
+// Delete removes a category
+func (r *MemoryCategories) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.categories[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.categories, id)
+	return nil
+}
+
+// Merge moves the transactions and subcategories of sourceID to targetID and deletes sourceID
+func (r *MemoryCategories) Merge(sourceID, targetID uint) ([]uint, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.categories[sourceID]; !ok {
+		return nil, ErrNotFound
+	}
+	if _, ok := r.store.categories[targetID]; !ok {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	var moved []uint
+	for id, t := range r.store.transactions {
+		if t.CategoryID != nil && *t.CategoryID == sourceID {
+			categoryID := targetID
+			t.CategoryID = &categoryID
+			t.UpdatedAt = now
+			r.store.transactions[id] = t
+			r.store.recordVersion(t, models.VersionOperationUpdate)
+			moved = append(moved, id)
+		}
+	}
+	for id, category := range r.store.categories {
+		if category.ParentID != nil && *category.ParentID == sourceID {
+			parentID := targetID
+			category.ParentID = &parentID
+			category.UpdatedAt = now
+			r.store.categories[id] = category
+		}
+	}
+	for id, payee := range r.store.payees {
+		if payee.DefaultCategoryID != nil && *payee.DefaultCategoryID == sourceID {
+			categoryID := targetID
+			payee.DefaultCategoryID = &categoryID
+			payee.UpdatedAt = now
+			r.store.payees[id] = payee
+		}
+	}
+	for id, schedule := range r.store.recurring {
+		if schedule.CategoryID != nil && *schedule.CategoryID == sourceID {
+			categoryID := targetID
+			schedule.CategoryID = &categoryID
+			schedule.UpdatedAt = now
+			r.store.recurring[id] = schedule
+		}
+	}
+	delete(r.store.categories, sourceID)
+	return moved, nil
+}
+
+// WithContext returns the repository itself; the in-memory store does not use contexts
+func (r *MemoryCategories) WithContext(ctx context.Context) CategoryRepository {
+	return r
+}
+
+// MemoryPayees is an in-memory PayeeRepository
+type MemoryPayees struct {
+	store *MemoryStore
+}
+
+// copyPayee returns a payee whose aliases do not share memory with the stored one
+func copyPayee(payee models.Payee) models.Payee {
+	payee.Aliases = append([]models.PayeeAlias(nil), payee.Aliases...)
+	return payee
+}
+
+// setAliases gives new aliases of a payee their IDs; the caller holds the lock
+func (r *MemoryPayees) setAliases(payee *models.Payee, now time.Time) {
+	for i := range payee.Aliases {
+		payee.Aliases[i].ID = r.store.newID("payee_aliases")
+		payee.Aliases[i].PayeeID = payee.ID
+		payee.Aliases[i].CreatedAt = now
+	}
+}
+
+// Create stores a new payee with its aliases and sets their IDs
+func (r *MemoryPayees) Create(payee *models.Payee) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	payee.ID = r.store.newID("payees")
+	payee.CreatedAt = now
+	payee.UpdatedAt = now
+	r.setAliases(payee, now)
+	r.store.payees[payee.ID] = copyPayee(*payee)
+	return nil
+}
+
+// FindByID returns a payee with its aliases
+func (r *MemoryPayees) FindByID(id uint) (models.Payee, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	payee, ok := r.store.payees[id]
+	if !ok {
+		return models.Payee{}, ErrNotFound
+	}
+	return copyPayee(payee), nil
+}
+
+// FindByName returns the payee with the given name, ignoring the payee excludeID
+func (r *MemoryPayees) FindByName(name string, excludeID uint) (models.Payee, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, payee := range r.store.payees {
+		if payee.Name == name && payee.ID != excludeID {
+			return copyPayee(payee), nil
+		}
+	}
+	return models.Payee{}, ErrNotFound
+}
+
+// List returns every payee with its aliases, ordered by name
+func (r *MemoryPayees) List() ([]models.Payee, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var payees []models.Payee
+	for _, payee := range r.store.payees {
+		payees = append(payees, copyPayee(payee))
+	}
+	sort.Slice(payees, func(i, j int) bool { return payees[i].Name < payees[j].Name })
+	return payees, nil
+}
+
+// Save writes the name and default category of a payee and replaces its aliases
+func (r *MemoryPayees) Save(payee *models.Payee) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.payees[payee.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	stored.Name = payee.Name
+	stored.DefaultCategoryID = payee.DefaultCategoryID
+	stored.UpdatedAt = now
+	r.setAliases(payee, now)
+	stored.Aliases = payee.Aliases
+	r.store.payees[payee.ID] = copyPayee(stored)
+	return nil
+}
+
+// Delete removes a payee with its aliases and unlinks its transactions
+func (r *MemoryPayees) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.payees[id]; !ok {
+		return ErrNotFound
+	}
+	for transactionID, t := range r.store.transactions {
+		if t.PayeeID != nil && *t.PayeeID == id {
+			t.PayeeID = nil
+			r.store.transactions[transactionID] = t
+		}
+	}
+	for scheduleID, schedule := range r.store.recurring {
+		if schedule.PayeeID != nil && *schedule.PayeeID == id {
+			schedule.PayeeID = nil
+			r.store.recurring[scheduleID] = schedule
+		}
+	}
+	delete(r.store.payees, id)
+	return nil
+}
+
+// Merge moves the transactions and aliases of sourceID to targetID and deletes sourceID
+func (r *MemoryPayees) Merge(sourceID, targetID uint) ([]uint, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	source, ok := r.store.payees[sourceID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	target, ok := r.store.payees[targetID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	var moved []uint
+	for id, t := range r.store.transactions {
+		if t.PayeeID != nil && *t.PayeeID == sourceID {
+			payeeID := targetID
+			t.PayeeID = &payeeID
+			t.UpdatedAt = now
+			r.store.transactions[id] = t
+			r.store.recordVersion(t, models.VersionOperationUpdate)
+			moved = append(moved, id)
+		}
+	}
+	for id, schedule := range r.store.recurring {
+		if schedule.PayeeID != nil && *schedule.PayeeID == sourceID {
+			payeeID := targetID
+			schedule.PayeeID = &payeeID
+			r.store.recurring[id] = schedule
+		}
+	}
+
+	target = copyPayee(target)
+	for _, alias := range source.Aliases {
+		alias.PayeeID = targetID
+		target.Aliases = append(target.Aliases, alias)
+	}
+	if target.DefaultCategoryID == nil {
+		target.DefaultCategoryID = source.DefaultCategoryID
+	}
+	target.UpdatedAt = now
+	r.store.payees[targetID] = target
+	delete(r.store.payees, sourceID)
+	return moved, nil
+}
+
+// WithContext returns the repository itself; the in-memory store does not use contexts
+func (r *MemoryPayees) WithContext(ctx context.Context) PayeeRepository {
+	return r
+}
+
+// MemoryRecurring is an in-memory RecurringRepository
+type MemoryRecurring struct {
+	store *MemoryStore
+}
+
+// Create stores a new schedule and sets its ID
+func (r *MemoryRecurring) Create(schedule *models.RecurringTransaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	schedule.ID = r.store.newID("recurring_transactions")
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	r.store.recurring[schedule.ID] = *schedule
+	return nil
+}
+
+// FindByID returns a schedule
+func (r *MemoryRecurring) FindByID(id uint) (models.RecurringTransaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	schedule, ok := r.store.recurring[id]
+	if !ok {
+		return models.RecurringTransaction{}, ErrNotFound
+	}
+	return schedule, nil
+}
+
+// FindBySubscriptionKey returns the schedule created from a detected subscription
+func (r *MemoryRecurring) FindBySubscriptionKey(key string) (models.RecurringTransaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, schedule := range r.store.recurring {
+		if schedule.SubscriptionKey == key {
+			return schedule, nil
+		}
+	}
+	return models.RecurringTransaction{}, ErrNotFound
+}
+
+// List returns every schedule, the next one due first
+func (r *MemoryRecurring) List() ([]models.RecurringTransaction, error) {
+	return r.matching(func(models.RecurringTransaction) bool { return true }), nil
+}
+
+// Due returns the schedules whose next date is on or before now
+func (r *MemoryRecurring) Due(now time.Time) ([]models.RecurringTransaction, error) {
+	return r.matching(func(schedule models.RecurringTransaction) bool { return !schedule.NextDate.After(now) }), nil
+}
+
+// matching returns the schedules keep accepts, the next one due first
+func (r *MemoryRecurring) matching(keep func(models.RecurringTransaction) bool) []models.RecurringTransaction {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var schedules []models.RecurringTransaction
+	for _, schedule := range r.store.recurring {
+		if keep(schedule) {
+			schedules = append(schedules, schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].NextDate.Equal(schedules[j].NextDate) {
+			return schedules[i].NextDate.Before(schedules[j].NextDate)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules
+}
+
+// Advance moves the next date of a schedule from from to to while it is still from
+func (r *MemoryRecurring) Advance(id uint, from, to time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	schedule, ok := r.store.recurring[id]
+	if !ok || !schedule.NextDate.Equal(from) {
+		return false, nil
+	}
+	schedule.NextDate = to
+	schedule.UpdatedAt = time.Now()
+	r.store.recurring[id] = schedule
+	return true, nil
+}
+
+// Atomic runs fn and restores the stored schedules and transactions if it fails.
+// Writes made outside Atomic blocks while fn runs are not isolated from it.
+func (r *MemoryRecurring) Atomic(ctx context.Context, fn func(ctx context.Context, schedules RecurringRepository) error) error {
+	r.store.atomic.Lock()
+	defer r.store.atomic.Unlock()
+
+	r.store.mu.RLock()
+	recurring := maps.Clone(r.store.recurring)
+	transactions := maps.Clone(r.store.transactions)
+	versions := maps.Clone(r.store.versions)
+	lastIDs := maps.Clone(r.store.lastIDs)
+	r.store.mu.RUnlock()
+
+	if err := fn(ctx, r); err != nil {
+		r.store.mu.Lock()
+		r.store.recurring = recurring
+		r.store.transactions = transactions
+		r.store.versions = versions
+		r.store.lastIDs = lastIDs
+		r.store.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Delete removes a schedule
+func (r *MemoryRecurring) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.recurring[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.recurring, id)
+	return nil
+}
+
+// WithContext returns the repository itself; the in-memory store does not use contexts
+func (r *MemoryRecurring) WithContext(ctx context.Context) RecurringRepository {
+	return r
+}
+
+// MemoryAccounts is an in-memory AccountRepository
+type MemoryAccounts struct {
+	store *MemoryStore
+}
+
+// Create stores a new bank account and sets its ID
+func (r *MemoryAccounts) Create(account *models.BankAccount) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	account.ID = r.store.newID("bank_accounts")
+	account.CreatedAt = now
+	account.UpdatedAt = now
+	r.store.accounts[account.ID] = *account
+	return nil
+}
+
+// FindByID returns a bank account
+func (r *MemoryAccounts) FindByID(id uint) (models.BankAccount, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	account, ok := r.store.accounts[id]
+	if !ok {
+		return models.BankAccount{}, ErrNotFound
+	}
+	return account, nil
+}
+
+// List returns active accounts, or every account when includeInactive is set
+func (r *MemoryAccounts) List(includeInactive bool) ([]models.BankAccount, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var accounts []models.BankAccount
+	for _, account := range r.store.accounts {
+		if includeInactive || account.IsActive {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts, nil
+}
+
+// Save writes every field of a bank account
+func (r *MemoryAccounts) Save(account *models.BankAccount) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.accounts[account.ID]; !ok {
+		return ErrNotFound
+	}
+	account.UpdatedAt = time.Now()
+	r.store.accounts[account.ID] = *account
+	return nil
+}
+
+// Delete removes a bank account
+func (r *MemoryAccounts) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.accounts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.accounts, id)
+	return nil
+}
+
+// WithContext returns the repository itself; the in-memory store does not use contexts
+func (r *MemoryAccounts) WithContext(ctx context.Context) AccountRepository {
+	return r
+}
+
+// MemoryUsers is an in-memory UserRepository
+type MemoryUsers struct {
+	store *MemoryStore
+}
+
+// Create stores a new user and sets its ID
+func (r *MemoryUsers) Create(user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	user.ID = r.store.newID("users")
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+// FindByTokenHash returns the user whose API token has the given hash
+func (r *MemoryUsers) FindByTokenHash(hash string) (models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if user.TokenHash == hash {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+// WithContext returns the repository itself; the in-memory store does not use contexts
+func (r *MemoryUsers) WithContext(ctx context.Context) UserRepository {
+	return r
+}
+
+// MemoryWebhooks is an in-memory WebhookRepository.
+// Nothing dispatches its deliveries; CreateDelivery adds them.
+type MemoryWebhooks struct {
+	store *MemoryStore
+}
+
+// Create stores a new subscription and sets its ID
+func (r *MemoryWebhooks) Create(subscription *models.WebhookSubscription) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	subscription.ID = r.store.newID("webhook_subscriptions")
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+	r.store.webhooks[subscription.ID] = *subscription
+	return nil
+}
+
+// FindByID returns a subscription
+func (r *MemoryWebhooks) FindByID(id uint) (models.WebhookSubscription, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	subscription, ok := r.store.webhooks[id]
+	if !ok {
+		return models.WebhookSubscription{}, ErrNotFound
+	}
+	return subscription, nil
+}
+
+// List returns the subscriptions of a user ordered by ID
+func (r *MemoryWebhooks) List(userID uint) ([]models.WebhookSubscription, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var subscriptions []models.WebhookSubscription
+	for _, subscription := range r.store.webhooks {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].ID < subscriptions[j].ID })
+	return subscriptions, nil
+}
+
+// Save writes every field of a subscription
+func (r *MemoryWebhooks) Save(subscription *models.WebhookSubscription) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.webhooks[subscription.ID]; !ok {
+		return ErrNotFound
+	}
+	subscription.UpdatedAt = time.Now()
+	r.store.webhooks[subscription.ID] = *subscription
+	return nil
+}
+
+// Delete removes a subscription
+func (r *MemoryWebhooks) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.webhooks, id)
+	return nil
+}
+
+// CreateDelivery stores a delivery and sets its ID
+func (r *MemoryWebhooks) CreateDelivery(delivery *models.WebhookDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	delivery.ID = r.store.newID("webhook_deliveries")
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	r.store.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// Deliveries returns up to limit deliveries of a subscription, newest first
+func (r *MemoryWebhooks) Deliveries(subscriptionID uint, status string, limit int) ([]models.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range r.store.deliveries {
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// WithContext returns the repository itself; the in-memory store does not use contexts
+func (r *MemoryWebhooks) WithContext(ctx context.Context) WebhookRepository {
+	return r
+}
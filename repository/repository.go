@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"expense-api/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned by every repository when a record does not exist
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// ErrDuplicate is returned when a write would break a unique index
+var ErrDuplicate = gorm.ErrDuplicatedKey
+
+// TransactionFilter narrows the transactions returned by List
+type TransactionFilter struct {
+	Type          string     // Only transactions of this type
+	ExcludeType   string     // Skip transactions of this type
+	BankAccountID *uint      // Transactions from or to this account
+	From          *time.Time // Transactions dated on or after this time
+	To            *time.Time // Transactions dated on or before this time
+}
+
+// TransactionTotals holds the count and summed amount of a set of transactions
+type TransactionTotals struct {
+	Count  int64
+	Amount float64
+}
+
+// TransactionRepository stores transactions
+type TransactionRepository interface {
+	// Create stores a new transaction and sets its ID
+	Create(transaction *models.Transaction) error
+	// CreateBatch stores transactions in inserts of up to batchSize rows and sets their IDs
+	CreateBatch(transactions []models.Transaction, batchSize int) error
+	// FindByID returns a transaction with its category and accounts loaded
+	FindByID(id uint) (models.Transaction, error)
+	// FindByIDs returns the transactions that exist among ids, with relations loaded
+	FindByIDs(ids []uint) ([]models.Transaction, error)
+	// List returns matching transactions with relations loaded, newest date first
+	List(filter TransactionFilter) ([]models.Transaction, error)
+	// Recent returns the most recently created transactions
+	Recent(limit int) ([]models.Transaction, error)
+	// Totals counts and sums transactions, optionally of one type
+	Totals(transactionType string) (TransactionTotals, error)
+	// Update applies the given column values to a transaction
+	Update(id uint, fields map[string]interface{}) error
+	// Delete soft deletes a transaction; it keeps its history and can be undeleted
+	Delete(id uint) error
+	// DeleteBatch soft deletes several transactions at once
+	DeleteBatch(ids []uint) error
+	// FindWithDeleted returns a transaction whether or not it is deleted, with relations loaded
+	FindWithDeleted(id uint) (models.Transaction, error)
+	// ListDeleted returns the deleted transactions with relations loaded, most recently deleted first
+	ListDeleted() ([]models.Transaction, error)
+	// Versions returns the recorded versions of a transaction, oldest first
+	Versions(id uint) ([]models.TransactionVersion, error)
+	// FindVersion returns one recorded version of a transaction
+	FindVersion(id uint, version int) (models.TransactionVersion, error)
+	// Restore writes the fields of a version back to its transaction and undeletes it
+	Restore(version models.TransactionVersion) error
+	// Undelete brings back a deleted transaction
+	Undelete(id uint) error
+	// Atomic runs fn against a repository whose writes are committed together
+	// when fn returns nil and rolled back when it returns an error
+	Atomic(fn func(TransactionRepository) error) error
+	// CountByCategory counts transactions using a category
+	CountByCategory(categoryID uint) (int64, error)
+	// CountByAccount counts transactions from or to a bank account
+	CountByAccount(accountID uint) (int64, error)
+	// WithContext returns a repository whose queries run with ctx, so they are traced as part of it
+	WithContext(ctx context.Context) TransactionRepository
+}
+
+// CategoryRepository stores categories
+type CategoryRepository interface {
+	// Create, Update and Merge return ErrDuplicate when a live sibling already has the name
+	Create(category *models.Category) error
+	FindByID(id uint) (models.Category, error)
+	// FindByName returns the category with the given name under parentID, or at
+	// the top level when parentID is nil, ignoring the category excludeID
+	FindByName(name string, parentID *uint, excludeID uint) (models.Category, error)
+	List() ([]models.Category, error)
+	Update(id uint, fields map[string]interface{}) error
+	Delete(id uint) error
+	// Merge moves every transaction of sourceID, including deleted ones, and its
+	// subcategories to targetID and deletes sourceID, all in one database
+	// transaction. It returns the IDs of the moved transactions.
+	Merge(sourceID, targetID uint) ([]uint, error)
+	WithContext(ctx context.Context) CategoryRepository
+}
+
+// PayeeRepository stores payees with their aliases
+type PayeeRepository interface {
+	// Create stores a new payee with its aliases and sets their IDs
+	Create(payee *models.Payee) error
+	// FindByID returns a payee with its aliases
+	FindByID(id uint) (models.Payee, error)
+	// FindByName returns the payee with the given name, ignoring the payee excludeID
+	FindByName(name string, excludeID uint) (models.Payee, error)
+	// List returns every payee with its aliases, ordered by name
+	List() ([]models.Payee, error)
+	// Save writes the name and default category of a payee and replaces its aliases
+	Save(payee *models.Payee) error
+	// Delete removes a payee with its aliases and unlinks its transactions and recurring transactions
+	Delete(id uint) error
+	// Merge moves every transaction of sourceID, including deleted ones, its
+	// recurring transactions and its aliases to targetID and deletes sourceID,
+	// all in one database transaction.
+	// The target keeps its default category, or takes the source's if it has none.
+	// It returns the IDs of the moved transactions.
+	Merge(sourceID, targetID uint) ([]uint, error)
+	WithContext(ctx context.Context) PayeeRepository
+}
+
+// RecurringRepository stores recurring transactions
+type RecurringRepository interface {
+	Create(schedule *models.RecurringTransaction) error
+	FindByID(id uint) (models.RecurringTransaction, error)
+	// FindBySubscriptionKey returns the schedule created from a detected subscription
+	FindBySubscriptionKey(key string) (models.RecurringTransaction, error)
+	// List returns every schedule, the next one due first
+	List() ([]models.RecurringTransaction, error)
+	// Due returns the schedules whose next date is on or before now
+	Due(now time.Time) ([]models.RecurringTransaction, error)
+	// Advance moves the next date of a schedule from from to to. It reports
+	// false, and changes nothing, when the next date is no longer from.
+	Advance(id uint, from, to time.Time) (bool, error)
+	Delete(id uint) error
+	// Atomic runs fn in a database transaction. The repository passed to fn
+	// and repositories used with the context passed to fn take part in it.
+	Atomic(ctx context.Context, fn func(ctx context.Context, schedules RecurringRepository) error) error
+	WithContext(ctx context.Context) RecurringRepository
+}
+
+// UserRepository stores the users API tokens are issued to
+type UserRepository interface {
+	Create(user *models.User) error
+	// FindByTokenHash returns the user whose API token has the given hash
+	FindByTokenHash(hash string) (models.User, error)
+	WithContext(ctx context.Context) UserRepository
+}
+
+// AccountRepository stores bank accounts
+type AccountRepository interface {
+	Create(account *models.BankAccount) error
+	FindByID(id uint) (models.BankAccount, error)
+	// List returns active accounts, or every account when includeInactive is set
+	List(includeInactive bool) ([]models.BankAccount, error)
+	Save(account *models.BankAccount) error
+	Delete(id uint) error
+	WithContext(ctx context.Context) AccountRepository
+}
+
+// WebhookRepository stores webhook subscriptions and lists their deliveries
+type WebhookRepository interface {
+	Create(subscription *models.WebhookSubscription) error
+	FindByID(id uint) (models.WebhookSubscription, error)
+	// List returns the subscriptions of a user ordered by ID
+	List(userID uint) ([]models.WebhookSubscription, error)
+	Save(subscription *models.WebhookSubscription) error
+	Delete(id uint) error
+	// Deliveries returns up to limit deliveries of a subscription, newest first,
+	// only those with the given status unless it is empty
+	Deliveries(subscriptionID uint, status string, limit int) ([]models.WebhookDelivery, error)
+	WithContext(ctx context.Context) WebhookRepository
+}
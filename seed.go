@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+
+	"expense-api/database"
+)
+
+const seedUsage = `usage: expense-api seed [--profile default|demo]
+
+profiles:
+  default     default categories and bank accounts, added to an empty database
+  demo        the defaults plus three months of sample transactions`
+
+// runSeed handles the seed subcommand
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	profile := fs.String("profile", database.SeedDefault, "data to load")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errors.New(seedUsage)
+	}
+	if *profile != database.SeedDefault && *profile != database.SeedDemo {
+		return errors.New(seedUsage)
+	}
+
+	if _, err := connectMigrated(); err != nil {
+		return err
+	}
+	return database.Seed(*profile)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/config"
+	"expense-api/container"
+	"expense-api/database"
+	"expense-api/events"
+	"expense-api/handlers"
+	"expense-api/logging"
+	"expense-api/metrics"
+	"expense-api/ratelimit"
+	"expense-api/server"
+	"expense-api/tracing"
+	"expense-api/webhooks"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"gorm.io/gorm"
+)
+
+const serveUsage = `usage: expense-api serve
+
+Listens on PORT (default 8080). On SIGINT or SIGTERM the server stops accepting
+connections and waits up to SHUTDOWN_TIMEOUT (default 30s) for requests in flight.`
+
+// webhookEventBuffer is how many published events may wait for the webhook notifier
+const webhookEventBuffer = 8192
+
+// runServe runs the API server until it receives SIGINT or SIGTERM
+func runServe(args []string) error {
+	if len(args) > 0 {
+		return errors.New(serveUsage)
+	}
+
+	logger := logging.For(logging.ComponentServer)
+
+	// Refuse to start with settings that are invalid or unsafe
+	cfg, err := config.Load(os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// A second signal stops the process without waiting for requests to drain
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Export spans when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
+	// Create Fiber app
+	appConfig := fiber.Config{
+		// Every error is rendered as an RFC 7807 problem with a stable code
+		ErrorHandler: apperrors.Handler,
+		// Bulk routes accept the largest bodies; every other route is held to handlers.MaxBodySize
+		BodyLimit: handlers.MaxBulkBodySize,
+	}
+	cfg.ApplyProxies(&appConfig)
+	app := fiber.New(appConfig)
+
+	// Middleware
+	app.Use(requestid.New())
+	app.Use(logging.Middleware())
+	app.Use(metrics.Default.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(cfg.SecurityMiddleware())
+	app.Use(cfg.CORSMiddleware())
+
+	// The database is only handed to the services once it is migrated and seeded,
+	// so until then the API answers 503 instead of querying a half-built schema
+	var ready atomic.Pointer[gorm.DB]
+
+	// Queue webhook deliveries for every published event, off the request path
+	notifier := webhooks.NewNotifier(ready.Load, webhookEventBuffer)
+	notifier.Listen(events.Default)
+
+	// Count the transactions created for /metrics
+	metrics.Default.Listen(events.Default)
+
+	// Services resolve the connection on use, so routes can be registered before it is ready
+	deps := container.New(ready.Load)
+	if workers, err := strconv.Atoi(os.Getenv("JOB_WORKERS")); err == nil && workers > 0 {
+		deps.Jobs.Workers = workers
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && ttl > 0 {
+		deps.Idempotency.TTL = ttl
+	}
+	deps.CheckAnomalies = os.Getenv("ANOMALY_CHECKS") == "true"
+	if timeout, err := time.ParseDuration(os.Getenv("HEALTH_CHECK_TIMEOUT")); err == nil && timeout > 0 {
+		deps.Liveness.Timeout = timeout
+		deps.Readiness.Timeout = timeout
+	}
+	rateLimits, err := rateLimitsFromEnv()
+	if err != nil {
+		return err
+	}
+	rateLimitStore := ratelimit.NewMemoryStore()
+	if os.Getenv("RATE_LIMIT_ENABLED") != "false" {
+		deps.RateLimiter = ratelimit.NewLimiter(rateLimitStore, rateLimits)
+	}
+	if err := metrics.Default.ObserveJobs(deps.Jobs); err != nil {
+		return err
+	}
+	server.RegisterRoutes(app, deps)
+
+	shutdownTimeout := 30 * time.Second
+	if timeout, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil && timeout > 0 {
+		shutdownTimeout = timeout
+	}
+
+	ln, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		return err
+	}
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := cfg.TLS.Config()
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	logger.Info("Server starting", "port", cfg.Port, "tls", cfg.TLS.Enabled())
+
+	// Background work runs until the requests in flight have drained
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Forget the rate limit buckets of clients that went quiet
+	go rateLimitStore.Run(background, time.Minute)
+
+	// The notifier outlives the jobs, so the events they publish last are still queued
+	notifying, stopNotifying := context.WithCancel(context.Background())
+	defer stopNotifying()
+	notified := make(chan struct{})
+	go func() {
+		defer close(notified)
+		notifier.Run(notifying)
+	}()
+
+	// Initialize database while the server already answers health checks
+	go func() {
+		logger.Info("Attempting to connect to database")
+		database.Connect()
+		if err := metrics.Default.ObserveDB(database.GetDB()); err != nil {
+			logger.Error("Failed to observe database metrics", "error", err)
+		}
+		if err := database.GetDB().Use(tracing.GormPlugin()); err != nil {
+			logger.Error("Failed to trace database queries", "error", err)
+		}
+		// Deployments that run "migrate up" as a release step can turn this off
+		if os.Getenv("AUTO_MIGRATE") != "false" {
+			database.Migrate()
+		}
+		if err := database.Seed(database.SeedDefault); err != nil {
+			logger.Error("Failed to seed database", "error", err)
+		}
+		ready.Store(database.GetDB())
+		logger.Info("Database initialization completed")
+
+		// Deliver queued webhooks and retry failed ones
+		go webhooks.NewDispatcher(database.GetDB()).Run(background, 5*time.Second)
+
+		// Drop stored idempotent responses once they expire
+		go deps.Idempotency.Run(background, time.Hour)
+
+		// Post the recurring transactions that are due, catching up after downtime
+		go handlers.PostRecurringTransactions(background, deps.Recurring, time.Hour)
+
+		// Run background jobs, resuming any interrupted by the last shutdown
+		if err := deps.Jobs.Start(background); err != nil {
+			logger.Error("Failed to start job workers", "error", err)
+		}
+	}()
+
+	err = server.Serve(ctx, app, ln, shutdownTimeout)
+	logger.Info("Server stopped, waiting for background jobs")
+
+	// Jobs interrupted here are requeued by the next start
+	stopBackground()
+	deps.Jobs.Wait()
+	stopNotifying()
+	<-notified
+	if closeErr := database.Close(); closeErr != nil {
+		logger.Error("Failed to close database", "error", closeErr)
+	}
+	return err
+}
+
+// rateLimitsFromEnv reads RATE_LIMIT_READ, RATE_LIMIT_WRITE and RATE_LIMIT_BULK, e.g. "100/1m",
+// keeping the default limit of every class that is not set
+func rateLimitsFromEnv() (map[ratelimit.Class]ratelimit.Limit, error) {
+	limits := ratelimit.DefaultLimits()
+	for class, name := range map[ratelimit.Class]string{
+		ratelimit.ClassRead:  "RATE_LIMIT_READ",
+		ratelimit.ClassWrite: "RATE_LIMIT_WRITE",
+		ratelimit.ClassBulk:  "RATE_LIMIT_BULK",
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		limits[class] = limit
+	}
+	return limits, nil
+}
@@ -0,0 +1,172 @@
+// Package server registers the routes of the expense API.
+package server
+
+import (
+	"time"
+
+	"expense-api/container"
+	"expense-api/handlers"
+	"expense-api/jobs"
+	"expense-api/metrics"
+	"expense-api/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterRoutes registers every route of the API on the app
+func RegisterRoutes(app *fiber.App, deps *container.Container) {
+	// API documentation
+	app.Get("/openapi.json", handlers.GetOpenAPISpec)
+	app.Get("/docs", handlers.GetAPIDocs)
+
+	// Liveness and readiness probes (work without database)
+	app.Get("/livez", handlers.Health(deps.Liveness))
+	app.Get("/readyz", handlers.Health(deps.Readiness))
+
+	// Prometheus metrics (work without database)
+	app.Get("/metrics", metrics.Default.Handler())
+
+	// Health check endpoint (works without database)
+	app.Get("/health", func(c *fiber.Ctx) error {
+		database := "ready"
+		if !deps.Ready() {
+			database = "connecting..."
+		}
+		return c.JSON(fiber.Map{
+			"status":    "healthy",
+			"message":   "Expense API is running",
+			"timestamp": time.Now().UTC(),
+			"database":  database,
+		})
+	})
+
+	// Database status endpoint
+	app.Get("/db-status", func(c *fiber.Ctx) error {
+		if deps.DB != nil && deps.DB() != nil {
+			sqlDB, err := deps.DB().DB()
+			if err == nil {
+				err = sqlDB.Ping()
+				if err == nil {
+					return c.JSON(fiber.Map{
+						"status":    "connected",
+						"message":   "Database is connected and responding",
+						"timestamp": time.Now().UTC(),
+					})
+				}
+			}
+		}
+		return c.JSON(fiber.Map{
+			"status":    "disconnected",
+			"message":   "Database is not connected",
+			"timestamp": time.Now().UTC(),
+		})
+	})
+
+	// Background job handlers
+	if deps.Jobs != nil {
+		deps.Jobs.Register(jobs.TypeBulkCreate, handlers.BulkCreateJob(deps.Transactions))
+	}
+
+	// API routes
+	api := app.Group("/api")
+
+	// Identify the user behind an API token, then limit every client's request
+	// rate and the size of request bodies
+	api.Use(handlers.Authenticate(deps.Users, deps.Ready))
+	api.Use(handlers.RateLimit(deps.RateLimiter))
+	api.Use(handlers.BodyLimit(handlers.MaxBodySize))
+
+	// Live event stream (works without database)
+	api.Get("/events/stream", handlers.StreamEvents)
+
+	// Every other API route needs the database
+	api.Use(handlers.RequireReady(deps.Ready))
+
+	// Retried POST requests with the same Idempotency-Key get the original response
+	api.Use(handlers.Idempotency(deps.Idempotency))
+
+	// Bank Account routes
+	bankAccounts := api.Group("/bank-accounts")
+	bankAccounts.Post("/", handlers.CreateBankAccount(deps.Accounts))
+	bankAccounts.Get("/", handlers.GetBankAccounts(deps.Accounts))
+	bankAccounts.Get("/:id", handlers.GetBankAccount(deps.Accounts))
+	bankAccounts.Put("/:id", handlers.UpdateBankAccount(deps.Accounts))
+	bankAccounts.Delete("/:id", handlers.DeleteBankAccount(deps.Accounts))
+
+	// Transaction routes, optionally judging new transactions for anomalies
+	var anomalyChecks services.AnomalyService
+	if deps.CheckAnomalies {
+		anomalyChecks = deps.Anomalies
+	}
+	transactions := api.Group("/transactions")
+	transactions.Post("/", handlers.CreateTransaction(deps.Transactions, anomalyChecks))
+	transactions.Post("/bulk", handlers.CreateBulkTransactions(deps.Transactions, deps.Jobs))
+	transactions.Post("/import", handlers.ImportTransactions(deps.Jobs))
+	transactions.Post("/transfer", handlers.CreateTransfer(deps.Transactions))
+	transactions.Delete("/bulk", handlers.DeleteBulkTransactions(deps.Transactions))
+	transactions.Get("/", handlers.GetTransactions(deps.Transactions))
+	transactions.Get("/transfers", handlers.GetTransfers(deps.Transactions))
+	transactions.Get("/summary", handlers.GetSummary(deps.Transactions))
+	transactions.Get("/aggregate", handlers.GetTransactionsAggregate(deps.Transactions, deps.Categories))
+	transactions.Get("/aggregate-table", handlers.GetTransactionsAggregateTable(deps.Transactions, deps.Categories))
+	transactions.Get("/date-range", handlers.GetTransactionsByDateRange(deps.Transactions))
+	transactions.Get("/trash", handlers.GetDeletedTransactions(deps.Transactions))
+	transactions.Get("/:id", handlers.GetTransaction(deps.Transactions))
+	transactions.Get("/:id/history", handlers.GetTransactionHistory(deps.Transactions))
+	transactions.Put("/:id", handlers.UpdateTransaction(deps.Transactions))
+	transactions.Patch("/:id/category", handlers.UpdateTransactionCategory(deps.Transactions))
+	transactions.Post("/:id/restore", handlers.RestoreTransaction(deps.Transactions))
+	transactions.Post("/:id/undelete", handlers.UndeleteTransaction(deps.Transactions))
+	transactions.Delete("/:id", handlers.DeleteTransaction(deps.Transactions))
+
+	// Category routes
+	categories := api.Group("/categories")
+	categories.Post("/", handlers.CreateCategory(deps.Categories))
+	categories.Get("/", handlers.GetCategories(deps.Categories))
+	categories.Get("/:id", handlers.GetCategory(deps.Categories))
+	categories.Put("/:id", handlers.UpdateCategory(deps.Categories))
+	categories.Post("/:id/move", handlers.MoveCategory(deps.Categories))
+	categories.Post("/:id/merge", handlers.MergeCategory(deps.Categories))
+	categories.Delete("/:id", handlers.DeleteCategory(deps.Categories))
+
+	// Payee routes
+	payees := api.Group("/payees")
+	payees.Post("/", handlers.CreatePayee(deps.Payees))
+	payees.Get("/", handlers.GetPayees(deps.Payees))
+	payees.Get("/:id", handlers.GetPayee(deps.Payees))
+	payees.Put("/:id", handlers.UpdatePayee(deps.Payees))
+	payees.Post("/:id/merge", handlers.MergePayee(deps.Payees))
+	payees.Delete("/:id", handlers.DeletePayee(deps.Payees))
+
+	// Report routes
+	reports := api.Group("/reports")
+	reports.Get("/payees", handlers.GetPayeeReport(deps.Transactions))
+
+	// Insight routes
+	insights := api.Group("/insights")
+	insights.Get("/subscriptions", handlers.GetSubscriptions(deps.Recurring))
+	insights.Post("/subscriptions/schedule", handlers.ScheduleSubscription(deps.Recurring))
+	insights.Get("/anomalies", handlers.GetAnomalies(deps.Anomalies))
+
+	// Recurring transaction routes
+	recurring := api.Group("/recurring")
+	recurring.Get("/", handlers.GetRecurringTransactions(deps.Recurring))
+	recurring.Get("/:id", handlers.GetRecurringTransaction(deps.Recurring))
+	recurring.Delete("/:id", handlers.DeleteRecurringTransaction(deps.Recurring))
+
+	// Job routes
+	jobRoutes := api.Group("/jobs")
+	jobRoutes.Get("/:id", handlers.GetJob(deps.Jobs))
+	jobRoutes.Post("/:id/cancel", handlers.CancelJob(deps.Jobs))
+	jobRoutes.Post("/:id/retry", handlers.RetryJob(deps.Jobs))
+
+	// Webhook routes
+	webhookRoutes := api.Group("/webhooks")
+	webhookRoutes.Post("/", handlers.CreateWebhook(deps.Webhooks))
+	webhookRoutes.Get("/", handlers.GetWebhooks(deps.Webhooks))
+	webhookRoutes.Get("/:id", handlers.GetWebhook(deps.Webhooks))
+	webhookRoutes.Put("/:id", handlers.UpdateWebhook(deps.Webhooks))
+	webhookRoutes.Delete("/:id", handlers.DeleteWebhook(deps.Webhooks))
+	webhookRoutes.Get("/:id/deliveries", handlers.GetWebhookDeliveries(deps.Webhooks))
+	webhookRoutes.Post("/:id/test", handlers.TestWebhook(deps.Webhooks))
+}
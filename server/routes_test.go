@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"expense-api/container"
+	"expense-api/database"
+	"expense-api/database/migrations"
+	"expense-api/health"
+	"expense-api/openapi"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// undocumentedRoutes serve the documentation itself and are not part of the spec
+var undocumentedRoutes = map[string]bool{
+	"GET /openapi.json": true,
+	"GET /docs":         true,
+}
+
+func TestEveryRouteHasOpenAPIEntry(t *testing.T) {
+	app := fiber.New()
+	RegisterRoutes(app, container.New(database.GetDB))
+
+	document := openapi.Build("Expense API", "test", openapi.Operations())
+
+	registered := map[string]bool{}
+	for _, route := range app.GetRoutes(true) {
+		// Fiber registers HEAD alongside every GET
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+		key := route.Method + " " + openapi.PathTemplate(route.Path)
+		registered[key] = true
+		if undocumentedRoutes[key] {
+			continue
+		}
+		assert.True(t, document.Has(route.Method, route.Path), "route %s has no OpenAPI entry", key)
+	}
+
+	// Every spec entry must also be routed, so the document does not drift the other way
+	for _, op := range openapi.Operations() {
+		key := op.Method + " " + openapi.PathTemplate(op.Path)
+		assert.True(t, registered[key], "OpenAPI entry %s is not routed", key)
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	app := fiber.New()
+	RegisterRoutes(app, container.New(database.GetDB))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/openapi.json", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var document map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+	assert.Equal(t, openapi.Version, document["openapi"])
+
+	schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	assert.Contains(t, schemas, "TransactionResponse")
+	assert.Contains(t, schemas, "BulkTransactionRequest")
+
+	bulk := schemas["BulkTransactionRequest"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"transactions"}, bulk["required"])
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/docs", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestReadyzWaitsForMigratedDatabase(t *testing.T) {
+	var ready *gorm.DB
+	deps := container.New(func() *gorm.DB { return ready })
+	app := fiber.New()
+	RegisterRoutes(app, deps)
+
+	probe := func(path string) (int, health.Report) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		var report health.Report
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		return resp.StatusCode, report
+	}
+
+	// Liveness does not depend on the database
+	status, report := probe("/livez")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, health.StatusOK, report.Status)
+
+	status, report = probe("/readyz")
+	assert.Equal(t, 503, status)
+	assert.Equal(t, health.StatusFail, report.Status)
+	names := []string{}
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+		assert.Equal(t, health.StatusFail, check.Status)
+	}
+	assert.Equal(t, []string{"database", "migrations", "storage", "jobs"}, names)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	migrator, err := migrations.New(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		deps.Jobs.Wait()
+	}()
+	ready = db
+	assert.NoError(t, deps.Jobs.Start(ctx))
+
+	status, report = probe("/readyz")
+	assert.Equal(t, 200, status, "%+v", report)
+	for _, check := range report.Checks {
+		assert.Equal(t, health.StatusOK, check.Status, check.Name)
+		assert.GreaterOrEqual(t, check.LatencyMS, 0.0)
+	}
+}
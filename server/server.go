@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Serve handles requests on ln until ctx is cancelled. It then stops accepting
+// connections and waits up to timeout for the requests in flight to finish.
+func Serve(ctx context.Context, app *fiber.App, ln net.Listener, timeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- app.Listener(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	if err := app.ShutdownWithTimeout(timeout); err != nil {
+		return err
+	}
+	return <-errc
+}
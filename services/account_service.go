@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/validation"
+)
+
+// accountService implements AccountService on top of repositories
+type accountService struct {
+	accounts     repository.AccountRepository
+	transactions repository.TransactionRepository
+}
+
+// NewAccountService creates an AccountService
+func NewAccountService(accounts repository.AccountRepository, transactions repository.TransactionRepository) AccountService {
+	return &accountService{accounts: accounts, transactions: transactions}
+}
+
+// WithContext returns a service whose queries run with ctx
+func (s *accountService) WithContext(ctx context.Context) AccountService {
+	return NewAccountService(s.accounts.WithContext(ctx), s.transactions.WithContext(ctx))
+}
+
+// Create validates and stores a bank account
+func (s *accountService) Create(request models.BankAccountRequest) (models.BankAccount, error) {
+	// Validate required fields and account type
+	if err := validation.Struct(&request); err != nil {
+		return models.BankAccount{}, apperrors.Validation(err)
+	}
+
+	account := models.BankAccount{
+		Name:          request.Name,
+		AccountNumber: request.AccountNumber,
+		BankName:      request.BankName,
+		AccountType:   request.AccountType,
+		IsActive:      request.IsActive,
+	}
+	if request.Balance != nil {
+		account.Balance = *request.Balance
+	}
+	// An account opened without an opening balance starts at its balance
+	account.OpeningBalance = account.Balance
+	if request.OpeningBalance != nil {
+		account.OpeningBalance = *request.OpeningBalance
+	}
+
+	if err := s.accounts.Create(&account); err != nil {
+		return models.BankAccount{}, apperrors.Internal("Failed to create bank account", err)
+	}
+
+	return account, nil
+}
+
+// Get returns a bank account
+func (s *accountService) Get(id uint) (models.BankAccount, error) {
+	account, err := s.accounts.FindByID(id)
+	if err != nil {
+		return models.BankAccount{}, apperrors.Lookup(err, apperrors.NotFound(apperrors.CodeBankAccountNotFound, "Bank account not found"))
+	}
+	return account, nil
+}
+
+// List returns active bank accounts, or every account when includeInactive is set
+func (s *accountService) List(includeInactive bool) ([]models.BankAccount, error) {
+	accounts, err := s.accounts.List(includeInactive)
+	if err != nil {
+		return nil, apperrors.Internal("Failed to retrieve bank accounts", err)
+	}
+	return accounts, nil
+}
+
+// Update copies the non-empty fields of changes onto a bank account.
+// The balances are copied whenever they are given, even as 0.
+func (s *accountService) Update(id uint, changes models.BankAccountRequest) (models.BankAccount, error) {
+	account, err := s.Get(id)
+	if err != nil {
+		return models.BankAccount{}, err
+	}
+
+	// Validate account type if provided
+	if err := validation.Var("account_type", changes.AccountType, "omitempty,account_type"); err != nil {
+		return models.BankAccount{}, apperrors.Validation(err)
+	}
+
+	// Update fields
+	if changes.Name != "" {
+		account.Name = changes.Name
+	}
+	if changes.AccountNumber != "" {
+		account.AccountNumber = changes.AccountNumber
+	}
+	if changes.BankName != "" {
+		account.BankName = changes.BankName
+	}
+	if changes.AccountType != "" {
+		account.AccountType = changes.AccountType
+	}
+	if changes.Balance != nil {
+		account.Balance = *changes.Balance
+	}
+	if changes.OpeningBalance != nil {
+		account.OpeningBalance = *changes.OpeningBalance
+	}
+	// Handle IsActive explicitly since it's a boolean
+	account.IsActive = changes.IsActive
+
+	if err := s.accounts.Save(&account); err != nil {
+		return models.BankAccount{}, apperrors.Internal("Failed to update bank account", err)
+	}
+
+	return account, nil
+}
+
+// Delete removes a bank account without transactions
+func (s *accountService) Delete(id uint) error {
+	account, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	// Check if there are any transactions associated with this account
+	count, err := s.transactions.CountByAccount(account.ID)
+	if err != nil {
+		return apperrors.Internal("Failed to check for associated transactions", err)
+	}
+	if count > 0 {
+		return apperrors.Conflict(apperrors.CodeBankAccountInUse, "Cannot delete bank account with associated transactions. Consider deactivating instead.")
+	}
+
+	if err := s.accounts.Delete(account.ID); err != nil {
+		return apperrors.Internal("Failed to delete bank account", err)
+	}
+	return nil
+}
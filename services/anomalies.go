@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"expense-api/models"
+	"expense-api/repository"
+)
+
+// AnomalySettings tune how transactions are judged
+type AnomalySettings struct {
+	// Window is how far back the transactions an amount is compared with go
+	Window time.Duration
+	// Threshold is the robust z-score above which an amount is anomalous
+	Threshold float64
+	// MinSamples is the number of earlier transactions a category or payee needs to be judged
+	MinSamples int
+}
+
+// DefaultAnomalySettings compares amounts with the last 180 days and uses the
+// usual cut-off of 3.5 for robust z-scores
+func DefaultAnomalySettings() AnomalySettings {
+	return AnomalySettings{Window: 180 * 24 * time.Hour, Threshold: 3.5, MinSamples: 5}
+}
+
+// Anomaly is a transaction whose amount is out of character for its category or payee
+type Anomaly struct {
+	Transaction models.Transaction
+	// Score is the highest score among the reasons
+	Score   float64
+	Reasons []models.AnomalyReason
+}
+
+// anomalyService implements AnomalyService on top of the transaction service
+type anomalyService struct {
+	transactions TransactionService
+}
+
+// NewAnomalyService creates an AnomalyService
+func NewAnomalyService(transactions TransactionService) AnomalyService {
+	return &anomalyService{transactions: transactions}
+}
+
+// WithContext returns a service whose queries run with ctx
+func (s *anomalyService) WithContext(ctx context.Context) AnomalyService {
+	return NewAnomalyService(s.transactions.WithContext(ctx))
+}
+
+// Anomalies judges the transactions matching filter against the ones of the
+// same type in the trailing window before each of them
+func (s *anomalyService) Anomalies(filter repository.TransactionFilter, settings AnomalySettings) ([]Anomaly, error) {
+	candidates, err := s.transactions.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	historyFilter := repository.TransactionFilter{Type: filter.Type, To: filter.To}
+	if filter.From != nil {
+		from := filter.From.Add(-settings.Window)
+		historyFilter.From = &from
+	}
+	history, err := s.transactions.List(historyFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return DetectAnomalies(candidates, history, settings), nil
+}
+
+// Check judges one stored transaction against the ones before it
+func (s *anomalyService) Check(transaction models.Transaction, settings AnomalySettings) (Anomaly, bool, error) {
+	if transaction.Type == "transfer" {
+		return Anomaly{}, false, nil
+	}
+
+	from := transaction.Date.Time.Add(-settings.Window)
+	to := transaction.Date.Time
+	history, err := s.transactions.List(repository.TransactionFilter{Type: transaction.Type, From: &from, To: &to})
+	if err != nil {
+		return Anomaly{}, false, err
+	}
+
+	anomalies := DetectAnomalies([]models.Transaction{transaction}, history, settings)
+	if len(anomalies) == 0 {
+		return Anomaly{}, false, nil
+	}
+	return anomalies[0], true, nil
+}
+
+// DetectAnomalies judges every candidate against the history of its category
+// and of its payee: the transactions of the same type dated in the window up to
+// the candidate's date. An amount is anomalous when its robust z-score,
+// 0.6745 × (amount − median) / MAD, is above the threshold in either of them.
+// Only amounts above the usual are flagged. The anomalies are ordered by
+// score, the highest first.
+func DetectAnomalies(candidates, history []models.Transaction, settings AnomalySettings) []Anomaly {
+	byScope := make(map[string][]models.Transaction)
+	for _, t := range history {
+		for _, key := range anomalyScopes(t) {
+			byScope[key.key] = append(byScope[key.key], t)
+		}
+	}
+
+	anomalies := []Anomaly{}
+	for _, candidate := range candidates {
+		anomaly := Anomaly{Transaction: candidate}
+		for _, scope := range anomalyScopes(candidate) {
+			var amounts []float64
+			from := candidate.Date.Time.Add(-settings.Window)
+			for _, t := range byScope[scope.key] {
+				if t.ID != candidate.ID && !t.Date.Time.Before(from) && !t.Date.Time.After(candidate.Date.Time) {
+					amounts = append(amounts, t.Amount)
+				}
+			}
+			if len(amounts) < max(settings.MinSamples, 1) {
+				continue
+			}
+
+			reason := robustScore(candidate.Amount, amounts)
+			if reason.Score <= settings.Threshold {
+				continue
+			}
+			reason.Scope, reason.ID, reason.Name = scope.scope, scope.id, scope.name
+			anomaly.Reasons = append(anomaly.Reasons, reason)
+			anomaly.Score = math.Max(anomaly.Score, reason.Score)
+		}
+		if len(anomaly.Reasons) > 0 {
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Transaction.Date.Time.After(b.Transaction.Date.Time)
+	})
+	return anomalies
+}
+
+// anomalyScope is a category or payee a transaction is compared within
+type anomalyScope struct {
+	key   string
+	scope string
+	id    uint
+	name  string
+}
+
+// anomalyScopes returns the category and payee of a transaction. Keys include
+// the type, so the refunds of a payee are not compared with its charges.
+func anomalyScopes(t models.Transaction) []anomalyScope {
+	if t.Type == "transfer" {
+		return nil
+	}
+
+	var scopes []anomalyScope
+	if t.CategoryID != nil {
+		scopes = append(scopes, anomalyScope{
+			key: fmt.Sprintf("%s:category:%d", t.Type, *t.CategoryID), scope: models.AnomalyScopeCategory, id: *t.CategoryID, name: t.Category.Name,
+		})
+	}
+	if t.PayeeID != nil {
+		scopes = append(scopes, anomalyScope{
+			key: fmt.Sprintf("%s:payee:%d", t.Type, *t.PayeeID), scope: models.AnomalyScopePayee, id: *t.PayeeID, name: t.Payee.Name,
+		})
+	}
+	return scopes
+}
+
+// robustScore compares amount with the median of amounts, which is not empty
+func robustScore(amount float64, amounts []float64) models.AnomalyReason {
+	middle := median(amounts)
+	deviations := make([]float64, len(amounts))
+	meanDeviation := 0.0
+	for i, value := range amounts {
+		deviations[i] = math.Abs(value - middle)
+		meanDeviation += deviations[i]
+	}
+	meanDeviation /= float64(len(amounts))
+	mad := median(deviations)
+
+	// MAD / 0.6745 estimates the standard deviation. When more than half the
+	// amounts are equal the MAD is 0 and the mean absolute deviation is used,
+	// and amounts that never vary get a spread of 1% of the median, so a
+	// different amount still stands out.
+	spread := mad / 0.6745
+	if spread == 0 {
+		spread = meanDeviation * 1.2533
+	}
+	spread = math.Max(spread, math.Abs(middle)*0.01)
+
+	reason := models.AnomalyReason{
+		Samples: len(amounts),
+		Median:  RoundCents(middle),
+		MAD:     RoundCents(mad),
+	}
+	if spread > 0 {
+		reason.Score = RoundCents((amount - middle) / spread)
+	}
+	if middle != 0 {
+		reason.Ratio = RoundCents(amount / middle)
+	}
+	return reason
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/validation"
+)
+
+// categoryService implements CategoryService on top of repositories
+type categoryService struct {
+	categories   repository.CategoryRepository
+	transactions repository.TransactionRepository
+}
+
+// NewCategoryService creates a CategoryService
+func NewCategoryService(categories repository.CategoryRepository, transactions repository.TransactionRepository) CategoryService {
+	return &categoryService{categories: categories, transactions: transactions}
+}
+
+// WithContext returns a service whose queries run with ctx
+func (s *categoryService) WithContext(ctx context.Context) CategoryService {
+	return NewCategoryService(s.categories.WithContext(ctx), s.transactions.WithContext(ctx))
+}
+
+// categoryNotFound is returned when a category ID does not exist
+func categoryNotFound() *apperrors.Error {
+	return apperrors.NotFound(apperrors.CodeCategoryNotFound, "Category not found")
+}
+
+// nameTaken is returned when a sibling already uses a category name
+func nameTaken() *apperrors.Error {
+	return apperrors.Conflict(apperrors.CodeCategoryNameTaken, "Category with this name already exists")
+}
+
+// checkNameAvailable fails if a sibling under parentID other than excludeID already uses name
+func (s *categoryService) checkNameAvailable(name string, parentID *uint, excludeID uint) error {
+	_, err := s.categories.FindByName(name, parentID, excludeID)
+	if err == nil {
+		return nameTaken()
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return apperrors.Internal("Failed to check category name", err)
+	}
+	return nil
+}
+
+// checkPlacement fails unless a category of categoryType can sit under parentID:
+// the parent must exist, have the same type and not be the category or one of
+// its subcategories, and the category's own subcategories must keep its type
+func (s *categoryService) checkPlacement(id uint, categoryType string, parentID *uint) error {
+	if id == 0 && parentID == nil {
+		return nil
+	}
+	tree, err := s.Tree()
+	if err != nil {
+		return err
+	}
+
+	if parentID != nil {
+		parent, ok := tree.Get(*parentID)
+		if !ok {
+			return apperrors.BadRequest(apperrors.CodeCategoryNotFound, "Parent category not found")
+		}
+		if id != 0 && tree.IsDescendant(parent.ID, id) {
+			return apperrors.BadRequest(apperrors.CodeCategoryCycle, "A category cannot be moved under itself or one of its subcategories")
+		}
+		if parent.Type != categoryType {
+			return apperrors.BadRequest(apperrors.CodeCategoryTypeMismatch, "Category type must match the type of its parent")
+		}
+	}
+
+	for _, child := range tree.Children(id) {
+		if child.Type != categoryType {
+			return apperrors.BadRequest(apperrors.CodeCategoryTypeMismatch, "Category type must match the type of its subcategories")
+		}
+	}
+	return nil
+}
+
+// Create validates and stores a category with a name unique among its siblings
+func (s *categoryService) Create(category models.Category) (models.Category, error) {
+	if err := validation.Struct(&category); err != nil {
+		return models.Category{}, apperrors.Validation(err)
+	}
+
+	if err := s.checkPlacement(0, category.Type, category.ParentID); err != nil {
+		return models.Category{}, err
+	}
+
+	if err := s.checkNameAvailable(category.Name, category.ParentID, 0); err != nil {
+		return models.Category{}, err
+	}
+
+	// The unique index catches a sibling created since the check
+	if err := s.categories.Create(&category); errors.Is(err, repository.ErrDuplicate) {
+		return models.Category{}, nameTaken()
+	} else if err != nil {
+		return models.Category{}, apperrors.Internal("Failed to create category", err)
+	}
+
+	return category, nil
+}
+
+// Get returns a category
+func (s *categoryService) Get(id uint) (models.Category, error) {
+	category, err := s.categories.FindByID(id)
+	if err != nil {
+		return models.Category{}, apperrors.Lookup(err, categoryNotFound())
+	}
+	return category, nil
+}
+
+// List returns every category
+func (s *categoryService) List() ([]models.Category, error) {
+	categories, err := s.categories.List()
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch categories", err)
+	}
+	return categories, nil
+}
+
+// Tree returns every category indexed by parent
+func (s *categoryService) Tree() (*CategoryTree, error) {
+	categories, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return NewCategoryTree(categories), nil
+}
+
+// Update changes the name, type or parent of a category
+func (s *categoryService) Update(id uint, fields map[string]interface{}) (models.Category, error) {
+	category, err := s.Get(id)
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	// Validate category type if provided
+	categoryType := category.Type
+	if value, exists := fields["type"]; exists {
+		if err := validation.Var("type", value, "category_type"); err != nil {
+			return models.Category{}, apperrors.Validation(err)
+		}
+		categoryType, _ = value.(string)
+	}
+
+	parentID := category.ParentID
+	if value, exists := fields["parent_id"]; exists {
+		if parentID, err = parseParentID(value); err != nil {
+			return models.Category{}, err
+		}
+		fields["parent_id"] = parentID
+	}
+
+	if err := s.checkPlacement(category.ID, categoryType, parentID); err != nil {
+		return models.Category{}, err
+	}
+
+	// Check if name already exists among the siblings (excluding current category)
+	name := category.Name
+	if value, exists := fields["name"]; exists {
+		name, _ = value.(string)
+		if err := validation.Var("name", name, "required"); err != nil {
+			return models.Category{}, apperrors.Validation(err)
+		}
+	}
+	if err := s.checkNameAvailable(name, parentID, category.ID); err != nil {
+		return models.Category{}, err
+	}
+
+	if err := s.categories.Update(category.ID, fields); errors.Is(err, repository.ErrDuplicate) {
+		return models.Category{}, nameTaken()
+	} else if err != nil {
+		return models.Category{}, apperrors.Internal("Failed to update category", err)
+	}
+
+	return s.Get(category.ID)
+}
+
+// Move places a category under parentID, or at the top level when parentID is nil
+func (s *categoryService) Move(id uint, parentID *uint) (models.Category, error) {
+	return s.Update(id, map[string]interface{}{"parent_id": parentID})
+}
+
+// Merge moves the transactions and subcategories of a category to targetID and deletes it
+func (s *categoryService) Merge(id, targetID uint) (MergeResult, error) {
+	category, err := s.Get(id)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	tree, err := s.Tree()
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	target, ok := tree.Get(targetID)
+	if !ok {
+		return MergeResult{}, apperrors.BadRequest(apperrors.CodeCategoryNotFound, "Target category not found")
+	}
+	if tree.IsDescendant(target.ID, category.ID) {
+		return MergeResult{}, apperrors.BadRequest(apperrors.CodeCategoryCycle, "A category cannot be merged into itself or one of its subcategories")
+	}
+	if target.Type != category.Type {
+		return MergeResult{}, apperrors.BadRequest(apperrors.CodeCategoryTypeMismatch, "Only categories of the same type can be merged")
+	}
+
+	children := tree.Children(category.ID)
+	for _, child := range children {
+		_, err := s.categories.FindByName(child.Name, &target.ID, child.ID)
+		if err == nil {
+			return MergeResult{}, apperrors.Conflict(apperrors.CodeCategoryNameTaken, fmt.Sprintf("Target category already has a subcategory named %q", child.Name))
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return MergeResult{}, apperrors.Internal("Failed to check category name", err)
+		}
+	}
+
+	moved, err := s.categories.Merge(category.ID, target.ID)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return MergeResult{}, apperrors.Conflict(apperrors.CodeCategoryNameTaken, "Target category already has a subcategory with the name of a moved one")
+	}
+	if err != nil {
+		return MergeResult{}, apperrors.Internal("Failed to merge categories", err)
+	}
+
+	transactions, err := s.transactions.FindByIDs(moved)
+	if err != nil {
+		return MergeResult{}, apperrors.Internal("Failed to load reassigned transactions", err)
+	}
+
+	return MergeResult{
+		Target:                 target,
+		ReassignedTransactions: int64(len(moved)),
+		MovedSubcategories:     len(children),
+		Transactions:           transactions,
+	}, nil
+}
+
+// parseParentID reads parent_id from a decoded JSON body: a positive integer or null
+func parseParentID(value interface{}) (*uint, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *uint:
+		return v, nil
+	case float64:
+		if v >= 1 && v == float64(uint(v)) {
+			id := uint(v)
+			return &id, nil
+		}
+	}
+	return nil, apperrors.BadRequest(apperrors.CodeInvalidParameter, "parent_id must be a positive integer or null")
+}
+
+// Delete removes a category that no transaction uses
+func (s *categoryService) Delete(id uint) error {
+	category, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	tree, err := s.Tree()
+	if err != nil {
+		return err
+	}
+	if len(tree.Children(category.ID)) > 0 {
+		return apperrors.Conflict(apperrors.CodeCategoryHasChildren, "Cannot delete category that has subcategories; move or delete them first")
+	}
+
+	// Check if category is being used by any transactions
+	count, err := s.transactions.CountByCategory(category.ID)
+	if err != nil {
+		return apperrors.Internal("Failed to check for associated transactions", err)
+	}
+	if count > 0 {
+		return apperrors.Conflict(apperrors.CodeCategoryInUse, "Cannot delete category that has associated transactions")
+	}
+
+	if err := s.categories.Delete(category.ID); err != nil {
+		return apperrors.Internal("Failed to delete category", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"expense-api/models"
+)
+
+// PathSeparator joins the names of a category and its ancestors in a path
+const PathSeparator = " / "
+
+// CategoryTree indexes categories by parent to walk their hierarchy. Categories
+// whose parent is missing, e.g. soft deleted, are treated as top level.
+type CategoryTree struct {
+	byID     map[uint]models.Category
+	children map[uint][]models.Category
+	roots    []models.Category
+}
+
+// NewCategoryTree builds the tree of the given categories; siblings are ordered by name
+func NewCategoryTree(categories []models.Category) *CategoryTree {
+	tree := &CategoryTree{
+		byID:     make(map[uint]models.Category, len(categories)),
+		children: make(map[uint][]models.Category),
+	}
+	for _, category := range categories {
+		tree.byID[category.ID] = category
+	}
+	for _, category := range categories {
+		if category.ParentID != nil {
+			if _, ok := tree.byID[*category.ParentID]; ok {
+				tree.children[*category.ParentID] = append(tree.children[*category.ParentID], category)
+				continue
+			}
+		}
+		tree.roots = append(tree.roots, category)
+	}
+
+	byName := func(list []models.Category) {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Name != list[j].Name {
+				return list[i].Name < list[j].Name
+			}
+			return list[i].ID < list[j].ID
+		})
+	}
+	byName(tree.roots)
+	for _, list := range tree.children {
+		byName(list)
+	}
+	return tree
+}
+
+// Get returns a category of the tree
+func (t *CategoryTree) Get(id uint) (models.Category, bool) {
+	category, ok := t.byID[id]
+	return category, ok
+}
+
+// Children returns the direct subcategories of a category
+func (t *CategoryTree) Children(id uint) []models.Category {
+	return t.children[id]
+}
+
+// Ancestors returns the category and its ancestors, starting with the top level one
+func (t *CategoryTree) Ancestors(id uint) []models.Category {
+	var chain []models.Category
+	seen := make(map[uint]bool)
+	for category, ok := t.byID[id]; ok && !seen[category.ID]; {
+		seen[category.ID] = true
+		chain = append(chain, category)
+		if category.ParentID == nil {
+			break
+		}
+		category, ok = t.byID[*category.ParentID]
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// IsDescendant reports whether id is ancestorID or one of its subcategories
+func (t *CategoryTree) IsDescendant(id, ancestorID uint) bool {
+	for _, category := range t.Ancestors(id) {
+		if category.ID == ancestorID {
+			return true
+		}
+	}
+	return false
+}
+
+// Path names a category with its ancestors, e.g. "Bills / Electricity"
+func (t *CategoryTree) Path(id uint) string {
+	var names []string
+	for _, category := range t.Ancestors(id) {
+		names = append(names, category.Name)
+	}
+	return strings.Join(names, PathSeparator)
+}
+
+// AtDepth returns the ancestor of a category at depth, where top level
+// categories are at depth 0. Categories at or above depth are returned as is.
+func (t *CategoryTree) AtDepth(id uint, depth int) (models.Category, bool) {
+	chain := t.Ancestors(id)
+	if len(chain) == 0 {
+		return models.Category{}, false
+	}
+	if depth < len(chain) {
+		return chain[depth], true
+	}
+	return chain[len(chain)-1], true
+}
+
+// Nodes returns the top level categories with their subcategories
+func (t *CategoryTree) Nodes() []models.CategoryTreeNode {
+	return t.nodes(t.roots, make(map[uint]bool))
+}
+
+func (t *CategoryTree) nodes(categories []models.Category, seen map[uint]bool) []models.CategoryTreeNode {
+	nodes := make([]models.CategoryTreeNode, 0, len(categories))
+	for _, category := range categories {
+		if seen[category.ID] {
+			continue
+		}
+		seen[category.ID] = true
+		nodes = append(nodes, models.CategoryTreeNode{
+			CategoryResponse: models.CategoryResponse{
+				ID:       category.ID,
+				Name:     category.Name,
+				Type:     category.Type,
+				ParentID: category.ParentID,
+			},
+			Children: t.nodes(t.children[category.ID], seen),
+		})
+	}
+	return nodes
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+
+	"expense-api/models"
+	"expense-api/repository"
+)
+
+// lookupCache remembers category and bank account lookups, including misses,
+// so a bulk request queries each referenced ID at most once. Payees are loaded
+// once, on first use, to match descriptions.
+type lookupCache struct {
+	categories      repository.CategoryRepository
+	accounts        repository.AccountRepository
+	payees          repository.PayeeRepository
+	categoryResults map[uint]categoryResult
+	accountResults  map[uint]accountResult
+	payeeResult     *payeeResult // shared with the caches withContext returns
+}
+
+type categoryResult struct {
+	category models.Category
+	err      error
+}
+
+type accountResult struct {
+	account models.BankAccount
+	err     error
+}
+
+type payeeResult struct {
+	loaded  bool
+	matcher *PayeeMatcher
+	err     error
+}
+
+// newLookupCache creates an empty cache over the given repositories
+func newLookupCache(categories repository.CategoryRepository, accounts repository.AccountRepository, payees repository.PayeeRepository) *lookupCache {
+	return &lookupCache{
+		categories:      categories,
+		accounts:        accounts,
+		payees:          payees,
+		categoryResults: map[uint]categoryResult{},
+		accountResults:  map[uint]accountResult{},
+		payeeResult:     &payeeResult{},
+	}
+}
+
+// withContext returns a cache sharing the results of c whose queries run with ctx
+func (c *lookupCache) withContext(ctx context.Context) *lookupCache {
+	bound := *c
+	bound.categories = c.categories.WithContext(ctx)
+	bound.accounts = c.accounts.WithContext(ctx)
+	bound.payees = c.payees.WithContext(ctx)
+	return &bound
+}
+
+// category returns a category, querying the repository on first use
+func (c *lookupCache) category(id uint) (models.Category, error) {
+	result, ok := c.categoryResults[id]
+	if !ok {
+		result.category, result.err = c.categories.FindByID(id)
+		c.categoryResults[id] = result
+	}
+	return result.category, result.err
+}
+
+// account returns a bank account, querying the repository on first use
+func (c *lookupCache) account(id uint) (models.BankAccount, error) {
+	result, ok := c.accountResults[id]
+	if !ok {
+		result.account, result.err = c.accounts.FindByID(id)
+		c.accountResults[id] = result
+	}
+	return result.account, result.err
+}
+
+// payeeMatcher returns a matcher over every payee, querying the repository on first use
+func (c *lookupCache) payeeMatcher() (*PayeeMatcher, error) {
+	result := c.payeeResult
+	if !result.loaded {
+		var payees []models.Payee
+		payees, result.err = c.payees.List()
+		result.matcher = NewPayeeMatcher(payees)
+		result.loaded = true
+	}
+	return result.matcher, result.err
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"expense-api/models"
+)
+
+// PayeeMatcher finds the payee of a raw bank description through the aliases
+// of every payee, e.g. "AMZN MKTP" matches "AMZN Mktp IN*2K3". Descriptions
+// and patterns are compared word by word, ignoring case and punctuation; a "*"
+// in a pattern matches the rest of a word and a pattern matches anywhere in the
+// description. The payee's own name is matched like an alias.
+type PayeeMatcher struct {
+	byID     map[uint]models.Payee
+	patterns []payeePattern
+}
+
+// payeePattern is a normalized alias of a payee
+type payeePattern struct {
+	payeeID uint
+	words   []string
+	// weight counts the letters and digits of the pattern, so more specific patterns win
+	weight int
+}
+
+// NewPayeeMatcher indexes the aliases of payees
+func NewPayeeMatcher(payees []models.Payee) *PayeeMatcher {
+	matcher := &PayeeMatcher{byID: make(map[uint]models.Payee, len(payees))}
+	for _, payee := range payees {
+		matcher.byID[payee.ID] = payee
+		patterns := []string{payee.Name}
+		for _, alias := range payee.Aliases {
+			patterns = append(patterns, alias.Pattern)
+		}
+		for _, pattern := range patterns {
+			words := patternWords(pattern)
+			if len(words) == 0 {
+				continue
+			}
+			weight := 0
+			for _, word := range words {
+				weight += len(strings.ReplaceAll(word, "*", ""))
+			}
+			matcher.patterns = append(matcher.patterns, payeePattern{payeeID: payee.ID, words: words, weight: weight})
+		}
+	}
+
+	sort.SliceStable(matcher.patterns, func(i, j int) bool {
+		a, b := matcher.patterns[i], matcher.patterns[j]
+		if a.weight != b.weight {
+			return a.weight > b.weight
+		}
+		return a.payeeID < b.payeeID
+	})
+	return matcher
+}
+
+// Get returns a payee of the matcher
+func (m *PayeeMatcher) Get(id uint) (models.Payee, bool) {
+	payee, ok := m.byID[id]
+	return payee, ok
+}
+
+// Match returns the payee of the most specific matching pattern, the one with
+// the most letters and digits; ties go to the payee created first
+func (m *PayeeMatcher) Match(description string) (models.Payee, bool) {
+	words := descriptionWords(description)
+	for _, pattern := range m.patterns {
+		if pattern.matches(words) {
+			return m.byID[pattern.payeeID], true
+		}
+	}
+	return models.Payee{}, false
+}
+
+// matches reports whether the pattern's words appear in a row among words
+func (p payeePattern) matches(words []string) bool {
+	for start := 0; start+len(p.words) <= len(words); start++ {
+		matched := true
+		for i, word := range p.words {
+			// Normalized words hold no path separators or other glob syntax than "*"
+			if ok, _ := path.Match(word, words[start+i]); !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizePattern returns an alias pattern as it is stored and matched, e.g.
+// "Amzn mktp*" becomes "AMZN MKTP*". It is empty when the pattern has no letter or digit.
+func NormalizePattern(pattern string) string {
+	return strings.Join(patternWords(pattern), " ")
+}
+
+// descriptionWords splits a description into upper case words of letters and digits
+func descriptionWords(description string) []string {
+	return strings.FieldsFunc(strings.ToUpper(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// patternWords splits a pattern like descriptionWords but keeps "*" wildcards.
+// Words made only of wildcards are kept when the pattern has other words.
+func patternWords(pattern string) []string {
+	words := strings.FieldsFunc(strings.ToUpper(pattern), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '*'
+	})
+	for _, word := range words {
+		if strings.Trim(word, "*") != "" {
+			return words
+		}
+	}
+	return nil
+}
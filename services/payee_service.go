@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/validation"
+)
+
+// payeeService implements PayeeService on top of repositories
+type payeeService struct {
+	payees       repository.PayeeRepository
+	categories   repository.CategoryRepository
+	transactions repository.TransactionRepository
+}
+
+// NewPayeeService creates a PayeeService
+func NewPayeeService(payees repository.PayeeRepository, categories repository.CategoryRepository, transactions repository.TransactionRepository) PayeeService {
+	return &payeeService{payees: payees, categories: categories, transactions: transactions}
+}
+
+// WithContext returns a service whose queries run with ctx
+func (s *payeeService) WithContext(ctx context.Context) PayeeService {
+	return NewPayeeService(s.payees.WithContext(ctx), s.categories.WithContext(ctx), s.transactions.WithContext(ctx))
+}
+
+// payeeNotFound is returned when a payee ID does not exist
+func payeeNotFound() *apperrors.Error {
+	return apperrors.NotFound(apperrors.CodePayeeNotFound, "Payee not found")
+}
+
+// build validates a request and returns the payee it describes, with its
+// aliases normalized and deduplicated. id is the payee being replaced, or 0.
+func (s *payeeService) build(id uint, request models.PayeeRequest) (models.Payee, error) {
+	request.Name = strings.TrimSpace(request.Name)
+	if err := validation.Struct(&request); err != nil {
+		return models.Payee{}, apperrors.Validation(err)
+	}
+
+	if _, err := s.payees.FindByName(request.Name, id); err == nil {
+		return models.Payee{}, apperrors.Conflict(apperrors.CodePayeeNameTaken, "Payee with this name already exists")
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return models.Payee{}, apperrors.Internal("Failed to check payee name", err)
+	}
+
+	if request.DefaultCategoryID != nil {
+		if _, err := s.categories.FindByID(*request.DefaultCategoryID); err != nil {
+			return models.Payee{}, apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeCategoryNotFound, "Default category not found"))
+		}
+	}
+
+	payee := models.Payee{ID: id, Name: request.Name, DefaultCategoryID: request.DefaultCategoryID}
+	seen := make(map[string]bool)
+	for _, alias := range request.Aliases {
+		pattern := NormalizePattern(alias)
+		if pattern == "" {
+			return models.Payee{}, apperrors.BadRequest(apperrors.CodeInvalidParameter, fmt.Sprintf("Alias %q must contain a letter or digit", alias))
+		}
+		if !seen[pattern] {
+			seen[pattern] = true
+			payee.Aliases = append(payee.Aliases, models.PayeeAlias{Pattern: pattern})
+		}
+	}
+
+	// The same pattern on two payees would make matching ambiguous
+	payees, err := s.payees.List()
+	if err != nil {
+		return models.Payee{}, apperrors.Internal("Failed to check payee aliases", err)
+	}
+	for _, other := range payees {
+		if other.ID == id {
+			continue
+		}
+		for _, alias := range other.Aliases {
+			if seen[alias.Pattern] {
+				return models.Payee{}, apperrors.Conflict(apperrors.CodePayeeAliasTaken, fmt.Sprintf("Alias %q already belongs to payee %q", alias.Pattern, other.Name))
+			}
+		}
+	}
+	return payee, nil
+}
+
+// Create validates and stores a payee with its aliases
+func (s *payeeService) Create(request models.PayeeRequest) (models.Payee, error) {
+	payee, err := s.build(0, request)
+	if err != nil {
+		return models.Payee{}, err
+	}
+
+	if err := s.payees.Create(&payee); err != nil {
+		return models.Payee{}, apperrors.Internal("Failed to create payee", err)
+	}
+
+	return s.Get(payee.ID)
+}
+
+// Get returns a payee with its aliases
+func (s *payeeService) Get(id uint) (models.Payee, error) {
+	payee, err := s.payees.FindByID(id)
+	if err != nil {
+		return models.Payee{}, apperrors.Lookup(err, payeeNotFound())
+	}
+	return payee, nil
+}
+
+// List returns every payee ordered by name
+func (s *payeeService) List() ([]models.Payee, error) {
+	payees, err := s.payees.List()
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch payees", err)
+	}
+	return payees, nil
+}
+
+// Update replaces the name, default category and aliases of a payee
+func (s *payeeService) Update(id uint, request models.PayeeRequest) (models.Payee, error) {
+	if _, err := s.Get(id); err != nil {
+		return models.Payee{}, err
+	}
+
+	payee, err := s.build(id, request)
+	if err != nil {
+		return models.Payee{}, err
+	}
+
+	if err := s.payees.Save(&payee); err != nil {
+		return models.Payee{}, apperrors.Internal("Failed to update payee", err)
+	}
+
+	return s.Get(id)
+}
+
+// Delete removes a payee; its transactions are kept without a payee
+func (s *payeeService) Delete(id uint) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+
+	if err := s.payees.Delete(id); err != nil {
+		return apperrors.Internal("Failed to delete payee", err)
+	}
+	return nil
+}
+
+// Merge moves the transactions and aliases of a payee to targetID and deletes it
+func (s *payeeService) Merge(id, targetID uint) (PayeeMergeResult, error) {
+	payee, err := s.Get(id)
+	if err != nil {
+		return PayeeMergeResult{}, err
+	}
+	if targetID == payee.ID {
+		return PayeeMergeResult{}, apperrors.BadRequest(apperrors.CodeInvalidParameter, "A payee cannot be merged into itself")
+	}
+	if _, err := s.payees.FindByID(targetID); err != nil {
+		return PayeeMergeResult{}, apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodePayeeNotFound, "Target payee not found"))
+	}
+
+	moved, err := s.payees.Merge(payee.ID, targetID)
+	if err != nil {
+		return PayeeMergeResult{}, apperrors.Internal("Failed to merge payees", err)
+	}
+
+	transactions, err := s.transactions.FindByIDs(moved)
+	if err != nil {
+		return PayeeMergeResult{}, apperrors.Internal("Failed to load reassigned transactions", err)
+	}
+
+	target, err := s.Get(targetID)
+	if err != nil {
+		return PayeeMergeResult{}, err
+	}
+	return PayeeMergeResult{Target: target, ReassignedTransactions: int64(len(moved)), Transactions: transactions}, nil
+}
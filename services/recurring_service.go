@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+	"expense-api/repository"
+)
+
+// recurringService implements RecurringService on top of repositories
+type recurringService struct {
+	schedules    repository.RecurringRepository
+	transactions TransactionService
+	// ctx is passed on to the database transactions that post charges
+	ctx context.Context
+}
+
+// NewRecurringService creates a RecurringService. Schedules post their
+// transactions through transactions, so the usual rules apply to them.
+func NewRecurringService(schedules repository.RecurringRepository, transactions TransactionService) RecurringService {
+	return &recurringService{schedules: schedules, transactions: transactions, ctx: context.Background()}
+}
+
+// WithContext returns a service whose queries run with ctx
+func (s *recurringService) WithContext(ctx context.Context) RecurringService {
+	return &recurringService{schedules: s.schedules.WithContext(ctx), transactions: s.transactions.WithContext(ctx), ctx: ctx}
+}
+
+// Subscriptions detects the recurring expenses as of now and links them to the
+// recurring transactions created from them
+func (s *recurringService) Subscriptions(now time.Time) ([]models.Subscription, error) {
+	history, err := s.transactions.List(repository.TransactionFilter{Type: "expense"})
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := DetectSubscriptions(history, now)
+
+	schedules, err := s.schedules.List()
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch recurring transactions", err)
+	}
+	scheduled := make(map[string]uint)
+	for _, schedule := range schedules {
+		if schedule.SubscriptionKey != "" {
+			scheduled[schedule.SubscriptionKey] = schedule.ID
+		}
+	}
+	for i := range subscriptions {
+		if id, ok := scheduled[subscriptions[i].Key]; ok {
+			subscriptions[i].ScheduleID = &id
+		}
+	}
+	return subscriptions, nil
+}
+
+// Schedule creates a recurring transaction from a detected subscription
+func (s *recurringService) Schedule(key string, now time.Time) (models.RecurringTransaction, error) {
+	if _, err := s.schedules.FindBySubscriptionKey(key); err == nil {
+		return models.RecurringTransaction{}, apperrors.Conflict(apperrors.CodeSubscriptionScheduled, "A recurring transaction was already created for this subscription")
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return models.RecurringTransaction{}, apperrors.Internal("Failed to check recurring transactions", err)
+	}
+
+	subscriptions, err := s.Subscriptions(now)
+	if err != nil {
+		return models.RecurringTransaction{}, err
+	}
+	var subscription *models.Subscription
+	for i := range subscriptions {
+		if subscriptions[i].Key == key {
+			subscription = &subscriptions[i]
+			break
+		}
+	}
+	if subscription == nil {
+		return models.RecurringTransaction{}, apperrors.BadRequest(apperrors.CodeSubscriptionNotFound, "Subscription not found")
+	}
+
+	// Charges missed before the schedule existed are not posted
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	anchorDay := subscription.FirstCharge.Day()
+	next := subscription.NextExpected
+	for next.Before(today) {
+		next = NextOccurrence(next, subscription.Cadence, anchorDay)
+	}
+
+	schedule := models.RecurringTransaction{
+		Description:     subscription.Name,
+		Amount:          subscription.LastAmount,
+		CategoryID:      subscription.CategoryID,
+		BankAccountID:   subscription.BankAccountID,
+		PayeeID:         subscription.PayeeID,
+		Cadence:         subscription.Cadence,
+		NextDate:        next,
+		AnchorDay:       anchorDay,
+		SubscriptionKey: subscription.Key,
+	}
+	if err := s.schedules.Create(&schedule); err != nil {
+		return models.RecurringTransaction{}, apperrors.Internal("Failed to create recurring transaction", err)
+	}
+	return schedule, nil
+}
+
+// Get returns a recurring transaction
+func (s *recurringService) Get(id uint) (models.RecurringTransaction, error) {
+	schedule, err := s.schedules.FindByID(id)
+	if err != nil {
+		return models.RecurringTransaction{}, apperrors.Lookup(err, apperrors.NotFound(apperrors.CodeRecurringNotFound, "Recurring transaction not found"))
+	}
+	return schedule, nil
+}
+
+// List returns every recurring transaction, the next one due first
+func (s *recurringService) List() ([]models.RecurringTransaction, error) {
+	schedules, err := s.schedules.List()
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch recurring transactions", err)
+	}
+	return schedules, nil
+}
+
+// Delete removes a recurring transaction
+func (s *recurringService) Delete(id uint) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+
+	if err := s.schedules.Delete(id); err != nil {
+		return apperrors.Internal("Failed to delete recurring transaction", err)
+	}
+	return nil
+}
+
+// PostDue creates the due transactions of every schedule. A schedule that
+// fails, e.g. because its bank account was deleted, stays due and the others
+// are still posted; the failures are returned together. Each charge is posted
+// in the same database transaction that advances its schedule, so a charge is
+// never posted twice, even by servers running PostDue at the same time.
+func (s *recurringService) PostDue(now time.Time) ([]models.Transaction, error) {
+	due, err := s.schedules.Due(now)
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch due recurring transactions", err)
+	}
+
+	var created []models.Transaction
+	var failures []error
+	for _, schedule := range due {
+		for date := schedule.NextDate; !date.After(now); date = NextOccurrence(date, schedule.Cadence, schedule.AnchorDay) {
+			transaction, posted, err := s.post(schedule, date)
+			if err != nil {
+				failures = append(failures, fmt.Errorf("recurring transaction %d: %w", schedule.ID, err))
+				break
+			}
+			if !posted {
+				// Another server got to the schedule first
+				break
+			}
+			created = append(created, transaction)
+		}
+	}
+	return created, errors.Join(failures...)
+}
+
+// post creates the charge of a schedule due on date and advances the schedule
+// past it, all or nothing. It reports false when the schedule was no longer due
+// on date.
+func (s *recurringService) post(schedule models.RecurringTransaction, date time.Time) (models.Transaction, bool, error) {
+	var transaction models.Transaction
+	var posted bool
+	err := s.schedules.Atomic(s.ctx, func(ctx context.Context, schedules repository.RecurringRepository) error {
+		var err error
+		posted, err = schedules.Advance(schedule.ID, date, NextOccurrence(date, schedule.Cadence, schedule.AnchorDay))
+		if err != nil || !posted {
+			return err
+		}
+
+		transaction, err = s.transactions.WithContext(ctx).Create(models.Transaction{
+			Amount:        schedule.Amount,
+			Type:          "expense",
+			CategoryID:    schedule.CategoryID,
+			BankAccountID: schedule.BankAccountID,
+			PayeeID:       schedule.PayeeID,
+			Description:   schedule.Description,
+			Date:          models.FlexibleDate{Time: date},
+		})
+		return err
+	})
+	if err != nil {
+		return models.Transaction{}, false, err
+	}
+	return transaction, posted, nil
+}
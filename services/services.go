@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"expense-api/models"
+	"expense-api/repository"
+)
+
+// TransactionService holds the business rules for transactions and transfers.
+// Errors returned by its methods are *apperrors.Error values ready to be sent to clients.
+type TransactionService interface {
+	Create(transaction models.Transaction) (models.Transaction, error)
+	Get(id uint) (models.Transaction, error)
+	// GetMany returns the transactions that exist among ids, ordered by ID
+	GetMany(ids []uint) ([]models.Transaction, error)
+	List(filter repository.TransactionFilter) ([]models.Transaction, error)
+	Update(id uint, fields map[string]interface{}) (models.Transaction, error)
+	UpdateCategory(id, categoryID uint) (models.Transaction, error)
+	Delete(id uint) error
+	// Deleted lists the deleted transactions, most recently deleted first
+	Deleted() ([]models.Transaction, error)
+	// History returns every recorded version of a transaction, deleted or not, oldest first
+	History(id uint) ([]models.TransactionVersion, error)
+	// Restore brings a transaction back to one of its versions, undeleting it;
+	// the accounts and category of the version must still exist
+	Restore(id uint, version int) (models.Transaction, error)
+	// Undelete brings back a deleted transaction
+	Undelete(id uint) (models.Transaction, error)
+	// CreateBulk and DeleteBulk apply every valid row, or in atomic mode all rows or none
+	CreateBulk(request models.BulkTransactionRequest, atomic bool) (BulkCreateResult, error)
+	DeleteBulk(request models.BulkDeleteRequest, atomic bool) (BulkDeleteResult, error)
+	CreateTransfer(request models.TransferRequest) (models.Transaction, error)
+	Summary(recent int) (Summary, error)
+	// WithContext returns a service whose queries run with ctx, so they are traced as part of it
+	WithContext(ctx context.Context) TransactionService
+}
+
+// CategoryService holds the business rules for categories
+type CategoryService interface {
+	Create(category models.Category) (models.Category, error)
+	Get(id uint) (models.Category, error)
+	List() ([]models.Category, error)
+	// Update also accepts "parent_id"; names are unique among siblings and
+	// subcategories share the type of their parent
+	Update(id uint, fields map[string]interface{}) (models.Category, error)
+	// Move places a category under parentID, or at the top level when it is nil
+	Move(id uint, parentID *uint) (models.Category, error)
+	// Merge moves the transactions and subcategories of a category to a target
+	// of the same type and deletes it, all or nothing
+	Merge(id, targetID uint) (MergeResult, error)
+	Delete(id uint) error
+	// Tree returns every category indexed by parent
+	Tree() (*CategoryTree, error)
+	WithContext(ctx context.Context) CategoryService
+}
+
+// PayeeService holds the business rules for payees and their aliases
+type PayeeService interface {
+	Create(request models.PayeeRequest) (models.Payee, error)
+	Get(id uint) (models.Payee, error)
+	List() ([]models.Payee, error)
+	// Update replaces the name, default category and aliases of a payee
+	Update(id uint, request models.PayeeRequest) (models.Payee, error)
+	// Delete removes a payee; its transactions are kept without a payee
+	Delete(id uint) error
+	// Merge moves the transactions and aliases of a payee to a target and deletes it, all or nothing
+	Merge(id, targetID uint) (PayeeMergeResult, error)
+	WithContext(ctx context.Context) PayeeService
+}
+
+// RecurringService detects subscriptions in the transaction history and posts
+// the recurring transactions created from them
+type RecurringService interface {
+	// Subscriptions detects the recurring expenses as of now
+	Subscriptions(now time.Time) ([]models.Subscription, error)
+	// Schedule creates a recurring transaction from the detected subscription key,
+	// starting with its next expected charge that is not in the past
+	Schedule(key string, now time.Time) (models.RecurringTransaction, error)
+	Get(id uint) (models.RecurringTransaction, error)
+	List() ([]models.RecurringTransaction, error)
+	// Delete removes a recurring transaction; the transactions it posted are kept
+	Delete(id uint) error
+	// PostDue creates the transactions of every schedule due by now, including
+	// charges missed while the server was down, and advances the schedules
+	PostDue(now time.Time) ([]models.Transaction, error)
+	WithContext(ctx context.Context) RecurringService
+}
+
+// AnomalyService flags transactions whose amount is out of character for their category or payee
+type AnomalyService interface {
+	// Anomalies judges the transactions matching filter, which names a type,
+	// against the earlier transactions of their category and payee
+	Anomalies(filter repository.TransactionFilter, settings AnomalySettings) ([]Anomaly, error)
+	// Check judges one stored transaction
+	Check(transaction models.Transaction, settings AnomalySettings) (Anomaly, bool, error)
+	WithContext(ctx context.Context) AnomalyService
+}
+
+// UserService verifies the API tokens of users
+type UserService interface {
+	// Authenticate returns the user a token was issued to, and false for unknown tokens
+	Authenticate(token string) (models.User, bool, error)
+	WithContext(ctx context.Context) UserService
+}
+
+// WebhookService manages webhook subscriptions. Every method acts on the
+// subscriptions of userID only; those of other users are not found.
+type WebhookService interface {
+	// Create stores a subscription, generating its signing secret if none is given
+	Create(userID uint, request models.WebhookSubscriptionRequest) (models.WebhookSubscription, error)
+	Get(userID, id uint) (models.WebhookSubscription, error)
+	List(userID uint) ([]models.WebhookSubscription, error)
+	// Update changes the fields set in request; omitted fields keep their values
+	Update(userID, id uint, request models.WebhookSubscriptionRequest) (models.WebhookSubscription, error)
+	// Delete removes a subscription; its pending deliveries are not sent
+	Delete(userID, id uint) error
+	// Deliveries returns up to limit deliveries of a subscription, newest first,
+	// only those with the given status unless it is empty
+	Deliveries(userID, id uint, status string, limit int) ([]models.WebhookDelivery, error)
+	// Test sends a webhook.test event to a subscription now and returns the delivery
+	Test(userID, id uint) (models.WebhookDelivery, error)
+	WithContext(ctx context.Context) WebhookService
+}
+
+// AccountService holds the business rules for bank accounts
+type AccountService interface {
+	// Create stores an account; without an opening balance it starts at its balance
+	Create(request models.BankAccountRequest) (models.BankAccount, error)
+	Get(id uint) (models.BankAccount, error)
+	List(includeInactive bool) ([]models.BankAccount, error)
+	// Update copies the fields set in changes onto an account
+	Update(id uint, changes models.BankAccountRequest) (models.BankAccount, error)
+	Delete(id uint) error
+	WithContext(ctx context.Context) AccountService
+}
+
+// MergeResult reports what merging a category into a target changed
+type MergeResult struct {
+	Target                 models.Category
+	ReassignedTransactions int64
+	MovedSubcategories     int
+	// Transactions are the moved transactions that are not deleted, as stored after the merge
+	Transactions []models.Transaction
+}
+
+// PayeeMergeResult reports what merging a payee into a target changed
+type PayeeMergeResult struct {
+	Target                 models.Payee
+	ReassignedTransactions int64
+	// Transactions are the moved transactions that are not deleted, as stored after the merge
+	Transactions []models.Transaction
+}
+
+// BulkCreateResult lists the created transactions and the rows that were rejected
+type BulkCreateResult struct {
+	Created []models.Transaction
+	Failed  []models.BulkTransactionError
+}
+
+// BulkDeleteResult lists the deleted transaction IDs and the IDs that could not be deleted
+type BulkDeleteResult struct {
+	Deleted []uint
+	Failed  []models.BulkDeleteError
+}
+
+// Summary holds the transaction counts, totals and latest transactions
+type Summary struct {
+	All      repository.TransactionTotals
+	Expenses repository.TransactionTotals
+	Income   repository.TransactionTotals
+	Recent   []models.Transaction
+}
@@ -0,0 +1,618 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+	"expense-api/repository"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestServices(t *testing.T) (TransactionService, CategoryService, AccountService) {
+	store := repository.NewMemoryStore()
+	transactions := NewTransactionService(store.Transactions(), store.Categories(), store.Accounts(), store.Payees())
+	categories := NewCategoryService(store.Categories(), store.Transactions())
+	accounts := NewAccountService(store.Accounts(), store.Transactions())
+	return transactions, categories, accounts
+}
+
+func assertCode(t *testing.T, err error, status int, code string) {
+	var appErr *apperrors.Error
+	if assert.True(t, errors.As(err, &appErr), "expected *apperrors.Error, got %v", err) {
+		assert.Equal(t, status, appErr.Status)
+		assert.Equal(t, code, appErr.Code)
+	}
+}
+
+func TestCategoryRules(t *testing.T) {
+	transactions, categories, accounts := newTestServices(t)
+
+	account, err := accounts.Create(models.BankAccountRequest{Name: "Checking", BankName: "Bank", AccountType: "checking", IsActive: true})
+	assert.NoError(t, err)
+
+	food, err := categories.Create(models.Category{Name: "Food", Type: "expense"})
+	assert.NoError(t, err)
+
+	_, err = categories.Create(models.Category{Name: "Food", Type: "income"})
+	assertCode(t, err, http.StatusConflict, apperrors.CodeCategoryNameTaken)
+
+	_, err = transactions.Create(models.Transaction{Amount: 12.5, Description: "Lunch", Type: "expense", CategoryID: &food.ID, BankAccountID: account.ID})
+	assert.NoError(t, err)
+
+	err = categories.Delete(food.ID)
+	assertCode(t, err, http.StatusConflict, apperrors.CodeCategoryInUse)
+
+	_, err = categories.Get(99)
+	assertCode(t, err, http.StatusNotFound, apperrors.CodeCategoryNotFound)
+}
+
+func TestCategoryHierarchy(t *testing.T) {
+	_, categories, _ := newTestServices(t)
+
+	bills, err := categories.Create(models.Category{Name: "Bills", Type: "expense"})
+	assert.NoError(t, err)
+	utilities, err := categories.Create(models.Category{Name: "Utilities", Type: "expense", ParentID: &bills.ID})
+	assert.NoError(t, err)
+	power, err := categories.Create(models.Category{Name: "Electricity", Type: "expense", ParentID: &utilities.ID})
+	assert.NoError(t, err)
+	salary, err := categories.Create(models.Category{Name: "Salary", Type: "income"})
+	assert.NoError(t, err)
+
+	// Names are unique among siblings only
+	nested, err := categories.Create(models.Category{Name: "Bills", Type: "expense", ParentID: &utilities.ID})
+	assert.NoError(t, err)
+	_, err = categories.Create(models.Category{Name: "Electricity", Type: "expense", ParentID: &utilities.ID})
+	assertCode(t, err, http.StatusConflict, apperrors.CodeCategoryNameTaken)
+
+	_, err = categories.Create(models.Category{Name: "Bonus", Type: "income", ParentID: &bills.ID})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryTypeMismatch)
+	missing := uint(99)
+	_, err = categories.Create(models.Category{Name: "Water", Type: "expense", ParentID: &missing})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryNotFound)
+
+	// A category cannot move under itself or its subcategories
+	_, err = categories.Move(bills.ID, &bills.ID)
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryCycle)
+	_, err = categories.Move(bills.ID, &power.ID)
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryCycle)
+	_, err = categories.Update(bills.ID, map[string]interface{}{"parent_id": float64(utilities.ID)})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryCycle)
+	_, err = categories.Move(power.ID, &salary.ID)
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryTypeMismatch)
+	_, err = categories.Update(bills.ID, map[string]interface{}{"type": "income"})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryTypeMismatch)
+	_, err = categories.Update(bills.ID, map[string]interface{}{"parent_id": "top"})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeInvalidParameter)
+
+	err = categories.Delete(utilities.ID)
+	assertCode(t, err, http.StatusConflict, apperrors.CodeCategoryHasChildren)
+
+	moved, err := categories.Move(power.ID, &bills.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &bills.ID, moved.ParentID)
+	moved, err = categories.Update(power.ID, map[string]interface{}{"parent_id": nil})
+	assert.NoError(t, err)
+	assert.Nil(t, moved.ParentID)
+
+	tree, err := categories.Tree()
+	assert.NoError(t, err)
+	assert.Equal(t, "Bills / Utilities / Bills", tree.Path(nested.ID))
+	nodes := tree.Nodes()
+	if assert.Len(t, nodes, 3) {
+		assert.Equal(t, []string{"Bills", "Electricity", "Salary"}, []string{nodes[0].Name, nodes[1].Name, nodes[2].Name})
+		assert.Equal(t, "Utilities", nodes[0].Children[0].Name)
+		assert.Equal(t, "Bills", nodes[0].Children[0].Children[0].Name)
+	}
+}
+
+func TestCategoryMerge(t *testing.T) {
+	transactions, categories, accounts := newTestServices(t)
+
+	account, err := accounts.Create(models.BankAccountRequest{Name: "Checking", BankName: "Bank", AccountType: "checking", IsActive: true})
+	assert.NoError(t, err)
+	food, err := categories.Create(models.Category{Name: "Food", Type: "expense"})
+	assert.NoError(t, err)
+	snacks, err := categories.Create(models.Category{Name: "Snacks", Type: "expense", ParentID: &food.ID})
+	assert.NoError(t, err)
+	groceries, err := categories.Create(models.Category{Name: "Groceries", Type: "expense"})
+	assert.NoError(t, err)
+	_, err = categories.Create(models.Category{Name: "Snacks", Type: "expense", ParentID: &groceries.ID})
+	assert.NoError(t, err)
+	salary, err := categories.Create(models.Category{Name: "Salary", Type: "income"})
+	assert.NoError(t, err)
+
+	lunch, err := transactions.Create(models.Transaction{Amount: 12.5, Description: "Lunch", Type: "expense", CategoryID: &groceries.ID, BankAccountID: account.ID})
+	assert.NoError(t, err)
+
+	_, err = categories.Merge(groceries.ID, salary.ID)
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryTypeMismatch)
+	_, err = categories.Merge(food.ID, snacks.ID)
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryCycle)
+	_, err = categories.Merge(groceries.ID, 99)
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryNotFound)
+	// Both have a Snacks subcategory, which cannot share the parent
+	_, err = categories.Merge(groceries.ID, food.ID)
+	assertCode(t, err, http.StatusConflict, apperrors.CodeCategoryNameTaken)
+
+	assert.NoError(t, categories.Delete(snacks.ID))
+	result, err := categories.Merge(groceries.ID, food.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, food.ID, result.Target.ID)
+	assert.Equal(t, int64(1), result.ReassignedTransactions)
+	assert.Equal(t, 1, result.MovedSubcategories)
+
+	lunch, err = transactions.Get(lunch.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Food", lunch.Category.Name)
+	_, err = categories.Get(groceries.ID)
+	assertCode(t, err, http.StatusNotFound, apperrors.CodeCategoryNotFound)
+}
+
+func TestCategoryTree(t *testing.T) {
+	parent := func(id uint) *uint { return &id }
+	tree := NewCategoryTree([]models.Category{
+		{ID: 1, Name: "Bills", Type: "expense"},
+		{ID: 2, Name: "Utilities", Type: "expense", ParentID: parent(1)},
+		{ID: 3, Name: "Electricity", Type: "expense", ParentID: parent(2)},
+		// The parent of an orphan is gone, e.g. soft deleted
+		{ID: 4, Name: "Orphan", Type: "expense", ParentID: parent(9)},
+	})
+
+	assert.Equal(t, "Bills / Utilities / Electricity", tree.Path(3))
+	assert.Equal(t, "Orphan", tree.Path(4))
+	assert.True(t, tree.IsDescendant(3, 1))
+	assert.False(t, tree.IsDescendant(1, 3))
+
+	for depth, want := range map[int]uint{0: 1, 1: 2, 2: 3, 5: 3} {
+		category, ok := tree.AtDepth(3, depth)
+		assert.True(t, ok)
+		assert.Equal(t, want, category.ID, "depth %d", depth)
+	}
+	_, ok := tree.AtDepth(9, 0)
+	assert.False(t, ok)
+}
+
+func TestPayeeMatcher(t *testing.T) {
+	matcher := NewPayeeMatcher([]models.Payee{
+		{ID: 1, Name: "Amazon", Aliases: []models.PayeeAlias{{Pattern: "AMZN MKTP"}, {Pattern: "AMZN*"}}},
+		{ID: 2, Name: "Amazon Prime", Aliases: []models.PayeeAlias{{Pattern: "PRIME VIDEO*"}}},
+		{ID: 3, Name: "Uber Rides", Aliases: []models.PayeeAlias{{Pattern: "UBER * TRIP"}}},
+	})
+
+	for description, want := range map[string]uint{
+		"AMZN Mktp IN*2K3":         1,
+		"Amazon.in order":          1,
+		"amzn.com/bill":            1,
+		"AMAZON PRIME*1A2B3":       2, // the longer name wins over "Amazon"
+		"Prime Video Channels":     2,
+		"UBER *EATS TRIP 8XK":      3,
+		"Card payment UBER   TRIP": 0, // the wildcard needs a word of its own
+		"Amazonia Books":           0, // patterns match whole words
+	} {
+		payee, ok := matcher.Match(description)
+		assert.Equal(t, want != 0, ok, description)
+		assert.Equal(t, want, payee.ID, description)
+	}
+
+	assert.Equal(t, "AMZN MKTP*", NormalizePattern("  amzn mktp*. "))
+	assert.Empty(t, NormalizePattern("*-*"))
+}
+
+func TestPayeeRules(t *testing.T) {
+	store := repository.NewMemoryStore()
+	transactions := NewTransactionService(store.Transactions(), store.Categories(), store.Accounts(), store.Payees())
+	categories := NewCategoryService(store.Categories(), store.Transactions())
+	accounts := NewAccountService(store.Accounts(), store.Transactions())
+	payees := NewPayeeService(store.Payees(), store.Categories(), store.Transactions())
+
+	account, err := accounts.Create(models.BankAccountRequest{Name: "Checking", BankName: "Bank", AccountType: "checking", IsActive: true})
+	assert.NoError(t, err)
+	shopping, err := categories.Create(models.Category{Name: "Shopping", Type: "expense"})
+	assert.NoError(t, err)
+	refunds, err := categories.Create(models.Category{Name: "Refunds", Type: "income"})
+	assert.NoError(t, err)
+
+	amazon, err := payees.Create(models.PayeeRequest{Name: "Amazon", DefaultCategoryID: &shopping.ID, Aliases: []string{"amzn mktp", "AMZN Mktp"}})
+	assert.NoError(t, err)
+	if assert.Len(t, amazon.Aliases, 1) {
+		assert.Equal(t, "AMZN MKTP", amazon.Aliases[0].Pattern)
+	}
+
+	_, err = payees.Create(models.PayeeRequest{Name: "Amazon"})
+	assertCode(t, err, http.StatusConflict, apperrors.CodePayeeNameTaken)
+	_, err = payees.Create(models.PayeeRequest{Name: "Marketplace", Aliases: []string{"AMZN-MKTP"}})
+	assertCode(t, err, http.StatusConflict, apperrors.CodePayeeAliasTaken)
+	_, err = payees.Create(models.PayeeRequest{Name: "Stars", Aliases: []string{"***"}})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeInvalidParameter)
+	missing := uint(99)
+	_, err = payees.Create(models.PayeeRequest{Name: "Nobody", DefaultCategoryID: &missing})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryNotFound)
+
+	// The payee is matched from the description and brings its default category
+	order, err := transactions.Create(models.Transaction{Amount: 25, Description: "AMZN Mktp IN*2K3", Type: "expense", BankAccountID: account.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, &amazon.ID, order.PayeeID)
+	assert.Equal(t, "Amazon", order.Payee.Name)
+	assert.Equal(t, &shopping.ID, order.CategoryID)
+
+	// A default category of another type does not apply
+	_, err = transactions.Create(models.Transaction{Amount: 25, Description: "Amazon refund", Type: "income", BankAccountID: account.ID})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeCategoryRequired)
+	refund, err := transactions.Create(models.Transaction{Amount: 25, Description: "Amazon refund", Type: "income", CategoryID: &refunds.ID, BankAccountID: account.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, &amazon.ID, refund.PayeeID)
+
+	_, err = transactions.Create(models.Transaction{Amount: 5, Description: "Corner shop", Type: "expense", CategoryID: &shopping.ID, PayeeID: &missing, BankAccountID: account.ID})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodePayeeNotFound)
+	_, err = transactions.Update(order.ID, map[string]interface{}{"payee_id": float64(99)})
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodePayeeNotFound)
+
+	// Merging moves the transactions and aliases
+	amzn, err := payees.Create(models.PayeeRequest{Name: "AMZN Digital", Aliases: []string{"AMZN DIGITAL*"}})
+	assert.NoError(t, err)
+	ebook, err := transactions.Create(models.Transaction{Amount: 9, Description: "AMZN Digital*KINDLE", Type: "expense", BankAccountID: account.ID, CategoryID: &shopping.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, &amzn.ID, ebook.PayeeID)
+
+	_, err = payees.Merge(amzn.ID, amzn.ID)
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeInvalidParameter)
+	result, err := payees.Merge(amzn.ID, amazon.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.ReassignedTransactions)
+	assert.Len(t, result.Target.Aliases, 2)
+	if assert.Len(t, result.Transactions, 1) {
+		assert.Equal(t, &amazon.ID, result.Transactions[0].PayeeID)
+	}
+	ebook, err = transactions.Get(ebook.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &amazon.ID, ebook.PayeeID)
+	// The reassignment is recorded in the history of the transaction
+	history, err := transactions.History(ebook.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, models.VersionOperationUpdate, history[1].Operation)
+	}
+
+	// Deleting a payee keeps its transactions
+	assert.NoError(t, payees.Delete(amazon.ID))
+	order, err = transactions.Get(order.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, order.PayeeID)
+	_, err = payees.Get(amazon.ID)
+	assertCode(t, err, http.StatusNotFound, apperrors.CodePayeeNotFound)
+}
+
+func TestDetectSubscriptions(t *testing.T) {
+	date := func(year int, month time.Month, day int) models.FlexibleDate {
+		return models.FlexibleDate{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+	}
+	domains := uint(7)
+	var history []models.Transaction
+	add := func(description string, amount float64, on models.FlexibleDate) {
+		history = append(history, models.Transaction{ID: uint(len(history) + 1), Type: "expense", Amount: amount, BankAccountID: 1, Description: description, Date: on})
+	}
+
+	// Monthly with a price rise and a new reference number every month
+	for month := time.January; month <= time.June; month++ {
+		amount := 499.0
+		if month >= time.April {
+			amount = 649
+		}
+		add(fmt.Sprintf("NETFLIX.COM %d", 84720+int(month)), amount, date(2024, month, 15))
+	}
+	// Weekly, stopped in January
+	for day := 2; day <= 23; day += 7 {
+		add("Gym pass", 10, date(2024, time.January, day))
+	}
+	// Weekly, but the amounts are ordinary shopping
+	for i, amount := range []float64{52.3, 80.1, 35.2, 61, 44.4} {
+		add("FRESH MART", amount, date(2024, time.May, 1+7*i))
+	}
+	// A yearly renewal among other purchases of the same payee
+	for _, charge := range []struct {
+		amount float64
+		on     models.FlexibleDate
+	}{{12, date(2023, time.March, 1)}, {40, date(2023, time.August, 10)}, {75, date(2024, time.January, 5)}, {12, date(2024, time.March, 1)}} {
+		add("DOMAINS INC", charge.amount, charge.on)
+		history[len(history)-1].PayeeID = &domains
+		history[len(history)-1].Payee = models.Payee{ID: domains, Name: "Domains Inc"}
+	}
+	history = append(history, models.Transaction{Type: "income", Amount: 3000, Description: "Salary", Date: date(2024, time.May, 31)})
+
+	subscriptions := DetectSubscriptions(history, time.Date(2024, time.June, 20, 0, 0, 0, 0, time.UTC))
+	if !assert.Len(t, subscriptions, 3) {
+		return
+	}
+
+	netflix := subscriptions[0]
+	assert.Equal(t, "description:NETFLIX COM", netflix.Key)
+	assert.Equal(t, models.CadenceMonthly, netflix.Cadence)
+	assert.Equal(t, 6, netflix.Occurrences)
+	assert.Equal(t, float64(574), netflix.AverageAmount)
+	assert.Equal(t, float64(649), netflix.LastAmount)
+	assert.Equal(t, date(2024, time.July, 15).Time, netflix.NextExpected)
+	assert.Equal(t, []models.PriceChange{{Date: date(2024, time.April, 15).Time, OldAmount: 499, NewAmount: 649}}, netflix.PriceChanges)
+	assert.True(t, netflix.Active)
+	assert.Equal(t, float64(7788), netflix.AnnualCost)
+
+	gym := subscriptions[1]
+	assert.Equal(t, models.CadenceWeekly, gym.Cadence)
+	assert.False(t, gym.Active)
+
+	renewal := subscriptions[2]
+	assert.Equal(t, "payee:7@12.00", renewal.Key)
+	assert.Equal(t, "Domains Inc", renewal.Name)
+	assert.Equal(t, models.CadenceYearly, renewal.Cadence)
+	assert.Equal(t, date(2025, time.March, 1).Time, renewal.NextExpected)
+
+	assert.Equal(t, date(2024, time.February, 29).Time, NextOccurrence(date(2024, time.January, 31).Time, models.CadenceMonthly, 0))
+	assert.Equal(t, date(2025, time.February, 28).Time, NextOccurrence(date(2024, time.February, 29).Time, models.CadenceYearly, 0))
+}
+
+func TestNextOccurrenceKeepsTheAnchorDay(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	// A series charged on the 31st returns to it after shorter months
+	next := date(2025, time.January, 31)
+	var walked []time.Time
+	for i := 0; i < 3; i++ {
+		next = NextOccurrence(next, models.CadenceMonthly, 31)
+		walked = append(walked, next)
+	}
+	assert.Equal(t, []time.Time{date(2025, time.February, 28), date(2025, time.March, 31), date(2025, time.April, 30)}, walked)
+
+	// Yearly charges on February 29 come back in leap years
+	next = NextOccurrence(date(2024, time.February, 29), models.CadenceYearly, 29)
+	assert.Equal(t, date(2025, time.February, 28), next)
+	for next.Year() < 2028 {
+		next = NextOccurrence(next, models.CadenceYearly, 29)
+	}
+	assert.Equal(t, date(2028, time.February, 29), next)
+
+	assert.Equal(t, date(2025, time.February, 7), NextOccurrence(date(2025, time.January, 31), models.CadenceWeekly, 31))
+}
+
+func TestRecurringSchedules(t *testing.T) {
+	store := repository.NewMemoryStore()
+	transactions := NewTransactionService(store.Transactions(), store.Categories(), store.Accounts(), store.Payees())
+	categories := NewCategoryService(store.Categories(), store.Transactions())
+	accounts := NewAccountService(store.Accounts(), store.Transactions())
+	recurring := NewRecurringService(store.Recurring(), transactions)
+
+	account, err := accounts.Create(models.BankAccountRequest{Name: "Checking", BankName: "Bank", AccountType: "checking", IsActive: true})
+	assert.NoError(t, err)
+	music, err := categories.Create(models.Category{Name: "Music", Type: "expense"})
+	assert.NoError(t, err)
+	for month := time.March; month <= time.May; month++ {
+		_, err := transactions.Create(models.Transaction{Amount: 119, Type: "expense", CategoryID: &music.ID, BankAccountID: account.ID,
+			Description: fmt.Sprintf("SPOTIFY P%d", month), Date: models.FlexibleDate{Time: time.Date(2024, month, 5, 0, 0, 0, 0, time.UTC)}})
+		assert.NoError(t, err)
+	}
+
+	// The June charge is overdue, so the schedule starts in July
+	now := time.Date(2024, time.June, 10, 12, 0, 0, 0, time.UTC)
+	schedule, err := recurring.Schedule("description:SPOTIFY", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.July, 5, 0, 0, 0, 0, time.UTC), schedule.NextDate)
+	assert.Equal(t, float64(119), schedule.Amount)
+	assert.Equal(t, &music.ID, schedule.CategoryID)
+
+	_, err = recurring.Schedule("description:SPOTIFY", now)
+	assertCode(t, err, http.StatusConflict, apperrors.CodeSubscriptionScheduled)
+	_, err = recurring.Schedule("description:NOTHING", now)
+	assertCode(t, err, http.StatusBadRequest, apperrors.CodeSubscriptionNotFound)
+
+	subscriptions, err := recurring.Subscriptions(now)
+	assert.NoError(t, err)
+	if assert.Len(t, subscriptions, 1) {
+		assert.Equal(t, &schedule.ID, subscriptions[0].ScheduleID)
+	}
+
+	// Charges missed while the server was down are caught up
+	pending := schedule
+	created, err := recurring.PostDue(time.Date(2024, time.August, 6, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	if assert.Len(t, created, 2) {
+		assert.Equal(t, time.Date(2024, time.July, 5, 0, 0, 0, 0, time.UTC), created[0].Date.Time)
+		assert.Equal(t, time.Date(2024, time.August, 5, 0, 0, 0, 0, time.UTC), created[1].Date.Time)
+		assert.Equal(t, "SPOTIFY P5", created[1].Description)
+	}
+	schedule, err = recurring.Get(schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.September, 5, 0, 0, 0, 0, time.UTC), schedule.NextDate)
+
+	created, err = recurring.PostDue(time.Date(2024, time.August, 7, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Empty(t, created)
+
+	// A server that read the schedule before it advanced does not post the charge again
+	_, posted, err := recurring.(*recurringService).post(pending, pending.NextDate)
+	assert.NoError(t, err)
+	assert.False(t, posted)
+
+	// A charge that cannot be created leaves its schedule due
+	broken := models.RecurringTransaction{Description: "GYM", Amount: 30, BankAccountID: 999, Cadence: models.CadenceMonthly,
+		NextDate: time.Date(2024, time.August, 1, 0, 0, 0, 0, time.UTC)}
+	assert.NoError(t, store.Recurring().Create(&broken))
+	created, err = recurring.PostDue(time.Date(2024, time.August, 7, 0, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+	assert.Empty(t, created)
+	broken, err = recurring.Get(broken.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.August, 1, 0, 0, 0, 0, time.UTC), broken.NextDate)
+	all, err := transactions.List(repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 5)
+
+	assert.NoError(t, recurring.Delete(schedule.ID))
+	_, err = recurring.Get(schedule.ID)
+	assertCode(t, err, http.StatusNotFound, apperrors.CodeRecurringNotFound)
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	day := func(n int) models.FlexibleDate {
+		return models.FlexibleDate{Time: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)}
+	}
+	food, rent, cafe := uint(1), uint(2), uint(3)
+	names := map[uint]string{food: "Food", rent: "Rent"}
+	var history []models.Transaction
+	add := func(categoryID uint, payeeID *uint, amount float64, on models.FlexibleDate) models.Transaction {
+		tx := models.Transaction{ID: uint(len(history) + 1), Type: "expense", Amount: amount, CategoryID: &categoryID, Category: models.Category{ID: categoryID, Name: names[categoryID]}, Description: "Expense", Date: on}
+		if payeeID != nil {
+			tx.PayeeID = payeeID
+			tx.Payee = models.Payee{ID: *payeeID, Name: "Cafe"}
+		}
+		history = append(history, tx)
+		return tx
+	}
+
+	// Old amounts fall out of the trailing window
+	add(food, nil, 500, day(-200))
+	for i, amount := range []float64{20, 22, 25, 18, 21, 24, 19, 23} {
+		add(food, nil, amount, day(-60+7*i))
+	}
+	for i := 0; i < 6; i++ {
+		add(rent, nil, 1000, day(-150+30*i))
+	}
+	for i, amount := range []float64{4, 4.5, 4, 5, 4.5} {
+		add(food, &cafe, amount, day(-20+i))
+	}
+
+	feast := add(food, nil, 110, day(0))
+	usual := add(food, nil, 26, day(0))
+	raise := add(rent, nil, 1200, day(30))
+	bigCoffee := add(food, &cafe, 19, day(1))
+	income := models.Transaction{ID: 99, Type: "income", Amount: 5000, Date: day(0)}
+
+	anomalies := DetectAnomalies([]models.Transaction{feast, usual, raise, bigCoffee, income}, history, DefaultAnomalySettings())
+	if !assert.Len(t, anomalies, 3) {
+		return
+	}
+
+	// Identical rents get a spread of 1% of the median, so a 20% raise stands out most
+	assert.Equal(t, raise.ID, anomalies[0].Transaction.ID)
+	assert.Equal(t, float64(20), anomalies[0].Score)
+
+	// The coffee is unusual for the café, not for food
+	assert.Equal(t, bigCoffee.ID, anomalies[1].Transaction.ID)
+	if assert.Len(t, anomalies[1].Reasons, 1) {
+		assert.Equal(t, models.AnomalyScopePayee, anomalies[1].Reasons[0].Scope)
+		assert.Equal(t, cafe, anomalies[1].Reasons[0].ID)
+		assert.Equal(t, 4.5, anomalies[1].Reasons[0].Median)
+	}
+
+	assert.Equal(t, feast.ID, anomalies[2].Transaction.ID)
+	if assert.Len(t, anomalies[2].Reasons, 1) {
+		reason := anomalies[2].Reasons[0]
+		assert.Equal(t, models.AnomalyScopeCategory, reason.Scope)
+		assert.Equal(t, "Food", reason.Name)
+		assert.Equal(t, 14, reason.Samples) // the café coffees are food too
+		assert.Equal(t, 19.5, reason.Median)
+		assert.Equal(t, 5.64, reason.Ratio)
+	}
+
+	// Too little history to judge
+	settings := DefaultAnomalySettings()
+	settings.MinSamples = 20
+	assert.Empty(t, DetectAnomalies([]models.Transaction{feast}, history, settings))
+}
+
+func TestAccountDeleteRequiresNoTransactions(t *testing.T) {
+	transactions, categories, accounts := newTestServices(t)
+
+	account, err := accounts.Create(models.BankAccountRequest{Name: "Checking", BankName: "Bank", AccountType: "checking", IsActive: true})
+	assert.NoError(t, err)
+
+	salary, err := categories.Create(models.Category{Name: "Salary", Type: "income"})
+	assert.NoError(t, err)
+
+	_, err = transactions.Create(models.Transaction{Amount: 100, Description: "Pay", Type: "income", CategoryID: &salary.ID, BankAccountID: account.ID})
+	assert.NoError(t, err)
+
+	err = accounts.Delete(account.ID)
+	assertCode(t, err, http.StatusConflict, apperrors.CodeBankAccountInUse)
+}
+
+func TestAccountBalancesCanBeSetToZero(t *testing.T) {
+	_, _, accounts := newTestServices(t)
+	amount := func(value float64) *float64 { return &value }
+
+	// An explicit opening balance of 0 is kept; an omitted one starts at the balance
+	account, err := accounts.Create(models.BankAccountRequest{Name: "Checking", BankName: "Bank", AccountType: "checking", IsActive: true, Balance: amount(250), OpeningBalance: amount(0)})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, account.OpeningBalance)
+	savings, err := accounts.Create(models.BankAccountRequest{Name: "Savings", BankName: "Bank", AccountType: "savings", IsActive: true, Balance: amount(80)})
+	assert.NoError(t, err)
+	assert.Equal(t, 80.0, savings.OpeningBalance)
+
+	updated, err := accounts.Update(savings.ID, models.BankAccountRequest{IsActive: true, Balance: amount(0)})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, updated.Balance)
+	assert.Equal(t, 80.0, updated.OpeningBalance)
+
+	updated, err = accounts.Update(savings.ID, models.BankAccountRequest{IsActive: true, OpeningBalance: amount(0)})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, updated.OpeningBalance)
+}
+
+func TestCreateBulkTracesEachStep(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	transactions, categories, accounts := newTestServices(t)
+	account, err := accounts.Create(models.BankAccountRequest{Name: "Checking", BankName: "Bank", AccountType: "checking", IsActive: true})
+	assert.NoError(t, err)
+	food, err := categories.Create(models.Category{Name: "Food", Type: "expense"})
+	assert.NoError(t, err)
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "request")
+	result, err := transactions.WithContext(ctx).CreateBulk(models.BulkTransactionRequest{Transactions: []models.Transaction{
+		{Amount: 10, Description: "Lunch", Type: "expense", CategoryID: &food.ID, BankAccountID: account.ID},
+		{Amount: 20, Description: "Unknown account", Type: "expense", CategoryID: &food.ID, BankAccountID: 99},
+		{Amount: 30, Description: "Dinner", Type: "expense", CategoryID: &food.ID, BankAccountID: account.ID},
+	}}, false)
+	request.End()
+	assert.NoError(t, err)
+	assert.Len(t, result.Created, 2)
+
+	spans := exporter.GetSpans()
+	byName := map[string][]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	if !assert.Len(t, byName["bulk.create"], 1) {
+		return
+	}
+	bulk := byName["bulk.create"][0]
+	assert.Equal(t, request.SpanContext().SpanID(), bulk.Parent.SpanID())
+
+	rows := byName["bulk.validate_row"]
+	if assert.Len(t, rows, 3) {
+		for i, row := range rows {
+			assert.Equal(t, bulk.SpanContext.SpanID(), row.Parent.SpanID())
+			assert.Contains(t, row.Attributes, attribute.Int("bulk.row", i))
+		}
+		assert.Equal(t, codes.Error, rows[1].Status.Code)
+		assert.Equal(t, apperrors.CodeBankAccountNotFound, rows[1].Status.Description)
+	}
+	assert.Len(t, byName["bulk.insert"], 1)
+	assert.Len(t, byName["bulk.reload"], 1)
+	assert.Contains(t, bulk.Attributes, attribute.Int("bulk.failed", 1))
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"expense-api/models"
+)
+
+const (
+	// priceTolerance is the relative difference up to which two charges have the same price
+	priceTolerance = 0.02
+	// maxPriceRatio bounds a price change between two charges of a subscription
+	maxPriceRatio = 1.5
+)
+
+// cadence describes the interval between the charges of a subscription
+type cadence struct {
+	name             string
+	minDays, maxDays float64
+	// minCharges is the number of charges needed before a series counts
+	minCharges int
+	// graceDays is how late a charge may be before the subscription counts as stopped
+	graceDays int
+	perYear   float64
+}
+
+// cadences are tried in order against the median interval of a series
+var cadences = []cadence{
+	{name: models.CadenceWeekly, minDays: 5, maxDays: 9, minCharges: 4, graceDays: 3, perYear: 52},
+	{name: models.CadenceMonthly, minDays: 26, maxDays: 35, minCharges: 3, graceDays: 10, perYear: 12},
+	{name: models.CadenceYearly, minDays: 350, maxDays: 380, minCharges: 2, graceDays: 31, perYear: 1},
+}
+
+// NextOccurrence returns the date one period of the cadence after date. Monthly
+// and yearly dates fall on anchorDay, the day of the month the series is charged
+// on, or on the last day of a shorter month; an anchorDay of 0 keeps the day of
+// date. Passing the anchor keeps a series charged on the 31st from drifting to
+// the 28th after February.
+func NextOccurrence(date time.Time, cadenceName string, anchorDay int) time.Time {
+	if cadenceName == models.CadenceWeekly {
+		return date.AddDate(0, 0, 7)
+	}
+	if anchorDay <= 0 {
+		anchorDay = date.Day()
+	}
+
+	year, month := date.Year(), date.Month()+1
+	if cadenceName == models.CadenceYearly {
+		year, month = year+1, date.Month()
+	}
+	// Day 0 of the following month is the last day of this one
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	hour, minute, second := date.Clock()
+	return time.Date(year, month, min(anchorDay, lastDay), hour, minute, second, date.Nanosecond(), date.Location())
+}
+
+// DetectSubscriptions finds the series of expenses with the same payee, or the
+// same description once reference numbers are ignored, charged at a weekly,
+// monthly or yearly cadence. Amounts may change now and then, as prices do. The
+// subscriptions are ordered by annual cost, the most expensive first.
+func DetectSubscriptions(transactions []models.Transaction, now time.Time) []models.Subscription {
+	groups := make(map[string][]models.Transaction)
+	var keys []string
+	for _, t := range transactions {
+		if t.Type != "expense" {
+			continue
+		}
+		key := subscriptionKey(t)
+		if key == "" {
+			continue
+		}
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], t)
+	}
+
+	subscriptions := []models.Subscription{}
+	for _, key := range keys {
+		charges := groups[key]
+		sortByDate(charges)
+		if subscription, ok := detectSeries(key, charges, now); ok {
+			subscriptions = append(subscriptions, subscription)
+			continue
+		}
+
+		// A payee may bill a subscription besides other purchases, e.g. a
+		// yearly membership between orders, so each price is tried on its own
+		bands := amountBands(charges)
+		if len(bands) < 2 {
+			continue
+		}
+		for _, band := range bands {
+			bandKey := fmt.Sprintf("%s@%.2f", key, band[0].Amount)
+			sortByDate(band)
+			if subscription, ok := detectSeries(bandKey, band, now); ok {
+				subscriptions = append(subscriptions, subscription)
+			}
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if a.AnnualCost != b.AnnualCost {
+			return a.AnnualCost > b.AnnualCost
+		}
+		return a.Key < b.Key
+	})
+	return subscriptions
+}
+
+// subscriptionKey groups the charges of one subscription: by payee, or else by
+// the words of the description without digits, which are usually references
+func subscriptionKey(t models.Transaction) string {
+	if t.PayeeID != nil {
+		return fmt.Sprintf("payee:%d", *t.PayeeID)
+	}
+
+	var words []string
+	for _, word := range descriptionWords(t.Description) {
+		if !strings.ContainsFunc(word, unicode.IsDigit) {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	return "description:" + strings.Join(words, " ")
+}
+
+// sortByDate orders charges by date, then by ID
+func sortByDate(charges []models.Transaction) {
+	sort.SliceStable(charges, func(i, j int) bool {
+		a, b := charges[i], charges[j]
+		if !a.Date.Time.Equal(b.Date.Time) {
+			return a.Date.Time.Before(b.Date.Time)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// amountBands splits charges into groups of the same price
+func amountBands(charges []models.Transaction) [][]models.Transaction {
+	sorted := append([]models.Transaction(nil), charges...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Amount < sorted[j].Amount })
+
+	var bands [][]models.Transaction
+	for _, t := range sorted {
+		last := len(bands) - 1
+		if last >= 0 && t.Amount <= bands[last][0].Amount*(1+priceTolerance) {
+			bands[last] = append(bands[last], t)
+			continue
+		}
+		bands = append(bands, []models.Transaction{t})
+	}
+	return bands
+}
+
+// detectSeries reports whether charges, ordered by date, form a subscription
+func detectSeries(key string, charges []models.Transaction, now time.Time) (models.Subscription, bool) {
+	if len(charges) < 2 {
+		return models.Subscription{}, false
+	}
+
+	intervals := make([]float64, len(charges)-1)
+	for i := 1; i < len(charges); i++ {
+		intervals[i-1] = charges[i].Date.Time.Sub(charges[i-1].Date.Time).Hours() / 24
+	}
+	c, ok := matchCadence(median(intervals))
+	if !ok || len(charges) < c.minCharges {
+		return models.Subscription{}, false
+	}
+
+	// A missed or late charge is tolerated as long as three in four intervals fit
+	regular := 0
+	for _, days := range intervals {
+		if days >= c.minDays && days <= c.maxDays {
+			regular++
+		}
+	}
+	if regular*4 < len(intervals)*3 {
+		return models.Subscription{}, false
+	}
+
+	// Prices change now and then; amounts that change at most charges are ordinary spending
+	changes := []models.PriceChange{}
+	total := charges[0].Amount
+	for i := 1; i < len(charges); i++ {
+		previous, current := charges[i-1].Amount, charges[i].Amount
+		total += current
+		if math.Abs(current-previous) <= previous*priceTolerance {
+			continue
+		}
+		if current > previous*maxPriceRatio || previous > current*maxPriceRatio {
+			return models.Subscription{}, false
+		}
+		changes = append(changes, models.PriceChange{Date: charges[i].Date.Time, OldAmount: previous, NewAmount: current})
+	}
+	if len(changes) > max(1, len(intervals)/3) {
+		return models.Subscription{}, false
+	}
+
+	last := charges[len(charges)-1]
+	name := last.Description
+	if last.Payee.Name != "" {
+		name = last.Payee.Name
+	}
+	next := NextOccurrence(last.Date.Time, c.name, charges[0].Date.Day())
+	return models.Subscription{
+		Key:           key,
+		Name:          name,
+		PayeeID:       last.PayeeID,
+		CategoryID:    last.CategoryID,
+		BankAccountID: last.BankAccountID,
+		Cadence:       c.name,
+		Occurrences:   len(charges),
+		AverageAmount: RoundCents(total / float64(len(charges))),
+		LastAmount:    last.Amount,
+		FirstCharge:   charges[0].Date.Time,
+		LastCharge:    last.Date.Time,
+		NextExpected:  next,
+		PriceChanges:  changes,
+		Active:        now.Before(next.AddDate(0, 0, c.graceDays)),
+		AnnualCost:    RoundCents(last.Amount * c.perYear),
+	}, true
+}
+
+// matchCadence returns the cadence whose interval range holds days
+func matchCadence(days float64) (cadence, bool) {
+	for _, c := range cadences {
+		if days >= c.minDays && days <= c.maxDays {
+			return c, true
+		}
+	}
+	return cadence{}, false
+}
+
+// median returns the middle value of values, which is not empty
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// RoundCents rounds an amount to two decimals
+func RoundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,655 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"expense-api/apperrors"
+	"expense-api/logging"
+	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/tracing"
+	"expense-api/validation"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// transactionService implements TransactionService on top of repositories
+type transactionService struct {
+	// ctx parents the spans of bulk creates
+	ctx          context.Context
+	transactions repository.TransactionRepository
+	categories   repository.CategoryRepository
+	accounts     repository.AccountRepository
+	payees       repository.PayeeRepository
+}
+
+// NewTransactionService creates a TransactionService
+func NewTransactionService(transactions repository.TransactionRepository, categories repository.CategoryRepository, accounts repository.AccountRepository, payees repository.PayeeRepository) TransactionService {
+	return &transactionService{ctx: context.Background(), transactions: transactions, categories: categories, accounts: accounts, payees: payees}
+}
+
+// WithContext returns a service whose queries run with ctx
+func (s *transactionService) WithContext(ctx context.Context) TransactionService {
+	return &transactionService{
+		ctx:          ctx,
+		transactions: s.transactions.WithContext(ctx),
+		categories:   s.categories.WithContext(ctx),
+		accounts:     s.accounts.WithContext(ctx),
+		payees:       s.payees.WithContext(ctx),
+	}
+}
+
+// transactionNotFound is returned when a transaction ID does not exist
+func transactionNotFound() *apperrors.Error {
+	return apperrors.NotFound(apperrors.CodeTransactionNotFound, "Transaction not found")
+}
+
+// bulkBatchSize is the number of rows sent per insert when creating transactions in bulk
+const bulkBatchSize = 500
+
+// Create validates and stores an expense, income or transfer transaction
+func (s *transactionService) Create(transaction models.Transaction) (models.Transaction, error) {
+	if err := s.prepare(&transaction, newLookupCache(s.categories, s.accounts, s.payees)); err != nil {
+		return models.Transaction{}, err
+	}
+
+	if err := s.transactions.Create(&transaction); err != nil {
+		return models.Transaction{}, apperrors.Internal("Failed to create transaction", err)
+	}
+
+	return s.reload(transaction.ID)
+}
+
+// prepare validates a new transaction against the stored categories and accounts,
+// fills in its default date, payee and category and clears the fields its type does not use
+func (s *transactionService) prepare(transaction *models.Transaction, lookups *lookupCache) *apperrors.Error {
+	if err := validation.Struct(transaction); err != nil {
+		return apperrors.Validation(err)
+	}
+
+	// Set default date if not provided
+	if transaction.Date.IsZero() {
+		transaction.Date = models.FlexibleDate{Time: time.Now()}
+	}
+
+	// Validate bank account exists
+	if _, err := lookups.account(transaction.BankAccountID); err != nil {
+		return apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeBankAccountNotFound, "Bank account not found"))
+	}
+
+	// Validate based on transaction type
+	if transaction.Type == "transfer" {
+		// For transfers, category is not required but destination account is
+		if transaction.DestinationBankAccountID == nil {
+			return apperrors.BadRequest(apperrors.CodeDestinationAccountRequired, "Destination bank account is required for transfers")
+		}
+
+		// Validate destination bank account exists
+		if _, err := lookups.account(*transaction.DestinationBankAccountID); err != nil {
+			return apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeDestinationAccountNotFound, "Destination bank account not found"))
+		}
+
+		// Cannot transfer to the same account
+		if transaction.BankAccountID == *transaction.DestinationBankAccountID {
+			return apperrors.BadRequest(apperrors.CodeSameAccountTransfer, "Cannot transfer to the same bank account")
+		}
+
+		// Set category and payee to nil for transfers
+		transaction.CategoryID = nil
+		transaction.PayeeID = nil
+	} else {
+		// Link the payee the description names, whose default category applies when none is given
+		if err := resolvePayee(transaction, lookups); err != nil {
+			return err
+		}
+
+		// For expense/income, category is required
+		if transaction.CategoryID == nil {
+			return apperrors.BadRequest(apperrors.CodeCategoryRequired, "Category is required for expense and income transactions")
+		}
+
+		category, err := lookups.category(*transaction.CategoryID)
+		if err != nil {
+			return apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeCategoryNotFound, "Category not found"))
+		}
+		if category.Type != transaction.Type {
+			return apperrors.BadRequest(apperrors.CodeCategoryTypeMismatch, "Category type does not match transaction type")
+		}
+
+		// Destination account should not be set for expense/income
+		transaction.DestinationBankAccountID = nil
+	}
+
+	return nil
+}
+
+// resolvePayee checks the payee of an expense or income transaction, or matches
+// one from its description, and fills in the payee's default category when the
+// transaction has none and the category has the transaction's type
+func resolvePayee(transaction *models.Transaction, lookups *lookupCache) *apperrors.Error {
+	matcher, err := lookups.payeeMatcher()
+	if err != nil {
+		return apperrors.Internal("Failed to load payees", err)
+	}
+
+	var payee models.Payee
+	if transaction.PayeeID != nil {
+		found, ok := matcher.Get(*transaction.PayeeID)
+		if !ok {
+			return apperrors.BadRequest(apperrors.CodePayeeNotFound, "Payee not found")
+		}
+		payee = found
+	} else if found, ok := matcher.Match(transaction.Description); ok {
+		payee = found
+		transaction.PayeeID = &payee.ID
+	} else {
+		return nil
+	}
+
+	if transaction.CategoryID == nil && payee.DefaultCategoryID != nil {
+		if category, err := lookups.category(*payee.DefaultCategoryID); err == nil && category.Type == transaction.Type {
+			transaction.CategoryID = &category.ID
+		}
+	}
+	return nil
+}
+
+// checkPayee verifies that a payee exists
+func (s *transactionService) checkPayee(payeeID uint) *apperrors.Error {
+	if _, err := s.payees.FindByID(payeeID); err != nil {
+		return apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodePayeeNotFound, "Payee not found"))
+	}
+	return nil
+}
+
+// checkCategory verifies that a category exists and matches the transaction type
+func (s *transactionService) checkCategory(categoryID uint, transactionType string) *apperrors.Error {
+	category, err := s.categories.FindByID(categoryID)
+	if err != nil {
+		return apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeCategoryNotFound, "Category not found"))
+	}
+
+	if category.Type != transactionType {
+		return apperrors.BadRequest(apperrors.CodeCategoryTypeMismatch, "Category type does not match transaction type")
+	}
+
+	return nil
+}
+
+// reload fetches a transaction with its relations after a write
+func (s *transactionService) reload(id uint) (models.Transaction, error) {
+	transaction, err := s.transactions.FindByID(id)
+	if err != nil {
+		return models.Transaction{}, apperrors.Lookup(err, transactionNotFound())
+	}
+	return transaction, nil
+}
+
+// Get returns a transaction
+func (s *transactionService) Get(id uint) (models.Transaction, error) {
+	return s.reload(id)
+}
+
+// GetMany returns the transactions that exist among ids, ordered by ID
+func (s *transactionService) GetMany(ids []uint) ([]models.Transaction, error) {
+	transactions, err := s.transactions.FindByIDs(ids)
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch transactions", err)
+	}
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID < transactions[j].ID })
+	return transactions, nil
+}
+
+// List returns the transactions matching filter
+func (s *transactionService) List(filter repository.TransactionFilter) ([]models.Transaction, error) {
+	transactions, err := s.transactions.List(filter)
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch transactions", err)
+	}
+	return transactions, nil
+}
+
+// Update changes the given fields of an expense or income transaction
+func (s *transactionService) Update(id uint, fields map[string]interface{}) (models.Transaction, error) {
+	transaction, err := s.reload(id)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	// Validate transaction type and amount if provided
+	if transactionType, exists := fields["type"]; exists {
+		if err := validation.Var("type", transactionType, "category_type"); err != nil {
+			return models.Transaction{}, apperrors.Validation(err)
+		}
+	}
+	if amount, exists := fields["amount"]; exists {
+		if err := validation.Var("amount", amount, "positive"); err != nil {
+			return models.Transaction{}, apperrors.Validation(err)
+		}
+	}
+
+	// Verify category exists and matches type if category_id is being updated
+	if categoryID, exists := fields["category_id"]; exists {
+		id, ok := categoryID.(float64)
+		if !ok || id <= 0 {
+			return models.Transaction{}, apperrors.BadRequest(apperrors.CodeCategoryNotFound, "Category not found")
+		}
+
+		// Check if type is being updated and matches category type
+		transactionType := transaction.Type
+		if t, exists := fields["type"]; exists {
+			transactionType = t.(string)
+		}
+
+		if err := s.checkCategory(uint(id), transactionType); err != nil {
+			return models.Transaction{}, err
+		}
+	}
+
+	// Verify the payee exists if payee_id is being set; null unlinks the payee
+	if value, exists := fields["payee_id"]; exists && value != nil {
+		id, ok := value.(float64)
+		if !ok || id < 1 || id != float64(uint(id)) {
+			return models.Transaction{}, apperrors.BadRequest(apperrors.CodeInvalidParameter, "payee_id must be a positive integer or null")
+		}
+		if err := s.checkPayee(uint(id)); err != nil {
+			return models.Transaction{}, err
+		}
+	}
+
+	if err := s.transactions.Update(transaction.ID, fields); err != nil {
+		return models.Transaction{}, apperrors.Internal("Failed to update transaction", err)
+	}
+
+	return s.reload(transaction.ID)
+}
+
+// UpdateCategory moves a transaction to another category of the same type
+func (s *transactionService) UpdateCategory(id, categoryID uint) (models.Transaction, error) {
+	transaction, err := s.reload(id)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	if err := validation.Var("category_id", categoryID, "required"); err != nil {
+		return models.Transaction{}, apperrors.Validation(err)
+	}
+
+	if err := s.checkCategory(categoryID, transaction.Type); err != nil {
+		return models.Transaction{}, err
+	}
+
+	if err := s.transactions.Update(transaction.ID, map[string]interface{}{"category_id": categoryID}); err != nil {
+		return models.Transaction{}, apperrors.Internal("Failed to update transaction category", err)
+	}
+
+	return s.reload(transaction.ID)
+}
+
+// Delete removes a transaction
+func (s *transactionService) Delete(id uint) error {
+	if _, err := s.reload(id); err != nil {
+		return err
+	}
+
+	if err := s.transactions.Delete(id); err != nil {
+		return apperrors.Internal("Failed to delete transaction", err)
+	}
+	return nil
+}
+
+// findWithDeleted returns a transaction whether or not it is deleted
+func (s *transactionService) findWithDeleted(id uint) (models.Transaction, error) {
+	transaction, err := s.transactions.FindWithDeleted(id)
+	if err != nil {
+		return models.Transaction{}, apperrors.Lookup(err, transactionNotFound())
+	}
+	return transaction, nil
+}
+
+// Deleted lists the deleted transactions, most recently deleted first
+func (s *transactionService) Deleted() ([]models.Transaction, error) {
+	transactions, err := s.transactions.ListDeleted()
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch deleted transactions", err)
+	}
+	return transactions, nil
+}
+
+// History returns every recorded version of a transaction, oldest first
+func (s *transactionService) History(id uint) ([]models.TransactionVersion, error) {
+	transaction, err := s.findWithDeleted(id)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := s.transactions.Versions(transaction.ID)
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch transaction history", err)
+	}
+	return versions, nil
+}
+
+// Restore brings a transaction back to one of its versions and undeletes it
+func (s *transactionService) Restore(id uint, versionNumber int) (models.Transaction, error) {
+	transaction, err := s.findWithDeleted(id)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	version, err := s.transactions.FindVersion(transaction.ID, versionNumber)
+	if err != nil {
+		return models.Transaction{}, apperrors.Lookup(err, apperrors.NotFound(apperrors.CodeTransactionVersionNotFound, "Transaction version not found"))
+	}
+
+	// The referenced accounts and category must still exist to restore the snapshot
+	if _, err := s.accounts.FindByID(version.BankAccountID); err != nil {
+		return models.Transaction{}, apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeBankAccountNotFound, "Bank account of this version no longer exists"))
+	}
+	if version.DestinationBankAccountID != nil {
+		if _, err := s.accounts.FindByID(*version.DestinationBankAccountID); err != nil {
+			return models.Transaction{}, apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeDestinationAccountNotFound, "Destination bank account of this version no longer exists"))
+		}
+	}
+	if version.CategoryID != nil {
+		if _, err := s.categories.FindByID(*version.CategoryID); err != nil {
+			return models.Transaction{}, apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeCategoryNotFound, "Category of this version no longer exists"))
+		}
+	}
+
+	if err := s.transactions.Restore(version); err != nil {
+		return models.Transaction{}, apperrors.Internal("Failed to restore transaction", err)
+	}
+
+	return s.reload(transaction.ID)
+}
+
+// Undelete brings back a deleted transaction
+func (s *transactionService) Undelete(id uint) (models.Transaction, error) {
+	transaction, err := s.findWithDeleted(id)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	if !transaction.DeletedAt.Valid {
+		return models.Transaction{}, apperrors.BadRequest(apperrors.CodeTransactionNotDeleted, "Transaction is not deleted")
+	}
+
+	if err := s.transactions.Undelete(transaction.ID); err != nil {
+		return models.Transaction{}, apperrors.Internal("Failed to undelete transaction", err)
+	}
+
+	return s.reload(transaction.ID)
+}
+
+// CreateBulk validates every row, then inserts the valid ones in batches.
+// In atomic mode nothing is stored unless every row is valid, and the rows are
+// inserted in a single database transaction.
+func (s *transactionService) CreateBulk(request models.BulkTransactionRequest, atomic bool) (BulkCreateResult, error) {
+	var result BulkCreateResult
+
+	// Each step gets a span, so a slow import shows whether lookups or inserts take the time
+	ctx, span := tracing.Tracer().Start(s.ctx, "bulk.create", trace.WithAttributes(
+		attribute.Int("bulk.rows", len(request.Transactions)),
+		attribute.Bool("bulk.atomic", atomic),
+	))
+	defer span.End()
+
+	if err := validation.Struct(&request); err != nil {
+		return result, apperrors.Validation(err)
+	}
+
+	lookups := newLookupCache(s.categories, s.accounts, s.payees)
+	var valid []models.Transaction
+	var indexes []int // request index of each valid row
+	for i, transaction := range request.Transactions {
+		rowCtx, rowSpan := tracing.Tracer().Start(ctx, "bulk.validate_row", trace.WithAttributes(attribute.Int("bulk.row", i)))
+		failure := s.prepare(&transaction, lookups.withContext(rowCtx))
+		if failure != nil {
+			rowSpan.SetStatus(codes.Error, failure.Code)
+			rowSpan.End()
+			result.Failed = append(result.Failed, bulkTransactionError(i, transaction, failure))
+			continue
+		}
+		rowSpan.End()
+		valid = append(valid, transaction)
+		indexes = append(indexes, i)
+	}
+	span.SetAttributes(attribute.Int("bulk.failed", len(result.Failed)))
+
+	if len(valid) == 0 || (atomic && len(result.Failed) > 0) {
+		return result, nil
+	}
+
+	insertCtx, insertSpan := tracing.Tracer().Start(ctx, "bulk.insert", trace.WithAttributes(attribute.Int("bulk.rows", len(valid))))
+	transactions := s.transactions.WithContext(insertCtx)
+	if atomic {
+		err := transactions.Atomic(func(transactions repository.TransactionRepository) error {
+			return transactions.CreateBatch(valid, bulkBatchSize)
+		})
+		if err != nil {
+			insertSpan.RecordError(err)
+			insertSpan.SetStatus(codes.Error, "atomic insert rolled back")
+			insertSpan.End()
+			return result, apperrors.Internal("Failed to create transactions", err)
+		}
+	} else if err := transactions.CreateBatch(valid, bulkBatchSize); err != nil {
+		// A failed batch is rolled back as a whole, so retry row by row to find the culprits
+		logging.For(logging.ComponentServices).WarnContext(ctx, "bulk create: batch insert failed, retrying one by one", "error", err)
+		insertSpan.AddEvent("batch insert failed, retrying one by one")
+		valid = s.createEach(insertCtx, valid, indexes, &result)
+		span.SetAttributes(attribute.Int("bulk.failed", len(result.Failed)))
+	}
+	insertSpan.End()
+
+	reloadCtx, reloadSpan := tracing.Tracer().Start(ctx, "bulk.reload")
+	created, err := s.reloadAll(reloadCtx, valid)
+	reloadSpan.End()
+	if err != nil {
+		return result, err
+	}
+	result.Created = created
+	return result, nil
+}
+
+// createEach inserts transactions one at a time, recording failures under the
+// request index of the row, and returns the ones that were stored
+func (s *transactionService) createEach(ctx context.Context, transactions []models.Transaction, indexes []int, result *BulkCreateResult) []models.Transaction {
+	var stored []models.Transaction
+	for i, transaction := range transactions {
+		rowCtx, span := tracing.Tracer().Start(ctx, "bulk.insert_row", trace.WithAttributes(attribute.Int("bulk.row", indexes[i])))
+		if err := s.transactions.WithContext(rowCtx).Create(&transaction); err != nil {
+			logging.For(logging.ComponentServices).ErrorContext(ctx, "bulk create: failed to create transaction", "index", indexes[i], "error", err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "insert failed")
+			span.End()
+			result.Failed = append(result.Failed, bulkTransactionError(indexes[i], transaction, apperrors.Internal("Failed to create transaction", err)))
+			continue
+		}
+		span.End()
+		stored = append(stored, transaction)
+	}
+	sort.Slice(result.Failed, func(i, j int) bool { return result.Failed[i].Index < result.Failed[j].Index })
+	return stored
+}
+
+// reloadAll fetches stored transactions with their relations, keeping their order
+func (s *transactionService) reloadAll(ctx context.Context, transactions []models.Transaction) ([]models.Transaction, error) {
+	ids := make([]uint, len(transactions))
+	for i, transaction := range transactions {
+		ids[i] = transaction.ID
+	}
+
+	loaded, err := s.transactions.WithContext(ctx).FindByIDs(ids)
+	if err != nil {
+		return nil, apperrors.Internal("Failed to load created transactions", err)
+	}
+
+	byID := make(map[uint]models.Transaction, len(loaded))
+	for _, transaction := range loaded {
+		byID[transaction.ID] = transaction
+	}
+	for i, transaction := range transactions {
+		if reloaded, ok := byID[transaction.ID]; ok {
+			transactions[i] = reloaded
+		}
+	}
+	return transactions, nil
+}
+
+// bulkTransactionError describes why a row of a bulk request was rejected
+func bulkTransactionError(index int, transaction models.Transaction, failure *apperrors.Error) models.BulkTransactionError {
+	message := failure.Detail
+	if failure.Code == apperrors.CodeValidationFailed && failure.Err != nil {
+		message = failure.Err.Error()
+	}
+	return models.BulkTransactionError{
+		Index:       index,
+		Transaction: transaction,
+		Code:        failure.Code,
+		Error:       message,
+		Fields:      failure.Fields,
+	}
+}
+
+// DeleteBulk deletes the requested transactions with a single statement.
+// In atomic mode nothing is deleted unless every ID exists, and the delete runs
+// in a database transaction.
+func (s *transactionService) DeleteBulk(request models.BulkDeleteRequest, atomic bool) (BulkDeleteResult, error) {
+	var result BulkDeleteResult
+
+	if err := validation.Struct(&request); err != nil {
+		return result, apperrors.Validation(err)
+	}
+
+	// Check which transactions exist with one query
+	existing, err := s.transactions.FindByIDs(request.TransactionIDs)
+	if err != nil {
+		return result, apperrors.Internal("Failed to query the database", err)
+	}
+	found := make(map[uint]bool, len(existing))
+	for _, transaction := range existing {
+		found[transaction.ID] = true
+	}
+
+	var ids []uint
+	for _, transactionID := range request.TransactionIDs {
+		if !found[transactionID] {
+			failure := transactionNotFound()
+			result.Failed = append(result.Failed, models.BulkDeleteError{
+				TransactionID: transactionID,
+				Code:          failure.Code,
+				Error:         failure.Detail,
+			})
+			continue
+		}
+		// Skip IDs repeated in the request
+		found[transactionID] = false
+		ids = append(ids, transactionID)
+	}
+
+	if len(ids) == 0 || (atomic && len(result.Failed) > 0) {
+		return result, nil
+	}
+
+	if atomic {
+		err := s.transactions.Atomic(func(transactions repository.TransactionRepository) error {
+			return transactions.DeleteBatch(ids)
+		})
+		if err != nil {
+			return result, apperrors.Internal("Failed to delete transactions", err)
+		}
+		result.Deleted = ids
+		return result, nil
+	}
+
+	if err := s.transactions.DeleteBatch(ids); err != nil {
+		// Fall back to one delete per ID to find the ones that fail
+		logging.For(logging.ComponentServices).WarnContext(s.ctx, "bulk delete: batch delete failed, retrying one by one", "error", err)
+		for _, transactionID := range ids {
+			if err := s.transactions.Delete(transactionID); err != nil {
+				logging.For(logging.ComponentServices).ErrorContext(s.ctx, "bulk delete: failed to delete transaction", "transaction_id", transactionID, "error", err)
+				result.Failed = append(result.Failed, models.BulkDeleteError{
+					TransactionID: transactionID,
+					Code:          apperrors.CodeInternal,
+					Error:         "Failed to delete transaction",
+				})
+				continue
+			}
+			result.Deleted = append(result.Deleted, transactionID)
+		}
+		return result, nil
+	}
+
+	result.Deleted = ids
+	return result, nil
+}
+
+// CreateTransfer moves money between two different bank accounts
+func (s *transactionService) CreateTransfer(request models.TransferRequest) (models.Transaction, error) {
+	if err := validation.Struct(&request); err != nil {
+		return models.Transaction{}, apperrors.Validation(err)
+	}
+
+	// Validate source and destination accounts are different
+	if request.BankAccountID == request.DestinationBankAccountID {
+		return models.Transaction{}, apperrors.BadRequest(apperrors.CodeSameAccountTransfer, "Cannot transfer to the same bank account")
+	}
+
+	// Validate source bank account exists
+	if _, err := s.accounts.FindByID(request.BankAccountID); err != nil {
+		return models.Transaction{}, apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeBankAccountNotFound, "Source bank account not found"))
+	}
+
+	// Validate destination bank account exists
+	if _, err := s.accounts.FindByID(request.DestinationBankAccountID); err != nil {
+		return models.Transaction{}, apperrors.Lookup(err, apperrors.BadRequest(apperrors.CodeDestinationAccountNotFound, "Destination bank account not found"))
+	}
+
+	// Set default date if not provided
+	if request.Date.IsZero() {
+		request.Date = models.FlexibleDate{Time: time.Now()}
+	}
+
+	transaction := models.Transaction{
+		TransactionID:            request.TransactionID,
+		Amount:                   request.Amount,
+		Type:                     "transfer",
+		CategoryID:               nil, // Transfers don't have categories
+		BankAccountID:            request.BankAccountID,
+		DestinationBankAccountID: &request.DestinationBankAccountID,
+		Description:              request.Description,
+		Date:                     request.Date,
+	}
+
+	if err := s.transactions.Create(&transaction); err != nil {
+		return models.Transaction{}, apperrors.Internal("Failed to create transfer", err)
+	}
+
+	return s.reload(transaction.ID)
+}
+
+// Summary returns the transaction counts and totals with the latest transactions
+func (s *transactionService) Summary(recent int) (Summary, error) {
+	var summary Summary
+	var err error
+
+	if summary.All, err = s.transactions.Totals(""); err != nil {
+		return summary, apperrors.Internal("Failed to compute summary", err)
+	}
+	if summary.Expenses, err = s.transactions.Totals("expense"); err != nil {
+		return summary, apperrors.Internal("Failed to compute summary", err)
+	}
+	if summary.Income, err = s.transactions.Totals("income"); err != nil {
+		return summary, apperrors.Internal("Failed to compute summary", err)
+	}
+	if summary.Recent, err = s.transactions.Recent(recent); err != nil {
+		return summary, apperrors.Internal("Failed to compute summary", err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"expense-api/admin"
+	"expense-api/models"
+	"expense-api/repository"
+)
+
+// userService implements UserService on top of a repository
+type userService struct {
+	users repository.UserRepository
+}
+
+// NewUserService creates a UserService
+func NewUserService(users repository.UserRepository) UserService {
+	return &userService{users: users}
+}
+
+// WithContext returns a service whose queries run with ctx
+func (s *userService) WithContext(ctx context.Context) UserService {
+	return NewUserService(s.users.WithContext(ctx))
+}
+
+// Authenticate returns the user an API token was issued to. Only the hash of
+// the token is looked up, as only the hash is stored.
+func (s *userService) Authenticate(token string) (models.User, bool, error) {
+	if token == "" {
+		return models.User{}, false, nil
+	}
+	user, err := s.users.FindByTokenHash(admin.HashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.User{}, false, nil
+	}
+	if err != nil {
+		return models.User{}, false, err
+	}
+	return user, true, nil
+}
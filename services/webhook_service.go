@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"expense-api/apperrors"
+	"expense-api/models"
+	"expense-api/repository"
+	"expense-api/validation"
+	"expense-api/webhooks"
+)
+
+// WebhookSender sends a test event to a subscription and returns the recorded delivery
+type WebhookSender func(ctx context.Context, subscription models.WebhookSubscription) (models.WebhookDelivery, error)
+
+// webhookService implements WebhookService on top of a repository
+type webhookService struct {
+	// ctx is passed to send
+	ctx      context.Context
+	webhooks repository.WebhookRepository
+	send     WebhookSender
+}
+
+// NewWebhookService creates a WebhookService. send may be nil when test events cannot be sent.
+func NewWebhookService(webhooks repository.WebhookRepository, send WebhookSender) WebhookService {
+	return &webhookService{ctx: context.Background(), webhooks: webhooks, send: send}
+}
+
+// WithContext returns a service whose queries run with ctx
+func (s *webhookService) WithContext(ctx context.Context) WebhookService {
+	return &webhookService{ctx: ctx, webhooks: s.webhooks.WithContext(ctx), send: s.send}
+}
+
+// webhookNotFound is returned when a subscription ID does not exist
+func webhookNotFound() *apperrors.Error {
+	return apperrors.NotFound(apperrors.CodeWebhookNotFound, "Webhook subscription not found")
+}
+
+// validateWebhookRequest checks the URL and event filter of a subscription request
+func validateWebhookRequest(request models.WebhookSubscriptionRequest) *apperrors.Error {
+	if err := validation.Struct(&request); err != nil {
+		return apperrors.Validation(err)
+	}
+
+	var errs validation.Errors
+	for i, event := range request.Events {
+		if !webhooks.IsKnownEvent(event) {
+			errs = append(errs, models.FieldError{
+				Field:   fmt.Sprintf("events[%d]", i),
+				Code:    "webhook_event",
+				Message: "must be '*' or one of: " + strings.Join(webhooks.KnownEvents, ", "),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return apperrors.Validation(errs)
+	}
+
+	return nil
+}
+
+// Create validates and stores a subscription, generating its secret if none is given
+func (s *webhookService) Create(userID uint, request models.WebhookSubscriptionRequest) (models.WebhookSubscription, error) {
+	if err := validateWebhookRequest(request); err != nil {
+		return models.WebhookSubscription{}, err
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		generated, err := webhooks.GenerateSecret()
+		if err != nil {
+			return models.WebhookSubscription{}, apperrors.Internal("Failed to generate webhook secret", err)
+		}
+		secret = generated
+	}
+
+	subscription := models.WebhookSubscription{
+		UserID:   userID,
+		URL:      request.URL,
+		Secret:   secret,
+		Events:   strings.Join(request.Events, ","),
+		IsActive: request.IsActive == nil || *request.IsActive,
+	}
+
+	if err := s.webhooks.Create(&subscription); err != nil {
+		return models.WebhookSubscription{}, apperrors.Internal("Failed to create webhook subscription", err)
+	}
+	return subscription, nil
+}
+
+// Get returns a subscription of userID
+func (s *webhookService) Get(userID, id uint) (models.WebhookSubscription, error) {
+	subscription, err := s.webhooks.FindByID(id)
+	if err != nil {
+		return models.WebhookSubscription{}, apperrors.Lookup(err, webhookNotFound())
+	}
+	if subscription.UserID != userID {
+		return models.WebhookSubscription{}, webhookNotFound()
+	}
+	return subscription, nil
+}
+
+// List returns the subscriptions of userID ordered by ID
+func (s *webhookService) List(userID uint) ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.webhooks.List(userID)
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch webhook subscriptions", err)
+	}
+	return subscriptions, nil
+}
+
+// Update changes the fields set in request; omitted fields keep their values
+func (s *webhookService) Update(userID, id uint, request models.WebhookSubscriptionRequest) (models.WebhookSubscription, error) {
+	subscription, err := s.Get(userID, id)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+
+	if request.URL == "" {
+		request.URL = subscription.URL
+	}
+	if len(request.Events) == 0 {
+		request.Events = webhooks.Events(subscription)
+	}
+
+	if err := validateWebhookRequest(request); err != nil {
+		return models.WebhookSubscription{}, err
+	}
+
+	subscription.URL = request.URL
+	subscription.Events = strings.Join(request.Events, ",")
+	if request.Secret != "" {
+		subscription.Secret = request.Secret
+	}
+	if request.IsActive != nil {
+		subscription.IsActive = *request.IsActive
+	}
+
+	if err := s.webhooks.Save(&subscription); err != nil {
+		return models.WebhookSubscription{}, apperrors.Internal("Failed to update webhook subscription", err)
+	}
+	return subscription, nil
+}
+
+// Delete removes a subscription; its pending deliveries are not sent
+func (s *webhookService) Delete(userID, id uint) error {
+	if _, err := s.Get(userID, id); err != nil {
+		return err
+	}
+
+	if err := s.webhooks.Delete(id); err != nil {
+		return apperrors.Internal("Failed to delete webhook subscription", err)
+	}
+	return nil
+}
+
+// Deliveries returns up to limit deliveries of a subscription, newest first
+func (s *webhookService) Deliveries(userID, id uint, status string, limit int) ([]models.WebhookDelivery, error) {
+	subscription, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != "" && status != webhooks.StatusPending && status != webhooks.StatusSucceeded && status != webhooks.StatusFailed {
+		return nil, apperrors.BadRequest(apperrors.CodeInvalidParameter, "Status must be either 'pending', 'succeeded', or 'failed'")
+	}
+
+	deliveries, err := s.webhooks.Deliveries(subscription.ID, status, limit)
+	if err != nil {
+		return nil, apperrors.Internal("Failed to fetch webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+// Test sends a webhook.test event to a subscription now. A failed attempt is
+// reported in the returned delivery rather than as an error.
+func (s *webhookService) Test(userID, id uint) (models.WebhookDelivery, error) {
+	subscription, err := s.Get(userID, id)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	if s.send == nil {
+		return models.WebhookDelivery{}, apperrors.Unavailable()
+	}
+
+	delivery, err := s.send(s.ctx, subscription)
+	if err != nil && delivery.ID == 0 {
+		return models.WebhookDelivery{}, apperrors.Internal("Failed to send test webhook", err)
+	}
+	return delivery, nil
+}
@@ -0,0 +1,188 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type item struct {
+	ID   uint
+	Name string
+}
+
+// setupTracing records the spans of the test in memory
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&item{}))
+	assert.NoError(t, db.Create(&item{Name: "first"}).Error)
+	assert.NoError(t, db.Use(GormPlugin()))
+	return db
+}
+
+func spanNamed(spans tracetest.SpanStubs, name string) (tracetest.SpanStub, bool) {
+	for _, span := range spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return tracetest.SpanStub{}, false
+}
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareContinuesIncomingTrace(t *testing.T) {
+	exporter := setupTracing(t)
+	db := setupTestDB(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		var found item
+		if err := db.WithContext(c.UserContext()).First(&found, c.Params("id")).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(found)
+	})
+
+	req := httptest.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	spans := exporter.GetSpans()
+	server, ok := spanNamed(spans, "GET /items/:id")
+	if !assert.True(t, ok, "no server span in %v", spans) {
+		return
+	}
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.True(t, server.Parent.IsRemote())
+	assert.Equal(t, "/items/:id", attributeValue(server, "http.route").AsString())
+	assert.Equal(t, int64(200), attributeValue(server, "http.response.status_code").AsInt64())
+
+	query, ok := spanNamed(spans, "query items")
+	if !assert.True(t, ok, "no query span in %v", spans) {
+		return
+	}
+	assert.Equal(t, trace.SpanKindClient, query.SpanKind)
+	assert.Equal(t, server.SpanContext.SpanID(), query.Parent.SpanID())
+	assert.Equal(t, "sqlite", attributeValue(query, "db.system").AsString())
+	assert.Contains(t, attributeValue(query, "db.query.text").AsString(), "FROM `items`")
+}
+
+func TestMiddlewareMarksServerErrors(t *testing.T) {
+	exporter := setupTracing(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return errors.New("boom")
+	})
+
+	for _, path := range []string{"/fail", "/unknown/1"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	spans := exporter.GetSpans()
+	failed, ok := spanNamed(spans, "GET /fail")
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.Error, failed.Status.Code)
+		assert.Equal(t, int64(500), attributeValue(failed, "http.response.status_code").AsInt64())
+		assert.Len(t, failed.Events, 1) // the recorded error
+	}
+
+	// Unknown paths do not get a span name of their own
+	unmatched, ok := spanNamed(spans, "GET")
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.Unset, unmatched.Status.Code)
+		assert.Equal(t, int64(404), attributeValue(unmatched, "http.response.status_code").AsInt64())
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	exporter := setupTracing(t)
+	db := setupTestDB(t)
+
+	// Queries outside a span, such as those of the job workers, are not recorded
+	assert.NoError(t, db.First(&item{}).Error)
+	assert.Empty(t, exporter.GetSpans())
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+	assert.NoError(t, db.WithContext(ctx).Create(&item{Name: "second"}).Error)
+	assert.ErrorIs(t, db.WithContext(ctx).First(&item{}, 99).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing").Error)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 4)
+
+	create, ok := spanNamed(spans, "create items")
+	if assert.True(t, ok) {
+		assert.Equal(t, parent.SpanContext().SpanID(), create.Parent.SpanID())
+		// Values are sent as parameters, so they never show up in the span
+		assert.NotContains(t, attributeValue(create, "db.query.text").AsString(), "second")
+	}
+
+	// A missing record is an expected outcome, not an error
+	query, ok := spanNamed(spans, "query items")
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.Unset, query.Status.Code)
+	}
+
+	raw, ok := spanNamed(spans, "gorm.raw")
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.Error, raw.Status.Code)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_SDK_DISABLED", "")
+	assert.False(t, Enabled())
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	assert.True(t, Enabled())
+
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	assert.False(t, Enabled())
+}
@@ -0,0 +1,180 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"expense-api/models"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Allowed values for the custom enum rules
+var (
+	TransactionTypes = []string{"expense", "income", "transfer"}
+	CategoryTypes    = []string{"expense", "income"}
+	AccountTypes     = []string{"checking", "savings", "credit", "investment", "other"}
+)
+
+// Errors lists every invalid field of a validated value
+type Errors []models.FieldError
+
+// Error joins the field messages into a single line
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, field := range e {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+var validate = newValidator()
+
+// newValidator creates a validator that reports JSON field names and knows the custom rules
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	v.RegisterValidation("transaction_type", oneOf(TransactionTypes))
+	v.RegisterValidation("category_type", oneOf(CategoryTypes))
+	v.RegisterValidation("account_type", oneOf(AccountTypes))
+	v.RegisterValidation("positive", func(fl validator.FieldLevel) bool {
+		switch fl.Field().Kind() {
+		case reflect.Float32, reflect.Float64:
+			return fl.Field().Float() > 0
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return fl.Field().Int() > 0
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return fl.Field().Uint() > 0
+		}
+		return false
+	})
+
+	return v
+}
+
+// oneOf builds a rule that accepts only the given string values
+func oneOf(allowed []string) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		value := fl.Field().String()
+		for _, candidate := range allowed {
+			if value == candidate {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Struct validates a struct against its validate tags
+func Struct(value interface{}) error {
+	return convert(validate.Struct(value))
+}
+
+// Var validates a single value against a tag, reporting failures under the given field name
+func Var(field string, value interface{}, tag string) error {
+	err := convert(validate.Var(value, tag))
+	if errs, ok := err.(Errors); ok {
+		for i := range errs {
+			errs[i].Field = field
+		}
+	}
+	return err
+}
+
+// Fields returns the field errors held by err, or nil if err is not a validation error
+func Fields(err error) []models.FieldError {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return nil
+}
+
+// convert turns validator errors into Errors with stable codes and readable messages
+func convert(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	errs := make(Errors, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		errs = append(errs, models.FieldError{
+			Field:   fieldPath(fieldErr),
+			Code:    fieldErr.Tag(),
+			Message: message(fieldErr),
+		})
+	}
+	return errs
+}
+
+// fieldPath drops the struct name from the namespace, e.g. TransferRequest.amount -> amount
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if _, rest, found := strings.Cut(namespace, "."); found {
+		return rest
+	}
+	return fieldErr.Field()
+}
+
+// message renders a human readable explanation of a failed rule
+func message(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	collection := fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map || fieldErr.Kind() == reflect.Array
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if collection {
+			return fmt.Sprintf("must contain at least %s items", param)
+		}
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", param)
+		}
+		return "must be at least " + param
+	case "max":
+		if collection {
+			return fmt.Sprintf("must contain at most %s items", param)
+		}
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", param)
+		}
+		return "must be at most " + param
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be greater than or equal to " + param
+	case "positive":
+		return "must be greater than 0"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(param, " ", ", ")
+	case "transaction_type":
+		return "must be one of: " + strings.Join(TransactionTypes, ", ")
+	case "category_type":
+		return "must be one of: " + strings.Join(CategoryTypes, ", ")
+	case "account_type":
+		return "must be one of: " + strings.Join(AccountTypes, ", ")
+	case "url", "http_url":
+		return "must be a valid http or https URL"
+	}
+
+	return "is invalid"
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"testing"
+
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructReportsEveryInvalidField(t *testing.T) {
+	err := Struct(&models.TransferRequest{Amount: -5})
+
+	fields := Fields(err)
+	assert.ElementsMatch(t, []models.FieldError{
+		{Field: "amount", Code: "positive", Message: "must be greater than 0"},
+		{Field: "bank_account_id", Code: "required", Message: "is required"},
+		{Field: "destination_bank_account_id", Code: "required", Message: "is required"},
+		{Field: "description", Code: "required", Message: "is required"},
+	}, fields)
+}
+
+func TestStructEnforcesCollectionBounds(t *testing.T) {
+	fields := Fields(Struct(&models.BulkTransactionRequest{}))
+	assert.Equal(t, []models.FieldError{{Field: "transactions", Code: "required", Message: "is required"}}, fields)
+
+	fields = Fields(Struct(&models.BulkDeleteRequest{TransactionIDs: make([]uint, 1001)}))
+	assert.Equal(t, []models.FieldError{{Field: "transaction_ids", Code: "max", Message: "must contain at most 1000 items"}}, fields)
+}
+
+func TestCustomRules(t *testing.T) {
+	fields := Fields(Struct(&models.Transaction{Amount: 10, Type: "refund", BankAccountID: 1}))
+	assert.Equal(t, []models.FieldError{{Field: "type", Code: "transaction_type", Message: "must be one of: expense, income, transfer"}}, fields)
+
+	fields = Fields(Struct(&models.BankAccount{Name: "Main", BankName: "Bank", AccountType: "piggy"}))
+	assert.Equal(t, "account_type", fields[0].Code)
+
+	fields = Fields(Struct(&models.Category{Name: "Refunds", Type: "transfer"}))
+	assert.Equal(t, "category_type", fields[0].Code)
+}
+
+func TestValidStructPasses(t *testing.T) {
+	assert.NoError(t, Struct(&models.Transaction{Amount: 10, Type: "expense", BankAccountID: 1}))
+	assert.Nil(t, Fields(nil))
+}
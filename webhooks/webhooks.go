@@ -0,0 +1,426 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"expense-api/events"
+	"expense-api/logging"
+	"expense-api/models"
+
+	"gorm.io/gorm"
+)
+
+// EventTest is sent by the test-fire endpoint
+const EventTest = "webhook.test"
+
+// AllEvents matches every event in a subscription filter
+const AllEvents = "*"
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// KnownEvents lists the event names accepted in subscription filters
+var KnownEvents = events.Types
+
+// Payload is the JSON body posted to subscribers
+type Payload struct {
+	DeliveryID uint        `json:"delivery_id"`
+	Event      string      `json:"event"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Data       interface{} `json:"data"`
+}
+
+// IsKnownEvent reports whether name can be used in a subscription filter
+func IsKnownEvent(name string) bool {
+	if name == AllEvents {
+		return true
+	}
+	for _, event := range KnownEvents {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Events returns the event names in a subscription's filter
+func Events(subscription models.WebhookSubscription) []string {
+	events := []string{}
+	for _, event := range strings.Split(subscription.Events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Matches reports whether a subscription's event filter includes the event
+func Matches(subscription models.WebhookSubscription, event string) bool {
+	for _, filter := range Events(subscription) {
+		if filter == AllEvents || filter == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 signature of body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a random secret for a new subscription
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Enqueue queues a delivery of each event for every active subscription that
+// matches it and whose owner may see it. The subscriptions are loaded once for the whole batch, and the
+// deliveries are stored in one database transaction, so a batch that fails
+// can be retried without queueing any delivery twice.
+func Enqueue(db *gorm.DB, batch ...events.Event) error {
+	var subscriptions []models.WebhookSubscription
+	if err := db.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, event := range batch {
+			for _, subscription := range subscriptions {
+				if !event.VisibleTo(subscription.UserID) || !Matches(subscription, event.Type) {
+					continue
+				}
+				if _, err := createDelivery(tx, subscription, event.Type, event.Data); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Notifier queues webhook deliveries for the events published on a bus. A
+// single worker stores them in batches, so publishers never wait for the
+// database; they only block while the buffer is full.
+type Notifier struct {
+	DB func() *gorm.DB
+	// BatchSize is the most events stored in one database transaction
+	BatchSize int
+	// RetryInterval is how long the worker waits before retrying a batch it could not store
+	RetryInterval time.Duration
+
+	events chan events.Event
+}
+
+// NewNotifier creates a notifier that buffers up to capacity events
+func NewNotifier(db func() *gorm.DB, capacity int) *Notifier {
+	return &Notifier{
+		DB:            db,
+		BatchSize:     100,
+		RetryInterval: 5 * time.Second,
+		events:        make(chan events.Event, capacity),
+	}
+}
+
+// Listen buffers every event published on the bus for Run
+func (n *Notifier) Listen(bus *events.Bus) {
+	bus.Listen(func(event events.Event) {
+		n.events <- event
+	})
+}
+
+// Run queues deliveries for the buffered events until ctx is cancelled, then
+// stores what is still buffered. A batch that fails is retried until it is
+// stored or ctx is cancelled, so a database outage does not lose events.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			for batch := n.drain(nil); len(batch) > 0; batch = n.drain(nil) {
+				if err := n.store(batch); err != nil {
+					logging.For(logging.ComponentWebhooks).Error("Dropped webhook events on shutdown", "events", len(batch), "error", err)
+				}
+			}
+			return
+		case event := <-n.events:
+			n.storeRetrying(ctx, n.drain([]events.Event{event}))
+		}
+	}
+}
+
+// storeRetrying stores a batch, retrying every RetryInterval until it succeeds or ctx is cancelled
+func (n *Notifier) storeRetrying(ctx context.Context, batch []events.Event) {
+	for {
+		err := n.store(batch)
+		if err == nil {
+			return
+		}
+		logging.For(logging.ComponentWebhooks).ErrorContext(ctx, "Failed to enqueue webhook events, retrying", "events", len(batch), "error", err)
+
+		select {
+		case <-ctx.Done():
+			logging.For(logging.ComponentWebhooks).Error("Dropped webhook events on shutdown", "events", len(batch), "error", err)
+			return
+		case <-time.After(n.RetryInterval):
+		}
+	}
+}
+
+// drain adds the events already buffered to batch, up to BatchSize
+func (n *Notifier) drain(batch []events.Event) []events.Event {
+	for len(batch) < max(n.BatchSize, 1) {
+		select {
+		case event := <-n.events:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// store queues deliveries for a batch; events published before the database is ready are skipped
+func (n *Notifier) store(batch []events.Event) error {
+	db := n.DB()
+	if db == nil {
+		return nil
+	}
+	return Enqueue(db, batch...)
+}
+
+// createDelivery stores a pending delivery with its rendered payload
+func createDelivery(db *gorm.DB, subscription models.WebhookSubscription, event string, data interface{}) (models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		Event:          event,
+		Payload:        "{}",
+		Status:         StatusPending,
+		NextAttemptAt:  time.Now().UTC(),
+	}
+
+	return delivery, db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+
+		// The payload embeds the delivery ID so receivers can deduplicate retries
+		body, err := json.Marshal(Payload{
+			DeliveryID: delivery.ID,
+			Event:      event,
+			CreatedAt:  delivery.CreatedAt.UTC(),
+			Data:       data,
+		})
+		if err != nil {
+			return err
+		}
+		delivery.Payload = string(body)
+
+		return tx.Model(&delivery).Update("payload", delivery.Payload).Error
+	})
+}
+
+// Dispatcher sends queued deliveries and schedules retries with exponential backoff.
+// A dispatcher claims each delivery before sending it, so several replicas can share one queue.
+type Dispatcher struct {
+	DB          *gorm.DB
+	Client      *http.Client
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	BatchSize   int
+	// ClaimTimeout is how long a claimed delivery is held before another dispatcher may retry it
+	ClaimTimeout time.Duration
+}
+
+// NewDispatcher creates a dispatcher with default retry settings
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		DB:          db,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: 8,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+		BatchSize:   50,
+		// Longer than an attempt can take with the client timeout
+		ClaimTimeout: time.Minute,
+	}
+}
+
+// Backoff returns the delay before the next attempt after the given number of attempts
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// Run processes due deliveries every interval until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.ProcessDue(ctx); err != nil {
+			logging.For(logging.ComponentWebhooks).ErrorContext(ctx, "Webhook dispatcher error", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue attempts every pending delivery whose next attempt is due and returns how many were attempted
+func (d *Dispatcher) ProcessDue(ctx context.Context) (int, error) {
+	var deliveries []models.WebhookDelivery
+	if err := d.DB.Where("status = ? AND next_attempt_at <= ?", StatusPending, time.Now().UTC()).
+		Order("next_attempt_at ASC").Limit(d.BatchSize).Find(&deliveries).Error; err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for i := range deliveries {
+		claimed, err := d.claim(&deliveries[i])
+		if err != nil {
+			return attempted, err
+		}
+		if !claimed {
+			continue
+		}
+		if err := d.Deliver(ctx, &deliveries[i]); err != nil {
+			return attempted, err
+		}
+		attempted++
+	}
+
+	return attempted, nil
+}
+
+// claim reserves a due delivery by moving its next attempt ClaimTimeout into the future.
+// Only one of several dispatchers racing for the same delivery gets it.
+func (d *Dispatcher) claim(delivery *models.WebhookDelivery) (bool, error) {
+	now := time.Now().UTC()
+	result := d.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, StatusPending, now).
+		Update("next_attempt_at", now.Add(d.ClaimTimeout))
+	return result.RowsAffected > 0, result.Error
+}
+
+// Deliver makes one attempt at sending a delivery and records the outcome.
+// Deliveries of deleted or inactive subscriptions are marked failed without being sent.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	var subscription models.WebhookSubscription
+	if err := d.DB.Unscoped().First(&subscription, delivery.SubscriptionID).Error; err != nil {
+		return err
+	}
+
+	if subscription.DeletedAt.Valid || !subscription.IsActive {
+		delivery.Status = StatusFailed
+		delivery.LastError = "subscription is inactive"
+		if subscription.DeletedAt.Valid {
+			delivery.LastError = "subscription was deleted"
+		}
+		return d.DB.Save(delivery).Error
+	}
+
+	delivery.Attempts++
+	statusCode, sendErr := d.send(ctx, subscription, delivery)
+	delivery.LastStatusCode = statusCode
+
+	if sendErr == nil {
+		now := time.Now().UTC()
+		delivery.Status = StatusSucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.LastError = sendErr.Error()
+		if delivery.Attempts >= d.MaxAttempts {
+			delivery.Status = StatusFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().UTC().Add(d.Backoff(delivery.Attempts))
+		}
+	}
+
+	return d.DB.Save(delivery).Error
+}
+
+// send posts the signed payload and treats any non-2xx response as a failure
+func (d *Dispatcher) send(ctx context.Context, subscription models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, fmt.Sprint(delivery.ID))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// SendTest queues a test event for one subscription and attempts it immediately
+func (d *Dispatcher) SendTest(ctx context.Context, subscription models.WebhookSubscription) (models.WebhookDelivery, error) {
+	delivery, err := createDelivery(d.DB, subscription, EventTest, map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"message":         "This is a test webhook delivery",
+	})
+	if err != nil {
+		return delivery, err
+	}
+
+	// Another dispatcher that claimed it first sends it instead
+	claimed, err := d.claim(&delivery)
+	if err != nil || !claimed {
+		return delivery, err
+	}
+
+	err = d.Deliver(ctx, &delivery)
+	return delivery, err
+}
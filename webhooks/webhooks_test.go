@@ -0,0 +1,294 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"expense-api/events"
+	"expense-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{})
+	assert.NoError(t, err)
+
+	return db
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func TestEnqueueAndDeliverSignedPayload(t *testing.T) {
+	db := setupTestDB(t)
+
+	var mu sync.Mutex
+	var received []receivedRequest
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedRequest{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	subscriptions := []models.WebhookSubscription{
+		{URL: receiver.URL, Secret: "s3cret", Events: events.TransactionCreated, IsActive: true},
+		{URL: receiver.URL, Secret: "other", Events: events.AccountUpdated, IsActive: true},
+	}
+	for i := range subscriptions {
+		assert.NoError(t, db.Create(&subscriptions[i]).Error)
+	}
+
+	assert.NoError(t, Enqueue(db, events.Event{Type: events.TransactionCreated, Data: map[string]interface{}{"id": 7}}))
+
+	dispatcher := NewDispatcher(db)
+	attempted, err := dispatcher.ProcessDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	assert.Len(t, received, 1)
+	request := received[0]
+	assert.Equal(t, events.TransactionCreated, request.header.Get(HeaderEvent))
+	assert.Equal(t, Sign("s3cret", request.body), request.header.Get(HeaderSignature))
+
+	var payload Payload
+	assert.NoError(t, json.Unmarshal(request.body, &payload))
+	assert.Equal(t, events.TransactionCreated, payload.Event)
+	assert.NotZero(t, payload.DeliveryID)
+
+	var delivery models.WebhookDelivery
+	assert.NoError(t, db.First(&delivery, payload.DeliveryID).Error)
+	assert.Equal(t, StatusSucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusNoContent, delivery.LastStatusCode)
+	assert.NotNil(t, delivery.DeliveredAt)
+}
+
+func TestEnqueueSkipsEventsTheOwnerMayNotSee(t *testing.T) {
+	db := setupTestDB(t)
+
+	subscriptions := []models.WebhookSubscription{
+		{UserID: 1, URL: "https://example.com/one", Secret: "s3cret", Events: AllEvents, IsActive: true},
+		{UserID: 2, URL: "https://example.com/two", Secret: "s3cret", Events: AllEvents, IsActive: true},
+	}
+	for i := range subscriptions {
+		assert.NoError(t, db.Create(&subscriptions[i]).Error)
+	}
+
+	assert.NoError(t, Enqueue(db,
+		events.Event{Type: events.TransactionCreated, Data: map[string]interface{}{"id": 1}, UserID: 1},
+		events.Event{Type: events.CategoryCreated, Data: map[string]interface{}{"id": 2}},
+	))
+
+	// Events of a user only reach their own subscriptions; unowned events reach everyone
+	for subscription, want := range map[uint]int64{subscriptions[0].ID: 2, subscriptions[1].ID: 1} {
+		var count int64
+		assert.NoError(t, db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscription).Count(&count).Error)
+		assert.Equal(t, want, count, subscription)
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	db := setupTestDB(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	subscription := models.WebhookSubscription{URL: receiver.URL, Secret: "s3cret", Events: AllEvents, IsActive: true}
+	assert.NoError(t, db.Create(&subscription).Error)
+	assert.NoError(t, Enqueue(db, events.Event{Type: events.TransactionDeleted, Data: map[string]interface{}{"id": 1}}))
+
+	dispatcher := NewDispatcher(db)
+	dispatcher.MaxAttempts = 2
+
+	_, err := dispatcher.ProcessDue(context.Background())
+	assert.NoError(t, err)
+
+	var delivery models.WebhookDelivery
+	assert.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, StatusPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, delivery.LastStatusCode)
+	assert.True(t, delivery.NextAttemptAt.After(time.Now().Add(20*time.Second)))
+
+	// Not due yet, so nothing is attempted
+	attempted, err := dispatcher.ProcessDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, attempted)
+
+	assert.NoError(t, dispatcher.Deliver(context.Background(), &delivery))
+	assert.Equal(t, StatusFailed, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+}
+
+func TestDeliverFailsInactiveAndDeletedSubscriptions(t *testing.T) {
+	db := setupTestDB(t)
+
+	sent := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	inactive := models.WebhookSubscription{URL: receiver.URL, Secret: "s3cret", Events: AllEvents, IsActive: true}
+	deleted := models.WebhookSubscription{URL: receiver.URL, Secret: "s3cret", Events: AllEvents, IsActive: true}
+	assert.NoError(t, db.Create(&inactive).Error)
+	assert.NoError(t, db.Create(&deleted).Error)
+	assert.NoError(t, Enqueue(db, events.Event{Type: events.TransactionCreated, Data: map[string]interface{}{"id": 1}}))
+
+	// Both are switched off after the event was queued
+	assert.NoError(t, db.Model(&inactive).Update("is_active", false).Error)
+	assert.NoError(t, db.Delete(&deleted).Error)
+
+	_, err := NewDispatcher(db).ProcessDue(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+
+	var deliveries []models.WebhookDelivery
+	assert.NoError(t, db.Order("subscription_id").Find(&deliveries).Error)
+	if assert.Len(t, deliveries, 2) {
+		assert.Equal(t, StatusFailed, deliveries[0].Status)
+		assert.Equal(t, "subscription is inactive", deliveries[0].LastError)
+		assert.Equal(t, StatusFailed, deliveries[1].Status)
+		assert.Equal(t, "subscription was deleted", deliveries[1].LastError)
+	}
+}
+
+func TestProcessDueClaimsDeliveries(t *testing.T) {
+	db := setupTestDB(t)
+
+	var mu sync.Mutex
+	sent := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	subscription := models.WebhookSubscription{URL: receiver.URL, Secret: "s3cret", Events: AllEvents, IsActive: true}
+	assert.NoError(t, db.Create(&subscription).Error)
+	assert.NoError(t, Enqueue(db, events.Event{Type: events.TransactionCreated, Data: map[string]interface{}{"id": 1}}))
+
+	// A replica loaded the delivery but another claimed it first
+	var delivery models.WebhookDelivery
+	assert.NoError(t, db.First(&delivery).Error)
+	other := NewDispatcher(db)
+	claimed, err := other.claim(&delivery)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	dispatcher := NewDispatcher(db)
+	claimed, err = dispatcher.claim(&delivery)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	attempted, err := dispatcher.ProcessDue(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, attempted)
+	assert.Zero(t, sent)
+
+	// The claim lapses if the other replica never finishes
+	assert.NoError(t, db.Model(&delivery).Update("next_attempt_at", time.Now().UTC().Add(-time.Second)).Error)
+	attempted, err = dispatcher.ProcessDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	assert.Equal(t, 1, sent)
+}
+
+func TestNotifierQueuesEventsInBatches(t *testing.T) {
+	db := setupTestDB(t)
+	// The worker uses its own goroutine, and every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	subscriptions := []models.WebhookSubscription{
+		{URL: "https://example.com/all", Secret: "s3cret", Events: AllEvents, IsActive: true},
+		{URL: "https://example.com/accounts", Secret: "s3cret", Events: events.AccountCreated, IsActive: true},
+	}
+	for i := range subscriptions {
+		assert.NoError(t, db.Create(&subscriptions[i]).Error)
+	}
+
+	var mu sync.Mutex
+	loads := 0
+	assert.NoError(t, db.Callback().Query().After("gorm:query").Register("test:count_subscription_loads", func(tx *gorm.DB) {
+		if tx.Statement.Table == "webhook_subscriptions" {
+			mu.Lock()
+			loads++
+			mu.Unlock()
+		}
+	}))
+	countDeliveries := func() int64 {
+		var count int64
+		assert.NoError(t, db.Model(&models.WebhookDelivery{}).Count(&count).Error)
+		return count
+	}
+
+	bus := events.NewBus(10)
+	notifier := NewNotifier(func() *gorm.DB { return db }, 10)
+	notifier.Listen(bus)
+
+	// Publishing only buffers the events; the worker stores them in one batch
+	for i := 0; i < 3; i++ {
+		bus.Publish(events.TransactionCreated, map[string]interface{}{"id": i})
+	}
+	assert.Zero(t, countDeliveries())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		notifier.Run(ctx)
+	}()
+	assert.Eventually(t, func() bool { return countDeliveries() == 3 }, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 1, loads)
+	mu.Unlock()
+
+	// Events still buffered at shutdown are stored before Run returns
+	cancel()
+	<-done
+	bus.Publish(events.AccountCreated, map[string]interface{}{"id": 1})
+	notifier.Run(ctx)
+	assert.Equal(t, int64(5), countDeliveries())
+}
+
+func TestBackoffIsExponentialAndCapped(t *testing.T) {
+	dispatcher := &Dispatcher{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	assert.Equal(t, time.Second, dispatcher.Backoff(1))
+	assert.Equal(t, 2*time.Second, dispatcher.Backoff(2))
+	assert.Equal(t, 8*time.Second, dispatcher.Backoff(4))
+	assert.Equal(t, 10*time.Second, dispatcher.Backoff(5))
+}
+
+func TestMatches(t *testing.T) {
+	subscription := models.WebhookSubscription{Events: "transaction.created, account.updated"}
+
+	assert.True(t, Matches(subscription, events.TransactionCreated))
+	assert.True(t, Matches(subscription, events.AccountUpdated))
+	assert.False(t, Matches(subscription, events.TransactionDeleted))
+	assert.True(t, Matches(models.WebhookSubscription{Events: AllEvents}, events.AccountDeleted))
+}